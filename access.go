@@ -0,0 +1,189 @@
+package kvsqlite
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// AccessStat is the access statistics of a key.
+type AccessStat struct {
+	Key            string
+	Count          int64
+	LastAccessedAt time.Time
+}
+
+type accessRecord struct {
+	count int64
+	last  int64
+}
+
+// accessTracker buffers access statistics in memory and writes them in batches,
+// so reads don't turn into writes one by one.
+type accessTracker struct {
+	sync.Mutex
+	store   *SQLite
+	pending map[string]*accessRecord
+
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func newAccessTracker(store *SQLite, interval time.Duration) *accessTracker {
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	t := &accessTracker{
+		store:   store,
+		pending: make(map[string]*accessRecord),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	go t.run(interval)
+	return t
+}
+
+func (t *accessTracker) run(interval time.Duration) {
+	defer close(t.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			t.flush()
+		case <-t.done:
+			t.flush()
+			return
+		}
+	}
+}
+
+// touch records an access to the given (prefixed) key.
+func (t *accessTracker) touch(key string) {
+	t.Lock()
+	defer t.Unlock()
+
+	r, ok := t.pending[key]
+	if !ok {
+		r = &accessRecord{}
+		t.pending[key] = r
+	}
+	r.count++
+	r.last = t.store.now()
+}
+
+// flush writes the pending statistics in a single transaction.
+func (t *accessTracker) flush() error {
+	t.Lock()
+	pending := t.pending
+	t.pending = make(map[string]*accessRecord)
+	t.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	return t.store.writeTx(context.Background(), func(db *tracer) error {
+		for key, r := range pending {
+			if _, err := db.Exec("UPDATE kv SET access_count = access_count + ?, last_accessed_at = MAX(last_accessed_at, ?) WHERE key = ?", r.count, r.last, key); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (t *accessTracker) stop() {
+	t.once.Do(func() {
+		close(t.done)
+		<-t.stopped
+	})
+}
+
+// FlushAccessStats writes the buffered access statistics to the database immediately.
+func (m *SQLite) FlushAccessStats() error {
+	if m.access == nil {
+		return nil
+	}
+
+	return m.access.flush()
+}
+
+// TopKeys returns the n most accessed keys, most accessed first.
+func (m *SQLite) TopKeys(n int) ([]AccessStat, error) {
+	if err := m.checkAccessAll(false); err != nil {
+		return nil, err
+	}
+
+	if err := m.FlushAccessStats(); err != nil {
+		return nil, err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	rows, err := m.db().Query("SELECT key, access_count, last_accessed_at FROM kv WHERE key LIKE ? ORDER BY access_count DESC, key LIMIT ?", m.Config.Prefix+"%", n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make([]AccessStat, 0)
+	for rows.Next() {
+		var key string
+		var count, last int64
+		if err := rows.Scan(&key, &count, &last); err != nil {
+			return nil, err
+		}
+
+		stat := AccessStat{
+			Key:   key[len(m.Config.Prefix):],
+			Count: count,
+		}
+		if last > 0 {
+			stat.LastAccessedAt = time.UnixMilli(last)
+		}
+		stats = append(stats, stat)
+	}
+
+	return stats, rows.Err()
+}
+
+// ColdKeys returns the keys which have not been accessed within olderThan, least recently accessed first.
+// Keys which have never been accessed are included.
+func (m *SQLite) ColdKeys(olderThan time.Duration) ([]string, error) {
+	if err := m.checkAccessAll(false); err != nil {
+		return nil, err
+	}
+
+	if err := m.FlushAccessStats(); err != nil {
+		return nil, err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	rows, err := m.db().Query("SELECT key FROM kv WHERE key LIKE ? AND last_accessed_at < ? ORDER BY last_accessed_at, key", m.Config.Prefix+"%", m.now()-olderThan.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]string, 0)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key[len(m.Config.Prefix):])
+	}
+
+	return keys, rows.Err()
+}
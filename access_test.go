@@ -0,0 +1,44 @@
+package kvsqlite
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAccessStats(t *testing.T) {
+	client, err := New(&SQLiteConfig{
+		Path:        "/tmp/test.db",
+		Prefix:      "go-zoox-test-access:",
+		TrackAccess: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.Clear()
+	defer client.Clear()
+
+	client.Set("hot", "value")
+	client.Set("cold", "value")
+
+	var value string
+	for i := 0; i < 3; i++ {
+		client.Get("hot", &value)
+	}
+
+	top, err := client.TopKeys(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) != 1 || top[0].Key != "hot" || top[0].Count != 3 {
+		t.Errorf("Expected hot to be accessed 3 times, got %v", top)
+	}
+
+	cold, err := client.ColdKeys(time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cold) != 1 || cold[0] != "cold" {
+		t.Errorf("Expected cold keys to be [cold], got %v", cold)
+	}
+}
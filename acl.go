@@ -0,0 +1,79 @@
+package kvsqlite
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrAccessDenied is returned when the access policy of the handle doesn't allow an operation, see AccessPolicy.
+var ErrAccessDenied = errors.New("sqlite: access denied")
+
+// AccessPolicy restricts the keys a handle may read and write by key prefix, relative to Config.Prefix,
+// so components embedding the same file, or sharing a prefix, can't stomp on each other's namespaces.
+// Operations over all keys which can't be restricted to the allowed prefixes, e.g. Clear or Size,
+// require access to all keys. Keys lists the readable keys only.
+type AccessPolicy struct {
+	// Read are the key prefixes the handle may read, nil for all keys. The keys it may write are readable too.
+	Read []string
+
+	// Write are the key prefixes the handle may write, nil for all keys, empty for none.
+	Write []string
+}
+
+// allows reports whether key may be read or written, i.e. starts with one of the prefixes.
+func allows(prefixes []string, key string) bool {
+	if prefixes == nil {
+		return true
+	}
+
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// canRead reports whether the handle may read the given key.
+func (m *SQLite) canRead(key string) bool {
+	policy := m.Config.Access
+	return policy == nil || allows(policy.Read, key) || (policy.Write != nil && allows(policy.Write, key))
+}
+
+// checkAccess checks whether the handle may read, or write, the given key.
+func (m *SQLite) checkAccess(key string, write bool) error {
+	policy := m.Config.Access
+	if policy == nil {
+		return nil
+	}
+
+	if write && !allows(policy.Write, key) {
+		return fmt.Errorf("%w: write of key %s", ErrAccessDenied, key)
+	}
+
+	if !write && !m.canRead(key) {
+		return fmt.Errorf("%w: read of key %s", ErrAccessDenied, key)
+	}
+
+	return nil
+}
+
+// checkAccessAll checks whether the handle may read, or write, all keys.
+func (m *SQLite) checkAccessAll(write bool) error {
+	policy := m.Config.Access
+	if policy == nil {
+		return nil
+	}
+
+	if write && policy.Write != nil {
+		return fmt.Errorf("%w: write of all keys", ErrAccessDenied)
+	}
+
+	if !write && policy.Read != nil {
+		return fmt.Errorf("%w: read of all keys", ErrAccessDenied)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package kvsqlite
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestAccessPolicy(t *testing.T) {
+	prefix := "go-zoox-test-acl:" + time.Now().String()
+	admin, err := New(&SQLiteConfig{Path: "/tmp/test.db", Prefix: prefix})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	defer admin.Clear()
+
+	billing, err := New(&SQLiteConfig{
+		Path:   "/tmp/test.db",
+		Prefix: prefix,
+		Access: &AccessPolicy{Read: []string{"users:"}, Write: []string{"billing:"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer billing.Close()
+
+	admin.Set("users:1", "alice")
+	admin.Set("sessions:1", "secret")
+
+	if err := billing.Set("billing:1", 42); err != nil {
+		t.Fatalf("Expected billing:1 to be writable, got %v", err)
+	}
+	if err := billing.Set("users:1", "mallory"); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Expected ErrAccessDenied writing users:1, got %v", err)
+	}
+	if err := billing.Delete("users:1"); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Expected ErrAccessDenied deleting users:1, got %v", err)
+	}
+
+	var value string
+	if err := billing.Get("users:1", &value); err != nil || value != "alice" {
+		t.Errorf("Expected users:1 to be readable, got %q (%v)", value, err)
+	}
+	var n int
+	if err := billing.Get("billing:1", &n); err != nil || n != 42 {
+		t.Errorf("Expected written keys to be readable, got %d (%v)", n, err)
+	}
+	if err := billing.Get("sessions:1", &value); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Expected ErrAccessDenied reading sessions:1, got %v", err)
+	}
+
+	if keys := billing.Keys(); len(keys) != 2 || keys[0] != "billing:1" || keys[1] != "users:1" {
+		t.Errorf("Expected only the readable keys, got %v", keys)
+	}
+
+	if _, err := billing.ExportRESP(io.Discard); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Expected ErrAccessDenied exporting, got %v", err)
+	}
+	if err := billing.BackupTo("/tmp/test-acl-backup.db"); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Expected ErrAccessDenied backing up, got %v", err)
+	}
+	if _, err := billing.QuarantineList(); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Expected ErrAccessDenied listing the quarantine, got %v", err)
+	}
+
+	if err := billing.Clear(); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Expected ErrAccessDenied clearing, got %v", err)
+	}
+	if _, err := billing.DeleteByPattern("*"); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Expected ErrAccessDenied deleting by pattern, got %v", err)
+	}
+	if admin.Size() != 3 {
+		t.Errorf("Expected the keys to be untouched, got %v", admin.Keys())
+	}
+
+	readOnly, err := New(&SQLiteConfig{
+		Path:   "/tmp/test.db",
+		Prefix: prefix,
+		Access: &AccessPolicy{Write: []string{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer readOnly.Close()
+
+	if err := readOnly.Set("any", 1); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Expected ErrAccessDenied writing with an empty write list, got %v", err)
+	}
+	if size, err := readOnly.SizeContext(context.Background()); err != nil || size != 3 {
+		t.Errorf("Expected to read all keys, got %d (%v)", size, err)
+	}
+}
@@ -0,0 +1,215 @@
+// Package admin is an embeddable web UI to browse the keys of a SQLite store,
+// view their decoded values and TTLs and delete them, protected by a token.
+package admin
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	kvsqlite "github.com/go-zoox/kv-sqlite"
+)
+
+// cookieName is the cookie the token is kept in after logging in with ?token=.
+const cookieName = "kvsqlite_admin_token"
+
+// maxListedKeys is the maximum number of keys listed on a page.
+const maxListedKeys = 500
+
+// Handler serves the admin UI. Mount it with http.StripPrefix when serving it under a path.
+type Handler struct {
+	Store *kvsqlite.SQLite
+	Token string
+}
+
+// New returns a new Handler for the given store, token is required.
+func New(store *kvsqlite.SQLite, token string) (*Handler, error) {
+	if token == "" {
+		return nil, errors.New("admin: token is required")
+	}
+
+	return &Handler{Store: store, Token: token}, nil
+}
+
+// ServeHTTP routes the requests of the UI.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// ?token= logs in the browser and is removed from the URL
+	if token := r.URL.Query().Get("token"); token != "" && h.valid(token) {
+		// SameSite keeps other sites from posting deletes with the cookie
+		http.SetCookie(w, &http.Cookie{Name: cookieName, Value: token, Path: "/", HttpOnly: true, SameSite: http.SameSiteStrictMode})
+
+		query := r.URL.Query()
+		query.Del("token")
+		target := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+		http.Redirect(w, r, target.String(), http.StatusSeeOther)
+		return
+	}
+
+	if !h.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch strings.TrimPrefix(r.URL.Path, "/") {
+	case "":
+		h.list(w, r)
+	case "key":
+		h.view(w, r)
+	case "delete":
+		h.delete(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *Handler) valid(token string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.Token)) == 1
+}
+
+func (h *Handler) authorized(r *http.Request) bool {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return h.valid(strings.TrimPrefix(auth, "Bearer "))
+	}
+
+	cookie, err := r.Cookie(cookieName)
+	return err == nil && h.valid(cookie.Value)
+}
+
+type listPage struct {
+	Query     string
+	Keys      []string
+	Total     int
+	Truncated bool
+}
+
+func (h *Handler) list(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.Store.KeysContext(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query().Get("q")
+	page := listPage{Query: query, Keys: make([]string, 0)}
+	for _, key := range keys {
+		if query != "" && !strings.Contains(key, query) {
+			continue
+		}
+		page.Keys = append(page.Keys, key)
+	}
+	sort.Strings(page.Keys)
+
+	page.Total = len(page.Keys)
+	if len(page.Keys) > maxListedKeys {
+		page.Keys = page.Keys[:maxListedKeys]
+		page.Truncated = true
+	}
+
+	render(w, listTemplate, page)
+}
+
+type keyPage struct {
+	Key       string
+	Value     string
+	ExpiresAt time.Time
+	TTL       time.Duration
+}
+
+func (h *Handler) view(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("k")
+
+	var raw json.RawMessage
+	found, err := h.Store.Lookup(key, &raw)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	page := keyPage{Key: key, Value: string(raw)}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, raw, "", "  "); err == nil {
+		page.Value = indented.String()
+	}
+
+	if expiresAt, err := h.Store.ExpiresAt(key); err == nil && !expiresAt.IsZero() {
+		page.ExpiresAt = expiresAt
+		page.TTL = time.Until(expiresAt).Round(time.Second)
+	}
+
+	render(w, keyTemplate, page)
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, err := h.Store.RemoveContext(r.Context(), r.FormValue("k")); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "./", http.StatusSeeOther)
+}
+
+func render(w http.ResponseWriter, t *template.Template, data any) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+const layout = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>kv-sqlite</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+pre { background: #f4f4f4; padding: 1em; overflow: auto; }
+li { font-family: monospace; }
+</style>
+</head>
+<body>
+{{template "content" .}}
+</body>
+</html>`
+
+var listTemplate = template.Must(template.Must(template.New("list").Parse(layout)).Parse(`{{define "content"}}
+<h1>Keys</h1>
+<form method="get" action="./">
+<input name="q" value="{{.Query}}" placeholder="search">
+<button type="submit">Search</button>
+</form>
+<p>{{.Total}} keys{{if .Truncated}}, showing the first {{len .Keys}}{{end}}</p>
+<ul>
+{{range .Keys}}<li><a href="key?k={{.}}">{{.}}</a></li>
+{{end}}</ul>
+{{end}}`))
+
+var keyTemplate = template.Must(template.Must(template.New("key").Parse(layout)).Parse(`{{define "content"}}
+<p><a href="./">&larr; Keys</a></p>
+<h1>{{.Key}}</h1>
+<p>{{if .ExpiresAt.IsZero}}No expiration{{else}}Expires at {{.ExpiresAt.Format "2006-01-02 15:04:05 MST"}} (in {{.TTL}}){{end}}</p>
+<pre>{{.Value}}</pre>
+<form method="post" action="delete" onsubmit="return confirm('Delete {{.Key}}?')">
+<input type="hidden" name="k" value="{{.Key}}">
+<button type="submit">Delete</button>
+</form>
+{{end}}`))
@@ -0,0 +1,74 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	kvsqlite "github.com/go-zoox/kv-sqlite"
+)
+
+func TestHandler(t *testing.T) {
+	store, err := kvsqlite.New(&kvsqlite.SQLiteConfig{Path: "/tmp/test.db", Prefix: "go-zoox-test-admin:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	defer store.Clear()
+
+	store.Set("user:1", map[string]string{"name": "zero"}, time.Hour)
+	store.Set("other", 1)
+
+	handler, err := New(store, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(method, target string, body url.Values, token string) *httptest.ResponseRecorder {
+		var req *http.Request
+		if body != nil {
+			req = httptest.NewRequest(method, target, strings.NewReader(body.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		} else {
+			req = httptest.NewRequest(method, target, nil)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do("GET", "/", nil, "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401, got %d", rec.Code)
+	}
+
+	rec := do("GET", "/?q=user", nil, "secret")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "user:1") || strings.Contains(rec.Body.String(), "other") {
+		t.Errorf("Expected the matching keys, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = do("GET", "/key?k=user:1", nil, "secret")
+	if !strings.Contains(rec.Body.String(), "&#34;name&#34;: &#34;zero&#34;") || !strings.Contains(rec.Body.String(), "Expires at") {
+		t.Errorf("Expected the decoded value and TTL, got %s", rec.Body.String())
+	}
+
+	if rec := do("GET", "/delete?k=user:1", nil, "secret"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rec.Code)
+	}
+	do("POST", "/delete", url.Values{"k": {"user:1"}}, "secret")
+	if store.Has("user:1") {
+		t.Error("Expected user:1 to be deleted")
+	}
+
+	// logging in with ?token= sets the cookie
+	rec = do("GET", "/?token=secret", nil, "")
+	if rec.Code != http.StatusSeeOther || len(rec.Result().Cookies()) != 1 {
+		t.Errorf("Expected a redirect setting the cookie, got %d", rec.Code)
+	}
+}
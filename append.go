@@ -0,0 +1,119 @@
+package kvsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"unicode/utf8"
+)
+
+// ErrNotString is returned by Append when the stored value is not a string.
+var ErrNotString = errors.New("sqlite: value is not a string")
+
+// errChunked is returned by the concatenation of Append when the stored value is chunked, see LargeValues.
+var errChunked = errors.New("sqlite: value is chunked")
+
+// Append appends data to the string value of the given key and returns the new length.
+// If the key does not exist (or is expired), it is created with data as its value.
+// The concatenation is done by SQLite in a single statement, so no read-modify-write is needed,
+// unless Config.Transformers are set or the value is stored as chunks: the value is then read, appended to
+// and written again in a transaction.
+func (m *SQLite) Append(key string, data string) (int, error) {
+	ctx := context.Background()
+	keyX, err := m.writeKey(key)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(m.Config.Transformers) > 0 {
+		return m.appendRewrite(ctx, key, keyX, data)
+	}
+
+	query := `INSERT INTO kv (key, value, expires_at, created_at) VALUES (?, json_quote(?), 0, ?)
+		ON CONFLICT(key) DO UPDATE SET
+			value = CASE
+				WHEN kv.expires_at > 0 AND kv.expires_at < ? THEN excluded.value
+				ELSE json_quote(json_extract(kv.value, '$') || ?)
+			END,
+			expires_at = CASE
+				WHEN kv.expires_at > 0 AND kv.expires_at < ? THEN 0
+				ELSE kv.expires_at
+			END,
+			chunks = 0
+		WHERE (json_type(kv.value) = 'text' AND kv.chunks = 0) OR (kv.expires_at > 0 AND kv.expires_at < ?)
+		RETURNING length(json_extract(value, '$'))`
+
+	var length int
+	err = m.write(ctx, func(db *tracer) error {
+		db = db.in(m.databaseOf(keyX))
+		if err := m.checkQuota(ctx, db, keyX, int64(len(data)), true, nil); err != nil {
+			return err
+		}
+
+		m.keyWritten(keyX)
+
+		ts := m.expiryCutoff()
+		if err := db.QueryRowContext(ctx, query, keyX, data, m.now(), ts, data, ts, ts).Scan(&length); err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+
+			var chunks int
+			if err := db.QueryRowContext(ctx, "SELECT chunks FROM kv WHERE key = ?", keyX).Scan(&chunks); err == nil && chunks > 0 {
+				return errChunked
+			}
+			return ErrNotString
+		}
+
+		return nil
+	})
+
+	if errors.Is(err, errChunked) {
+		return m.appendRewrite(ctx, key, keyX, data)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	m.publish(EventSet, key)
+	return length, nil
+}
+
+// appendRewrite is Append for transformed and chunked values, which SQLite can't concatenate in place.
+func (m *SQLite) appendRewrite(ctx context.Context, key, keyX string, data string) (int, error) {
+	var length int
+	err := m.writeTx(ctx, func(db *tracer) error {
+		r, err := m.readRow(ctx, db, keyX)
+		if err != nil {
+			return err
+		}
+
+		var current string
+		var expiresAt int64
+		if r != nil {
+			if err := m.decodeValue(keyX, []byte(r.value), &current); err != nil {
+				if errors.Is(err, ErrValueTypeMismatch) {
+					return ErrNotString
+				}
+
+				return err
+			}
+			expiresAt = r.expiresAt
+		}
+
+		valueX, err := m.encodeValue(current + data)
+		if err != nil {
+			return err
+		}
+
+		// like length() in SQL, the length is in characters
+		length = utf8.RuneCountInString(current + data)
+		return m.writeRow(ctx, db, keyX, &row{value: valueX, expiresAt: expiresAt})
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	m.publish(EventSet, key)
+	return length, nil
+}
@@ -0,0 +1,95 @@
+package kvsqlite
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestAppend(t *testing.T) {
+	client := createClient()
+	client.Clear()
+	defer client.Clear()
+
+	if n, err := client.Append("log", "hello"); err != nil || n != 5 {
+		t.Fatalf("Expected length 5, got %d (%v)", n, err)
+	}
+	if n, err := client.Append("log", " world"); err != nil || n != 11 {
+		t.Fatalf("Expected length 11, got %d (%v)", n, err)
+	}
+
+	var value string
+	if err := client.Get("log", &value); err != nil || value != "hello world" {
+		t.Errorf("Expected value to be 'hello world', got %q (%v)", value, err)
+	}
+
+	if err := client.Set("number", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Append("number", "x"); err != ErrNotString {
+		t.Errorf("Expected ErrNotString, got %v", err)
+	}
+}
+
+func TestAppendTransformed(t *testing.T) {
+	path := "/tmp/test-append-transformed.db"
+	os.Remove(path)
+	defer os.Remove(path)
+
+	aes, err := AESGCM([]byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:", Transformers: []ValueTransformer{aes}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	client.Append("log", "SECRET-")
+	if n, err := client.Append("log", "APPEND"); err != nil || n != 13 {
+		t.Fatalf("Expected length 13, got %d (%v)", n, err)
+	}
+
+	var value string
+	if err := client.Get("log", &value); err != nil || value != "SECRET-APPEND" {
+		t.Errorf("Expected value to be 'SECRET-APPEND', got %q (%v)", value, err)
+	}
+
+	var stored string
+	client.Core.QueryRow("SELECT value FROM kv WHERE key = ?", "go-zoox-test:log").Scan(&stored)
+	if stored == "" || strings.Contains(stored, "SECRET") {
+		t.Errorf("Expected the appended value to be encrypted, got %q", stored)
+	}
+
+	client.Set("number", 1)
+	if _, err := client.Append("number", "x"); err != ErrNotString {
+		t.Errorf("Expected ErrNotString, got %v", err)
+	}
+
+	if err := client.SetReader("stream", strings.NewReader("SECRET-READER")); err != ErrStreamTransformed {
+		t.Errorf("Expected ErrStreamTransformed, got %v", err)
+	}
+}
+
+func TestAppendChunked(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	client.Config.LargeValues = &LargeValueConfig{Threshold: 1024, ChunkSize: 100}
+	defer func() { client.Config.LargeValues = nil }()
+
+	large := strings.Repeat("0123456789", 500)
+	if err := client.Set("large", large); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := client.Append("large", "!"); err != nil || n != len(large)+1 {
+		t.Fatalf("Expected length %d, got %d (%v)", len(large)+1, n, err)
+	}
+
+	var got string
+	if err := client.Get("large", &got); err != nil || got != large+"!" {
+		t.Errorf("Expected the appended value, got %d bytes (%v)", len(got), err)
+	}
+}
@@ -0,0 +1,195 @@
+package kvsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// archiveSchema is the schema name the archive database is attached as.
+const archiveSchema = "kv_archive"
+
+// ArchiveConfig is the configuration of cold data archiving.
+type ArchiveConfig struct {
+	// Path is the path of the archive database file.
+	Path string
+
+	// After is how long a key must not have been accessed to be archived by the background archiver.
+	// Default is 30 days.
+	After time.Duration
+
+	// Interval is how often the background archiver runs.
+	// Default is 0, which means Archive has to be called explicitly.
+	Interval time.Duration
+
+	// Transparent makes Get and Has fall back to the archive on miss.
+	// Archived keys found by Get are restored to the store.
+	Transparent bool
+}
+
+// Archive moves the keys which have not been accessed within olderThan to the archive database,
+// and returns the number of archived keys. It uses the access statistics of TrackAccess:
+// keys which have never been read count as not accessed, so freshly written keys are archived too
+// unless read in between, which Transparent makes harmless.
+// Only keys of the main database are archived.
+func (m *SQLite) Archive(olderThan time.Duration) (int64, error) {
+	if err := m.checkAccessAll(true); err != nil {
+		return 0, err
+	}
+
+	if m.Config.Archive == nil {
+		return 0, errors.New("sqlite: archive is not configured")
+	}
+
+	if err := m.FlushAccessStats(); err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+	var n int64
+	err := m.writeTx(ctx, func(db *tracer) error {
+		ts := m.now()
+		cutoff := ts - olderThan.Milliseconds()
+		where := "WHERE key LIKE ? AND " + notReserved + " AND last_accessed_at < ? AND " + unexpired
+		args := []any{m.Config.Prefix + "%", m.reservedPattern(), cutoff, m.expiryCutoff()}
+
+		// unqualified kv is the main database, attached ones come after it in the search order
+		_, err := db.ExecContext(ctx,
+			"INSERT INTO "+archiveSchema+"."+m.tableIdent()+" (key, value, expires_at, created_at) SELECT key, "+valueExpr+", expires_at, created_at FROM kv "+where+
+				" ON CONFLICT(key) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at, chunks = 0",
+			args...,
+		)
+		if err != nil {
+			return err
+		}
+
+		res, err := db.ExecContext(ctx, "DELETE FROM kv "+where, args...)
+		if err != nil {
+			return err
+		}
+
+		if n, err = res.RowsAffected(); err != nil {
+			return err
+		}
+
+		if n > 0 {
+			m.keysCleared()
+			return m.rebuildBloom(ctx, db)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// GetArchived returns the archived value for the given key and reports whether it was found.
+func (m *SQLite) GetArchived(key string, value any) (bool, error) {
+	data, found, err := m.readArchived(context.Background(), key)
+	if err != nil || !found {
+		return false, err
+	}
+
+	return true, m.decodeValue(key, data, value)
+}
+
+func (m *SQLite) readArchived(ctx context.Context, key string) ([]byte, bool, error) {
+	if m.Config.Archive == nil {
+		return nil, false, nil
+	}
+
+	keyX, err := m.getKey(key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	var value string
+	err = m.db().in(archiveSchema).QueryRowContext(ctx,
+		"SELECT "+valueExpr+" FROM kv WHERE key = ? AND "+unexpired,
+		keyX, m.expiryCutoff(),
+	).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return []byte(value), true, nil
+}
+
+// hasArchived reports whether the given key is archived, if the archive is transparent.
+func (m *SQLite) hasArchived(ctx context.Context, key string) (bool, error) {
+	if m.Config.Archive == nil || !m.Config.Archive.Transparent {
+		return false, nil
+	}
+
+	_, found, err := m.readArchived(ctx, key)
+	return found, err
+}
+
+// unarchive restores the given key from the archive on a miss, if the archive is transparent.
+func (m *SQLite) unarchive(ctx context.Context, key string) ([]byte, bool, error) {
+	if m.Config.Archive == nil || !m.Config.Archive.Transparent {
+		return nil, false, nil
+	}
+
+	keyX, err := m.getKey(key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var r *row
+	err = m.writeTx(ctx, func(db *tracer) error {
+		archived := &row{}
+		err := db.in(archiveSchema).QueryRowContext(ctx,
+			"SELECT "+valueExpr+", expires_at FROM kv WHERE key = ? AND "+unexpired,
+			keyX, m.expiryCutoff(),
+		).Scan(&archived.value, &archived.expiresAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		r = archived
+
+		if _, err := db.in(archiveSchema).ExecContext(ctx, "DELETE FROM kv WHERE key = ?", keyX); err != nil {
+			return err
+		}
+
+		m.keyWritten(keyX)
+		_, err = m.upsert(ctx, db.in(m.databaseOf(keyX)), keyX, r.value, r.expiresAt, false, false)
+		return err
+	})
+	if err != nil || r == nil {
+		return nil, false, err
+	}
+
+	if m.access != nil {
+		m.access.touch(keyX)
+	}
+
+	return []byte(r.value), true, nil
+}
+
+func (m *SQLite) startArchiver() {
+	cfg := m.Config.Archive
+	after := cfg.After
+	if after <= 0 {
+		after = 30 * 24 * time.Hour
+	}
+
+	m.archiver = newWorker(cfg.Interval, func() {
+		if _, err := m.Archive(after); err != nil && m.Config.Debug {
+			m.logger().Printf("[sqlite] archive failed: %s", err)
+		}
+	})
+}
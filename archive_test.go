@@ -0,0 +1,66 @@
+package kvsqlite
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestArchive(t *testing.T) {
+	path, archivePath := "/tmp/test-archive.db", "/tmp/test-archive-cold.db"
+	for _, p := range []string{path, archivePath} {
+		os.Remove(p)
+		defer os.Remove(p)
+	}
+
+	clock := NewManualClock(time.Now())
+	client, err := New(&SQLiteConfig{
+		Path:        path,
+		Prefix:      "go-zoox-test:",
+		Clock:       clock,
+		TrackAccess: true,
+		Archive:     &ArchiveConfig{Path: archivePath},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	client.Set("cold", "c")
+	client.Set("hot", "h")
+
+	clock.Add(48 * time.Hour)
+	var value string
+	client.Get("hot", &value)
+
+	n, err := client.Archive(24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 archived key, got %d", n)
+	}
+
+	if client.Has("cold") {
+		t.Error("Expected cold to be archived")
+	}
+	if found, _ := client.GetArchived("cold", &value); !found || value != "c" {
+		t.Errorf("Expected the archived value, got %v %s", found, value)
+	}
+
+	// transparent reads restore archived keys
+	client.Config.Archive.Transparent = true
+	if !client.Has("cold") {
+		t.Error("Expected Has to see the archive")
+	}
+	value = ""
+	if client.Get("cold", &value); value != "c" {
+		t.Errorf("Expected c, got %s", value)
+	}
+	if found, _ := client.GetArchived("cold", &value); found {
+		t.Error("Expected cold to be restored")
+	}
+	if client.Size() != 2 {
+		t.Errorf("Expected 2 keys, got %d", client.Size())
+	}
+}
@@ -0,0 +1,229 @@
+package kvsqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+)
+
+// row is the stored value and expiration of a key.
+type row struct {
+	value     string
+	expiresAt int64
+
+	// sliding is the sliding TTL of the key in milliseconds when read, it is not written, see WithSlidingTTL.
+	sliding int64
+}
+
+// readRow returns the row of keyX if it exists and is not expired.
+func (m *SQLite) readRow(ctx context.Context, db *tracer, keyX string) (*row, error) {
+	r := &row{}
+	err := db.in(m.databaseOf(keyX)).QueryRowContext(ctx,
+		"SELECT "+valueExpr+", expires_at, sliding_ttl FROM kv WHERE key = ? AND "+unexpired,
+		keyX, m.expiryCutoff(),
+	).Scan(&r.value, &r.expiresAt, &r.sliding)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// writeRow writes the row of keyX, within the quota.
+func (m *SQLite) writeRow(ctx context.Context, db *tracer, keyX string, r *row) error {
+	db = db.in(m.databaseOf(keyX))
+	if err := m.checkQuota(ctx, db, keyX, int64(len(r.value)), false, nil); err != nil {
+		return err
+	}
+
+	m.keyWritten(keyX)
+	_, err := m.upsert(ctx, db, keyX, r.value, r.expiresAt, false, false)
+	return err
+}
+
+// deleteRow deletes the row of keyX.
+func (m *SQLite) deleteRow(ctx context.Context, db *tracer, keyX string) error {
+	res, err := db.in(m.databaseOf(keyX)).ExecContext(ctx, "DELETE FROM kv WHERE key = ?", keyX)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n > 0 {
+		m.keyRemoved(keyX)
+	}
+
+	return nil
+}
+
+// MoveValue moves the value of src to dst, with its expiration, in a single transaction.
+// An existing dst is overwritten. It returns ErrNotFound if src does not exist or is expired.
+func (m *SQLite) MoveValue(src, dst string) error {
+	if src == dst {
+		return nil
+	}
+
+	ctx := context.Background()
+	err := m.writeTx(ctx, func(db *tracer) error {
+		srcX, err := m.writeKey(src)
+		if err != nil {
+			return err
+		}
+		dstX, err := m.writeKey(dst)
+		if err != nil {
+			return err
+		}
+
+		r, err := m.readRow(ctx, db, srcX)
+		if err != nil {
+			return err
+		}
+		if r == nil {
+			return ErrNotFound
+		}
+
+		if err := m.deleteRow(ctx, db, srcX); err != nil {
+			return err
+		}
+
+		return m.writeRow(ctx, db, dstX, r)
+	})
+	if err != nil {
+		return err
+	}
+
+	m.publish(EventDelete, src)
+	m.publish(EventSet, dst)
+	return nil
+}
+
+// SwapKeys swaps the values of a and b, with their expirations, in a single transaction.
+// A missing key swaps like a value, i.e. the other key is moved to it.
+func (m *SQLite) SwapKeys(a, b string) error {
+	if a == b {
+		return nil
+	}
+
+	ctx := context.Background()
+	var ra, rb *row
+	err := m.writeTx(ctx, func(db *tracer) error {
+		aX, err := m.writeKey(a)
+		if err != nil {
+			return err
+		}
+		bX, err := m.writeKey(b)
+		if err != nil {
+			return err
+		}
+
+		if ra, err = m.readRow(ctx, db, aX); err != nil {
+			return err
+		}
+		if rb, err = m.readRow(ctx, db, bX); err != nil {
+			return err
+		}
+
+		for _, keyX := range []string{aX, bX} {
+			if err := m.deleteRow(ctx, db, keyX); err != nil {
+				return err
+			}
+		}
+
+		for _, swap := range []struct {
+			keyX string
+			r    *row
+		}{{aX, rb}, {bX, ra}} {
+			if swap.r == nil {
+				continue
+			}
+
+			if err := m.writeRow(ctx, db, swap.keyX, swap.r); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for key, r := range map[string]*row{a: rb, b: ra} {
+		if r == nil {
+			m.publish(EventDelete, key)
+		} else {
+			m.publish(EventSet, key)
+		}
+	}
+	return nil
+}
+
+// SplitValue splits the JSON object stored under key into other keys, in a single transaction:
+// each field listed in fields is written to the key it maps to, with the expiration of key, and key is deleted.
+// Fields missing from the object leave their target key untouched.
+// It returns ErrNotFound if key does not exist or is expired, or a ValueTypeError if it is not an object.
+func (m *SQLite) SplitValue(key string, fields map[string]string) error {
+	ctx := context.Background()
+	written := make([]string, 0, len(fields))
+	err := m.writeTx(ctx, func(db *tracer) error {
+		keyX, err := m.writeKey(key)
+		if err != nil {
+			return err
+		}
+
+		r, err := m.readRow(ctx, db, keyX)
+		if err != nil {
+			return err
+		}
+		if r == nil {
+			return ErrNotFound
+		}
+
+		var object map[string]json.RawMessage
+		if err := m.decodeValue(key, []byte(r.value), &object); err != nil {
+			return err
+		}
+
+		if err := m.deleteRow(ctx, db, keyX); err != nil {
+			return err
+		}
+
+		for field, target := range fields {
+			value, ok := object[field]
+			if !ok {
+				continue
+			}
+
+			targetX, err := m.writeKey(target)
+			if err != nil {
+				return err
+			}
+
+			valueX, err := m.transform(value)
+			if err != nil {
+				return err
+			}
+
+			if err := m.writeRow(ctx, db, targetX, &row{value: valueX, expiresAt: r.expiresAt}); err != nil {
+				return err
+			}
+			written = append(written, target)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	m.publish(EventDelete, key)
+	for _, target := range written {
+		m.publish(EventSet, target)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package kvsqlite
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMoveValue(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	client.Set("src", "value", time.Hour)
+	if err := client.MoveValue("src", "dst"); err != nil {
+		t.Fatal(err)
+	}
+
+	var value string
+	if client.Get("dst", &value); value != "value" {
+		t.Errorf("Expected value, got %s", value)
+	}
+	if client.Has("src") {
+		t.Error("Expected src to be removed")
+	}
+	if next, _ := client.NextToExpire(1); len(next) != 1 || next[0].Key != "dst" {
+		t.Errorf("Expected the expiration to move along, got %v", next)
+	}
+
+	if err := client.MoveValue("src", "dst"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestSwapKeys(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	client.Set("a", "1")
+	client.Set("b", "2")
+	if err := client.SwapKeys("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	var a, b string
+	client.Get("a", &a)
+	client.Get("b", &b)
+	if a != "2" || b != "1" {
+		t.Errorf("Expected swapped values, got %s and %s", a, b)
+	}
+
+	client.SwapKeys("a", "missing")
+	if client.Has("a") || !client.Has("missing") {
+		t.Error("Expected a to be moved to the missing key")
+	}
+}
+
+func TestSplitValue(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	client.Set("user", map[string]any{"name": "zero", "profile": map[string]any{"age": 1}})
+	if err := client.SplitValue("user", map[string]string{"name": "user:name", "profile": "user:profile", "other": "user:other"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var name string
+	var profile map[string]int
+	client.Get("user:name", &name)
+	client.Get("user:profile", &profile)
+	if name != "zero" || profile["age"] != 1 {
+		t.Errorf("Expected split fields, got %s and %v", name, profile)
+	}
+	if client.Has("user") || client.Has("user:other") {
+		t.Error("Expected the source removed and missing fields skipped")
+	}
+
+	client.Set("number", 1)
+	if err := client.SplitValue("number", map[string]string{"a": "b"}); !errors.Is(err, ErrValueTypeMismatch) {
+		t.Errorf("Expected ErrValueTypeMismatch, got %v", err)
+	}
+}
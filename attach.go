@@ -0,0 +1,201 @@
+package kvsqlite
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// AttachedDatabase is a database file attached to the store, e.g. hot data on tmpfs next to cold data on disk.
+//
+// Keys starting with one of its prefixes (relative to Config.Prefix) are stored in its kv table instead of the main one.
+// The KV methods and their Context variants, Append, SetReader, GetReader, GetMulti, ExpireBatch and PersistBatch
+// are routed, the other features only see the main database.
+type AttachedDatabase struct {
+	// Name is the schema name of the database, e.g. hot.
+	Name string
+
+	// Path is the path of the database file.
+	Path string
+
+	// Prefixes are the key prefixes routed to the database, the first matching database wins.
+	Prefixes []string
+}
+
+var schemaName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// kvTables matches the unqualified kv tables in a statement.
+var kvTables = regexp.MustCompile(`(^|[^.\w])(kv|kv_chunks|kv_tags)\b`)
+
+// attachDrivers numbers the drivers registered for stores with attached databases.
+var attachDrivers int64
+
+// openCore opens the database of the store with its dialect, see Dialect.Open.
+func openCore(cfg *SQLiteConfig, params ...string) (*sql.DB, error) {
+	return dialectOf(cfg).Open(cfg, params...)
+}
+
+// openSQLite opens the SQLite database of the store, attaching the configured databases to every connection.
+// The given parameters are added to the DSN, e.g. _txlock=immediate.
+func openSQLite(cfg *SQLiteConfig, params ...string) (*sql.DB, error) {
+	dsn := cfg.Path
+	if cfg.ReadOnly {
+		dsn = "file:" + cfg.Path + "?mode=ro"
+	}
+	for _, param := range params {
+		if strings.Contains(dsn, "?") {
+			dsn += "&" + param
+		} else {
+			dsn += "?" + param
+		}
+	}
+
+	if len(cfg.Attach) == 0 && cfg.Archive == nil {
+		return sql.Open("sqlite3", dsn)
+	}
+
+	for _, db := range cfg.Attach {
+		if !schemaName.MatchString(db.Name) || strings.EqualFold(db.Name, "main") || strings.EqualFold(db.Name, "temp") || strings.EqualFold(db.Name, archiveSchema) {
+			return nil, fmt.Errorf("sqlite: invalid attached database name %q", db.Name)
+		}
+		if db.Path == "" {
+			return nil, fmt.Errorf("sqlite: path of attached database %s is required", db.Name)
+		}
+	}
+
+	// ATTACH is per connection, so it runs in the connect hook of a driver registered for this store
+	attached := attachedDatabases(cfg)
+	name := fmt.Sprintf("sqlite3_kvsqlite_attach_%d", atomic.AddInt64(&attachDrivers, 1))
+	sql.Register(name, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			for _, db := range attached {
+				if _, err := conn.Exec("ATTACH DATABASE ? AS "+db.Name, []driver.Value{db.Path}); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	})
+
+	return sql.Open(name, dsn)
+}
+
+// attachedDatabases returns the databases attached to the store, including the archive.
+func attachedDatabases(cfg *SQLiteConfig) []AttachedDatabase {
+	attached := append([]AttachedDatabase(nil), cfg.Attach...)
+	if cfg.Archive != nil {
+		attached = append(attached, AttachedDatabase{Name: archiveSchema, Path: cfg.Archive.Path})
+	}
+
+	return attached
+}
+
+// createAttachedSchemas creates the kv tables of the attached databases.
+func createAttachedSchemas(core *sql.DB, attached []AttachedDatabase, valueType string) error {
+	for _, db := range attached {
+		statements := []string{
+			"CREATE TABLE IF NOT EXISTS " + db.Name + ".kv (key TEXT PRIMARY KEY, value " + valueType + ", expires_at INTEGER)",
+			"CREATE INDEX IF NOT EXISTS " + db.Name + ".kv_expires_at ON kv (expires_at)",
+		}
+		for _, statement := range statements {
+			if _, err := core.Exec(statement); err != nil {
+				return err
+			}
+		}
+
+		if err := migrate(core, db.Name, "kv"); err != nil {
+			return err
+		}
+
+		for _, statement := range immutableTriggers(db.Name+".", "kv") {
+			if _, err := core.Exec(statement); err != nil {
+				return err
+			}
+		}
+
+		if err := createChunkSchema(core, db.Name); err != nil {
+			return err
+		}
+
+		if err := createTagSchema(core, db.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// qualify rewrites the kv tables of the query to the given attached database, "" for main,
+// and the kv table to the given table of the prefix, "" for the shared one.
+func qualify(query string, schema string, table string) string {
+	if schema == "" && table == "" {
+		return query
+	}
+
+	return kvTables.ReplaceAllStringFunc(query, func(match string) string {
+		// the leading character is never a word character, see kvTables
+		lead, name := "", match
+		if !strings.HasPrefix(match, "kv") {
+			lead, name = match[:1], match[1:]
+		}
+
+		if name == "kv" && table != "" {
+			name = quoteIdent(table)
+		}
+		if schema != "" {
+			name = schema + "." + name
+		}
+
+		return lead + name
+	})
+}
+
+// databaseOf returns the attached database the (prefixed) key is routed to, "" for main.
+func (m *SQLite) databaseOf(keyX string) string {
+	key := strings.TrimPrefix(keyX, m.Config.Prefix)
+	for _, db := range m.Config.Attach {
+		for _, prefix := range db.Prefixes {
+			if strings.HasPrefix(key, prefix) {
+				return db.Name
+			}
+		}
+	}
+
+	return ""
+}
+
+// databases returns all databases holding kv tables, "" for main first.
+func (m *SQLite) databases() []string {
+	names := make([]string, 0, len(m.Config.Attach)+1)
+	names = append(names, "")
+	for _, db := range m.Config.Attach {
+		names = append(names, db.Name)
+	}
+
+	return names
+}
+
+// byDatabase groups the given keys to read, or write, by the database they are routed to, as stored keys.
+func (m *SQLite) byDatabase(keys []string, write bool) (map[string][]string, error) {
+	groups := make(map[string][]string)
+	for _, key := range keys {
+		keyX, err := m.getKey(key)
+		if write {
+			keyX, err = m.writeKey(key)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		db := m.databaseOf(keyX)
+		groups[db] = append(groups[db], keyX)
+	}
+
+	return groups, nil
+}
@@ -0,0 +1,55 @@
+package kvsqlite
+
+import (
+	"os"
+	"testing"
+)
+
+func TestAttach(t *testing.T) {
+	path, hotPath := "/tmp/test-attach.db", "/tmp/test-attach-hot.db"
+	for _, p := range []string{path, hotPath} {
+		os.Remove(p)
+		defer os.Remove(p)
+	}
+
+	client, err := New(&SQLiteConfig{
+		Path:   path,
+		Prefix: "go-zoox-test:",
+		Attach: []AttachedDatabase{
+			{Name: "hot", Path: hotPath, Prefixes: []string{"session:"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	client.Set("session:1", "hot")
+	client.Set("user:1", "cold")
+
+	var count int
+	client.Core.QueryRow("SELECT count(*) FROM hot.kv").Scan(&count)
+	if count != 1 {
+		t.Errorf("Expected 1 key in the attached database, got %d", count)
+	}
+	client.Core.QueryRow("SELECT count(*) FROM main.kv").Scan(&count)
+	if count != 1 {
+		t.Errorf("Expected 1 key in the main database, got %d", count)
+	}
+
+	var value string
+	if client.Get("session:1", &value); value != "hot" {
+		t.Errorf("Expected hot, got %s", value)
+	}
+	if !client.Has("session:1") || client.Size() != 2 || len(client.Keys()) != 2 {
+		t.Errorf("Expected both databases to be visible, got %v", client.Keys())
+	}
+
+	if n, _ := client.DeleteAll(); n != 2 {
+		t.Errorf("Expected 2 deleted keys, got %d", n)
+	}
+
+	if _, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:", Attach: []AttachedDatabase{{Name: "main", Path: hotPath}}}); err == nil {
+		t.Error("Expected an error for a reserved database name")
+	}
+}
@@ -0,0 +1,157 @@
+package kvsqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// CheckpointMode is the mode of a WAL checkpoint.
+type CheckpointMode string
+
+const (
+	// CheckpointPassive checkpoints as many frames as possible without waiting for readers or writers.
+	CheckpointPassive CheckpointMode = "PASSIVE"
+	// CheckpointFull waits for writers, then checkpoints all frames.
+	CheckpointFull CheckpointMode = "FULL"
+	// CheckpointRestart is like CheckpointFull and also waits for readers so the next writer restarts the WAL.
+	CheckpointRestart CheckpointMode = "RESTART"
+	// CheckpointTruncate is like CheckpointRestart and also truncates the WAL file to zero bytes.
+	CheckpointTruncate CheckpointMode = "TRUNCATE"
+)
+
+// CheckpointResult is the result of a WAL checkpoint.
+type CheckpointResult struct {
+	// Busy is true if the checkpoint could not complete because of concurrent readers or writers.
+	Busy bool
+	// Log is the number of frames in the WAL.
+	Log int
+	// Checkpointed is the number of frames written back into the database.
+	Checkpointed int
+}
+
+var journalModes = map[string]bool{
+	"DELETE":   true,
+	"TRUNCATE": true,
+	"PERSIST":  true,
+	"MEMORY":   true,
+	"WAL":      true,
+	"OFF":      true,
+}
+
+func setJournalMode(core *sql.DB, mode string) error {
+	mode = strings.ToUpper(mode)
+	if !journalModes[mode] {
+		return fmt.Errorf("sqlite: unknown journal mode %s", mode)
+	}
+
+	_, err := core.Exec("PRAGMA journal_mode = " + mode)
+	return err
+}
+
+// Checkpoint runs a WAL checkpoint with the given mode.
+// It is a no-op when the database is not in WAL mode.
+func (m *SQLite) Checkpoint(mode CheckpointMode) (*CheckpointResult, error) {
+	if err := m.supports(FeaturePragmas); err != nil {
+		return nil, err
+	}
+
+	switch mode {
+	case CheckpointPassive, CheckpointFull, CheckpointRestart, CheckpointTruncate:
+	default:
+		return nil, fmt.Errorf("sqlite: unknown checkpoint mode %s", mode)
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	var busy int
+	res := &CheckpointResult{}
+	if err := m.wdb().QueryRow("PRAGMA wal_checkpoint("+string(mode)+")").Scan(&busy, &res.Log, &res.Checkpointed); err != nil {
+		return nil, err
+	}
+	res.Busy = busy != 0
+
+	return res, nil
+}
+
+// BackupTo writes a consistent copy of the database to the given path using the SQLite online backup API.
+// It requires access to all keys, see AccessPolicy.
+func (m *SQLite) BackupTo(path string) error {
+	if err := m.supports(FeatureBackup); err != nil {
+		return err
+	}
+
+	// the copy holds every key of the database
+	if err := m.checkAccessAll(false); err != nil {
+		return err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	dst, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	ctx := context.Background()
+	dstConn, err := dst.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer dstConn.Close()
+
+	srcConn, err := m.core.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return dstConn.Raw(func(d any) error {
+		return srcConn.Raw(func(s any) error {
+			backup, err := d.(*sqlite3.SQLiteConn).Backup("main", s.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+
+			if _, err := backup.Step(-1); err != nil {
+				backup.Finish()
+				return err
+			}
+
+			return backup.Finish()
+		})
+	})
+}
+
+// BackupStream writes a consistent page-level snapshot of the database to w
+// and returns the number of bytes written.
+// It is suitable for streaming backups to remote storage, e.g. S3.
+func (m *SQLite) BackupStream(w io.Writer) (int64, error) {
+	f, err := os.CreateTemp("", "kv-sqlite-backup-*.db")
+	if err != nil {
+		return 0, err
+	}
+	path := f.Name()
+	f.Close()
+	defer os.Remove(path)
+
+	if err := m.BackupTo(path); err != nil {
+		return 0, err
+	}
+
+	snapshot, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer snapshot.Close()
+
+	return io.Copy(w, snapshot)
+}
@@ -0,0 +1,49 @@
+package kvsqlite
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestBackupStream(t *testing.T) {
+	client, err := New(&SQLiteConfig{
+		Path:        "/tmp/test-wal.db",
+		Prefix:      "go-zoox-test:",
+		JournalMode: "WAL",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.Clear()
+	defer client.Clear()
+
+	client.Set("key", "value")
+
+	if _, err := client.Checkpoint(CheckpointTruncate); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	if _, err := client.BackupStream(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	path := "/tmp/test-backup.db"
+	defer os.Remove(path)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+
+	var value string
+	if err := restored.Get("key", &value); err != nil || value != "value" {
+		t.Errorf("Expected restored value to be 'value', got %q (%v)", value, err)
+	}
+}
@@ -0,0 +1,182 @@
+package kvsqlite
+
+import (
+	"context"
+	"time"
+)
+
+// BatchDeleteOptions are the options of ClearBatched and DeleteByPatternBatched.
+type BatchDeleteOptions struct {
+	// BatchSize is the number of keys deleted per statement, default is 1000.
+	BatchSize int
+
+	// Pause is how long to wait between batches, so concurrent writes and checkpoints get the database, default is none.
+	// The write lock is released between batches either way.
+	Pause time.Duration
+
+	// Progress is called after each batch with the number of keys deleted so far.
+	Progress func(deleted int64)
+}
+
+// ClearBatched is like Clear but deletes the keys in batches, each with its own short write,
+// so clearing millions of keys neither blocks other writes for long nor grows the WAL by as much.
+// Concurrent writes may land between batches, and are deleted by the following ones, or not if
+// they come after the last. It stops at the first error, batches deleted before stay deleted.
+// The sorted sets of the prefix are deleted in batches too, after the keys, but not counted.
+func (m *SQLite) ClearBatched(opts ...*BatchDeleteOptions) (int64, error) {
+	return m.ClearBatchedContext(context.Background(), opts...)
+}
+
+// ClearBatchedContext is like ClearBatched but stops between batches when ctx is done.
+func (m *SQLite) ClearBatchedContext(ctx context.Context, opts ...*BatchDeleteOptions) (int64, error) {
+	n, err := m.deleteBatched(ctx, "*", true, opts)
+	if n > 0 {
+		m.publish(EventClear, "")
+	}
+
+	return n, err
+}
+
+// DeleteByPatternBatched is like DeleteByPattern but deletes the keys in batches, see ClearBatched.
+func (m *SQLite) DeleteByPatternBatched(pattern string, opts ...*BatchDeleteOptions) (int64, error) {
+	return m.DeleteByPatternBatchedContext(context.Background(), pattern, opts...)
+}
+
+// DeleteByPatternBatchedContext is like DeleteByPatternBatched but stops between batches when ctx is done.
+func (m *SQLite) DeleteByPatternBatchedContext(ctx context.Context, pattern string, opts ...*BatchDeleteOptions) (int64, error) {
+	return m.deleteBatched(ctx, pattern, false, opts)
+}
+
+// deleteBatched deletes the keys matching the glob pattern in batches, except the reserved keys.
+// Keys deleted by pattern are published one by one, a clear is published by the caller.
+func (m *SQLite) deleteBatched(ctx context.Context, pattern string, clear bool, opts []*BatchDeleteOptions) (int64, error) {
+	if err := m.checkAccessAll(true); err != nil {
+		return 0, err
+	}
+
+	opt := &BatchDeleteOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		opt = opts[0]
+	}
+
+	batchSize := opt.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	query := "DELETE FROM kv WHERE key IN (SELECT key FROM kv WHERE key GLOB ? AND " + notReserved + " LIMIT ?) RETURNING key"
+	args := []any{globEscape(m.Config.Prefix) + pattern, m.reservedPattern(), batchSize}
+
+	var total int64
+	for _, database := range m.databases() {
+		for {
+			if err := ctx.Err(); err != nil {
+				return total, err
+			}
+
+			removed := make([]string, 0, batchSize)
+			err := m.write(ctx, func(db *tracer) error {
+				rows, err := db.in(database).QueryContext(ctx, query, args...)
+				if err != nil {
+					return err
+				}
+				defer rows.Close()
+
+				for rows.Next() {
+					var keyX string
+					if err := rows.Scan(&keyX); err != nil {
+						return err
+					}
+
+					m.keyRemoved(keyX)
+					removed = append(removed, keyX[len(m.Config.Prefix):])
+				}
+
+				return rows.Err()
+			})
+			if err != nil {
+				return total, err
+			}
+
+			total += int64(len(removed))
+			if !clear {
+				for _, key := range removed {
+					m.publish(EventDelete, key)
+				}
+			}
+
+			if len(removed) == 0 {
+				break
+			}
+			if opt.Progress != nil {
+				opt.Progress(total)
+			}
+			if len(removed) < batchSize {
+				break
+			}
+
+			if err := pause(ctx, opt.Pause); err != nil {
+				return total, err
+			}
+		}
+	}
+
+	if !clear {
+		return total, nil
+	}
+
+	return total, m.clearBatchedTail(ctx, batchSize, opt.Pause)
+}
+
+// clearBatchedTail deletes the sorted sets of the prefix in batches, then resets the in-memory state like DeleteAll.
+func (m *SQLite) clearBatchedTail(ctx context.Context, batchSize int, wait time.Duration) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var n int64
+		err := m.write(ctx, func(db *tracer) error {
+			// kv_zset is never qualified, it resolves to the main database
+			res, err := db.ExecContext(ctx, "DELETE FROM kv_zset WHERE rowid IN (SELECT rowid FROM kv_zset WHERE key LIKE ? LIMIT ?)", m.Config.Prefix+"%", batchSize)
+			if err != nil {
+				return err
+			}
+
+			n, err = res.RowsAffected()
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if n < int64(batchSize) {
+			break
+		}
+
+		if err := pause(ctx, wait); err != nil {
+			return err
+		}
+	}
+
+	return m.writeTx(ctx, func(db *tracer) error {
+		m.keysCleared()
+		return m.rebuildBloom(ctx, db)
+	})
+}
+
+// pause waits for d between batches, or until ctx is done.
+func pause(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,52 @@
+package kvsqlite
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestClearBatched(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	for i := 0; i < 25; i++ {
+		client.Set("batch:"+strconv.Itoa(i), i)
+		client.ZAdd("board", strconv.Itoa(i), float64(i))
+	}
+
+	calls := 0
+	n, err := client.ClearBatched(&BatchDeleteOptions{BatchSize: 10, Progress: func(deleted int64) { calls++ }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 25 || calls != 3 {
+		t.Errorf("Expected 25 keys deleted in 3 batches, got %d in %d", n, calls)
+	}
+	if size := client.Size(); size != 0 {
+		t.Errorf("Expected an empty store, got %d keys", size)
+	}
+	if n, _ := client.ZCard("board"); n != 0 {
+		t.Errorf("Expected the sorted sets to be deleted, got %d members", n)
+	}
+}
+
+func TestDeleteByPatternBatched(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	for i := 0; i < 12; i++ {
+		client.Set("page:"+strconv.Itoa(i), i)
+	}
+	client.Set("user:1", 1)
+
+	n, err := client.DeleteByPatternBatched("page:*", &BatchDeleteOptions{BatchSize: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 12 {
+		t.Errorf("Expected 12 keys deleted, got %d", n)
+	}
+	if keys := client.Keys(); len(keys) != 1 || keys[0] != "user:1" {
+		t.Errorf("Expected user:1 to be kept, got %v", keys)
+	}
+}
@@ -0,0 +1,186 @@
+// Package bench runs read/write workloads against a SQLite KV store
+// and reports throughput and latency percentiles.
+package bench
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	kvsqlite "github.com/go-zoox/kv-sqlite"
+)
+
+// Workload is the kind of operations to run.
+type Workload string
+
+const (
+	// WorkloadRead runs only reads.
+	WorkloadRead Workload = "read"
+	// WorkloadWrite runs only writes.
+	WorkloadWrite Workload = "write"
+	// WorkloadMixed runs reads and writes according to ReadRatio.
+	WorkloadMixed Workload = "mixed"
+)
+
+// Config is the configuration of a benchmark run.
+type Config struct {
+	// Store is the configuration used to open the store under test.
+	Store *kvsqlite.SQLiteConfig
+
+	// Workload is the kind of operations to run, default is mixed.
+	Workload Workload
+
+	// ReadRatio is the fraction of reads in a mixed workload, default is 0.8.
+	ReadRatio float64
+
+	// Operations is the total number of operations, default is 10000.
+	Operations int
+
+	// Concurrency is the number of concurrent workers, default is 4.
+	Concurrency int
+
+	// Keys is the size of the key space, default is 1000.
+	Keys int
+
+	// ValueSize is the size of each written value in bytes, default is 128.
+	ValueSize int
+}
+
+// Report is the result of a benchmark run.
+type Report struct {
+	Workload   Workload
+	Operations int
+	Errors     int
+	Duration   time.Duration
+	Throughput float64
+
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+// String returns a human readable summary of the report.
+func (r *Report) String() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "workload:   %s\n", r.Workload)
+	fmt.Fprintf(b, "operations: %d (%d errors)\n", r.Operations, r.Errors)
+	fmt.Fprintf(b, "duration:   %s\n", r.Duration)
+	fmt.Fprintf(b, "throughput: %.0f ops/s\n", r.Throughput)
+	fmt.Fprintf(b, "latency:    p50=%s p90=%s p99=%s max=%s\n", r.P50, r.P90, r.P99, r.Max)
+	return b.String()
+}
+
+// Run opens the store and runs the configured workload against it.
+func Run(cfg *Config) (*Report, error) {
+	if cfg.Store == nil {
+		return nil, errors.New("bench: store config is required")
+	}
+
+	c := *cfg
+	if c.Workload == "" {
+		c.Workload = WorkloadMixed
+	}
+	if c.ReadRatio <= 0 {
+		c.ReadRatio = 0.8
+	}
+	if c.Operations <= 0 {
+		c.Operations = 10000
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = 4
+	}
+	if c.Keys <= 0 {
+		c.Keys = 1000
+	}
+	if c.ValueSize <= 0 {
+		c.ValueSize = 128
+	}
+
+	store, err := kvsqlite.New(c.Store)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	value := strings.Repeat("x", c.ValueSize)
+	if c.Workload != WorkloadWrite {
+		// seed the key space so reads hit
+		for i := 0; i < c.Keys; i++ {
+			if err := store.Set(key(i), value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	latencies := make([]time.Duration, c.Operations)
+	errs := make([]bool, c.Operations)
+
+	jobs := make(chan int)
+	wg := &sync.WaitGroup{}
+	start := time.Now()
+	for w := 0; w < c.Concurrency; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed))
+
+			for i := range jobs {
+				k := key(rnd.Intn(c.Keys))
+				read := c.Workload == WorkloadRead || (c.Workload == WorkloadMixed && rnd.Float64() < c.ReadRatio)
+
+				begin := time.Now()
+				var err error
+				if read {
+					var v string
+					err = store.Get(k, &v)
+				} else {
+					err = store.Set(k, value)
+				}
+				latencies[i] = time.Since(begin)
+				errs[i] = err != nil
+			}
+		}(int64(w))
+	}
+
+	for i := 0; i < c.Operations; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	report := &Report{
+		Workload:   c.Workload,
+		Operations: c.Operations,
+		Duration:   elapsed,
+		Throughput: float64(c.Operations) / elapsed.Seconds(),
+	}
+	for _, failed := range errs {
+		if failed {
+			report.Errors++
+		}
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	report.P50 = percentile(latencies, 0.50)
+	report.P90 = percentile(latencies, 0.90)
+	report.P99 = percentile(latencies, 0.99)
+	report.Max = latencies[len(latencies)-1]
+
+	return report, nil
+}
+
+func key(i int) string {
+	return "bench:" + strconv.Itoa(i)
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	idx := int(float64(len(sorted)-1) * p)
+	return sorted[idx]
+}
@@ -0,0 +1,28 @@
+package bench
+
+import (
+	"testing"
+
+	kvsqlite "github.com/go-zoox/kv-sqlite"
+)
+
+func TestRun(t *testing.T) {
+	report, err := Run(&Config{
+		Store: &kvsqlite.SQLiteConfig{
+			Path:   "/tmp/test-bench.db",
+			Prefix: "go-zoox-bench:",
+		},
+		Operations: 200,
+		Keys:       50,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Operations != 200 || report.Errors != 0 {
+		t.Errorf("Expected 200 operations without errors, got %d (%d errors)", report.Operations, report.Errors)
+	}
+	if report.P50 > report.P99 || report.P99 > report.Max {
+		t.Errorf("Expected ordered percentiles, got %s", report)
+	}
+}
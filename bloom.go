@@ -0,0 +1,241 @@
+package kvsqlite
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+// BloomConfig is the configuration of the in-memory bloom filter over keys.
+type BloomConfig struct {
+	// ExpectedKeys is the number of keys the filter is sized for, default is 100000.
+	ExpectedKeys int
+
+	// FalsePositiveRate is the target false positive rate, default is 0.01.
+	FalsePositiveRate float64
+}
+
+// bloomFilter is a counting bloom filter, so keys can be removed as well as added.
+type bloomFilter struct {
+	sync.RWMutex
+	counters []uint8
+	hashes   int
+}
+
+func newBloomFilter(cfg *BloomConfig) *bloomFilter {
+	n := cfg.ExpectedKeys
+	if n <= 0 {
+		n = 100000
+	}
+
+	p := cfg.FalsePositiveRate
+	if p <= 0 || p >= 1 {
+		p = 0.01
+	}
+
+	size := int(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	hashes := int(math.Round(float64(size) / float64(n) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+
+	return &bloomFilter{
+		counters: make([]uint8, size),
+		hashes:   hashes,
+	}
+}
+
+// positions returns the counter indexes of key, using double hashing.
+func (b *bloomFilter) positions(key string) []int {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	h1, h2 := uint32(sum), uint32(sum>>32)|1
+
+	positions := make([]int, b.hashes)
+	for i := range positions {
+		positions[i] = int((h1 + uint32(i)*h2) % uint32(len(b.counters)))
+	}
+
+	return positions
+}
+
+func (b *bloomFilter) add(key string) {
+	b.Lock()
+	defer b.Unlock()
+
+	for _, i := range b.positions(key) {
+		// saturated counters are never decremented
+		if b.counters[i] < math.MaxUint8 {
+			b.counters[i]++
+		}
+	}
+}
+
+func (b *bloomFilter) remove(key string) {
+	b.Lock()
+	defer b.Unlock()
+
+	for _, i := range b.positions(key) {
+		if b.counters[i] > 0 && b.counters[i] < math.MaxUint8 {
+			b.counters[i]--
+		}
+	}
+}
+
+func (b *bloomFilter) mayContain(key string) bool {
+	b.RLock()
+	defer b.RUnlock()
+
+	for _, i := range b.positions(key) {
+		if b.counters[i] == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (b *bloomFilter) reset() {
+	b.Lock()
+	defer b.Unlock()
+
+	for i := range b.counters {
+		b.counters[i] = 0
+	}
+}
+
+// replace swaps the counters of the filter with the ones of other.
+func (b *bloomFilter) replace(other *bloomFilter) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.counters = other.counters
+}
+
+// bloomPending are the bloom filter updates of the running transaction, applied once it commits.
+type bloomPending struct {
+	removed []string
+	rebuild bool
+}
+
+// deferBloom records the bloom filter updates of the transaction starting on core, and returns the function
+// to call once it ends. Additions are applied right away, as a key wrongly in the filter is only a false positive,
+// while removals and rebuilds wait for the commit, so a rollback never hides a stored key.
+// It must be called with the write lock held.
+func (m *SQLite) deferBloom(core writeConn) func(committed bool) {
+	if m.bloom == nil {
+		return func(bool) {}
+	}
+
+	pending := &bloomPending{}
+	m.bloomTx = pending
+
+	return func(committed bool) {
+		m.bloomTx = nil
+		if !committed {
+			return
+		}
+
+		if pending.rebuild {
+			// the write is committed already, a failed rebuild keeps the previous filter and the keys added since
+			if err := m.rebuildBloom(context.Background(), &tracer{store: m, core: core, table: m.table}); err != nil && m.Config.Debug {
+				m.logger().Printf("[sqlite] bloom filter rebuild failed: %s", err)
+			}
+			return
+		}
+
+		for _, keyX := range pending.removed {
+			m.bloom.remove(keyX)
+		}
+	}
+}
+
+// rebuildBloom fills the bloom filter with the keys stored under the prefix.
+// In a transaction the keys are only added, and the filter is rebuilt once it commits, see deferBloom.
+// It must be called with the write lock held, or before the store is shared.
+func (m *SQLite) rebuildBloom(ctx context.Context, db *tracer) error {
+	if m.bloom == nil {
+		return nil
+	}
+
+	filter := m.bloom
+	if m.bloomTx != nil {
+		m.bloomTx.rebuild = true
+	} else {
+		filter = newBloomFilter(m.Config.BloomFilter)
+	}
+
+	for _, database := range m.databases() {
+		rows, err := db.in(database).QueryContext(ctx, "SELECT key FROM kv WHERE key LIKE ?", m.Config.Prefix+"%")
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var key string
+			if err := rows.Scan(&key); err != nil {
+				rows.Close()
+				return err
+			}
+
+			filter.add(key)
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	if filter != m.bloom {
+		m.bloom.replace(filter)
+	}
+	return nil
+}
+
+// keyWritten updates the in-memory state after the (prefixed) key was written.
+// It must be called with the write lock held.
+func (m *SQLite) keyWritten(keyX string) {
+	if m.bloom != nil {
+		m.bloom.add(keyX)
+	}
+
+	if m.cache != nil {
+		m.cache.remove(keyX)
+	}
+}
+
+// keyRemoved updates the in-memory state after the (prefixed) key was removed.
+// It must be called with the write lock held.
+func (m *SQLite) keyRemoved(keyX string) {
+	if m.bloomTx != nil {
+		m.bloomTx.removed = append(m.bloomTx.removed, keyX)
+	} else if m.bloom != nil {
+		m.bloom.remove(keyX)
+	}
+
+	if m.cache != nil {
+		m.cache.remove(keyX)
+	}
+}
+
+// keysCleared resets the in-memory state after all keys of the prefix were removed or renamed.
+// It must be called with the write lock held.
+func (m *SQLite) keysCleared() {
+	if m.bloomTx != nil {
+		m.bloomTx.rebuild = true
+	} else if m.bloom != nil {
+		m.bloom.reset()
+	}
+
+	if m.cache != nil {
+		m.cache.reset()
+	}
+}
+
+// mayContain reports whether the (prefixed) key may exist; false means it certainly does not.
+func (m *SQLite) mayContain(keyX string) bool {
+	return m.bloom == nil || m.bloom.mayContain(keyX)
+}
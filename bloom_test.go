@@ -0,0 +1,94 @@
+package kvsqlite
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestBloomFilter(t *testing.T) {
+	seed := createClient()
+	seed.Clear()
+	defer seed.Clear()
+	seed.Set("existing", "value")
+
+	client, err := New(&SQLiteConfig{
+		Path:        "/tmp/test.db",
+		Prefix:      "go-zoox-test:",
+		BloomFilter: &BloomConfig{ExpectedKeys: 1000},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if !client.Has("existing") {
+		t.Error("Expected keys stored before startup to be in the filter")
+	}
+
+	client.Set("key", "value")
+	if !client.Has("key") {
+		t.Error("Expected set key to be found")
+	}
+
+	client.Delete("key")
+	if client.mayContain(client.Config.Prefix+"key") || client.Has("key") {
+		t.Error("Expected deleted key to be removed from the filter")
+	}
+
+	falsePositives := 0
+	for i := 0; i < 1000; i++ {
+		if client.mayContain(client.Config.Prefix + "missing" + strconv.Itoa(i)) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 50 {
+		t.Errorf("Expected few false positives, got %d/1000", falsePositives)
+	}
+}
+
+func TestBloomFilterRollback(t *testing.T) {
+	client, err := New(&SQLiteConfig{
+		Path:        "/tmp/test.db",
+		Prefix:      "go-zoox-test:",
+		BloomFilter: &BloomConfig{ExpectedKeys: 1000},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.Clear()
+	defer client.Clear()
+
+	client.Set("key", "value")
+	keyX := client.Config.Prefix + "key"
+
+	// removals and resets of a transaction which rolls back are never applied
+	ctx := context.Background()
+	failed := errors.New("failed")
+	err = client.writeTx(ctx, func(db *tracer) error {
+		if err := client.deleteRow(ctx, db, keyX); err != nil {
+			return err
+		}
+		client.keysCleared()
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("Expected the write to fail, got %v", err)
+	}
+	if !client.Has("key") {
+		t.Error("Expected the key of a rolled back delete to be found")
+	}
+
+	// and they are once it commits
+	err = client.writeTx(ctx, func(db *tracer) error {
+		return client.deleteRow(ctx, db, keyX)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.mayContain(keyX) {
+		t.Error("Expected the committed delete to remove the key from the filter")
+	}
+}
@@ -0,0 +1,104 @@
+package kvsqlite
+
+import (
+	"context"
+	"database/sql"
+)
+
+// LargeValueConfig is the configuration of chunked storage for large values.
+type LargeValueConfig struct {
+	// Threshold is the encoded size above which a value is split into chunks.
+	// Default is 1 MiB.
+	Threshold int
+
+	// ChunkSize is the size of each chunk.
+	// Default is 256 KiB.
+	ChunkSize int
+}
+
+// valueExpr selects the stored value of a kv row, reassembling it from kv_chunks if it is chunked.
+const valueExpr = "CASE WHEN kv.chunks > 0 THEN (SELECT group_concat(data, '') FROM (SELECT data FROM kv_chunks WHERE kv_chunks.key = kv.key ORDER BY seq)) ELSE kv.value END"
+
+// valueSizeExpr selects the size in bytes of the stored value of a kv row.
+const valueSizeExpr = "CASE WHEN kv.chunks > 0 THEN (SELECT coalesce(sum(length(data)), 0) FROM kv_chunks WHERE kv_chunks.key = kv.key) ELSE length(CAST(kv.value AS BLOB)) END"
+
+// createChunkSchema creates the chunk table and the triggers which keep it in sync with the kv table
+// of the given database ("" for main), so deleting, overwriting or renaming a chunked key needs no special handling by writers.
+func createChunkSchema(core *sql.DB, schema string) error {
+	q := ""
+	if schema != "" {
+		q = schema + "."
+	}
+
+	statements := []string{
+		"CREATE TABLE IF NOT EXISTS " + q + "kv_chunks (key TEXT NOT NULL, seq INTEGER NOT NULL, data BLOB NOT NULL, PRIMARY KEY (key, seq)) WITHOUT ROWID",
+	}
+	for _, statement := range append(statements, chunkTriggers(q, "kv")...) {
+		if _, err := core.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// chunkTriggers returns the statements creating the chunk triggers of the given kv table, in the database qualifier q.
+func chunkTriggers(q string, table string) []string {
+	return []string{
+		`CREATE TRIGGER IF NOT EXISTS ` + q + quoteIdent(table+"_chunks_delete") + ` AFTER DELETE ON ` + quoteIdent(table) + ` WHEN old.chunks > 0 BEGIN
+			DELETE FROM kv_chunks WHERE key = old.key;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS ` + q + quoteIdent(table+"_chunks_overwrite") + ` AFTER UPDATE OF value ON ` + quoteIdent(table) + ` WHEN old.chunks > 0 BEGIN
+			DELETE FROM kv_chunks WHERE key = old.key;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS ` + q + quoteIdent(table+"_chunks_rename") + ` AFTER UPDATE OF key ON ` + quoteIdent(table) + ` WHEN old.chunks > 0 AND old.key <> new.key BEGIN
+			UPDATE kv_chunks SET key = new.key WHERE key = old.key;
+		END`,
+	}
+}
+
+// chunkSizes returns the threshold and chunk size of large values, or zeros if chunking is disabled.
+func (m *SQLite) chunkSizes() (threshold int, size int) {
+	cfg := m.Config.LargeValues
+	if cfg == nil {
+		return 0, 0
+	}
+
+	threshold, size = cfg.Threshold, cfg.ChunkSize
+	if threshold <= 0 {
+		threshold = 1 << 20
+	}
+	if size <= 0 {
+		size = 256 << 10
+	}
+
+	return threshold, size
+}
+
+// shouldChunk reports whether a value of the given encoded size is stored in chunks.
+func (m *SQLite) shouldChunk(size int) bool {
+	threshold, _ := m.chunkSizes()
+	return threshold > 0 && size > threshold
+}
+
+// writeChunks stores value as chunk rows of keyX and marks the kv row as chunked.
+// The kv row must have been written just before, which removed its previous chunks.
+func (m *SQLite) writeChunks(ctx context.Context, db *tracer, keyX string, value []byte) error {
+	_, size := m.chunkSizes()
+
+	var seq int
+	for offset := 0; offset < len(value); offset += size {
+		end := offset + size
+		if end > len(value) {
+			end = len(value)
+		}
+
+		if _, err := db.ExecContext(ctx, "INSERT INTO kv_chunks (key, seq, data) VALUES (?, ?, ?)", keyX, seq, value[offset:end]); err != nil {
+			return err
+		}
+		seq++
+	}
+
+	_, err := db.ExecContext(ctx, "UPDATE kv SET chunks = ? WHERE key = ?", seq, keyX)
+	return err
+}
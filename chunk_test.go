@@ -0,0 +1,78 @@
+package kvsqlite
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLargeValues(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	client.Config.LargeValues = &LargeValueConfig{Threshold: 1024, ChunkSize: 100}
+	defer func() { client.Config.LargeValues = nil }()
+
+	large := strings.Repeat("0123456789", 500)
+	if err := client.Set("large", large); err != nil {
+		t.Fatal(err)
+	}
+
+	var chunks int
+	client.Core.QueryRow("SELECT count(*) FROM kv_chunks WHERE key = ?", client.Config.Prefix+"large").Scan(&chunks)
+	if chunks != 51 {
+		t.Errorf("Expected 51 chunks, got %d", chunks)
+	}
+
+	var got string
+	if err := client.Get("large", &got); err != nil || got != large {
+		t.Fatalf("Expected the value to be reassembled, got %d bytes (%v)", len(got), err)
+	}
+
+	// overwriting with a small value removes the chunks
+	client.Set("large", "small")
+	client.Core.QueryRow("SELECT count(*) FROM kv_chunks WHERE key = ?", client.Config.Prefix+"large").Scan(&chunks)
+	if chunks != 0 {
+		t.Errorf("Expected chunks to be removed on overwrite, got %d", chunks)
+	}
+	if client.Get("large", &got); got != "small" {
+		t.Errorf("Expected small, got %s", got)
+	}
+
+	client.Set("large", large)
+	client.Delete("large")
+	client.Core.QueryRow("SELECT count(*) FROM kv_chunks WHERE key = ?", client.Config.Prefix+"large").Scan(&chunks)
+	if chunks != 0 {
+		t.Errorf("Expected chunks to be removed on delete, got %d", chunks)
+	}
+}
+
+func TestLargeValuesStream(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	client.Config.LargeValues = &LargeValueConfig{ChunkSize: 100}
+	defer func() { client.Config.LargeValues = nil }()
+
+	data := bytes.Repeat([]byte{0, 1, 2, 255}, 1000)
+	if err := client.SetReader("stream", bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	usage, _ := client.Usage()
+	if usage.Bytes < int64(len(data)) {
+		t.Errorf("Expected usage to count chunks, got %d bytes", usage.Bytes)
+	}
+
+	r, err := client.GetReader("stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	got, _ := io.ReadAll(r)
+	if !bytes.Equal(got, data) {
+		t.Errorf("Expected %d bytes back, got %d", len(data), len(got))
+	}
+}
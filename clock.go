@@ -0,0 +1,96 @@
+package kvsqlite
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the time source of the store.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// ManualClock is a Clock which only moves when told to, for tests and simulations.
+type ManualClock struct {
+	sync.Mutex
+	now time.Time
+}
+
+// NewManualClock returns a new ManualClock set to t.
+func NewManualClock(t time.Time) *ManualClock {
+	return &ManualClock{now: t}
+}
+
+// Now returns the current time of the clock.
+func (c *ManualClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.now
+}
+
+// Set sets the current time of the clock.
+func (c *ManualClock) Set(t time.Time) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.now = t
+}
+
+// Add moves the clock forward by d.
+func (c *ManualClock) Add(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func (m *SQLite) clock() Clock {
+	if m.Config.Clock != nil {
+		return m.Config.Clock
+	}
+
+	return systemClock{}
+}
+
+// now returns the current time in milliseconds.
+func (m *SQLite) now() int64 {
+	return m.clock().Now().UnixMilli()
+}
+
+// expiryCutoff returns the time in milliseconds before which an expiration time has passed:
+// keys are only considered expired ClockSkew after their expiration time.
+// Rows with expires_at = 0 or expires_at >= the cutoff are not expired, see unexpired.
+func (m *SQLite) expiryCutoff() int64 {
+	return m.now() - m.Config.ClockSkew.Milliseconds()
+}
+
+// unexpired is the condition of kv rows which are not expired, bound to expiryCutoff.
+const unexpired = "(expires_at = 0 OR expires_at >= ?)"
+
+// expiresAt returns the expiration time in milliseconds for the given maxAge,
+// rounded up to the configured TTL resolution.
+func (m *SQLite) expiresAt(maxAge time.Duration) int64 {
+	return m.roundExpiresAt(m.now() + int64(maxAge/time.Millisecond))
+}
+
+// expiresAtTime returns the expiration time in milliseconds for the absolute time t,
+// rounded up to the configured TTL resolution.
+func (m *SQLite) expiresAtTime(t time.Time) int64 {
+	return m.roundExpiresAt(t.UnixMilli())
+}
+
+func (m *SQLite) roundExpiresAt(expiresAt int64) int64 {
+	resolution := int64(m.Config.TTLResolution / time.Millisecond)
+	if resolution > 1 && expiresAt%resolution != 0 {
+		expiresAt += resolution - expiresAt%resolution
+	}
+
+	return expiresAt
+}
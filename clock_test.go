@@ -0,0 +1,83 @@
+package kvsqlite
+
+import (
+	"testing"
+	"time"
+)
+
+func TestManualClock(t *testing.T) {
+	clock := NewManualClock(time.UnixMilli(1000500))
+	client, err := New(&SQLiteConfig{
+		Path:          "/tmp/test.db",
+		Prefix:        "go-zoox-test-clock:",
+		Clock:         clock,
+		TTLResolution: time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.Clear()
+	defer client.Clear()
+
+	if got := client.expiresAt(time.Minute); got != 1061000 {
+		t.Errorf("Expected expiration to be rounded up to 1061000, got %d", got)
+	}
+
+	if err := client.Set("key", "value", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Add(30 * time.Second)
+	var value string
+	if err := client.Get("key", &value); err != nil || value != "value" {
+		t.Errorf("Expected value to be 'value', got %q (%v)", value, err)
+	}
+
+	clock.Add(time.Minute)
+	client.Get("key", &value)
+	if client.Has("key") {
+		t.Error("Expected key to be expired")
+	}
+}
+
+func TestClockSkew(t *testing.T) {
+	writerClock := NewManualClock(time.Now())
+	readerClock := NewManualClock(writerClock.Now().Add(3 * time.Second))
+
+	writer, err := New(&SQLiteConfig{Path: "/tmp/test.db", Prefix: "go-zoox-test-skew:", Clock: writerClock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+	defer writer.Clear()
+
+	// the reader's clock is 3 seconds ahead of the writer's
+	reader, err := New(&SQLiteConfig{Path: "/tmp/test.db", Prefix: "go-zoox-test-skew:", Clock: readerClock, ClockSkew: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	writer.Set("key", "value", 2*time.Second)
+	writer.Set("expired", "value", time.Millisecond)
+	writerClock.Add(time.Second)
+
+	var value string
+	if err := reader.Get("key", &value); err != nil || value != "value" {
+		t.Errorf("Expected the key to be read within the tolerance, got %q (%v)", value, err)
+	}
+	if !reader.Has("key") || reader.Size() != 2 || len(reader.Keys()) != 2 {
+		t.Errorf("Expected Has, Size and Keys to agree within the tolerance, got %d keys", reader.Size())
+	}
+
+	// expired for the writer, which has no tolerance
+	if writer.Has("expired") || writer.Size() != 1 {
+		t.Error("Expected the writer to filter the expired key")
+	}
+
+	readerClock.Add(5 * time.Second)
+	if reader.Has("key") || reader.Size() != 0 {
+		t.Error("Expected the key to be expired after the tolerance")
+	}
+}
@@ -0,0 +1,244 @@
+package kvsqlite
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// Compaction steps, see CompactOptions.Progress.
+const (
+	CompactVacuum  = "vacuum"
+	CompactReindex = "reindex"
+)
+
+// CompactOptions are the options of Compact.
+type CompactOptions struct {
+	// Pages is the number of free pages reclaimed per step of the incremental vacuum, default is 256.
+	// The store lock is released between steps, so other operations go on during the compaction.
+	Pages int
+
+	// Progress is called after each step with the step kind, CompactVacuum or CompactReindex,
+	// the number of steps of that kind done and their total.
+	Progress func(step string, done, total int)
+}
+
+// CompactReport is the outcome of Compact.
+type CompactReport struct {
+	// BytesBefore and BytesAfter are the sizes of the databases of the store.
+	BytesBefore int64
+	BytesAfter  int64
+
+	// BytesReclaimed is the size of the free pages released to the file system.
+	BytesReclaimed int64
+
+	// Indexes is the number of rebuilt indexes.
+	Indexes int
+
+	Duration time.Duration
+}
+
+// Compact reclaims the free pages of the databases of the store with incremental vacuum, in steps,
+// then rebuilds their indexes one at a time with REINDEX, e.g. for scheduled maintenance.
+// Free pages are only reclaimed from databases with incremental auto-vacuum, see Config.Vacuum.
+// With WAL, the file only shrinks after the next checkpoint, see Checkpoint.
+func (m *SQLite) Compact(opts ...*CompactOptions) (*CompactReport, error) {
+	return m.CompactContext(context.Background(), opts...)
+}
+
+// CompactContext is like Compact but stops between steps when ctx is done, and returns ctx.Err().
+func (m *SQLite) CompactContext(ctx context.Context, opts ...*CompactOptions) (*CompactReport, error) {
+	if err := m.supports(FeaturePragmas); err != nil {
+		return nil, err
+	}
+
+	opt := &CompactOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		opt = opts[0]
+	}
+
+	pages := opt.Pages
+	if pages <= 0 {
+		pages = 256
+	}
+
+	progress := func(step string, done, total int) {
+		if opt.Progress != nil {
+			opt.Progress(step, done, total)
+		}
+	}
+
+	start := time.Now()
+	report := &CompactReport{}
+
+	var err error
+	if report.BytesBefore, err = m.databaseBytes(ctx); err != nil {
+		return nil, err
+	}
+
+	// free pages by database with incremental auto-vacuum
+	free := make(map[string]int)
+	var totalSteps int
+	for _, database := range m.databases() {
+		var mode, count int
+		if err := m.db().QueryRowContext(ctx, "PRAGMA "+schemaIdent(database)+".auto_vacuum").Scan(&mode); err != nil {
+			return nil, err
+		}
+		if mode != 2 {
+			continue
+		}
+
+		if err := m.db().QueryRowContext(ctx, "PRAGMA "+schemaIdent(database)+".freelist_count").Scan(&count); err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			free[database] = count
+			totalSteps += (count + pages - 1) / pages
+		}
+	}
+
+	var pageSize int64
+	if err := m.db().QueryRowContext(ctx, "PRAGMA page_size").Scan(&pageSize); err != nil {
+		return nil, err
+	}
+
+	done := 0
+	for _, database := range m.databases() {
+		for remaining := free[database]; remaining > 0; remaining -= pages {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			reclaimed, err := m.vacuumStep(ctx, database, pages)
+			if err != nil {
+				return nil, err
+			}
+
+			report.BytesReclaimed += reclaimed * pageSize
+			done++
+			progress(CompactVacuum, done, totalSteps)
+			if reclaimed == 0 {
+				break
+			}
+		}
+	}
+
+	indexes, err := m.indexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, index := range indexes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		m.Lock()
+		_, err := m.wdb().ExecContext(ctx, "REINDEX "+index)
+		m.Unlock()
+		if err != nil {
+			return nil, err
+		}
+
+		report.Indexes++
+		progress(CompactReindex, i+1, len(indexes))
+	}
+
+	if report.BytesAfter, err = m.databaseBytes(ctx); err != nil {
+		return nil, err
+	}
+
+	report.Duration = time.Since(start)
+	return report, nil
+}
+
+// vacuumStep reclaims up to pages free pages of the given database and returns how many were reclaimed.
+func (m *SQLite) vacuumStep(ctx context.Context, database string, pages int) (int64, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	schema := schemaIdent(database)
+	var before, after int64
+	if err := m.wdb().QueryRowContext(ctx, "PRAGMA "+schema+".freelist_count").Scan(&before); err != nil {
+		return 0, err
+	}
+
+	// the pragma reclaims one page per step, so the rows must be drained
+	rows, err := m.wdb().QueryContext(ctx, "PRAGMA "+schema+".incremental_vacuum("+strconv.Itoa(pages)+")")
+	if err != nil {
+		return 0, err
+	}
+	for rows.Next() {
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if err := m.wdb().QueryRowContext(ctx, "PRAGMA "+schema+".freelist_count").Scan(&after); err != nil {
+		return 0, err
+	}
+
+	return before - after, nil
+}
+
+// indexes returns the qualified names of the indexes of the databases of the store.
+func (m *SQLite) indexes(ctx context.Context) ([]string, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	indexes := make([]string, 0)
+	for _, database := range m.databases() {
+		schema := schemaIdent(database)
+		rows, err := m.db().QueryContext(ctx, "SELECT name FROM "+schema+".sqlite_master WHERE type = 'index' ORDER BY name")
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				rows.Close()
+				return nil, err
+			}
+
+			indexes = append(indexes, schema+"."+quoteIdent(name))
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return indexes, nil
+}
+
+// databaseBytes returns the total size of the databases of the store.
+func (m *SQLite) databaseBytes(ctx context.Context) (int64, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	var total int64
+	for _, database := range m.databases() {
+		var count, size int64
+		if err := m.db().QueryRowContext(ctx, "PRAGMA "+schemaIdent(database)+".page_count").Scan(&count); err != nil {
+			return 0, err
+		}
+		if err := m.db().QueryRowContext(ctx, "PRAGMA "+schemaIdent(database)+".page_size").Scan(&size); err != nil {
+			return 0, err
+		}
+		total += count * size
+	}
+
+	return total, nil
+}
+
+// schemaIdent returns the identifier of the given database, "" for main.
+func schemaIdent(database string) string {
+	if database == "" {
+		return "main"
+	}
+
+	return quoteIdent(database)
+}
@@ -0,0 +1,71 @@
+package kvsqlite
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompact(t *testing.T) {
+	path := "/tmp/test-compact.db"
+	os.Remove(path)
+	defer os.Remove(path)
+
+	client, err := New(&SQLiteConfig{
+		Path:   path,
+		Prefix: "go-zoox-test:",
+		Vacuum: &VacuumConfig{Interval: time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	value := strings.Repeat("x", 4096)
+	for _, key := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		client.Set(key, value)
+	}
+	client.Clear()
+
+	steps := make(map[string]int)
+	report, err := client.Compact(&CompactOptions{
+		Pages: 2,
+		Progress: func(step string, done, total int) {
+			if done > total {
+				t.Errorf("Expected done <= total, got %d > %d", done, total)
+			}
+			steps[step]++
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.BytesReclaimed == 0 {
+		t.Error("Expected bytes reclaimed")
+	}
+	if report.BytesAfter >= report.BytesBefore {
+		t.Errorf("Expected the database to shrink, got %d -> %d", report.BytesBefore, report.BytesAfter)
+	}
+	if report.Indexes == 0 || steps[CompactReindex] != report.Indexes {
+		t.Errorf("Expected a progress call per index, got %d for %d", steps[CompactReindex], report.Indexes)
+	}
+	if steps[CompactVacuum] < 2 {
+		t.Errorf("Expected the vacuum to run in steps, got %d", steps[CompactVacuum])
+	}
+
+	after, err := client.Fragmentation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after != 0 {
+		t.Errorf("Expected no free pages after compact, got %f", after)
+	}
+
+	client.Set("a", "1")
+	var v string
+	if err := client.Get("a", &v); err != nil || v != "1" {
+		t.Errorf("Expected 1, got %q (%v)", v, err)
+	}
+}
@@ -0,0 +1,157 @@
+package kvsqlite
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrCompositeKey is returned by EncodeKey for parts of unsupported types, and by DecodeKey for malformed keys.
+var ErrCompositeKey = errors.New("sqlite: invalid composite key")
+
+// The tags of the parts of composite keys. Parts of different types order by tag.
+const (
+	keyTagInt    = 'i'
+	keyTagUint   = 'u'
+	keyTagString = 's'
+	keyTagTime   = 't'
+)
+
+// Strings end with keyEnd, and the bytes below keyEscaped are escaped, so a string orders before its extensions
+// and the bytes of strings keep their order.
+const (
+	keyEscape  = '\x01'
+	keyEnd     = "\x01\x01"
+	keyEscaped = '\x02'
+)
+
+// EncodeKey builds a composite key from typed parts: strings, signed and unsigned integers and times,
+// which orders like its parts, part by part, so range scans over keys built from IDs or timestamps,
+// e.g. with GetRange or FirstKey, follow the order of the values. Integers and times have a fixed width
+// and the encoding of leading parts is a prefix of the key, e.g. EncodeKey("user", 42) of EncodeKey("user", 42, t).
+// Times are stored with nanoseconds in UTC, so they must be within the years 1678 to 2262.
+// Keys are text, but strings end with control characters, which SanitizeKeys rejects. DecodeKey parses them back.
+func EncodeKey(parts ...any) (string, error) {
+	var b strings.Builder
+	for i, part := range parts {
+		switch v := part.(type) {
+		case string:
+			b.WriteByte(keyTagString)
+			for j := 0; j < len(v); j++ {
+				if c := v[j]; c < keyEscaped {
+					b.WriteByte(keyEscape)
+					b.WriteByte(c + keyEscaped)
+				} else {
+					b.WriteByte(c)
+				}
+			}
+			b.WriteString(keyEnd)
+		case int:
+			writeKeyInt(&b, int64(v))
+		case int8:
+			writeKeyInt(&b, int64(v))
+		case int16:
+			writeKeyInt(&b, int64(v))
+		case int32:
+			writeKeyInt(&b, int64(v))
+		case int64:
+			writeKeyInt(&b, v)
+		case uint:
+			writeKeyUint(&b, keyTagUint, uint64(v))
+		case uint8:
+			writeKeyUint(&b, keyTagUint, uint64(v))
+		case uint16:
+			writeKeyUint(&b, keyTagUint, uint64(v))
+		case uint32:
+			writeKeyUint(&b, keyTagUint, uint64(v))
+		case uint64:
+			writeKeyUint(&b, keyTagUint, v)
+		case time.Time:
+			writeKeyUint(&b, keyTagTime, uint64(v.UnixNano())^1<<63)
+		default:
+			return "", fmt.Errorf("%w: part %d of type %T", ErrCompositeKey, i, part)
+		}
+	}
+
+	return b.String(), nil
+}
+
+// writeKeyInt writes v with its sign bit flipped, so negative integers order first.
+func writeKeyInt(b *strings.Builder, v int64) {
+	writeKeyUint(b, keyTagInt, uint64(v)^1<<63)
+}
+
+// writeKeyUint writes v as 16 hexadecimal digits, which order like v.
+func writeKeyUint(b *strings.Builder, tag byte, v uint64) {
+	b.WriteByte(tag)
+
+	digits := strconv.FormatUint(v, 16)
+	b.WriteString(strings.Repeat("0", 16-len(digits)))
+	b.WriteString(digits)
+}
+
+// DecodeKey parses a key built by EncodeKey back into its parts: strings as string, signed integers as int64,
+// unsigned integers as uint64 and times as time.Time in UTC.
+func DecodeKey(key string) ([]any, error) {
+	parts := make([]any, 0)
+	for i := 0; i < len(key); {
+		tag := key[i]
+		i++
+
+		switch tag {
+		case keyTagString:
+			var b strings.Builder
+			for {
+				if i >= len(key) {
+					return nil, fmt.Errorf("%w: unterminated string", ErrCompositeKey)
+				}
+
+				c := key[i]
+				if c != keyEscape {
+					b.WriteByte(c)
+					i++
+					continue
+				}
+
+				if i+1 >= len(key) {
+					return nil, fmt.Errorf("%w: unterminated string", ErrCompositeKey)
+				}
+				escaped := key[i+1]
+				i += 2
+				if escaped == keyEscape {
+					break
+				}
+				if escaped < keyEscaped || escaped > keyEscaped+keyEscape {
+					return nil, fmt.Errorf("%w: invalid escape", ErrCompositeKey)
+				}
+				b.WriteByte(escaped - keyEscaped)
+			}
+			parts = append(parts, b.String())
+		case keyTagInt, keyTagUint, keyTagTime:
+			if i+16 > len(key) {
+				return nil, fmt.Errorf("%w: truncated number", ErrCompositeKey)
+			}
+
+			v, err := strconv.ParseUint(key[i:i+16], 16, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrCompositeKey, err)
+			}
+			i += 16
+
+			switch tag {
+			case keyTagInt:
+				parts = append(parts, int64(v^1<<63))
+			case keyTagUint:
+				parts = append(parts, v)
+			default:
+				parts = append(parts, time.Unix(0, int64(v^1<<63)).UTC())
+			}
+		default:
+			return nil, fmt.Errorf("%w: unknown part tag %q", ErrCompositeKey, tag)
+		}
+	}
+
+	return parts, nil
+}
@@ -0,0 +1,96 @@
+package kvsqlite
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestEncodeKeyOrder(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ordered := [][]any{
+		{int64(-10)},
+		{int64(-1)},
+		{int64(0)},
+		{int64(9)},
+		{int64(10)},
+		{"a"},
+		{"a", int64(1)},
+		{"a\x00"},
+		{"a\x01"},
+		{"a!"},
+		{"ab"},
+		{"b", base.Add(-time.Hour)},
+		{"b", base},
+		{"b", base.Add(time.Nanosecond)},
+	}
+
+	keys := make([]string, len(ordered))
+	for i, parts := range ordered {
+		key, err := EncodeKey(parts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = key
+
+		decoded, err := DecodeKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded, parts) {
+			t.Errorf("Expected %v, got %v", parts, decoded)
+		}
+	}
+
+	if !sort.StringsAreSorted(keys) {
+		t.Errorf("Expected the keys to order like their parts: %q", keys)
+	}
+}
+
+func TestEncodeKeyErrors(t *testing.T) {
+	if _, err := EncodeKey(1.5); !errors.Is(err, ErrCompositeKey) {
+		t.Errorf("Expected ErrCompositeKey, got %v", err)
+	}
+
+	for _, key := range []string{"x", "sabc", "i123", "sa\x01\x09"} {
+		if _, err := DecodeKey(key); !errors.Is(err, ErrCompositeKey) {
+			t.Errorf("Expected ErrCompositeKey for %q, got %v", key, err)
+		}
+	}
+}
+
+func TestEncodeKeyRange(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	for _, id := range []int{2, 9, 10, 100} {
+		key, _ := EncodeKey("order", id)
+		client.Set(key, id)
+	}
+
+	start, _ := EncodeKey("order", 9)
+	end, _ := EncodeKey("order", 100)
+	items, err := client.GetRange(start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make([]int, len(items))
+	for i, item := range items {
+		item.Decode(&ids[i])
+	}
+	if !reflect.DeepEqual(ids, []int{9, 10}) {
+		t.Errorf("Expected [9 10], got %v", ids)
+	}
+
+	prefix, _ := EncodeKey("order")
+	last, err := client.LastKey(prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts, _ := DecodeKey(last); !reflect.DeepEqual(parts, []any{"order", int64(100)}) {
+		t.Errorf("Expected the last order to be 100, got %v", parts)
+	}
+}
@@ -0,0 +1,79 @@
+package kvsqlite
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+)
+
+// WriteConcern is the durability of a write, see WithWriteConcern.
+type WriteConcern int
+
+const (
+	// WriteDefault writes with the synchronous mode of the database, NORMAL unless changed.
+	// With WAL, a commit survives a crash of the application, but may be lost on a power loss.
+	WriteDefault WriteConcern = iota
+
+	// WriteDurable syncs the commit to disk before returning (synchronous FULL),
+	// so it also survives a power loss.
+	WriteDurable
+
+	// WriteRelaxed leaves syncing to the operating system (synchronous OFF): the commit survives
+	// a crash of the application, but may be lost, with other recent commits, on a power loss.
+	WriteRelaxed
+)
+
+// synchronous returns the synchronous mode of the concern.
+func (c WriteConcern) synchronous() string {
+	switch c {
+	case WriteDurable:
+		return "FULL"
+	case WriteRelaxed:
+		return "OFF"
+	}
+
+	return ""
+}
+
+// writeConn is the database, or connection, writes run on.
+type writeConn interface {
+	execer
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// writeConcerned is like writeWith, with the given write concern. The synchronous mode is per connection,
+// so the write runs on a connection of its own, switched to the mode of the concern and back.
+func (m *SQLite) writeConcerned(ctx context.Context, concern WriteConcern, transactional bool, fn func(db *tracer) error) error {
+	mode := concern.synchronous()
+	if mode == "" {
+		return m.writeWith(ctx, transactional, fn)
+	}
+	if err := m.supports(FeaturePragmas); err != nil {
+		return err
+	}
+
+	unlock := m.lockWrites()
+	defer unlock()
+
+	conn, err := m.writeCore().Conn(ctx)
+	if err != nil {
+		return m.classify(err)
+	}
+	defer conn.Close()
+
+	for _, database := range m.databases() {
+		schema := schemaIdent(database)
+
+		var previous int
+		if err := conn.QueryRowContext(ctx, "PRAGMA "+schema+".synchronous").Scan(&previous); err != nil {
+			return m.classify(err)
+		}
+		if _, err := conn.ExecContext(ctx, "PRAGMA "+schema+".synchronous = "+mode); err != nil {
+			return m.classify(err)
+		}
+
+		defer conn.ExecContext(context.Background(), "PRAGMA "+schema+".synchronous = "+strconv.Itoa(previous))
+	}
+
+	return m.runWrite(ctx, conn, transactional, fn)
+}
@@ -0,0 +1,45 @@
+package kvsqlite
+
+import (
+	"os"
+	"testing"
+)
+
+func TestWriteConcern(t *testing.T) {
+	path := "/tmp/test-concern.db"
+	os.Remove(path)
+	defer os.Remove(path)
+
+	client, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// a single connection, so the mode read is the one of the writes
+	client.core.SetMaxOpenConns(1)
+
+	synchronous := func() int {
+		var mode int
+		if err := client.core.QueryRow("PRAGMA synchronous").Scan(&mode); err != nil {
+			t.Fatal(err)
+		}
+		return mode
+	}
+	before := synchronous()
+
+	for _, concern := range []WriteConcern{WriteDurable, WriteRelaxed, WriteDefault} {
+		if _, err := client.SetWith("concern", int(concern), WithWriteConcern(concern)); err != nil {
+			t.Fatal(err)
+		}
+
+		var v int
+		if err := client.Get("concern", &v); err != nil || v != int(concern) {
+			t.Errorf("Expected %d, got %d (%v)", concern, v, err)
+		}
+
+		if mode := synchronous(); mode != before {
+			t.Errorf("Expected the synchronous mode to be restored to %d, got %d", before, mode)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package kvsqlite
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// contentKeyPrefix is the prefix of the keys returned by Put.
+const contentKeyPrefix = "sha256:"
+
+// Put stores the value under the SHA-256 hash of its JSON encoding and returns the key, e.g. sha256:2c26….
+// Putting the same value again writes nothing and returns the same key, so values are deduplicated.
+// The key is immutable, see SetImmutable, and read with Get like any other key.
+func (m *SQLite) Put(value any) (string, error) {
+	return m.PutContext(context.Background(), value)
+}
+
+// PutContext is like Put but honors ctx.
+func (m *SQLite) PutContext(ctx context.Context, value any) (string, error) {
+	// hashed before the transformers, whose output may differ for the same value, e.g. AESGCM
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(raw)
+	key := contentKeyPrefix + hex.EncodeToString(sum[:])
+
+	if _, err := m.set(ctx, key, value, WithNX(), WithImmutable()); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
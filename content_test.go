@@ -0,0 +1,47 @@
+package kvsqlite
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPut(t *testing.T) {
+	client, err := New(&SQLiteConfig{
+		Path:   "/tmp/test.db",
+		Prefix: "go-zoox-test-content:" + time.Now().String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	key, err := client.Put(map[string]string{"name": "artifact"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, "sha256:") || len(key) != len("sha256:")+64 {
+		t.Fatalf("Expected a sha256 key, got %s", key)
+	}
+
+	again, err := client.Put(map[string]string{"name": "artifact"})
+	if err != nil || again != key {
+		t.Errorf("Expected the same key %s, got %s (%v)", key, again, err)
+	}
+	if other, _ := client.Put("other"); other == key {
+		t.Error("Expected another value to get another key")
+	}
+	if n := client.Size(); n != 2 {
+		t.Errorf("Expected 2 stored values, got %d", n)
+	}
+
+	var value map[string]string
+	if err := client.Get(key, &value); err != nil || value["name"] != "artifact" {
+		t.Errorf("Expected the stored value, got %v (%v)", value, err)
+	}
+
+	if err := client.Set(key, "tampered"); !errors.Is(err, ErrImmutable) {
+		t.Errorf("Expected ErrImmutable, got %v", err)
+	}
+}
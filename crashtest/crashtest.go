@@ -0,0 +1,319 @@
+// Package crashtest simulates crashes of a process writing to a SQLite store, from copies of its files
+// taken between writes, and checks that the store recovers from them without silent data loss,
+// so the durability settings of an application can be verified.
+package crashtest
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	kvsqlite "github.com/go-zoox/kv-sqlite"
+)
+
+var (
+	// ErrDataLoss is returned when acknowledged writes are missing after a crash which must not lose any.
+	ErrDataLoss = errors.New("crashtest: acknowledged writes lost")
+
+	// ErrCorruption is returned when the database recovered from a crash is corrupt, holds a wrong value,
+	// or holds a write but not an earlier one.
+	ErrCorruption = errors.New("crashtest: database corrupt after crash")
+)
+
+// walHeaderSize is the size of the header of the WAL file.
+const walHeaderSize = 32
+
+// Mode is the kind of crash simulated.
+type Mode int
+
+const (
+	// Kill kills the process: the files hold everything written, synced or not, so no acknowledged write may be lost.
+	Kill Mode = iota
+
+	// PowerLoss loses the power: the WAL is cut at a random byte, as if its end wasn't synced to disk.
+	// Acknowledged writes may be lost, e.g. with synchronous NORMAL, see Report.Lost, but only the latest ones.
+	PowerLoss
+
+	// TornCheckpoint loses the power during a checkpoint: the database file holds a random mix of its pages
+	// before and after the checkpoint, which WAL recovery must repair, so no acknowledged write may be lost.
+	TornCheckpoint
+)
+
+func (m Mode) String() string {
+	switch m {
+	case Kill:
+		return "kill"
+	case PowerLoss:
+		return "power loss"
+	case TornCheckpoint:
+		return "torn checkpoint"
+	}
+
+	return "mode " + strconv.Itoa(int(m))
+}
+
+// Options are the options of Run.
+type Options struct {
+	// Config is the configuration of the store under test, its Path is set to a file of Dir.
+	// Default is a store with the prefix crashtest:. The journal mode must be WAL, the default here.
+	Config *kvsqlite.SQLiteConfig
+
+	// Dir is the directory of the database and its copies, default is a temporary directory.
+	Dir string
+
+	// Writes is the number of writes, default is 100.
+	Writes int
+
+	// Crashes is the number of crashes simulated during the writes, default is 10.
+	Crashes int
+
+	// Seed seeds the random choices: when to checkpoint and where to cut or tear the files, default is 1.
+	Seed int64
+}
+
+// Report is the outcome of Run.
+type Report struct {
+	Mode Mode
+
+	// Crashes is the number of crashes the store recovered from.
+	Crashes int
+
+	// Acknowledged is the number of writes acknowledged by the store.
+	Acknowledged int
+
+	// Lost is the number of acknowledged writes lost over all crashes, only with PowerLoss.
+	Lost int
+}
+
+// Run writes to a store and simulates crashes in the given mode while it does, recovering a copy of the files
+// of the store at each crash. Writes set the keys crash:0, crash:1... to their index, one after the other,
+// with checkpoints in between, so crashes happen between WAL writes and checkpoints.
+// It returns ErrDataLoss or ErrCorruption if a recovery fails.
+func Run(mode Mode, opts ...*Options) (*Report, error) {
+	opt := &Options{}
+	if len(opts) > 0 && opts[0] != nil {
+		*opt = *opts[0]
+	}
+
+	if opt.Writes <= 0 {
+		opt.Writes = 100
+	}
+	if opt.Crashes <= 0 {
+		opt.Crashes = 10
+	}
+	if opt.Crashes > opt.Writes {
+		opt.Crashes = opt.Writes
+	}
+	if opt.Seed == 0 {
+		opt.Seed = 1
+	}
+
+	if opt.Dir == "" {
+		dir, err := os.MkdirTemp("", "kv-sqlite-crashtest-*")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+		opt.Dir = dir
+	}
+
+	cfg := &kvsqlite.SQLiteConfig{Prefix: "crashtest:"}
+	if opt.Config != nil {
+		*cfg = *opt.Config
+	}
+	if cfg.JournalMode == "" {
+		cfg.JournalMode = "WAL"
+	}
+	if cfg.JournalMode != "WAL" && cfg.JournalMode != "wal" {
+		return nil, fmt.Errorf("crashtest: journal mode %s is not WAL", cfg.JournalMode)
+	}
+	cfg.Path = filepath.Join(opt.Dir, "store.db")
+
+	store, err := kvsqlite.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	r := &runner{
+		mode:   mode,
+		cfg:    cfg,
+		store:  store,
+		rng:    rand.New(rand.NewSource(opt.Seed)),
+		report: &Report{Mode: mode},
+	}
+
+	every := opt.Writes / opt.Crashes
+	for i := 0; i < opt.Writes; i++ {
+		if err := store.Set(key(i), i); err != nil {
+			return nil, err
+		}
+		r.report.Acknowledged++
+
+		if r.rng.Intn(10) == 0 {
+			if _, err := store.Checkpoint(kvsqlite.CheckpointPassive); err != nil {
+				return nil, err
+			}
+		}
+
+		if (i+1)%every == 0 {
+			if err := r.crash(filepath.Join(opt.Dir, fmt.Sprintf("crash-%d.db", r.report.Crashes))); err != nil {
+				return r.report, fmt.Errorf("%w (%s after %d writes)", err, mode, r.report.Acknowledged)
+			}
+			r.report.Crashes++
+		}
+	}
+
+	return r.report, nil
+}
+
+func key(i int) string {
+	return "crash:" + strconv.Itoa(i)
+}
+
+type runner struct {
+	mode   Mode
+	cfg    *kvsqlite.SQLiteConfig
+	store  *kvsqlite.SQLite
+	rng    *rand.Rand
+	report *Report
+}
+
+// crash writes the files of the store after a crash to path, recovers them and checks the writes.
+func (r *runner) crash(path string) error {
+	defer func() {
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			os.Remove(path + suffix)
+		}
+	}()
+
+	var before []byte
+	if r.mode == TornCheckpoint {
+		var err error
+		if before, err = os.ReadFile(r.cfg.Path); err != nil {
+			return err
+		}
+		if _, err := r.store.Checkpoint(kvsqlite.CheckpointPassive); err != nil {
+			return err
+		}
+	}
+
+	db, err := os.ReadFile(r.cfg.Path)
+	if err != nil {
+		return err
+	}
+	wal, err := os.ReadFile(r.cfg.Path + "-wal")
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	switch r.mode {
+	case PowerLoss:
+		if len(wal) > walHeaderSize {
+			wal = wal[:walHeaderSize+r.rng.Intn(len(wal)-walHeaderSize+1)]
+		}
+	case TornCheckpoint:
+		db = r.tear(before, db)
+	}
+
+	if err := os.WriteFile(path, db, 0o644); err != nil {
+		return err
+	}
+	if wal != nil {
+		if err := os.WriteFile(path+"-wal", wal, 0o644); err != nil {
+			return err
+		}
+	}
+
+	return r.recover(path)
+}
+
+// tear mixes the pages of the database file before and after a checkpoint at random.
+func (r *runner) tear(before, after []byte) []byte {
+	if len(after) < 18 {
+		return after
+	}
+
+	pageSize := int(binary.BigEndian.Uint16(after[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+
+	torn := append([]byte(nil), after...)
+	for offset := 0; offset+pageSize <= len(before) && offset+pageSize <= len(torn); offset += pageSize {
+		if r.rng.Intn(2) == 0 {
+			copy(torn[offset:offset+pageSize], before[offset:offset+pageSize])
+		}
+	}
+
+	return torn
+}
+
+// recover opens the crashed database at path and checks it holds the acknowledged writes.
+func (r *runner) recover(path string) error {
+	if err := checkIntegrity(path); err != nil {
+		return err
+	}
+
+	cfg := *r.cfg
+	cfg.Path = path
+	store, err := kvsqlite.New(&cfg)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrCorruption, err)
+	}
+	defer store.Close()
+
+	recovered := -1
+	for i := 0; i < r.report.Acknowledged; i++ {
+		var v int
+		found, err := store.Lookup(key(i), &v)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %s", ErrCorruption, key(i), err)
+		}
+
+		switch {
+		case found && recovered >= 0:
+			return fmt.Errorf("%w: %s recovered but not %s", ErrCorruption, key(i), key(recovered))
+		case found && v != i:
+			return fmt.Errorf("%w: %s holds %d", ErrCorruption, key(i), v)
+		case !found && recovered < 0:
+			recovered = i
+		}
+	}
+
+	if recovered < 0 {
+		return nil
+	}
+
+	lost := r.report.Acknowledged - recovered
+	if r.mode != PowerLoss {
+		return fmt.Errorf("%w: %d of %d", ErrDataLoss, lost, r.report.Acknowledged)
+	}
+
+	r.report.Lost += lost
+	return nil
+}
+
+// checkIntegrity runs the integrity check of SQLite on the database at path.
+func checkIntegrity(path string) error {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("%w: %s", ErrCorruption, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("%w: %s", ErrCorruption, result)
+	}
+
+	return nil
+}
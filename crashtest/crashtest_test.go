@@ -0,0 +1,39 @@
+package crashtest
+
+import (
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	for _, mode := range []Mode{Kill, PowerLoss, TornCheckpoint} {
+		t.Run(mode.String(), func(t *testing.T) {
+			report, err := Run(mode, &Options{Writes: 60, Crashes: 6})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if report.Crashes != 6 || report.Acknowledged != 60 {
+				t.Errorf("Expected 6 crashes over 60 writes, got %+v", report)
+			}
+			if mode != PowerLoss && report.Lost != 0 {
+				t.Errorf("Expected no lost writes, got %d", report.Lost)
+			}
+		})
+	}
+}
+
+func TestRunPowerLossLoses(t *testing.T) {
+	// writes since the last checkpoint only live in the WAL, so cutting it loses some
+	lost := 0
+	for seed := int64(1); seed <= 5; seed++ {
+		report, err := Run(PowerLoss, &Options{Writes: 40, Crashes: 4, Seed: seed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		lost += report.Lost
+	}
+
+	if lost == 0 {
+		t.Error("Expected power losses to lose unsynced writes")
+	}
+}
@@ -0,0 +1,141 @@
+package kvsqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Logger is the logger used by the store.
+// *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...any)
+}
+
+// maxDebugArgLength is the length after which bound parameters are truncated in debug logs.
+const maxDebugArgLength = 64
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// tracer runs statements and logs them when debug is enabled.
+type tracer struct {
+	store *SQLite
+	core  execer
+
+	// schema is the attached database the kv tables of statements refer to, "" for main.
+	schema string
+
+	// table is the kv table of the prefix statements refer to, "" for the shared kv table.
+	table string
+}
+
+// db returns the handle to run statements on the database with.
+func (m *SQLite) db() *tracer {
+	return &tracer{store: m, core: m.core, table: m.table}
+}
+
+// wdb returns the handle to run writes with, on the write connection with Config.SplitReadWrite.
+func (m *SQLite) wdb() *tracer {
+	return &tracer{store: m, core: m.writeCore(), table: m.table}
+}
+
+// tx returns the handle to run statements in the given transaction with.
+func (m *SQLite) tx(tx *sql.Tx) *tracer {
+	return &tracer{store: m, core: tx, table: m.table}
+}
+
+// in returns a handle which runs statements on the kv tables of the given attached database.
+func (t *tracer) in(schema string) *tracer {
+	return &tracer{t.store, t.core, schema, t.table}
+}
+
+func (t *tracer) Exec(query string, args ...any) (sql.Result, error) {
+	return t.ExecContext(context.Background(), query, args...)
+}
+
+func (t *tracer) Query(query string, args ...any) (*sql.Rows, error) {
+	return t.QueryContext(context.Background(), query, args...)
+}
+
+func (t *tracer) QueryRow(query string, args ...any) *sql.Row {
+	return t.QueryRowContext(context.Background(), query, args...)
+}
+
+func (t *tracer) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	query = qualify(query, t.schema, t.table)
+	start := time.Now()
+	res, err := t.core.ExecContext(ctx, query, args...)
+	t.store.trace(query, args, start, err)
+	err = t.store.classify(err)
+	t.store.sqliteDone(start, err)
+	return res, err
+}
+
+func (t *tracer) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	query = qualify(query, t.schema, t.table)
+	start := time.Now()
+	rows, err := t.core.QueryContext(ctx, query, args...)
+	t.store.trace(query, args, start, err)
+	err = t.store.classify(err)
+	t.store.sqliteDone(start, err)
+	return rows, err
+}
+
+func (t *tracer) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	query = qualify(query, t.schema, t.table)
+	start := time.Now()
+	row := t.core.QueryRowContext(ctx, query, args...)
+	t.store.trace(query, args, start, row.Err())
+	t.store.sqliteDone(start, t.store.classify(row.Err()))
+	return row
+}
+
+func (m *SQLite) logger() Logger {
+	if m.Config.Logger != nil {
+		return m.Config.Logger
+	}
+
+	return log.Default()
+}
+
+func (m *SQLite) trace(query string, args []any, start time.Time, err error) {
+	if !m.Config.Debug {
+		return
+	}
+
+	params := make([]string, len(args))
+	for i, arg := range args {
+		params[i] = m.formatArg(arg)
+	}
+
+	status := "ok"
+	if err != nil {
+		status = err.Error()
+	}
+
+	m.logger().Printf("[sqlite] %s [%s] (%s) %s", strings.Join(strings.Fields(query), " "), strings.Join(params, ", "), time.Since(start), status)
+}
+
+func (m *SQLite) formatArg(arg any) string {
+	s, ok := arg.(string)
+	if !ok {
+		return fmt.Sprintf("%v", arg)
+	}
+
+	if m.Config.DebugRedactKeys && strings.HasPrefix(s, m.Config.Prefix) && len(s) > len(m.Config.Prefix) {
+		return fmt.Sprintf("%q", m.Config.Prefix+"***")
+	}
+
+	if len(s) > maxDebugArgLength {
+		s = s[:maxDebugArgLength] + "..."
+	}
+
+	return fmt.Sprintf("%q", s)
+}
@@ -0,0 +1,39 @@
+package kvsqlite
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type testLogger struct {
+	lines []string
+}
+
+func (l *testLogger) Printf(format string, v ...any) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestDebug(t *testing.T) {
+	logger := &testLogger{}
+	client, err := New(&SQLiteConfig{
+		Path:            "/tmp/test.db",
+		Prefix:          "go-zoox-test:",
+		Debug:           true,
+		DebugRedactKeys: true,
+		Logger:          logger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	client.Has("secret-key")
+
+	if len(logger.lines) != 1 {
+		t.Fatalf("Expected 1 log line, got %d", len(logger.lines))
+	}
+	if !strings.Contains(logger.lines[0], "SELECT 1 FROM kv") || strings.Contains(logger.lines[0], "secret-key") {
+		t.Errorf("Expected redacted statement log, got %s", logger.lines[0])
+	}
+}
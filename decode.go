@@ -0,0 +1,132 @@
+package kvsqlite
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// ErrCorrupt is matched by a CorruptValueError with errors.Is.
+var ErrCorrupt = errors.New("sqlite: stored value is corrupt")
+
+// CorruptValueError is returned by Get when the stored value can't be restored or isn't valid JSON, see Config.OnCorrupt.
+type CorruptValueError struct {
+	// Key is the key of the value.
+	Key string
+
+	// Err is the underlying error, e.g. ErrChecksum or a *json.SyntaxError.
+	Err error
+}
+
+func (e *CorruptValueError) Error() string {
+	return fmt.Sprintf("sqlite: stored value of key %s is corrupt: %s", e.Key, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *CorruptValueError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrCorrupt.
+func (e *CorruptValueError) Is(target error) bool {
+	return target == ErrCorrupt
+}
+
+// ErrValueTypeMismatch is matched by a ValueTypeError with errors.Is.
+var ErrValueTypeMismatch = errors.New("sqlite: stored value does not match destination type")
+
+// ValueTypeError is returned by Get when the stored value can't be decoded into the destination.
+type ValueTypeError struct {
+	// Key is the key of the value.
+	Key string
+
+	// Stored is the JSON type of the stored value (or of the mismatching field): object, array, string, number, bool or null.
+	Stored string
+
+	// Target is the Go type of the destination (or of the mismatching field).
+	Target reflect.Type
+
+	// Field is the path of the mismatching field, empty for the value itself.
+	Field string
+
+	// Err is the underlying decoding error.
+	Err error
+}
+
+func (e *ValueTypeError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("sqlite: cannot decode %s field %s of key %s into %s", e.Stored, e.Field, e.Key, e.Target)
+	}
+
+	return fmt.Sprintf("sqlite: cannot decode %s value of key %s into %s", e.Stored, e.Key, e.Target)
+}
+
+// Unwrap returns the underlying decoding error.
+func (e *ValueTypeError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrValueTypeMismatch.
+func (e *ValueTypeError) Is(target error) bool {
+	return target == ErrValueTypeMismatch
+}
+
+func (m *SQLite) decodeValue(key string, data []byte, value any) error {
+	data, err := m.restore(data)
+	if err != nil {
+		return &CorruptValueError{Key: key, Err: err}
+	}
+	if m.Config.LegacyValues && !json.Valid(data) {
+		return decodeLegacy(key, data, value)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if m.Config.StrictDecoding {
+		decoder.DisallowUnknownFields()
+	}
+	if m.Config.UseNumber {
+		decoder.UseNumber()
+	}
+
+	err = decoder.Decode(value)
+	if err == nil {
+		// the decoder stops after the first value, anything after it is corruption too
+		if _, err := decoder.Token(); err != io.EOF {
+			return &CorruptValueError{Key: key, Err: errors.New("sqlite: unexpected data after the value")}
+		}
+
+		return nil
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return &CorruptValueError{Key: key, Err: err}
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &ValueTypeError{
+			Key:    key,
+			Stored: jsonTypeName(typeErr.Value),
+			Target: typeErr.Type,
+			Field:  typeErr.Field,
+			Err:    err,
+		}
+	}
+
+	return err
+}
+
+// jsonTypeName normalizes the value description of json.UnmarshalTypeError, e.g. "number 1.5" to "number".
+func jsonTypeName(value string) string {
+	for _, name := range []string{"object", "array", "string", "number", "bool", "null"} {
+		if value == name || len(value) > len(name) && value[:len(name)+1] == name+" " {
+			return name
+		}
+	}
+
+	return value
+}
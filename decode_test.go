@@ -0,0 +1,99 @@
+package kvsqlite
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeTypeMismatch(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	client.Set("decode", "value")
+
+	var n int
+	err := client.Get("decode", &n)
+	if !errors.Is(err, ErrValueTypeMismatch) {
+		t.Fatalf("Expected ErrValueTypeMismatch, got %v", err)
+	}
+
+	var typeErr *ValueTypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("Expected ValueTypeError, got %T", err)
+	}
+	if typeErr.Key != "decode" || typeErr.Stored != "string" || typeErr.Target != reflect.TypeOf(0) {
+		t.Errorf("Unexpected error details: %+v", typeErr)
+	}
+}
+
+func TestDecodeStrict(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	client.Set("decode", map[string]any{"name": "zero", "extra": 1})
+
+	var v struct {
+		Name string `json:"name"`
+	}
+	if err := client.Get("decode", &v); err != nil || v.Name != "zero" {
+		t.Fatalf("Expected lenient decoding, got %v", err)
+	}
+
+	client.Config.StrictDecoding = true
+	defer func() { client.Config.StrictDecoding = false }()
+	if err := client.Get("decode", &v); err == nil {
+		t.Error("Expected an error for the unknown field")
+	}
+}
+
+func TestDecodeUseNumber(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	var id int64 = 1<<62 + 1
+	client.Set("decode", map[string]any{"id": id})
+
+	var v map[string]any
+	client.Get("decode", &v)
+	if _, ok := v["id"].(float64); !ok {
+		t.Fatalf("Expected float64 by default, got %T", v["id"])
+	}
+
+	client.Config.UseNumber = true
+	defer func() { client.Config.UseNumber = false }()
+
+	v = nil
+	client.Get("decode", &v)
+	n, ok := v["id"].(json.Number)
+	if !ok {
+		t.Fatalf("Expected json.Number, got %T", v["id"])
+	}
+	if got, err := n.Int64(); err != nil || got != id {
+		t.Errorf("Expected %d, got %d (%v)", id, got, err)
+	}
+
+	client.ForEach(func(key string, value interface{}) {
+		if key == "decode" {
+			if _, ok := value.(map[string]any)["id"].(json.Number); !ok {
+				t.Errorf("Expected json.Number in ForEach, got %T", value.(map[string]any)["id"])
+			}
+		}
+	})
+}
+
+func TestDecodeTrailingData(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	for _, stored := range []string{"1 garbage", "1 2"} {
+		client.Set("decode", 1)
+		client.Core.Exec("UPDATE kv SET value = ? WHERE key = ?", stored, client.Config.Prefix+"decode")
+
+		var n int
+		if err := client.Get("decode", &n); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("Expected ErrCorrupt for %q, got %v", stored, err)
+		}
+	}
+}
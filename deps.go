@@ -0,0 +1,329 @@
+package kvsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// DependencyConfig is the configuration of the dependency links between keys.
+type DependencyConfig struct {
+	// Interval is how often the janitor removes the dependents of expired parents, default is 1 minute.
+	Interval time.Duration
+}
+
+// errDependencies is returned when links are used on a store without Config.Dependencies.
+var errDependencies = errors.New("sqlite: dependencies are not configured")
+
+// createDependencySchema creates the table of the links between keys, in the main database.
+// Keys are stored prefixed, so a single table serves every prefix and attached database.
+func createDependencySchema(core *sql.DB) error {
+	statements := []string{
+		"CREATE TABLE IF NOT EXISTS kv_deps (parent TEXT NOT NULL, child TEXT NOT NULL, PRIMARY KEY (parent, child)) WITHOUT ROWID",
+		"CREATE INDEX IF NOT EXISTS kv_deps_child ON kv_deps (child)",
+	}
+	for _, statement := range statements {
+		if _, err := core.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Depend links child to the given parents: deleting or expiring any of them deletes child too,
+// e.g. Depend("user:42:profile-html", "user:42") for a value derived from another.
+// Links are transitive and survive writes of both keys. Deletes cascade immediately,
+// expirations when the parent is read or by the janitor, see SweepDependencies.
+func (m *SQLite) Depend(child string, parents ...string) error {
+	return m.DependContext(context.Background(), child, parents...)
+}
+
+// DependContext is like Depend but honors ctx.
+func (m *SQLite) DependContext(ctx context.Context, child string, parents ...string) error {
+	if m.Config.Dependencies == nil {
+		return errDependencies
+	}
+
+	childX, err := m.writeKey(child)
+	if err != nil {
+		return err
+	}
+
+	parentsX := make([]string, 0, len(parents))
+	for _, parent := range parents {
+		parentX, err := m.writeKey(parent)
+		if err != nil {
+			return err
+		}
+
+		parentsX = append(parentsX, parentX)
+	}
+
+	return m.writeTx(ctx, func(db *tracer) error {
+		for _, parentX := range parentsX {
+			if _, err := db.ExecContext(ctx, "INSERT OR IGNORE INTO kv_deps (parent, child) VALUES (?, ?)", parentX, childX); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Undepend removes the links of child to the given parents, or to all of its parents if none are given.
+func (m *SQLite) Undepend(child string, parents ...string) error {
+	if m.Config.Dependencies == nil {
+		return errDependencies
+	}
+
+	childX, err := m.writeKey(child)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	return m.writeTx(ctx, func(db *tracer) error {
+		if len(parents) == 0 {
+			_, err := db.ExecContext(ctx, "DELETE FROM kv_deps WHERE child = ?", childX)
+			return err
+		}
+
+		for _, parent := range parents {
+			parentX, err := m.writeKey(parent)
+			if err != nil {
+				return err
+			}
+
+			if _, err := db.ExecContext(ctx, "DELETE FROM kv_deps WHERE parent = ? AND child = ?", parentX, childX); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Dependents returns the keys directly linked to the given parent, sorted.
+func (m *SQLite) Dependents(parent string) ([]string, error) {
+	if m.Config.Dependencies == nil {
+		return nil, errDependencies
+	}
+
+	parentX, err := m.getKey(parent)
+	if err != nil {
+		return nil, err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	rows, err := m.db().QueryContext(context.Background(), "SELECT child FROM kv_deps WHERE parent = ? ORDER BY child", parentX)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]string, 0)
+	for rows.Next() {
+		var childX string
+		if err := rows.Scan(&childX); err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, childX[len(m.Config.Prefix):])
+	}
+
+	return keys, rows.Err()
+}
+
+// cascade deletes the dependents of the removed (prefixed) key, transitively, in the transaction of the removal.
+// It returns the deleted dependents, unprefixed. It must be called with the write lock held.
+func (m *SQLite) cascade(ctx context.Context, db *tracer, keyX string) ([]string, error) {
+	if m.Config.Dependencies == nil {
+		return nil, nil
+	}
+
+	removed := make([]string, 0)
+	visited := map[string]bool{keyX: true}
+	queue := []string{keyX}
+	for len(queue) > 0 {
+		parentX := queue[0]
+		queue = queue[1:]
+
+		children, err := m.unlink(ctx, db, parentX)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, childX := range children {
+			if visited[childX] {
+				continue
+			}
+			visited[childX] = true
+			queue = append(queue, childX)
+
+			res, err := db.in(m.databaseOf(childX)).ExecContext(ctx, "DELETE FROM kv WHERE key = ?", childX)
+			if err != nil {
+				return nil, err
+			}
+
+			n, err := res.RowsAffected()
+			if err != nil {
+				return nil, err
+			}
+			if n > 0 {
+				m.keyRemoved(childX)
+				removed = append(removed, childX[len(m.Config.Prefix):])
+			}
+		}
+	}
+
+	return removed, nil
+}
+
+// unlink drops the links of the removed (prefixed) key, as a parent and as a child, and returns its children.
+func (m *SQLite) unlink(ctx context.Context, db *tracer, keyX string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "DELETE FROM kv_deps WHERE parent = ? RETURNING child", keyX)
+	if err != nil {
+		return nil, err
+	}
+
+	children := make([]string, 0)
+	for rows.Next() {
+		var childX string
+		if err := rows.Scan(&childX); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		children = append(children, childX)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := db.ExecContext(ctx, "DELETE FROM kv_deps WHERE child = ?", keyX); err != nil {
+		return nil, err
+	}
+
+	return children, nil
+}
+
+// publishDependents publishes the deletion of the dependents removed by cascade.
+func (m *SQLite) publishDependents(keys []string) {
+	for _, key := range keys {
+		m.publish(EventDelete, key)
+	}
+}
+
+// SweepDependencies deletes the dependents of the parents which expired, or were deleted without cascading,
+// e.g. by DeleteByPattern or Clear, and drops the links of dependents which no longer exist.
+// It returns the number of deleted dependents. The janitor of Config.Dependencies runs it periodically.
+func (m *SQLite) SweepDependencies() (int64, error) {
+	if m.Config.Dependencies == nil {
+		return 0, errDependencies
+	}
+
+	if err := m.checkAccessAll(true); err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+	expired := make([]string, 0)
+	removed := make([]string, 0)
+	err := m.writeTx(ctx, func(db *tracer) error {
+		for _, database := range m.databases() {
+			// kv_deps is never qualified, it resolves to the main database
+			parents, err := m.orphans(ctx, db.in(database), "parent", database, "SELECT key FROM kv WHERE "+unexpired, m.expiryCutoff())
+			if err != nil {
+				return err
+			}
+
+			for _, parentX := range parents {
+				res, err := db.in(database).ExecContext(ctx, "DELETE FROM kv WHERE key = ?", parentX)
+				if err != nil {
+					return err
+				}
+
+				if n, err := res.RowsAffected(); err != nil {
+					return err
+				} else if n > 0 {
+					m.keyRemoved(parentX)
+					expired = append(expired, parentX[len(m.Config.Prefix):])
+				}
+
+				dependents, err := m.cascade(ctx, db, parentX)
+				if err != nil {
+					return err
+				}
+				removed = append(removed, dependents...)
+			}
+
+			children, err := m.orphans(ctx, db.in(database), "child", database, "SELECT key FROM kv")
+			if err != nil {
+				return err
+			}
+
+			for _, childX := range children {
+				if _, err := db.ExecContext(ctx, "DELETE FROM kv_deps WHERE child = ?", childX); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, key := range expired {
+		m.publish(EventExpire, key)
+	}
+	m.publishDependents(removed)
+	return int64(len(removed)), nil
+}
+
+// orphans returns the linked (prefixed) keys of the given column routed to database which are not returned by the
+// rows subquery, run in that database.
+func (m *SQLite) orphans(ctx context.Context, db *tracer, column string, database string, rows string, args ...any) ([]string, error) {
+	res, err := db.QueryContext(ctx,
+		"SELECT DISTINCT "+column+" FROM kv_deps WHERE "+column+" LIKE ? AND "+column+" NOT IN ("+rows+")",
+		append([]any{m.Config.Prefix + "%"}, args...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	keys := make([]string, 0)
+	for res.Next() {
+		var keyX string
+		if err := res.Scan(&keyX); err != nil {
+			return nil, err
+		}
+
+		if m.databaseOf(keyX) == database {
+			keys = append(keys, keyX)
+		}
+	}
+
+	return keys, res.Err()
+}
+
+func (m *SQLite) startDependencies() {
+	interval := m.Config.Dependencies.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	m.dependencies = newWorker(interval, func() {
+		if _, err := m.SweepDependencies(); err != nil && m.Config.Debug {
+			m.logger().Printf("[sqlite] dependency sweep failed: %s", err)
+		}
+	})
+}
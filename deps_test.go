@@ -0,0 +1,127 @@
+package kvsqlite
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDepend(t *testing.T) {
+	path := "/tmp/test-deps.db"
+	os.Remove(path)
+	defer os.Remove(path)
+
+	clock := NewManualClock(time.Now())
+	client, err := New(&SQLiteConfig{
+		Path:         path,
+		Prefix:       "go-zoox-test:",
+		Clock:        clock,
+		Dependencies: &DependencyConfig{Interval: time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	client.Set("user:42", "alice")
+	client.Set("user:42:profile", "<p>alice</p>")
+	client.Set("user:42:profile:thumb", "<img>")
+	if err := client.Depend("user:42:profile", "user:42"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Depend("user:42:profile:thumb", "user:42:profile"); err != nil {
+		t.Fatal(err)
+	}
+
+	dependents, err := client.Dependents("user:42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dependents) != 1 || dependents[0] != "user:42:profile" {
+		t.Errorf("Expected [user:42:profile], got %v", dependents)
+	}
+
+	// deletes cascade transitively
+	if err := client.Delete("user:42"); err != nil {
+		t.Fatal(err)
+	}
+	if client.Has("user:42:profile") || client.Has("user:42:profile:thumb") {
+		t.Error("Expected the dependents to be deleted with their parent")
+	}
+
+	// expirations cascade when the parent is read
+	client.Set("session", "s", time.Second)
+	client.Set("session:view", "v")
+	client.Depend("session:view", "session")
+	clock.Add(2 * time.Second)
+	var v string
+	client.Get("session", &v)
+	if client.Has("session:view") {
+		t.Error("Expected the dependent of an expired parent to be deleted")
+	}
+
+	// and by the janitor otherwise
+	client.Set("token", "t", time.Second)
+	client.Set("token:claims", "c")
+	client.Depend("token:claims", "token")
+	clock.Add(2 * time.Second)
+	n, err := client.SweepDependencies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || client.Has("token:claims") {
+		t.Errorf("Expected the sweep to delete 1 dependent, got %d", n)
+	}
+
+	// unlinked keys survive their parent
+	client.Set("a", "1")
+	client.Set("b", "2")
+	client.Depend("b", "a")
+	if err := client.Undepend("b"); err != nil {
+		t.Fatal(err)
+	}
+	client.Delete("a")
+	if !client.Has("b") {
+		t.Error("Expected an unlinked key to survive")
+	}
+}
+
+func TestDependNotConfigured(t *testing.T) {
+	client := createClient()
+
+	if err := client.Depend("child", "parent"); err == nil {
+		t.Error("Expected an error without Config.Dependencies")
+	}
+}
+
+func TestDependMigratePrefix(t *testing.T) {
+	path := "/tmp/test-deps-migrate.db"
+	os.Remove(path)
+	defer os.Remove(path)
+
+	client, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:", Dependencies: &DependencyConfig{Interval: time.Hour}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	client.Set("v1:user", "alice")
+	client.Set("v1:user:html", "<p>alice</p>")
+	client.Depend("v1:user:html", "v1:user")
+
+	if _, err := client.MigratePrefix("go-zoox-test:v1:", "go-zoox-test:v2:"); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := client.SweepDependencies(); err != nil || n != 0 {
+		t.Errorf("Expected the sweep to keep the dependents of renamed parents, got %d (%v)", n, err)
+	}
+	if !client.Has("v2:user:html") {
+		t.Fatal("Expected the renamed dependent to survive")
+	}
+
+	client.Delete("v2:user")
+	if client.Has("v2:user:html") {
+		t.Error("Expected the renamed link to cascade")
+	}
+}
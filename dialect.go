@@ -0,0 +1,140 @@
+package kvsqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// ErrUnsupported is returned when a feature is not supported by the dialect of the store, see Dialect.
+var ErrUnsupported = errors.New("sqlite: not supported by the dialect")
+
+// Dialect is the database engine of a store: how it connects, how its errors are classified,
+// and which features beyond the statements of the store it supports.
+// The statements of the store are SQLite SQL (upserts, RETURNING, JSON functions and GLOB),
+// so a dialect targets an engine speaking it, e.g. libSQL.
+type Dialect interface {
+	// Name identifies the engine in errors, e.g. "sqlite".
+	Name() string
+
+	// Open opens the database of the store at cfg.Path, with the given driver parameters of the DSN,
+	// e.g. _txlock=immediate, which it may ignore.
+	Open(cfg *SQLiteConfig, params ...string) (*sql.DB, error)
+
+	// ErrorKind returns the kind of the given driver error, see Error, or nil if it has none.
+	ErrorKind(err error) error
+
+	// Supports reports whether the engine supports the given feature.
+	Supports(feature Feature) bool
+}
+
+// Feature is a feature a dialect may not support.
+type Feature int
+
+const (
+	// FeaturePragmas is the journal mode, the synchronous mode, checkpoints and vacuum:
+	// Config.JournalMode, Config.SplitReadWrite, Config.Vacuum, WithWriteConcern, Checkpoint, Fragmentation,
+	// IncrementalVacuum and Compact.
+	FeaturePragmas Feature = iota
+
+	// FeatureAttach is attaching databases: Config.Attach and Config.Archive.
+	FeatureAttach
+
+	// FeatureBackup is the online backup of the database: BackupTo and BackupStream.
+	FeatureBackup
+)
+
+func (f Feature) String() string {
+	switch f {
+	case FeaturePragmas:
+		return "pragmas"
+	case FeatureAttach:
+		return "attached databases"
+	case FeatureBackup:
+		return "backup"
+	}
+
+	return fmt.Sprintf("feature %d", int(f))
+}
+
+// SQLiteDialect is the dialect of SQLite with github.com/mattn/go-sqlite3, the default.
+var SQLiteDialect Dialect = sqliteDialect{}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string {
+	return "sqlite"
+}
+
+func (sqliteDialect) Open(cfg *SQLiteConfig, params ...string) (*sql.DB, error) {
+	return openSQLite(cfg, params...)
+}
+
+func (sqliteDialect) ErrorKind(err error) error {
+	var driverErr sqlite3.Error
+	if !errors.As(err, &driverErr) {
+		return nil
+	}
+
+	switch driverErr.Code {
+	case sqlite3.ErrBusy, sqlite3.ErrLocked:
+		return ErrBusy
+	case sqlite3.ErrReadonly:
+		return ErrReadOnly
+	case sqlite3.ErrTooBig:
+		return ErrTooLarge
+	case sqlite3.ErrConstraint:
+		if driverErr.ExtendedCode == sqlite3.ErrConstraintUnique || driverErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+			return ErrConflict
+		} else if driverErr.ExtendedCode == sqlite3.ErrConstraintTrigger && driverErr.Error() == immutableMessage {
+			return ErrImmutable
+		}
+	}
+
+	return nil
+}
+
+func (sqliteDialect) Supports(feature Feature) bool {
+	return true
+}
+
+// dialectOf returns the dialect of the configuration, SQLiteDialect by default.
+func dialectOf(cfg *SQLiteConfig) Dialect {
+	if cfg.Dialect != nil {
+		return cfg.Dialect
+	}
+
+	return SQLiteDialect
+}
+
+// checkDialect checks that the configuration only uses features supported by its dialect.
+func checkDialect(cfg *SQLiteConfig) error {
+	d := dialectOf(cfg)
+
+	uses := map[Feature]bool{
+		FeaturePragmas: cfg.JournalMode != "" || cfg.SplitReadWrite || cfg.Vacuum != nil,
+		FeatureAttach:  len(cfg.Attach) > 0 || cfg.Archive != nil,
+	}
+	for _, feature := range []Feature{FeaturePragmas, FeatureAttach} {
+		if uses[feature] && !d.Supports(feature) {
+			return unsupported(d, feature)
+		}
+	}
+
+	return nil
+}
+
+// supports returns ErrUnsupported if the dialect of the store does not support the given feature.
+func (m *SQLite) supports(feature Feature) error {
+	if d := dialectOf(m.Config); !d.Supports(feature) {
+		return unsupported(d, feature)
+	}
+
+	return nil
+}
+
+func unsupported(d Dialect, feature Feature) error {
+	return fmt.Errorf("%w: %s with %s", ErrUnsupported, feature, d.Name())
+}
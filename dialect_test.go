@@ -0,0 +1,65 @@
+package kvsqlite
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var errRemoteLocked = errors.New("remote: database is locked")
+
+// limitedDialect is SQLite without any optional feature, with an error of its own.
+type limitedDialect struct {
+	Dialect
+}
+
+func (limitedDialect) Name() string {
+	return "limited"
+}
+
+func (limitedDialect) Supports(feature Feature) bool {
+	return false
+}
+
+func (d limitedDialect) ErrorKind(err error) error {
+	if errors.Is(err, errRemoteLocked) {
+		return ErrBusy
+	}
+
+	return d.Dialect.ErrorKind(err)
+}
+
+func TestDialect(t *testing.T) {
+	dialect := limitedDialect{SQLiteDialect}
+
+	if _, err := New(&SQLiteConfig{Path: "/tmp/test.db", Prefix: "go-zoox-test:", Dialect: dialect, Vacuum: &VacuumConfig{}}); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("Expected ErrUnsupported for vacuum, got %v", err)
+	}
+
+	client, err := New(&SQLiteConfig{Path: "/tmp/test.db", Prefix: "go-zoox-test-dialect:" + time.Now().String(), Dialect: dialect})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	defer client.Clear()
+
+	client.Set("a", 1)
+	var v int
+	if err := client.Get("a", &v); err != nil || v != 1 {
+		t.Fatalf("Expected 1, got %d (%v)", v, err)
+	}
+
+	if _, err := client.SetWith("a", 2, WithWriteConcern(WriteDurable)); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported for the write concern, got %v", err)
+	}
+	if _, err := client.Checkpoint(CheckpointPassive); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported for checkpoints, got %v", err)
+	}
+	if err := client.BackupTo("/tmp/test-dialect-backup.db"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported for backups, got %v", err)
+	}
+
+	if err := client.classify(errRemoteLocked); !errors.Is(err, ErrBusy) || !errors.Is(err, errRemoteLocked) {
+		t.Errorf("Expected the error of the dialect to be classified as ErrBusy, got %v", err)
+	}
+}
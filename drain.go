@@ -0,0 +1,29 @@
+package kvsqlite
+
+import "context"
+
+// Drain stops background goroutines, flushes buffered writes and checkpoints the WAL.
+// It is meant to be wired into service shutdown hooks and called before Close.
+// The checkpoint is skipped with a dialect which doesn't support FeaturePragmas.
+// If ctx is done before draining completes, ctx.Err() is returned and draining continues in the background.
+func (m *SQLite) Drain(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		m.stopBackground()
+
+		if m.supports(FeaturePragmas) != nil {
+			done <- nil
+			return
+		}
+
+		_, err := m.Checkpoint(CheckpointTruncate)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,41 @@
+package kvsqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDrain(t *testing.T) {
+	client, err := New(&SQLiteConfig{
+		Path:                "/tmp/test-wal.db",
+		Prefix:              "go-zoox-test-drain:",
+		JournalMode:         "WAL",
+		TrackAccess:         true,
+		AccessFlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.Clear()
+	defer client.Clear()
+
+	client.Set("key", "value")
+	var value string
+	client.Get("key", &value)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	if err := client.Core.QueryRow("SELECT access_count FROM kv WHERE key = ?", client.Config.Prefix+"key").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("Expected buffered access to be flushed, got count %d", count)
+	}
+}
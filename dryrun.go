@@ -0,0 +1,117 @@
+package kvsqlite
+
+import (
+	"context"
+	"sort"
+)
+
+// dryRunSampleSize is the number of keys listed by a DryRunReport.
+const dryRunSampleSize = 10
+
+// DryRunReport is what a destructive operation would affect, see DryRun.
+type DryRunReport struct {
+	// Count is the number of keys which would be affected.
+	Count int64
+
+	// Sample is the first affected keys in key order, up to 10.
+	Sample []string
+}
+
+func (r *DryRunReport) add(key string) {
+	r.Count++
+	if len(r.Sample) < dryRunSampleSize {
+		r.Sample = append(r.Sample, key)
+	}
+}
+
+// DryRunner reports what destructive operations would affect, without running them, see SQLite.DryRun.
+type DryRunner struct {
+	store *SQLite
+}
+
+// DryRun returns a DryRunner of the store, e.g. to check the keys matched by a pattern before deleting them:
+//
+//	report, err := store.DryRun().DeleteByPattern("page:*")
+//
+// The report is a snapshot, so the operation may still affect other keys if the store is written to meanwhile.
+func (m *SQLite) DryRun() *DryRunner {
+	return &DryRunner{store: m}
+}
+
+// Clear reports the keys Clear and DeleteAll would remove.
+func (d *DryRunner) Clear() (*DryRunReport, error) {
+	m := d.store
+	return d.report(context.Background(), "key LIKE ? AND "+notReserved, m.Config.Prefix+"%", m.reservedPattern())
+}
+
+// DeleteByPattern reports the keys DeleteByPattern would delete.
+func (d *DryRunner) DeleteByPattern(pattern string) (*DryRunReport, error) {
+	m := d.store
+	return d.report(context.Background(), "key GLOB ? AND "+notReserved, globEscape(m.Config.Prefix)+pattern, m.reservedPattern())
+}
+
+// DeleteByTag reports the keys DeleteByTag would delete.
+func (d *DryRunner) DeleteByTag(tag string) (*DryRunReport, error) {
+	m := d.store
+	return d.report(context.Background(), "key LIKE ? AND key IN (SELECT key FROM kv_tags WHERE tag = ?)", m.Config.Prefix+"%", tag)
+}
+
+// UpdateWhere reports the keys UpdateWhere would rewrite: fn is called like in UpdateWhere,
+// but the values it returns are not written.
+func (d *DryRunner) UpdateWhere(opt *UpdateWhereOptions, fn UpdateFunc) (*DryRunReport, error) {
+	report := &DryRunReport{Sample: make([]string, 0)}
+	if _, err := d.store.updateWhere(context.Background(), opt, fn, report); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// report counts and samples the rows of every database matching the condition.
+func (d *DryRunner) report(ctx context.Context, where string, args ...any) (*DryRunReport, error) {
+	m := d.store
+	if err := m.checkAccessAll(false); err != nil {
+		return nil, err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	report := &DryRunReport{Sample: make([]string, 0)}
+	for _, database := range m.databases() {
+		db := m.db().in(database)
+
+		var count int64
+		if err := db.QueryRowContext(ctx, "SELECT count(*) FROM kv WHERE "+where, args...).Scan(&count); err != nil {
+			return nil, err
+		}
+		report.Count += count
+
+		rows, err := db.QueryContext(ctx, "SELECT key FROM kv WHERE "+where+" ORDER BY key LIMIT ?", append(args, dryRunSampleSize)...)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var keyX string
+			if err := rows.Scan(&keyX); err != nil {
+				rows.Close()
+				return nil, err
+			}
+
+			report.Sample = append(report.Sample, keyX[len(m.Config.Prefix):])
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(report.Sample)
+	if len(report.Sample) > dryRunSampleSize {
+		report.Sample = report.Sample[:dryRunSampleSize]
+	}
+
+	return report, nil
+}
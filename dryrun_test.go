@@ -0,0 +1,74 @@
+package kvsqlite
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDryRun(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	for i := 0; i < 15; i++ {
+		client.Set(fmt.Sprintf("page:%02d", i), i)
+	}
+	client.SetWith("auth:1", "token", WithTags("auth"))
+
+	report, err := client.DryRun().DeleteByPattern("page:*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Count != 15 || len(report.Sample) != 10 || report.Sample[0] != "page:00" {
+		t.Errorf("Expected 15 keys sampled from page:00, got %+v", report)
+	}
+
+	if report, _ := client.DryRun().Clear(); report.Count != 16 {
+		t.Errorf("Expected 16 keys, got %d", report.Count)
+	}
+
+	if report, _ := client.DryRun().DeleteByTag("auth"); report.Count != 1 || report.Sample[0] != "auth:1" {
+		t.Errorf("Expected auth:1, got %+v", report)
+	}
+
+	report, err = client.DryRun().UpdateWhere(&UpdateWhereOptions{Pattern: "page:*"}, func(key string, raw []byte) (any, bool, error) {
+		return 0, string(raw) != "0", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Count != 14 || report.Sample[0] != "page:01" {
+		t.Errorf("Expected 14 keys from page:01, got %+v", report)
+	}
+
+	// nothing was changed
+	var value int
+	client.Get("page:05", &value)
+	if size := client.Size(); size != 16 || value != 5 {
+		t.Errorf("Expected the store to be untouched, got %d keys and page:05 = %d", size, value)
+	}
+}
+
+func TestDryRunReserved(t *testing.T) {
+	path := "/tmp/test-dryrun.db"
+	os.Remove(path)
+	defer os.Remove(path)
+
+	client, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:", StatsKeys: &StatsKeysConfig{Interval: time.Hour}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	client.Set("a", 1)
+	client.Set("b", 2)
+
+	report, err := client.DryRun().Clear()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := client.DeleteAll(); report.Count != 2 || n != report.Count {
+		t.Errorf("Expected the dry run to match DeleteAll, got %d and %d", report.Count, n)
+	}
+}
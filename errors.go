@@ -0,0 +1,94 @@
+package kvsqlite
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The failure modes of the store, to be matched with errors.Is.
+var (
+	// ErrNotFound is returned when the key does not exist.
+	ErrNotFound = errors.New("sqlite: key not found")
+
+	// ErrExpired is returned when the key exists but is expired, it also matches ErrNotFound.
+	ErrExpired = fmt.Errorf("%w: expired", ErrNotFound)
+
+	// ErrConflict is returned when a write conflicts with existing data, e.g. a key which already exists.
+	ErrConflict = errors.New("sqlite: conflict")
+
+	// ErrReadOnly is returned when writing to a read-only database.
+	ErrReadOnly = errors.New("sqlite: database is read-only")
+
+	// ErrTooLarge is returned when a value exceeds the limits of SQLite.
+	ErrTooLarge = errors.New("sqlite: value too large")
+
+	// ErrClosed is returned when using a closed store.
+	ErrClosed = errors.New("sqlite: store is closed")
+
+	// ErrBusy is returned when the database is locked by another connection for too long.
+	ErrBusy = errors.New("sqlite: database is busy")
+
+	// ErrInvalidKey is returned when a key is rejected by Config.KeySanitizer.
+	ErrInvalidKey = errors.New("sqlite: invalid key")
+
+	// ErrImmutable is returned when changing or deleting an immutable key, see SetImmutable.
+	ErrImmutable = errors.New("sqlite: key is immutable")
+)
+
+// Error is a database error classified by kind.
+// errors.Is matches its Kind, errors.As reaches the underlying driver error, e.g. sqlite3.Error.
+type Error struct {
+	// Kind is one of ErrConflict, ErrReadOnly, ErrTooLarge, ErrClosed, ErrBusy or ErrImmutable.
+	Kind error
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Kind.Error() + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the kind of the error.
+func (e *Error) Is(target error) bool {
+	return errors.Is(e.Kind, target)
+}
+
+// classify wraps a database error of SQLite into an Error of its kind, other errors are returned as is.
+func classify(err error) error {
+	return classifyAs(SQLiteDialect, err)
+}
+
+// classify wraps a database error into an Error of its kind in the dialect of the store.
+func (m *SQLite) classify(err error) error {
+	return classifyAs(dialectOf(m.Config), err)
+}
+
+// classifyAs wraps a database error into an Error of its kind in the given dialect, other errors are returned as is.
+func classifyAs(d Dialect, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var classified *Error
+	if errors.As(err, &classified) {
+		return err
+	}
+
+	kind := d.ErrorKind(err)
+	if kind == nil && err.Error() == "sql: database is closed" {
+		// database/sql doesn't export this error
+		kind = ErrClosed
+	}
+
+	if kind == nil {
+		return err
+	}
+
+	return &Error{Kind: kind, Err: err}
+}
@@ -0,0 +1,70 @@
+package kvsqlite
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		err  error
+		kind error
+	}{
+		{sqlite3.Error{Code: sqlite3.ErrBusy}, ErrBusy},
+		{sqlite3.Error{Code: sqlite3.ErrLocked}, ErrBusy},
+		{sqlite3.Error{Code: sqlite3.ErrReadonly}, ErrReadOnly},
+		{sqlite3.Error{Code: sqlite3.ErrTooBig}, ErrTooLarge},
+		{sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintUnique}, ErrConflict},
+	}
+	for _, c := range cases {
+		err := classify(c.err)
+		if !errors.Is(err, c.kind) {
+			t.Errorf("Expected %v to be classified as %v", c.err, c.kind)
+		}
+
+		var driverErr sqlite3.Error
+		if !errors.As(err, &driverErr) {
+			t.Errorf("Expected the driver error to be reachable from %v", err)
+		}
+	}
+
+	other := errors.New("other")
+	if classify(other) != other {
+		t.Error("Expected unknown errors to be returned as is")
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	path := "/tmp/test-errors.db"
+	os.Remove(path)
+	defer os.Remove(path)
+
+	clock := NewManualClock(time.Now())
+	client, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:", Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.Set("a", 1)
+	client.Set("b", 2)
+	if _, err := client.MigratePrefix("go-zoox-test:a", "go-zoox-test:b"); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict, got %v", err)
+	}
+
+	client.SetReader("stream", strings.NewReader("data"), time.Second)
+	clock.Add(time.Minute)
+	_, err = client.GetReader("stream")
+	if !errors.Is(err, ErrExpired) || !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrExpired matching ErrNotFound, got %v", err)
+	}
+
+	client.Close()
+	if err := client.Set("a", 3); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
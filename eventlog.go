@@ -0,0 +1,163 @@
+package kvsqlite
+
+import (
+	"context"
+	"time"
+)
+
+// StreamOptions are the options of an event stream.
+type StreamOptions struct {
+	// MaxAge is how long events are kept, default is forever.
+	MaxAge time.Duration
+
+	// MaxLen is how many of the latest events are kept, default is all.
+	MaxLen int
+}
+
+// EventStream is an append-only log of events, see SQLite.Stream.
+type EventStream struct {
+	store *SQLite
+	name  string
+	opt   StreamOptions
+}
+
+// StreamEvent is an event read from a stream.
+type StreamEvent struct {
+	// Seq is the sequence number of the event in its stream, starting at 1.
+	Seq        uint64
+	AppendedAt time.Time
+
+	store *SQLite
+	name  string
+	data  []byte
+}
+
+// Decode decodes the event into value, like Get.
+func (e *StreamEvent) Decode(value any) error {
+	return e.store.decodeValue(e.name, e.data, value)
+}
+
+// Stream returns the event stream of the given name, scoped to the prefix.
+// Sequence numbers are strictly increasing and never reused, even once older events are removed by retention,
+// which is applied on Append.
+func (m *SQLite) Stream(name string, opts ...*StreamOptions) *EventStream {
+	s := &EventStream{store: m, name: name}
+	if len(opts) > 0 && opts[0] != nil {
+		s.opt = *opts[0]
+	}
+
+	return s
+}
+
+func (m *SQLite) ensureStreams() error {
+	return m.ensureSchema("eventlog",
+		"CREATE TABLE IF NOT EXISTS kv_streams (name TEXT PRIMARY KEY, seq INTEGER NOT NULL)",
+		"CREATE TABLE IF NOT EXISTS kv_stream_events (stream TEXT NOT NULL, seq INTEGER NOT NULL, event BLOB, appended_at INTEGER NOT NULL, PRIMARY KEY (stream, seq)) WITHOUT ROWID",
+		"CREATE INDEX IF NOT EXISTS kv_stream_events_appended_at ON kv_stream_events (stream, appended_at)",
+	)
+}
+
+// Append appends the event to the stream and returns its sequence number.
+func (s *EventStream) Append(event any) (uint64, error) {
+	m := s.store
+	if err := m.ensureStreams(); err != nil {
+		return 0, err
+	}
+
+	data, err := m.encodeValue(event)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+	nameX := m.Config.Prefix + s.name
+
+	var seq uint64
+	err = m.writeTx(ctx, func(db *tracer) error {
+		err := db.QueryRowContext(ctx,
+			"INSERT INTO kv_streams (name, seq) VALUES (?, 1) ON CONFLICT(name) DO UPDATE SET seq = seq + 1 RETURNING seq",
+			nameX,
+		).Scan(&seq)
+		if err != nil {
+			return err
+		}
+
+		now := m.now()
+		if _, err := db.ExecContext(ctx, "INSERT INTO kv_stream_events (stream, seq, event, appended_at) VALUES (?, ?, ?, ?)", nameX, seq, data, now); err != nil {
+			return err
+		}
+
+		if s.opt.MaxAge > 0 {
+			if _, err := db.ExecContext(ctx, "DELETE FROM kv_stream_events WHERE stream = ? AND appended_at < ?", nameX, now-s.opt.MaxAge.Milliseconds()); err != nil {
+				return err
+			}
+		}
+
+		if s.opt.MaxLen > 0 && seq > uint64(s.opt.MaxLen) {
+			if _, err := db.ExecContext(ctx, "DELETE FROM kv_stream_events WHERE stream = ? AND seq <= ?", nameX, seq-uint64(s.opt.MaxLen)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return seq, err
+}
+
+// Read returns up to limit events with a sequence number of at least from, oldest first.
+// Events removed by retention are skipped, so the first event may be after from.
+func (s *EventStream) Read(from uint64, limit int) ([]StreamEvent, error) {
+	m := s.store
+	if err := m.ensureStreams(); err != nil {
+		return nil, err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	query := "SELECT seq, event, appended_at FROM kv_stream_events WHERE stream = ? AND seq >= ?"
+	args := []any{m.Config.Prefix + s.name, from}
+	if s.opt.MaxAge > 0 {
+		// not removed yet, but out of retention already
+		query += " AND appended_at >= ?"
+		args = append(args, m.now()-s.opt.MaxAge.Milliseconds())
+	}
+	query += " ORDER BY seq LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := m.db().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]StreamEvent, 0)
+	for rows.Next() {
+		e := StreamEvent{store: m, name: s.name}
+		var appendedAt int64
+		if err := rows.Scan(&e.Seq, &e.data, &appendedAt); err != nil {
+			return nil, err
+		}
+
+		e.AppendedAt = time.UnixMilli(appendedAt)
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+// LastSeq returns the sequence number of the last event appended to the stream, 0 if none.
+func (s *EventStream) LastSeq() (uint64, error) {
+	m := s.store
+	if err := m.ensureStreams(); err != nil {
+		return 0, err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	var seq uint64
+	err := m.db().QueryRow("SELECT coalesce((SELECT seq FROM kv_streams WHERE name = ?), 0)", m.Config.Prefix+s.name).Scan(&seq)
+	return seq, err
+}
@@ -0,0 +1,71 @@
+package kvsqlite
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventStream(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	client, err := New(&SQLiteConfig{
+		Path:   "/tmp/test.db",
+		Prefix: "go-zoox-test-eventlog:" + time.Now().String(),
+		Clock:  clock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	orders := client.Stream("orders", &StreamOptions{MaxLen: 3})
+	for i := 1; i <= 5; i++ {
+		seq, err := orders.Append(map[string]int{"n": i})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seq != uint64(i) {
+			t.Errorf("Expected seq %d, got %d", i, seq)
+		}
+	}
+
+	events, err := orders.Read(0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 || events[0].Seq != 3 {
+		t.Fatalf("Expected the 3 latest events, got %+v", events)
+	}
+
+	var event map[string]int
+	if err := events[2].Decode(&event); err != nil || event["n"] != 5 {
+		t.Errorf("Expected n = 5, got %v (%v)", event, err)
+	}
+
+	if events, _ := orders.Read(5, 10); len(events) != 1 || events[0].Seq != 5 {
+		t.Errorf("Expected to read from seq 5, got %+v", events)
+	}
+
+	if seq, _ := orders.LastSeq(); seq != 5 {
+		t.Errorf("Expected last seq 5, got %d", seq)
+	}
+
+	// streams are independent
+	if seq, _ := client.Stream("payments").Append("paid"); seq != 1 {
+		t.Errorf("Expected seq 1 for another stream, got %d", seq)
+	}
+
+	audit := client.Stream("audit", &StreamOptions{MaxAge: time.Minute})
+	audit.Append("a")
+	clock.Add(2 * time.Minute)
+	if events, _ := audit.Read(0, 10); len(events) != 0 {
+		t.Errorf("Expected events out of retention to be skipped, got %d", len(events))
+	}
+
+	// sequence numbers are not reused once events are removed
+	if seq, _ := audit.Append("b"); seq != 2 {
+		t.Errorf("Expected seq 2, got %d", seq)
+	}
+	if events, _ := audit.Read(0, 10); len(events) != 1 || events[0].Seq != 2 {
+		t.Errorf("Expected only the new event, got %+v", events)
+	}
+}
@@ -0,0 +1,93 @@
+package kvsqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// KeyExpiry is a key with its expiration time.
+type KeyExpiry struct {
+	Key       string
+	ExpiresAt time.Time
+}
+
+// NextToExpire returns up to n keys which will expire next, soonest first.
+// Keys without expiration and keys already expired are not included.
+func (m *SQLite) NextToExpire(n int) ([]KeyExpiry, error) {
+	return m.expiring(m.now(), 0, n)
+}
+
+// ExpiringWithin returns the keys which will expire within d, soonest first.
+func (m *SQLite) ExpiringWithin(d time.Duration) ([]KeyExpiry, error) {
+	ts := m.now()
+	return m.expiring(ts, ts+d.Milliseconds(), -1)
+}
+
+// expiring returns the keys expiring from from to until (0 is no bound), limited to limit rows (-1 is no limit).
+func (m *SQLite) expiring(from, until int64, limit int) ([]KeyExpiry, error) {
+	if err := m.checkAccessAll(false); err != nil {
+		return nil, err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	query := "SELECT key, expires_at FROM kv WHERE key LIKE ? AND " + notReserved + " AND expires_at >= ?"
+	args := []any{m.Config.Prefix + "%", m.reservedPattern(), from}
+	if until > 0 {
+		query += " AND expires_at <= ?"
+		args = append(args, until)
+	}
+	query += " ORDER BY expires_at, key LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := m.db().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]KeyExpiry, 0)
+	for rows.Next() {
+		var key string
+		var expiresAt int64
+		if err := rows.Scan(&key, &expiresAt); err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, KeyExpiry{key[len(m.Config.Prefix):], time.UnixMilli(expiresAt)})
+	}
+
+	return keys, rows.Err()
+}
+
+// ExpiresAt returns the expiration time of the given key, the zero time if it doesn't expire.
+// It returns ErrNotFound if the key does not exist, or ErrExpired if it is expired.
+func (m *SQLite) ExpiresAt(key string) (time.Time, error) {
+	keyX, err := m.getKey(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	var expiresAt int64
+	err = m.db().in(m.databaseOf(keyX)).QueryRow("SELECT expires_at FROM kv WHERE key = ?", keyX).Scan(&expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, ErrNotFound
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if expiresAt == 0 {
+		return time.Time{}, nil
+	}
+	if expiresAt < m.expiryCutoff() {
+		return time.Time{}, ErrExpired
+	}
+
+	return time.UnixMilli(expiresAt), nil
+}
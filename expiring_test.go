@@ -0,0 +1,67 @@
+package kvsqlite
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExpiring(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	client, err := New(&SQLiteConfig{
+		Path:   "/tmp/test.db",
+		Prefix: "go-zoox-test:",
+		Clock:  clock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.Clear()
+	defer client.Clear()
+
+	client.Set("later", "v", time.Hour)
+	client.Set("soon", "v", time.Minute)
+	client.Set("soonish", "v", 10*time.Minute)
+	client.Set("never", "v")
+
+	next, err := client.NextToExpire(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(next) != 2 || next[0].Key != "soon" || next[1].Key != "soonish" {
+		t.Errorf("Expected soon, soonish, got %v", next)
+	}
+
+	within, err := client.ExpiringWithin(30 * time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(within) != 2 {
+		t.Errorf("Expected 2 keys expiring within 30 minutes, got %v", within)
+	}
+
+	clock.Add(5 * time.Minute)
+	next, _ = client.NextToExpire(10)
+	if len(next) != 2 || next[0].Key != "soonish" {
+		t.Errorf("Expected expired keys to be excluded, got %v", next)
+	}
+}
+
+func TestExpiresAt(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	client.Set("persistent", 1)
+	client.Set("expiring", 1, time.Hour)
+
+	if at, err := client.ExpiresAt("persistent"); err != nil || !at.IsZero() {
+		t.Errorf("Expected no expiration, got %v (%v)", at, err)
+	}
+	if at, err := client.ExpiresAt("expiring"); err != nil || time.Until(at) <= 59*time.Minute {
+		t.Errorf("Expected an expiration in 1h, got %v (%v)", at, err)
+	}
+	if _, err := client.ExpiresAt("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package kvsqlite
+
+import (
+	"expvar"
+	"sync"
+)
+
+// expvars are the stores published via expvar by name.
+// expvar can't unpublish, so a name stays registered and serves whichever store holds it.
+var expvars = struct {
+	sync.Mutex
+	stores map[string]*SQLite
+}{stores: map[string]*SQLite{}}
+
+// publishExpvar publishes the statistics of the store under the given name.
+func (m *SQLite) publishExpvar(name string) {
+	expvars.Lock()
+	defer expvars.Unlock()
+
+	if _, ok := expvars.stores[name]; !ok {
+		expvar.Publish(name, expvar.Func(func() any {
+			return expvarStats(name)
+		}))
+	}
+	expvars.stores[name] = m
+}
+
+// unpublishExpvar makes the name publish nothing, unless another store took it over.
+func (m *SQLite) unpublishExpvar(name string) {
+	expvars.Lock()
+	defer expvars.Unlock()
+
+	if expvars.stores[name] == m {
+		expvars.stores[name] = nil
+	}
+}
+
+func expvarStats(name string) any {
+	expvars.Lock()
+	m := expvars.stores[name]
+	expvars.Unlock()
+
+	if m == nil {
+		return nil
+	}
+
+	stats, err := m.Stats()
+	if err != nil {
+		return map[string]string{"error": err.Error()}
+	}
+
+	return stats
+}
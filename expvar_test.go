@@ -0,0 +1,42 @@
+package kvsqlite
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+)
+
+func TestExpvar(t *testing.T) {
+	open := func() *SQLite {
+		client, err := New(&SQLiteConfig{Path: "/tmp/test.db", Prefix: "go-zoox-test-expvar:", Expvar: "kvsqlite_test"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client
+	}
+
+	client := open()
+	client.Set("a", 1)
+
+	var stats Stats
+	if err := json.Unmarshal([]byte(expvar.Get("kvsqlite_test").String()), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Sets != 1 || stats.DBSize <= 0 {
+		t.Errorf("Unexpected published stats %+v", stats)
+	}
+
+	client.Close()
+	if value := expvar.Get("kvsqlite_test").String(); value != "null" {
+		t.Errorf("Expected nothing published after Close, got %s", value)
+	}
+
+	// reopening takes the name over instead of panicking on the duplicate
+	client = open()
+	defer client.Close()
+	defer client.Clear()
+
+	if value := expvar.Get("kvsqlite_test").String(); value == "null" {
+		t.Error("Expected the reopened store to be published")
+	}
+}
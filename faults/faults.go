@@ -0,0 +1,276 @@
+// Package faults wraps a KV store with injected failures, so the cache failure handling of applications
+// can be tested against the errors a SQLite store returns, without breaking the database file.
+package faults
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	kvsqlite "github.com/go-zoox/kv-sqlite"
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// The errors of the store for common failure modes, as returned by a SQLite store.
+var (
+	// ErrBusy is the error of a database locked by another connection for too long.
+	ErrBusy error = &kvsqlite.Error{Kind: kvsqlite.ErrBusy, Err: sqlite3.Error{Code: sqlite3.ErrBusy}}
+
+	// ErrReadOnly is the error of a write to a read-only database.
+	ErrReadOnly error = &kvsqlite.Error{Kind: kvsqlite.ErrReadOnly, Err: sqlite3.Error{Code: sqlite3.ErrReadonly}}
+
+	// ErrDiskFull is the error of a write to a full disk.
+	ErrDiskFull error = sqlite3.Error{Code: sqlite3.ErrFull}
+
+	// ErrClosed is the error of a closed store.
+	ErrClosed error = &kvsqlite.Error{Kind: kvsqlite.ErrClosed, Err: errors.New("sql: database is closed")}
+)
+
+// Op is an operation of the store, given to faults.
+type Op struct {
+	// Name is the name of the operation: set, get, delete, has, keys, size, clear or foreach.
+	Name string
+
+	// Key is the key of the operation, empty for the operations on all keys.
+	Key string
+
+	// Write reports whether the operation writes.
+	Write bool
+}
+
+// Fault decides whether an operation fails, before it runs.
+type Fault interface {
+	// Inject returns the error the operation fails with, or nil to run it.
+	Inject(ctx context.Context, op Op) error
+}
+
+// FaultFunc is a function used as a Fault.
+type FaultFunc func(ctx context.Context, op Op) error
+
+// Inject calls f.
+func (f FaultFunc) Inject(ctx context.Context, op Op) error {
+	return f(ctx, op)
+}
+
+type errAfterN struct {
+	n     int64
+	err   error
+	count int64
+}
+
+// ErrAfterN returns a fault letting the first n operations run, then failing all others with err, e.g. ErrBusy.
+func ErrAfterN(n int, err error) Fault {
+	return &errAfterN{n: int64(n), err: err}
+}
+
+func (f *errAfterN) Inject(ctx context.Context, op Op) error {
+	if atomic.AddInt64(&f.count, 1) > f.n {
+		return f.err
+	}
+
+	return nil
+}
+
+// LatencyInjector delays operations, e.g. to test timeouts. It returns ctx.Err() if ctx is done first.
+type LatencyInjector struct {
+	// Latency is the delay of each operation.
+	Latency time.Duration
+
+	// Jitter is the maximum random delay added to Latency.
+	Jitter time.Duration
+
+	// Writes only delays the operations which write.
+	Writes bool
+}
+
+// Inject delays the operation.
+func (l *LatencyInjector) Inject(ctx context.Context, op Op) error {
+	if l.Writes && !op.Write {
+		return nil
+	}
+
+	delay := l.Latency
+	if l.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(l.Jitter)))
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ReadOnlyToggle fails the operations which write with ErrReadOnly while it is on,
+// like a database remounted read-only. The zero value is off.
+type ReadOnlyToggle struct {
+	on int32
+}
+
+// Set turns the toggle on or off.
+func (t *ReadOnlyToggle) Set(on bool) {
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&t.on, v)
+}
+
+// On reports whether the toggle is on.
+func (t *ReadOnlyToggle) On() bool {
+	return atomic.LoadInt32(&t.on) == 1
+}
+
+// Inject fails writes while the toggle is on.
+func (t *ReadOnlyToggle) Inject(ctx context.Context, op Op) error {
+	if op.Write && t.On() {
+		return ErrReadOnly
+	}
+
+	return nil
+}
+
+// KV is a store running its operations through faults first.
+// The operations of typing.KV which can't return errors report a miss on failure: false, no keys, 0 or no entries.
+type KV struct {
+	kv     kvsqlite.ContextKV
+	faults []Fault
+}
+
+var _ kvsqlite.ContextKV = (*KV)(nil)
+
+// New returns kv with the given faults, checked in order.
+func New(kv kvsqlite.ContextKV, faults ...Fault) *KV {
+	return &KV{kv: kv, faults: faults}
+}
+
+func (k *KV) inject(ctx context.Context, op Op) error {
+	for _, f := range k.faults {
+		if err := f.Inject(ctx, op); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Set sets the value for the given key.
+func (k *KV) Set(key string, value any, maxAge ...time.Duration) error {
+	return k.SetContext(context.Background(), key, value, maxAge...)
+}
+
+// SetContext is like Set but honors ctx.
+func (k *KV) SetContext(ctx context.Context, key string, value any, maxAge ...time.Duration) error {
+	if err := k.inject(ctx, Op{Name: "set", Key: key, Write: true}); err != nil {
+		return err
+	}
+
+	return k.kv.SetContext(ctx, key, value, maxAge...)
+}
+
+// Get returns the value for the given key.
+func (k *KV) Get(key string, value any) error {
+	return k.GetContext(context.Background(), key, value)
+}
+
+// GetContext is like Get but honors ctx.
+func (k *KV) GetContext(ctx context.Context, key string, value any) error {
+	if err := k.inject(ctx, Op{Name: "get", Key: key}); err != nil {
+		return err
+	}
+
+	return k.kv.GetContext(ctx, key, value)
+}
+
+// Delete deletes the value for the given key.
+func (k *KV) Delete(key string) error {
+	return k.DeleteContext(context.Background(), key)
+}
+
+// DeleteContext is like Delete but honors ctx.
+func (k *KV) DeleteContext(ctx context.Context, key string) error {
+	if err := k.inject(ctx, Op{Name: "delete", Key: key, Write: true}); err != nil {
+		return err
+	}
+
+	return k.kv.DeleteContext(ctx, key)
+}
+
+// Has returns true if the given key exists in the kv.
+func (k *KV) Has(key string) bool {
+	ok, _ := k.HasContext(context.Background(), key)
+	return ok
+}
+
+// HasContext is like Has but honors ctx and returns the error.
+func (k *KV) HasContext(ctx context.Context, key string) (bool, error) {
+	if err := k.inject(ctx, Op{Name: "has", Key: key}); err != nil {
+		return false, err
+	}
+
+	return k.kv.HasContext(ctx, key)
+}
+
+// Keys returns the keys of the kv.
+func (k *KV) Keys() []string {
+	keys, _ := k.KeysContext(context.Background())
+	return keys
+}
+
+// KeysContext is like Keys but honors ctx and returns the error.
+func (k *KV) KeysContext(ctx context.Context) ([]string, error) {
+	if err := k.inject(ctx, Op{Name: "keys"}); err != nil {
+		return nil, err
+	}
+
+	return k.kv.KeysContext(ctx)
+}
+
+// Size returns the number of entries in the kv.
+func (k *KV) Size() int {
+	size, _ := k.SizeContext(context.Background())
+	return size
+}
+
+// SizeContext is like Size but honors ctx and returns the error.
+func (k *KV) SizeContext(ctx context.Context) (int, error) {
+	if err := k.inject(ctx, Op{Name: "size"}); err != nil {
+		return 0, err
+	}
+
+	return k.kv.SizeContext(ctx)
+}
+
+// Clear clears the kv.
+func (k *KV) Clear() error {
+	return k.ClearContext(context.Background())
+}
+
+// ClearContext is like Clear but honors ctx.
+func (k *KV) ClearContext(ctx context.Context) error {
+	if err := k.inject(ctx, Op{Name: "clear", Write: true}); err != nil {
+		return err
+	}
+
+	return k.kv.ClearContext(ctx)
+}
+
+// ForEach iterates over the kv and calls the given function for each entry.
+func (k *KV) ForEach(f func(key string, value any)) {
+	k.ForEachContext(context.Background(), f)
+}
+
+// ForEachContext is like ForEach but honors ctx and returns the error.
+func (k *KV) ForEachContext(ctx context.Context, f func(key string, value any)) error {
+	if err := k.inject(ctx, Op{Name: "foreach"}); err != nil {
+		return err
+	}
+
+	return k.kv.ForEachContext(ctx, f)
+}
@@ -0,0 +1,98 @@
+package faults
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	kvsqlite "github.com/go-zoox/kv-sqlite"
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+func createStore(t *testing.T) *kvsqlite.SQLite {
+	kv, err := kvsqlite.New(&kvsqlite.SQLiteConfig{
+		Path:   "/tmp/test.db",
+		Prefix: "go-zoox-test-faults:" + time.Now().String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		kv.Clear()
+		kv.Close()
+	})
+
+	return kv
+}
+
+func TestErrAfterN(t *testing.T) {
+	kv := New(createStore(t), ErrAfterN(2, ErrBusy))
+
+	if err := kv.Set("a", 1); err != nil {
+		t.Fatal(err)
+	}
+	var v int
+	if err := kv.Get("a", &v); err != nil || v != 1 {
+		t.Fatalf("Expected 1, got %d (%v)", v, err)
+	}
+
+	err := kv.Get("a", &v)
+	if !errors.Is(err, kvsqlite.ErrBusy) {
+		t.Fatalf("Expected ErrBusy, got %v", err)
+	}
+	var driverErr sqlite3.Error
+	if !errors.As(err, &driverErr) || driverErr.Code != sqlite3.ErrBusy {
+		t.Errorf("Expected the driver error, got %v", err)
+	}
+
+	if kv.Has("a") || kv.Size() != 0 || kv.Keys() != nil {
+		t.Error("Expected misses on failure")
+	}
+}
+
+func TestLatencyInjector(t *testing.T) {
+	kv := New(createStore(t), &LatencyInjector{Latency: 50 * time.Millisecond})
+
+	start := time.Now()
+	if err := kv.Set("a", 1); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected a delay, got %s", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var v int
+	if err := kv.GetContext(ctx, "a", &v); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestReadOnlyToggle(t *testing.T) {
+	toggle := &ReadOnlyToggle{}
+	kv := New(createStore(t), toggle)
+
+	if err := kv.Set("a", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	toggle.Set(true)
+	if err := kv.Set("a", 2); !errors.Is(err, kvsqlite.ErrReadOnly) {
+		t.Fatalf("Expected ErrReadOnly, got %v", err)
+	}
+	if err := kv.Delete("a"); !errors.Is(err, kvsqlite.ErrReadOnly) {
+		t.Fatalf("Expected ErrReadOnly, got %v", err)
+	}
+
+	var v int
+	if err := kv.Get("a", &v); err != nil || v != 1 {
+		t.Fatalf("Expected reads to go on, got %d (%v)", v, err)
+	}
+
+	toggle.Set(false)
+	if err := kv.Set("a", 2); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,52 @@
+package kvsqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrFenced is returned when a write is rejected because another handle acquired a newer epoch.
+var ErrFenced = errors.New("sqlite: fenced by a newer epoch")
+
+// AcquireEpoch increments the epoch (fencing token) stored for the prefix and adopts it for this handle.
+// From then on, every write of this handle first checks that its epoch is still the stored one,
+// so when two instances both believe they own the store, the writes of the stale one are rejected with ErrFenced.
+func (m *SQLite) AcquireEpoch() (int64, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	var epoch int64
+	err := m.wdb().QueryRow(
+		"INSERT INTO kv_epoch (prefix, epoch) VALUES (?, 1) ON CONFLICT(prefix) DO UPDATE SET epoch = epoch + 1 RETURNING epoch",
+		m.Config.Prefix,
+	).Scan(&epoch)
+	if err != nil {
+		return 0, err
+	}
+
+	m.epoch = epoch
+	return epoch, nil
+}
+
+// Epoch returns the epoch held by this handle, or 0 if fencing is not in use.
+func (m *SQLite) Epoch() int64 {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.epoch
+}
+
+// checkEpoch checks that the epoch held by this handle is still the stored one.
+func (m *SQLite) checkEpoch(ctx context.Context, db *tracer) error {
+	var current int64
+	if err := db.QueryRowContext(ctx, "SELECT epoch FROM kv_epoch WHERE prefix = ?", m.Config.Prefix).Scan(&current); err != nil {
+		return err
+	}
+
+	if current != m.epoch {
+		return fmt.Errorf("%w: holding %d, current is %d", ErrFenced, m.epoch, current)
+	}
+
+	return nil
+}
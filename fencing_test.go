@@ -0,0 +1,40 @@
+package kvsqlite
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFencing(t *testing.T) {
+	stale := createClient()
+	stale.Clear()
+	defer stale.Clear()
+
+	if _, err := stale.AcquireEpoch(); err != nil {
+		t.Fatal(err)
+	}
+	if err := stale.Set("key", "stale"); err != nil {
+		t.Fatal(err)
+	}
+
+	owner := createClient()
+	epoch, err := owner.AcquireEpoch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if epoch <= stale.Epoch() {
+		t.Errorf("Expected new epoch to be greater than %d, got %d", stale.Epoch(), epoch)
+	}
+
+	if err := stale.Set("key", "stale-again"); !errors.Is(err, ErrFenced) {
+		t.Errorf("Expected ErrFenced, got %v", err)
+	}
+	if err := owner.Set("key", "owner"); err != nil {
+		t.Fatal(err)
+	}
+
+	var value string
+	if err := owner.Get("key", &value); err != nil || value != "owner" {
+		t.Errorf("Expected value to be 'owner', got %q (%v)", value, err)
+	}
+}
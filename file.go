@@ -0,0 +1,97 @@
+package kvsqlite
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// PutFile stores the content of the file at path under the given key, streamed with SetReader,
+// followed by its SHA-256 so GetFile can verify it. maxAge is handled like in Set.
+// Like SetReader, it returns ErrStreamTransformed on a store with Config.Transformers.
+func (m *SQLite) PutFile(key string, path string, maxAge ...time.Duration) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	digest := sha256.New()
+	return m.SetReader(key, io.MultiReader(io.TeeReader(f, digest), &digestReader{hash: digest}), maxAge...)
+}
+
+// digestReader reads the sum of hash once the content before it has been read.
+type digestReader struct {
+	hash hash.Hash
+	sum  *bytes.Reader
+}
+
+func (r *digestReader) Read(p []byte) (int, error) {
+	if r.sum == nil {
+		r.sum = bytes.NewReader(r.hash.Sum(nil))
+	}
+
+	return r.sum.Read(p)
+}
+
+// GetFile writes the file stored under the given key with PutFile to destPath, streamed with GetReader.
+// The file is written next to destPath first and only renamed to it once its checksum is verified,
+// so destPath is never left with partial or corrupt content. It returns ErrNotFound if the key does not exist,
+// ErrExpired if it is expired, or ErrChecksum if the content doesn't match its checksum,
+// e.g. because it was not stored with PutFile.
+func (m *SQLite) GetFile(key string, destPath string) error {
+	r, err := m.openReader(key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if r.size < sha256.Size {
+		return ErrChecksum
+	}
+
+	// a unique name, so concurrent GetFile calls for the same destPath don't write into each other
+	f, err := os.CreateTemp(filepath.Dir(destPath), filepath.Base(destPath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+
+	ok := false
+	defer func() {
+		if !ok {
+			f.Close()
+			os.Remove(tmp)
+		}
+	}()
+
+	digest := sha256.New()
+	if _, err := io.CopyN(io.MultiWriter(f, digest), r, r.size-sha256.Size); err != nil {
+		return err
+	}
+
+	sum, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(sum, digest.Sum(nil)) {
+		return ErrChecksum
+	}
+
+	if err := f.Chmod(0o644); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	ok = true
+	return os.Rename(tmp, destPath)
+}
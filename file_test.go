@@ -0,0 +1,75 @@
+package kvsqlite
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestPutFileGetFile(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "artifact.bin")
+	data := bytes.Repeat([]byte("artifact"), streamChunkSize/4)
+	if err := os.WriteFile(src, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.PutFile("artifact", src); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(dir, "restored.bin")
+	if err := client.GetFile("artifact", dest); err != nil {
+		t.Fatal(err)
+	}
+	if restored, _ := os.ReadFile(dest); !bytes.Equal(restored, data) {
+		t.Errorf("Expected %d bytes restored, got %d", len(data), len(restored))
+	}
+
+	// corrupt the stored content
+	client.SetReader("artifact", bytes.NewReader(append([]byte("x"), data...)))
+	corrupt := filepath.Join(dir, "corrupt.bin")
+	if err := client.GetFile("artifact", corrupt); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Expected ErrChecksum, got %v", err)
+	}
+	if _, err := os.Stat(corrupt); !os.IsNotExist(err) {
+		t.Errorf("Expected no file written on checksum mismatch, got %v", err)
+	}
+	if tmps, _ := filepath.Glob(corrupt + ".*.tmp"); len(tmps) != 0 {
+		t.Errorf("Expected the temporary file to be removed, got %v", tmps)
+	}
+
+	// concurrent restores of the same destination don't share a temporary file
+	client.PutFile("artifact", src)
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = client.GetFile("artifact", dest)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Errorf("Expected concurrent GetFile to succeed, got %v", err)
+		}
+	}
+	if restored, _ := os.ReadFile(dest); !bytes.Equal(restored, data) {
+		t.Errorf("Expected %d bytes restored concurrently, got %d", len(data), len(restored))
+	}
+	if info, err := os.Stat(dest); err != nil || info.Mode().Perm() != 0o644 {
+		t.Errorf("Expected the restored file to be 0644, got %v (%v)", info, err)
+	}
+
+	if err := client.GetFile("missing", dest); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
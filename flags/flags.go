@@ -0,0 +1,185 @@
+// Package flags is a feature flag store built on the SQLite KV store.
+package flags
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"strings"
+	"sync"
+
+	kvsqlite "github.com/go-zoox/kv-sqlite"
+)
+
+// prefix is the key prefix of flags.
+const prefix = "flag:"
+
+// Flag is a feature flag.
+type Flag struct {
+	// Enabled turns the flag on.
+	Enabled bool `json:"enabled"`
+
+	// Percentage is the share of subjects (1-100) the flag is enabled for, when rolling out gradually.
+	// 0 means all subjects.
+	Percentage int `json:"percentage,omitempty"`
+
+	// Value is the value of a typed flag, read with GetString, GetInt, GetFloat or GetValue.
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Change is a change of flags sent by Watch.
+type Change struct {
+	// Name is the name of the flag which changed, empty if Cleared is set.
+	Name string
+
+	// Cleared is set when all flags were removed, e.g. by Clear on the underlying store.
+	Cleared bool
+}
+
+// Store stores feature flags.
+type Store struct {
+	kv *kvsqlite.SQLite
+}
+
+// New returns a new flag Store.
+func New(kv *kvsqlite.SQLite) *Store {
+	return &Store{kv}
+}
+
+// SetFlag sets the flag with the given name.
+func (s *Store) SetFlag(name string, flag Flag) error {
+	return s.kv.Set(prefix+name, flag)
+}
+
+// DeleteFlag deletes the flag with the given name.
+func (s *Store) DeleteFlag(name string) error {
+	return s.kv.Delete(prefix + name)
+}
+
+// GetFlag returns the flag with the given name and whether it exists.
+func (s *Store) GetFlag(name string) (Flag, bool, error) {
+	var flag Flag
+	found, err := s.kv.Lookup(prefix+name, &flag)
+	return flag, found, err
+}
+
+// IsEnabled reports whether the flag is enabled, or defaultVal if it does not exist or cannot be read.
+// A flag with a partial rollout is enabled only for the subjects given to IsEnabledFor.
+func (s *Store) IsEnabled(name string, defaultVal bool) bool {
+	flag, found, err := s.GetFlag(name)
+	if err != nil || !found {
+		return defaultVal
+	}
+
+	return flag.Enabled && (flag.Percentage <= 0 || flag.Percentage >= 100)
+}
+
+// IsEnabledFor reports whether the flag is enabled for the given subject, e.g. a user ID,
+// or defaultVal if it does not exist or cannot be read.
+// Subjects are bucketed by hashing, so a subject stays in or out of a rollout consistently.
+func (s *Store) IsEnabledFor(name, subject string, defaultVal bool) bool {
+	flag, found, err := s.GetFlag(name)
+	if err != nil || !found {
+		return defaultVal
+	}
+
+	if !flag.Enabled {
+		return false
+	}
+
+	if flag.Percentage <= 0 || flag.Percentage >= 100 {
+		return true
+	}
+
+	return bucket(name, subject) < flag.Percentage
+}
+
+// Watch returns a channel of the changes of flags, and a function to stop watching.
+// Clearing the underlying store is sent as a Change with Cleared set.
+func (s *Store) Watch() (<-chan Change, func()) {
+	events, cancel := s.kv.SubscribePattern(prefix + "*")
+
+	changes := make(chan Change, cap(events))
+	done := make(chan struct{})
+	go func() {
+		defer close(changes)
+
+		for event := range events {
+			change := Change{Name: strings.TrimPrefix(event.Key, prefix)}
+			if event.Type == kvsqlite.EventClear {
+				change = Change{Cleared: true}
+			}
+
+			select {
+			case changes <- change:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return changes, func() {
+		once.Do(func() {
+			close(done)
+			cancel()
+		})
+	}
+}
+
+// GetValue decodes the value of the flag with the given name into value,
+// and reports whether the flag exists, is enabled and has a value.
+// Partial rollouts don't apply to values, they are served to all subjects.
+func (s *Store) GetValue(name string, value any) (bool, error) {
+	flag, found, err := s.GetFlag(name)
+	if err != nil || !found || !flag.Enabled || len(flag.Value) == 0 {
+		return false, err
+	}
+
+	if err := json.Unmarshal(flag.Value, value); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetString returns the string value of the flag,
+// or defaultVal if it does not exist, is disabled or has no string value.
+func (s *Store) GetString(name string, defaultVal string) string {
+	var value string
+	if found, err := s.GetValue(name, &value); err != nil || !found {
+		return defaultVal
+	}
+
+	return value
+}
+
+// GetInt returns the integer value of the flag,
+// or defaultVal if it does not exist, is disabled or has no integer value.
+func (s *Store) GetInt(name string, defaultVal int) int {
+	var value int
+	if found, err := s.GetValue(name, &value); err != nil || !found {
+		return defaultVal
+	}
+
+	return value
+}
+
+// GetFloat returns the number value of the flag,
+// or defaultVal if it does not exist, is disabled or has no number value.
+func (s *Store) GetFloat(name string, defaultVal float64) float64 {
+	var value float64
+	if found, err := s.GetValue(name, &value); err != nil || !found {
+		return defaultVal
+	}
+
+	return value
+}
+
+// bucket returns the rollout bucket (0-99) of the subject for the flag.
+func bucket(name, subject string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write([]byte(subject))
+	return int(h.Sum32() % 100)
+}
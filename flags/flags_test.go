@@ -0,0 +1,84 @@
+package flags
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	kvsqlite "github.com/go-zoox/kv-sqlite"
+)
+
+func TestFlags(t *testing.T) {
+	kv, err := kvsqlite.New(&kvsqlite.SQLiteConfig{
+		Path:   "/tmp/test.db",
+		Prefix: "go-zoox-test-flags:",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer kv.Close()
+	kv.Clear()
+	defer kv.Clear()
+
+	store := New(kv)
+	changes, stop := store.Watch()
+	defer stop()
+
+	if !store.IsEnabled("missing", true) {
+		t.Error("Expected default value for missing flag")
+	}
+
+	store.SetFlag("dark-mode", Flag{Enabled: true})
+	if !store.IsEnabled("dark-mode", false) {
+		t.Error("Expected dark-mode to be enabled")
+	}
+	if change := <-changes; change.Name != "dark-mode" || change.Cleared {
+		t.Errorf("Expected change notification for dark-mode, got %+v", change)
+	}
+
+	store.SetFlag("beta", Flag{Enabled: true, Percentage: 30})
+	enabled := 0
+	for i := 0; i < 1000; i++ {
+		subject := "user:" + strconv.Itoa(i)
+		if store.IsEnabledFor("beta", subject, false) {
+			enabled++
+		}
+		if store.IsEnabledFor("beta", subject, false) != store.IsEnabledFor("beta", subject, false) {
+			t.Fatal("Expected rollout to be consistent per subject")
+		}
+	}
+	if enabled < 200 || enabled > 400 {
+		t.Errorf("Expected about 30%% of subjects to be enabled, got %d/1000", enabled)
+	}
+
+	<-changes
+
+	// typed values
+	store.SetFlag("theme", Flag{Enabled: true, Value: json.RawMessage(`"dark"`)})
+	store.SetFlag("limit", Flag{Enabled: true, Value: json.RawMessage(`20`)})
+	store.SetFlag("ratio", Flag{Enabled: false, Value: json.RawMessage(`0.5`)})
+	if v := store.GetString("theme", "light"); v != "dark" {
+		t.Errorf("Expected dark, got %s", v)
+	}
+	if v := store.GetInt("limit", 10); v != 20 {
+		t.Errorf("Expected 20, got %d", v)
+	}
+	if v := store.GetInt("theme", 10); v != 10 {
+		t.Errorf("Expected the default for a value of another type, got %d", v)
+	}
+	if v := store.GetFloat("ratio", 1); v != 1 {
+		t.Errorf("Expected the default for a disabled flag, got %v", v)
+	}
+	if v := store.GetString("missing", "light"); v != "light" {
+		t.Errorf("Expected the default for a missing flag, got %s", v)
+	}
+	for i := 0; i < 3; i++ {
+		<-changes
+	}
+
+	// clearing the store is an explicit change
+	kv.Clear()
+	if change := <-changes; !change.Cleared || change.Name != "" {
+		t.Errorf("Expected a cleared change, got %+v", change)
+	}
+}
@@ -0,0 +1,131 @@
+package kvsqlite
+
+import (
+	"context"
+	"sync"
+)
+
+// forEachPageSize is the number of rows read per page by ForEachParallel.
+const forEachPageSize = 1000
+
+type rawEntry struct {
+	key   string
+	value []byte
+}
+
+// ForEachParallel streams all entries in pages and calls fn for each of them across a pool of workers,
+// passing the raw stored value for fn to decode.
+// No lock is held while fn runs, so fn may write to the store, e.g. to re-encode values.
+// It stops at the first error returned by fn and returns it.
+func (m *SQLite) ForEachParallel(workers int, fn func(key string, raw []byte) error) error {
+	return m.ForEachParallelContext(context.Background(), workers, fn)
+}
+
+// ForEachParallelContext is like ForEachParallel but stops when ctx is done and returns ctx.Err().
+func (m *SQLite) ForEachParallelContext(ctx context.Context, workers int, fn func(key string, raw []byte) error) error {
+	if err := m.checkAccessAll(false); err != nil {
+		return err
+	}
+
+	if workers <= 0 {
+		workers = 1
+	}
+
+	entries := make(chan rawEntry)
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	var firstErr error
+
+	fail := func(err error) {
+		stopOnce.Do(func() {
+			firstErr = err
+			close(stop)
+		})
+	}
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for entry := range entries {
+				if err := fn(entry.key, entry.value); err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+
+	after := ""
+pages:
+	for {
+		page, err := m.page(ctx, after, forEachPageSize)
+		if err != nil {
+			fail(err)
+			break
+		}
+
+		for _, entry := range page {
+			if err := ctx.Err(); err != nil {
+				fail(err)
+				break pages
+			}
+
+			select {
+			case entries <- entry:
+			case <-stop:
+				break pages
+			case <-ctx.Done():
+				fail(ctx.Err())
+				break pages
+			}
+		}
+
+		if len(page) < forEachPageSize {
+			break
+		}
+		after = page[len(page)-1].key
+	}
+
+	close(entries)
+	wg.Wait()
+
+	return firstErr
+}
+
+// page returns up to limit unexpired entries with keys greater than after, ordered by key.
+func (m *SQLite) page(ctx context.Context, after string, limit int) ([]rawEntry, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	rows, err := m.db().QueryContext(ctx,
+		"SELECT key, "+valueExpr+" FROM kv WHERE key LIKE ? AND key > ? AND "+notReserved+" AND "+unexpired+" ORDER BY key LIMIT ?",
+		m.Config.Prefix+"%", m.Config.Prefix+after, m.reservedPattern(), m.expiryCutoff(), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := make([]rawEntry, 0, limit)
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+
+		raw, err := m.restore([]byte(value))
+		if err != nil {
+			return nil, err
+		}
+
+		page = append(page, rawEntry{key[len(m.Config.Prefix):], raw})
+	}
+
+	return page, rows.Err()
+}
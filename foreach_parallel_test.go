@@ -0,0 +1,41 @@
+package kvsqlite
+
+import (
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestForEachParallel(t *testing.T) {
+	client := createClient()
+	client.Clear()
+	defer client.Clear()
+
+	for i := 0; i < 1200; i++ {
+		client.Set("key"+strconv.Itoa(i), i)
+	}
+
+	var sum int64
+	err := client.ForEachParallel(4, func(key string, raw []byte) error {
+		n, err := strconv.Atoi(string(raw))
+		if err != nil {
+			return err
+		}
+
+		atomic.AddInt64(&sum, int64(n))
+		// writing back must not deadlock
+		return client.Set(key, n+1)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != 1199*1200/2 {
+		t.Errorf("Expected sum %d, got %d", 1199*1200/2, sum)
+	}
+
+	stop := errors.New("stop")
+	if err := client.ForEachParallel(2, func(key string, raw []byte) error { return stop }); err != stop {
+		t.Errorf("Expected the error of fn, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package kvsqlite
+
+import (
+	"context"
+	"errors"
+	"reflect"
+)
+
+// GetAllByPrefix reads the unexpired keys starting with the given sub-prefix in a single query
+// and decodes their values into values, which must be a non-nil map[string]T, keyed by the full keys,
+// e.g. GetAllByPrefix("user:", users) instead of listing the keys and getting them one by one.
+// Reserved keys are left out. It fails on the first value which does not decode into T.
+func (m *SQLite) GetAllByPrefix(sub string, values any) error {
+	return m.GetAllByPrefixContext(context.Background(), sub, values)
+}
+
+// GetAllByPrefixContext is like GetAllByPrefix but honors ctx.
+func (m *SQLite) GetAllByPrefixContext(ctx context.Context, sub string, values any) error {
+	rv := reflect.ValueOf(values)
+	if rv.Kind() != reflect.Map || rv.IsNil() || rv.Type().Key().Kind() != reflect.String {
+		return errors.New("sqlite: values must be a non-nil map[string]T")
+	}
+	elemType := rv.Type().Elem()
+
+	if err := m.checkAccessAll(false); err != nil {
+		return err
+	}
+
+	raw, err := m.scanPrefix(ctx, sub)
+	if err != nil {
+		return err
+	}
+
+	// decoding may repair corrupt values, which needs the write lock, see decodeRead
+	for _, item := range raw {
+		value := reflect.New(elemType)
+		found, err := m.decodeRead(ctx, item.Key, item.data, value.Interface())
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+
+		rv.SetMapIndex(reflect.ValueOf(item.Key).Convert(rv.Type().Key()), value.Elem())
+	}
+
+	return nil
+}
+
+// scanPrefix returns the stored values of the unexpired, unreserved keys starting with the given sub-prefix,
+// and pushes the expirations of those with a sliding TTL.
+func (m *SQLite) scanPrefix(ctx context.Context, sub string) ([]RangeItem, error) {
+	items, sliding, err := m.readPrefix(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+
+	return items, m.slideAll(ctx, sliding)
+}
+
+func (m *SQLite) readPrefix(ctx context.Context, sub string) ([]RangeItem, []slidingKey, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	items := make([]RangeItem, 0)
+	sliding := make([]slidingKey, 0)
+	for _, database := range m.databases() {
+		rows, err := m.db().in(database).QueryContext(ctx,
+			"SELECT key, "+valueExpr+", expires_at, sliding_ttl FROM kv WHERE key >= ? AND key < ? AND "+notReserved+" AND "+unexpired,
+			m.Config.Prefix+sub, m.Config.Prefix+sub+prefixEnd, m.reservedPattern(), m.expiryCutoff(),
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for rows.Next() {
+			var keyX, value string
+			var s slidingKey
+			if err := rows.Scan(&keyX, &value, &s.expiresAt, &s.sliding); err != nil {
+				rows.Close()
+				return nil, nil, err
+			}
+
+			items = append(items, RangeItem{Key: keyX[len(m.Config.Prefix):], store: m, data: []byte(value)})
+			if s.sliding > 0 {
+				s.keyX = keyX
+				sliding = append(sliding, s)
+			}
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return items, sliding, nil
+}
@@ -0,0 +1,42 @@
+package kvsqlite
+
+import (
+	"testing"
+)
+
+func TestGetAllByPrefix(t *testing.T) {
+	client := createClient()
+	client.Clear()
+	defer client.Clear()
+
+	client.Set("user:1", "alice")
+	client.Set("user:2", "bob")
+	client.Set("usera", "not a user")
+	client.Set("group:1", "admins")
+
+	users := map[string]string{}
+	if err := client.GetAllByPrefix("user:", users); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users["user:1"] != "alice" || users["user:2"] != "bob" {
+		t.Errorf("Expected the two users, got %v", users)
+	}
+
+	empty := map[string]string{}
+	if err := client.GetAllByPrefix("missing:", empty); err != nil {
+		t.Fatal(err)
+	}
+	if len(empty) != 0 {
+		t.Errorf("Expected no values, got %v", empty)
+	}
+
+	client.Set("num:1", 1)
+	client.Set("num:2", "two")
+	if err := client.GetAllByPrefix("num:", map[string]int{}); err == nil {
+		t.Error("Expected a decode error")
+	}
+
+	if err := client.GetAllByPrefix("user:", users["user:1"]); err == nil {
+		t.Error("Expected an error for a non map destination")
+	}
+}
@@ -0,0 +1,57 @@
+package kvsqlite
+
+import (
+	"context"
+)
+
+// GetJSON returns the JSON object stored under key as a map, e.g. for admin tools which don't know its type.
+// It returns ErrNotFound if the key does not exist, and a ValueTypeError if the value is not an object.
+// Numbers are float64, or json.Number with Config.UseNumber.
+func (m *SQLite) GetJSON(key string) (map[string]any, error) {
+	var value map[string]any
+	if err := m.getJSON(context.Background(), key, &value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// GetJSONArray is like GetJSON for a JSON array.
+func (m *SQLite) GetJSONArray(key string) ([]any, error) {
+	var value []any
+	if err := m.getJSON(context.Background(), key, &value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// GetAny is like GetJSON for any JSON value: a map, a slice, a string, a number, a bool or nil.
+func (m *SQLite) GetAny(key string) (any, error) {
+	var value any
+	if err := m.getJSON(context.Background(), key, &value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// getJSON decodes the value of key into value, or returns ErrNotFound.
+func (m *SQLite) getJSON(ctx context.Context, key string, value any) error {
+	data, found, err := m.read(ctx, key)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotFound
+	}
+
+	if found, err = m.decodeRead(ctx, key, data, value); err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package kvsqlite
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGetJSON(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	client.Set("object", map[string]any{"name": "zero", "tags": []string{"a"}})
+	client.Set("array", []int{1, 2})
+	client.Set("string", "value")
+
+	object, err := client.GetJSON("object")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if object["name"] != "zero" || len(object["tags"].([]any)) != 1 {
+		t.Errorf("Unexpected object %v", object)
+	}
+
+	array, err := client.GetJSONArray("array")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(array) != 2 || array[1] != float64(2) {
+		t.Errorf("Unexpected array %v", array)
+	}
+
+	if v, err := client.GetAny("string"); err != nil || v != "value" {
+		t.Errorf("Expected value, got %v (%v)", v, err)
+	}
+
+	if _, err := client.GetJSON("string"); !errors.Is(err, ErrValueTypeMismatch) {
+		t.Errorf("Expected ErrValueTypeMismatch, got %v", err)
+	}
+	if _, err := client.GetJSON("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package kvsqlite
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// maxBatchParams is the number of keys bound in a single statement by bulk operations.
+const maxBatchParams = 500
+
+// MultiResult is the result of GetMulti.
+type MultiResult struct {
+	// Found are the keys which were found and decoded.
+	Found []string
+	// Missing are the keys which do not exist or are expired.
+	Missing []string
+	// Errors are the keys which were found but failed to decode, with their errors.
+	Errors map[string]error
+}
+
+// GetMulti reads the given keys in bulk and decodes the found values into values,
+// which must be a non-nil map[string]T.
+// A key which is missing or fails to decode does not fail the batch, it is reported in the result instead.
+func (m *SQLite) GetMulti(keys []string, values any) (*MultiResult, error) {
+	rv := reflect.ValueOf(values)
+	if rv.Kind() != reflect.Map || rv.IsNil() || rv.Type().Key().Kind() != reflect.String {
+		return nil, errors.New("sqlite: values must be a non-nil map[string]T")
+	}
+	elemType := rv.Type().Elem()
+
+	raw, err := m.getRaw(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &MultiResult{
+		Found:   make([]string, 0, len(raw)),
+		Missing: make([]string, 0),
+		Errors:  make(map[string]error),
+	}
+	for _, key := range keys {
+		data, ok := raw[key]
+		if !ok {
+			res.Missing = append(res.Missing, key)
+			continue
+		}
+
+		value := reflect.New(elemType)
+		found, err := m.decodeRead(context.Background(), key, data, value.Interface())
+		if err != nil {
+			res.Errors[key] = err
+			continue
+		}
+		if !found {
+			res.Missing = append(res.Missing, key)
+			continue
+		}
+
+		rv.SetMapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()), value.Elem())
+		res.Found = append(res.Found, key)
+	}
+
+	return res, nil
+}
+
+// getRaw returns the stored values of the given keys which exist and are not expired,
+// and pushes the expirations of those with a sliding TTL.
+func (m *SQLite) getRaw(keys []string) (map[string][]byte, error) {
+	raw, sliding, err := m.readRaw(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return raw, m.slideAll(context.Background(), sliding)
+}
+
+func (m *SQLite) readRaw(keys []string) (map[string][]byte, []slidingKey, error) {
+	groups, err := m.byDatabase(keys, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the stored keys may differ from the given ones, see Config.KeySanitizer
+	originals := make(map[string]string, len(keys))
+	for _, key := range keys {
+		keyX, _ := m.getKey(key)
+		originals[keyX] = key
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	raw := make(map[string][]byte, len(keys))
+	sliding := make([]slidingKey, 0)
+	ts := m.expiryCutoff()
+	for database, group := range groups {
+		for start := 0; start < len(group); start += maxBatchParams {
+			end := start + maxBatchParams
+			if end > len(group) {
+				end = len(group)
+			}
+			batch := group[start:end]
+
+			args := make([]any, 0, len(batch)+1)
+			for _, keyX := range batch {
+				args = append(args, keyX)
+			}
+			args = append(args, ts)
+
+			rows, err := m.db().in(database).Query(
+				"SELECT key, "+valueExpr+", expires_at, sliding_ttl FROM kv WHERE key IN ("+placeholders(len(batch))+") AND "+unexpired,
+				args...,
+			)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			for rows.Next() {
+				var key, value string
+				var s slidingKey
+				if err := rows.Scan(&key, &value, &s.expiresAt, &s.sliding); err != nil {
+					rows.Close()
+					return nil, nil, err
+				}
+
+				raw[originals[key]] = []byte(value)
+				if s.sliding > 0 {
+					s.keyX = key
+					sliding = append(sliding, s)
+				}
+			}
+			rows.Close()
+
+			if err := rows.Err(); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	return raw, sliding, nil
+}
+
+// placeholders returns n comma separated bind parameters.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
@@ -0,0 +1,36 @@
+package kvsqlite
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestGetMulti(t *testing.T) {
+	client := createClient()
+	client.Clear()
+	defer client.Clear()
+
+	client.Set("a", 1)
+	client.Set("b", 2)
+	client.Set("bad", "not a number")
+
+	values := map[string]int{}
+	res, err := client.GetMulti([]string{"a", "b", "missing", "bad"}, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(res.Found)
+	if len(res.Found) != 2 || res.Found[0] != "a" || res.Found[1] != "b" {
+		t.Errorf("Expected found to be [a b], got %v", res.Found)
+	}
+	if values["a"] != 1 || values["b"] != 2 {
+		t.Errorf("Expected decoded values, got %v", values)
+	}
+	if len(res.Missing) != 1 || res.Missing[0] != "missing" {
+		t.Errorf("Expected missing to be [missing], got %v", res.Missing)
+	}
+	if res.Errors["bad"] == nil {
+		t.Error("Expected a decode error for bad")
+	}
+}
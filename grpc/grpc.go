@@ -0,0 +1,366 @@
+// Package grpc serves a SQLite store as a gRPC KV service, see kv.proto,
+// so services in other languages on the same host can share one store.
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	kvsqlite "github.com/go-zoox/kv-sqlite"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The messages of kv.proto, declared with protobuf struct tags instead of generated code.
+
+// GetRequest is the request of Get.
+type GetRequest struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3"`
+}
+
+// GetResponse is the response of Get.
+type GetResponse struct {
+	Value []byte `protobuf:"bytes,1,opt,name=value,proto3"`
+	Found bool   `protobuf:"varint,2,opt,name=found,proto3"`
+}
+
+// SetRequest is the request of Set.
+type SetRequest struct {
+	Key   string `protobuf:"bytes,1,opt,name=key,proto3"`
+	Value []byte `protobuf:"bytes,2,opt,name=value,proto3"`
+	TTLMs int64  `protobuf:"varint,3,opt,name=ttl_ms,json=ttlMs,proto3"`
+}
+
+// SetResponse is the response of Set.
+type SetResponse struct{}
+
+// DeleteRequest is the request of Delete.
+type DeleteRequest struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3"`
+}
+
+// DeleteResponse is the response of Delete.
+type DeleteResponse struct {
+	Deleted bool `protobuf:"varint,1,opt,name=deleted,proto3"`
+}
+
+// ScanRequest is the request of Scan.
+type ScanRequest struct {
+	Prefix string `protobuf:"bytes,1,opt,name=prefix,proto3"`
+}
+
+// Entry is an entry streamed by Scan.
+type Entry struct {
+	Key   string `protobuf:"bytes,1,opt,name=key,proto3"`
+	Value []byte `protobuf:"bytes,2,opt,name=value,proto3"`
+}
+
+// WatchRequest is the request of Watch.
+type WatchRequest struct {
+	Pattern string `protobuf:"bytes,1,opt,name=pattern,proto3"`
+}
+
+// Event is a keyspace event streamed by Watch.
+type Event struct {
+	Type string `protobuf:"bytes,1,opt,name=type,proto3"`
+	Key  string `protobuf:"bytes,2,opt,name=key,proto3"`
+}
+
+func (m *GetRequest) Reset()     { *m = GetRequest{} }
+func (m *GetResponse) Reset()    { *m = GetResponse{} }
+func (m *SetRequest) Reset()     { *m = SetRequest{} }
+func (m *SetResponse) Reset()    { *m = SetResponse{} }
+func (m *DeleteRequest) Reset()  { *m = DeleteRequest{} }
+func (m *DeleteResponse) Reset() { *m = DeleteResponse{} }
+func (m *ScanRequest) Reset()    { *m = ScanRequest{} }
+func (m *Entry) Reset()          { *m = Entry{} }
+func (m *WatchRequest) Reset()   { *m = WatchRequest{} }
+func (m *Event) Reset()          { *m = Event{} }
+
+func (m *GetRequest) String() string     { return fmt.Sprintf("%+v", *m) }
+func (m *GetResponse) String() string    { return fmt.Sprintf("%+v", *m) }
+func (m *SetRequest) String() string     { return fmt.Sprintf("%+v", *m) }
+func (m *SetResponse) String() string    { return fmt.Sprintf("%+v", *m) }
+func (m *DeleteRequest) String() string  { return fmt.Sprintf("%+v", *m) }
+func (m *DeleteResponse) String() string { return fmt.Sprintf("%+v", *m) }
+func (m *ScanRequest) String() string    { return fmt.Sprintf("%+v", *m) }
+func (m *Entry) String() string          { return fmt.Sprintf("%+v", *m) }
+func (m *WatchRequest) String() string   { return fmt.Sprintf("%+v", *m) }
+func (m *Event) String() string          { return fmt.Sprintf("%+v", *m) }
+
+func (*GetRequest) ProtoMessage()     {}
+func (*GetResponse) ProtoMessage()    {}
+func (*SetRequest) ProtoMessage()     {}
+func (*SetResponse) ProtoMessage()    {}
+func (*DeleteRequest) ProtoMessage()  {}
+func (*DeleteResponse) ProtoMessage() {}
+func (*ScanRequest) ProtoMessage()    {}
+func (*Entry) ProtoMessage()          {}
+func (*WatchRequest) ProtoMessage()   {}
+func (*Event) ProtoMessage()          {}
+
+// service is the handler type of the KV service.
+type service interface {
+	Get(context.Context, *GetRequest) (*GetResponse, error)
+	Set(context.Context, *SetRequest) (*SetResponse, error)
+	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
+	Scan(*ScanRequest, gogrpc.ServerStream) error
+	Watch(*WatchRequest, gogrpc.ServerStream) error
+}
+
+// Server implements the KV service on top of a store.
+type Server struct {
+	Store *kvsqlite.SQLite
+}
+
+// NewServer returns a new Server serving the given store.
+func NewServer(store *kvsqlite.SQLite) *Server {
+	return &Server{Store: store}
+}
+
+var _ service = (*Server)(nil)
+
+// Register registers the KV service on the given gRPC server.
+func (s *Server) Register(server *gogrpc.Server) {
+	server.RegisterService(&serviceDesc, s)
+}
+
+// Get returns the JSON encoded value of the key.
+func (s *Server) Get(ctx context.Context, req *GetRequest) (*GetResponse, error) {
+	var value json.RawMessage
+	found, err := s.Store.Lookup(req.Key, &value)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &GetResponse{Value: value, Found: found}, nil
+}
+
+// Set sets the key to the given JSON encoded value.
+func (s *Server) Set(ctx context.Context, req *SetRequest) (*SetResponse, error) {
+	if !json.Valid(req.Value) {
+		return nil, status.Error(codes.InvalidArgument, "value is not valid JSON")
+	}
+
+	var maxAge []time.Duration
+	if req.TTLMs > 0 {
+		maxAge = append(maxAge, time.Duration(req.TTLMs)*time.Millisecond)
+	}
+
+	if err := s.Store.SetContext(ctx, req.Key, json.RawMessage(req.Value), maxAge...); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &SetResponse{}, nil
+}
+
+// Delete deletes the key.
+func (s *Server) Delete(ctx context.Context, req *DeleteRequest) (*DeleteResponse, error) {
+	deleted, err := s.Store.RemoveContext(ctx, req.Key)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &DeleteResponse{Deleted: deleted}, nil
+}
+
+// Scan streams the entries whose key starts with the prefix of the request.
+func (s *Server) Scan(req *ScanRequest, stream gogrpc.ServerStream) error {
+	err := s.Store.ForEachParallelContext(stream.Context(), 1, func(key string, raw []byte) error {
+		if !strings.HasPrefix(key, req.Prefix) {
+			return nil
+		}
+
+		return stream.SendMsg(&Entry{Key: key, Value: raw})
+	})
+
+	return toStatus(err)
+}
+
+// Watch streams the keyspace events of the keys matching the pattern of the request, until the client cancels.
+func (s *Server) Watch(req *WatchRequest, stream gogrpc.ServerStream) error {
+	events, cancel := s.Store.SubscribePattern(req.Pattern)
+	defer cancel()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return status.Error(codes.Unavailable, "store closed")
+			}
+
+			if err := stream.SendMsg(&Event{Type: string(event.Type), Key: event.Key}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// toStatus maps store errors to gRPC status errors.
+func toStatus(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, kvsqlite.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, kvsqlite.ErrQuotaExceeded), errors.Is(err, kvsqlite.ErrTooLarge):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, kvsqlite.ErrReadOnly), errors.Is(err, kvsqlite.ErrFenced):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, kvsqlite.ErrConflict):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, kvsqlite.ErrBusy), errors.Is(err, kvsqlite.ErrClosed):
+		return status.Error(codes.Unavailable, err.Error())
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
+
+var serviceDesc = gogrpc.ServiceDesc{
+	ServiceName: "kvsqlite.v1.KV",
+	HandlerType: (*service)(nil),
+	Methods: []gogrpc.MethodDesc{
+		{MethodName: "Get", Handler: unaryHandler("Get", func(srv service, ctx context.Context, req *GetRequest) (any, error) { return srv.Get(ctx, req) })},
+		{MethodName: "Set", Handler: unaryHandler("Set", func(srv service, ctx context.Context, req *SetRequest) (any, error) { return srv.Set(ctx, req) })},
+		{MethodName: "Delete", Handler: unaryHandler("Delete", func(srv service, ctx context.Context, req *DeleteRequest) (any, error) { return srv.Delete(ctx, req) })},
+	},
+	Streams: []gogrpc.StreamDesc{
+		{StreamName: "Scan", ServerStreams: true, Handler: streamHandler(func(srv service, req *ScanRequest, stream gogrpc.ServerStream) error { return srv.Scan(req, stream) })},
+		{StreamName: "Watch", ServerStreams: true, Handler: streamHandler(func(srv service, req *WatchRequest, stream gogrpc.ServerStream) error { return srv.Watch(req, stream) })},
+	},
+	Metadata: "kv.proto",
+}
+
+func unaryHandler[Req any](method string, call func(srv service, ctx context.Context, req *Req) (any, error)) func(any, context.Context, func(any) error, gogrpc.UnaryServerInterceptor) (any, error) {
+	return func(srv any, ctx context.Context, dec func(any) error, interceptor gogrpc.UnaryServerInterceptor) (any, error) {
+		req := new(Req)
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+
+		if interceptor == nil {
+			return call(srv.(service), ctx, req)
+		}
+
+		info := &gogrpc.UnaryServerInfo{Server: srv, FullMethod: "/kvsqlite.v1.KV/" + method}
+		return interceptor(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+			return call(srv.(service), ctx, req.(*Req))
+		})
+	}
+}
+
+func streamHandler[Req any](call func(srv service, req *Req, stream gogrpc.ServerStream) error) func(any, gogrpc.ServerStream) error {
+	return func(srv any, stream gogrpc.ServerStream) error {
+		req := new(Req)
+		if err := stream.RecvMsg(req); err != nil {
+			return err
+		}
+
+		return call(srv.(service), req, stream)
+	}
+}
+
+// Client is a Go client of the KV service.
+type Client struct {
+	conn *gogrpc.ClientConn
+}
+
+// NewClient returns a new Client on the given connection.
+func NewClient(conn *gogrpc.ClientConn) *Client {
+	return &Client{conn: conn}
+}
+
+// Get returns the JSON encoded value of the key and whether it was found.
+func (c *Client) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	res := &GetResponse{}
+	if err := c.conn.Invoke(ctx, "/kvsqlite.v1.KV/Get", &GetRequest{Key: key}, res); err != nil {
+		return nil, false, err
+	}
+
+	return res.Value, res.Found, nil
+}
+
+// Set sets the key to the given JSON encoded value, ttl 0 keeps the default of the store.
+func (c *Client) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.conn.Invoke(ctx, "/kvsqlite.v1.KV/Set", &SetRequest{Key: key, Value: value, TTLMs: ttl.Milliseconds()}, &SetResponse{})
+}
+
+// Delete deletes the key and reports whether it existed.
+func (c *Client) Delete(ctx context.Context, key string) (bool, error) {
+	res := &DeleteResponse{}
+	if err := c.conn.Invoke(ctx, "/kvsqlite.v1.KV/Delete", &DeleteRequest{Key: key}, res); err != nil {
+		return false, err
+	}
+
+	return res.Deleted, nil
+}
+
+// Scan calls fn for each entry whose key starts with prefix.
+func (c *Client) Scan(ctx context.Context, prefix string, fn func(key string, value []byte)) error {
+	stream, err := c.stream(ctx, "Scan", &ScanRequest{Prefix: prefix})
+	if err != nil {
+		return err
+	}
+
+	for {
+		entry := &Entry{}
+		if err := stream.RecvMsg(entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		fn(entry.Key, entry.Value)
+	}
+}
+
+// Watch calls fn for each keyspace event of the keys matching pattern, until ctx is done.
+func (c *Client) Watch(ctx context.Context, pattern string, fn func(event *Event)) error {
+	stream, err := c.stream(ctx, "Watch", &WatchRequest{Pattern: pattern})
+	if err != nil {
+		return err
+	}
+
+	for {
+		event := &Event{}
+		if err := stream.RecvMsg(event); err != nil {
+			if errors.Is(err, io.EOF) || status.Code(err) == codes.Canceled {
+				return nil
+			}
+			return err
+		}
+
+		fn(event)
+	}
+}
+
+func (c *Client) stream(ctx context.Context, method string, req any) (gogrpc.ClientStream, error) {
+	desc := &gogrpc.StreamDesc{StreamName: method, ServerStreams: true}
+	stream, err := c.conn.NewStream(ctx, desc, "/kvsqlite.v1.KV/"+method)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := stream.SendMsg(req); err != nil {
+		return nil, err
+	}
+
+	return stream, stream.CloseSend()
+}
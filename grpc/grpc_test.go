@@ -0,0 +1,106 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	kvsqlite "github.com/go-zoox/kv-sqlite"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newClient(t *testing.T) (*Client, *kvsqlite.SQLite) {
+	store, err := kvsqlite.New(&kvsqlite.SQLiteConfig{Path: "/tmp/test.db", Prefix: "go-zoox-test-grpc:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	server := gogrpc.NewServer()
+	NewServer(store).Register(server)
+	go server.Serve(listener)
+
+	conn, err := gogrpc.Dial("bufnet",
+		gogrpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		gogrpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop()
+		store.Clear()
+		store.Close()
+	})
+
+	return NewClient(conn), store
+}
+
+func TestServer(t *testing.T) {
+	client, _ := newClient(t)
+	ctx := context.Background()
+
+	if err := client.Set(ctx, "user:1", []byte(`{"name":"zero"}`), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	client.Set(ctx, "user:2", []byte(`2`), 0)
+	client.Set(ctx, "other", []byte(`3`), 0)
+
+	value, found, err := client.Get(ctx, "user:1")
+	if err != nil || !found || string(value) != `{"name":"zero"}` {
+		t.Errorf("Expected the stored value, got %s %v (%v)", value, found, err)
+	}
+
+	if _, found, _ := client.Get(ctx, "missing"); found {
+		t.Error("Expected missing to be not found")
+	}
+
+	keys := make([]string, 0)
+	if err := client.Scan(ctx, "user:", func(key string, value []byte) { keys = append(keys, key) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Errorf("Expected 2 scanned keys, got %v", keys)
+	}
+
+	if deleted, _ := client.Delete(ctx, "user:2"); !deleted {
+		t.Error("Expected user:2 to be deleted")
+	}
+
+	err = client.Set(ctx, "invalid", []byte(`{`), 0)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	client, store := newClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan *Event, 1)
+	go client.Watch(ctx, "watch:*", func(event *Event) { events <- event })
+
+	// the subscription is made once the stream is served
+	deadline := time.After(5 * time.Second)
+	for {
+		store.Set("watch:1", 1)
+		select {
+		case event := <-events:
+			if event.Type != "set" || event.Key != "watch:1" {
+				t.Errorf("Unexpected event %v", event)
+			}
+			return
+		case <-time.After(50 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("Expected an event")
+		}
+	}
+}
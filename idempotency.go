@@ -0,0 +1,212 @@
+package kvsqlite
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// idempotencyPrefix starts the keys of the idempotency records, in the reserved namespace.
+const idempotencyPrefix = ReservedPrefix + "idempotency:"
+
+// ErrInProgress is returned by Idempotency.GetResult when the idempotency key is claimed but not completed yet.
+var ErrInProgress = errors.New("sqlite: idempotency key is in progress")
+
+// ErrCompleted is returned by Idempotency.Complete when the idempotency key is already completed.
+var ErrCompleted = errors.New("sqlite: idempotency key is already completed")
+
+// ErrClaimLost is returned by Idempotency.Complete and Idempotency.Release when the claim of the token
+// expired, possibly taken over by another caller since.
+var ErrClaimLost = errors.New("sqlite: idempotency claim lost")
+
+// idempotencyRecord is the stored state of an idempotency key.
+type idempotencyRecord struct {
+	Token  string          `json:"token"`
+	Done   bool            `json:"done"`
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// Idempotency claims idempotency keys and stores the results of their operations, e.g. for the Idempotency-Key
+// header of requests: only the caller claiming a key runs the operation, the others read its result.
+// Records are kept in the reserved namespace, see ReservedPrefix.
+type Idempotency struct {
+	store *SQLite
+}
+
+// Idempotency returns the idempotency keys of the store.
+func (m *SQLite) Idempotency() *Idempotency {
+	return &Idempotency{store: m}
+}
+
+// Begin claims the idempotency key for ttl and returns the token of the claim, which Complete and Release require,
+// and whether it was claimed. A claim which is not completed within ttl expires, so the operation can be retried
+// if its owner died.
+func (i *Idempotency) Begin(key string, ttl time.Duration) (string, bool, error) {
+	return i.BeginContext(context.Background(), key, ttl)
+}
+
+// BeginContext is like Begin but honors ctx.
+func (i *Idempotency) BeginContext(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	m := i.store
+	if ttl <= 0 {
+		return "", false, errors.New("sqlite: idempotency ttl must be positive")
+	}
+
+	keyX, err := i.key(key, true)
+	if err != nil {
+		return "", false, err
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", false, err
+	}
+	rec := &idempotencyRecord{Token: hex.EncodeToString(token)}
+
+	valueX, err := m.encodeValue(rec)
+	if err != nil {
+		return "", false, err
+	}
+
+	var claimed bool
+	err = m.writeTx(ctx, func(db *tracer) error {
+		r, err := m.readRow(ctx, db, keyX)
+		if err != nil || r != nil {
+			return err
+		}
+
+		claimed = true
+		return m.writeRow(ctx, db, keyX, &row{value: valueX, expiresAt: m.expiresAt(ttl)})
+	})
+	if err != nil || !claimed {
+		return "", false, err
+	}
+
+	return rec.Token, true, nil
+}
+
+// Complete stores the result of the operation of the idempotency key claimed with token, see Begin.
+// The result is kept for ttl if given, or else until the claim would have expired.
+// It returns ErrClaimLost if the claim expired, and ErrCompleted if the key is already completed.
+func (i *Idempotency) Complete(key, token string, result any, ttl ...time.Duration) error {
+	return i.CompleteContext(context.Background(), key, token, result, ttl...)
+}
+
+// CompleteContext is like Complete but honors ctx.
+func (i *Idempotency) CompleteContext(ctx context.Context, key, token string, result any, ttl ...time.Duration) error {
+	m := i.store
+	keyX, err := i.key(key, true)
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	valueX, err := m.encodeValue(&idempotencyRecord{Token: token, Done: true, Result: raw})
+	if err != nil {
+		return err
+	}
+
+	return m.writeTx(ctx, func(db *tracer) error {
+		r, rec, err := i.claim(ctx, db, keyX, token)
+		if err != nil {
+			return err
+		}
+		if rec.Done {
+			return ErrCompleted
+		}
+
+		expiresAt := r.expiresAt
+		if len(ttl) > 0 && ttl[0] > 0 {
+			expiresAt = m.expiresAt(ttl[0])
+		}
+
+		return m.writeRow(ctx, db, keyX, &row{value: valueX, expiresAt: expiresAt})
+	})
+}
+
+// Release gives up the claim of the idempotency key taken with token, e.g. after its operation failed,
+// so it can be retried right away. A completed key is kept. It returns ErrClaimLost if the claim expired.
+func (i *Idempotency) Release(key, token string) error {
+	m := i.store
+	keyX, err := i.key(key, true)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	return m.writeTx(ctx, func(db *tracer) error {
+		_, rec, err := i.claim(ctx, db, keyX, token)
+		if err != nil || rec.Done {
+			return err
+		}
+
+		return m.deleteRow(ctx, db, keyX)
+	})
+}
+
+// GetResult decodes the result stored by Complete for the idempotency key into result.
+// It returns ErrInProgress if the key is claimed but not completed, and ErrNotFound if it is not claimed.
+func (i *Idempotency) GetResult(key string, result any) error {
+	m := i.store
+	keyX, err := i.key(key, false)
+	if err != nil {
+		return err
+	}
+
+	m.RLock()
+	_, rec, err := i.read(context.Background(), m.db(), keyX)
+	m.RUnlock()
+	if err != nil {
+		return err
+	}
+	if !rec.Done {
+		return ErrInProgress
+	}
+
+	return json.Unmarshal(rec.Result, result)
+}
+
+// key returns the stored key of the record of the given idempotency key.
+func (i *Idempotency) key(key string, write bool) (string, error) {
+	if err := i.store.checkAccess(key, write); err != nil {
+		return "", err
+	}
+
+	return i.store.storedKey(idempotencyPrefix + key)
+}
+
+// claim returns the row and the record of the (prefixed) idempotency key if it is claimed with token,
+// or ErrClaimLost.
+func (i *Idempotency) claim(ctx context.Context, db *tracer, keyX, token string) (*row, *idempotencyRecord, error) {
+	r, rec, err := i.read(ctx, db, keyX)
+	if errors.Is(err, ErrNotFound) || (err == nil && rec.Token != token) {
+		return nil, nil, ErrClaimLost
+	}
+
+	return r, rec, err
+}
+
+// read returns the row and the record of the (prefixed) idempotency key, or ErrNotFound.
+func (i *Idempotency) read(ctx context.Context, db *tracer, keyX string) (*row, *idempotencyRecord, error) {
+	r, err := i.store.readRow(ctx, db, keyX)
+	if err != nil {
+		return nil, nil, err
+	}
+	if r == nil {
+		return nil, nil, ErrNotFound
+	}
+
+	rec := &idempotencyRecord{}
+	if err := i.store.decodeValue(keyX, []byte(r.value), rec); err != nil {
+		return nil, nil, err
+	}
+
+	return r, rec, nil
+}
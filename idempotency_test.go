@@ -0,0 +1,86 @@
+package kvsqlite
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestIdempotency(t *testing.T) {
+	// records are reserved keys, which Clear keeps
+	path := "/tmp/test-idempotency.db"
+	os.Remove(path)
+	defer os.Remove(path)
+
+	clock := NewManualClock(time.Now())
+	client, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:", Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	keys := client.Idempotency()
+	token, claimed, err := keys.Begin("payment-1", time.Minute)
+	if err != nil || !claimed || token == "" {
+		t.Fatalf("Expected the key to be claimed, got %v (%v)", claimed, err)
+	}
+
+	if _, claimed, err := keys.Begin("payment-1", time.Minute); err != nil || claimed {
+		t.Fatalf("Expected the key to be claimed once, got %v (%v)", claimed, err)
+	}
+
+	var result map[string]string
+	if err := keys.GetResult("payment-1", &result); !errors.Is(err, ErrInProgress) {
+		t.Errorf("Expected ErrInProgress, got %v", err)
+	}
+
+	if err := keys.Complete("payment-1", "other", nil); !errors.Is(err, ErrClaimLost) {
+		t.Errorf("Expected ErrClaimLost for another token, got %v", err)
+	}
+	if err := keys.Complete("payment-1", token, map[string]string{"status": "paid"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.Complete("payment-1", token, nil); !errors.Is(err, ErrCompleted) {
+		t.Errorf("Expected ErrCompleted, got %v", err)
+	}
+
+	if err := keys.GetResult("payment-1", &result); err != nil || result["status"] != "paid" {
+		t.Errorf("Expected the stored result, got %v (%v)", result, err)
+	}
+
+	// records are kept out of the keys of the store
+	if keys := client.Keys(); len(keys) != 0 {
+		t.Errorf("Expected no keys, got %v", keys)
+	}
+
+	// a released claim can be taken again
+	token, _, _ = keys.Begin("payment-2", time.Minute)
+	if err := keys.Release("payment-2", token); err != nil {
+		t.Fatal(err)
+	}
+	if _, claimed, _ := keys.Begin("payment-2", time.Minute); !claimed {
+		t.Error("Expected a released key to be claimed again")
+	}
+
+	// an expired claim taken over by another caller is lost for its first owner
+	stale, _, _ := keys.Begin("payment-3", time.Minute)
+	clock.Add(2 * time.Minute)
+	current, claimed, _ := keys.Begin("payment-3", time.Minute)
+	if !claimed {
+		t.Fatal("Expected an expired claim to be taken over")
+	}
+	if err := keys.Complete("payment-3", stale, 1); !errors.Is(err, ErrClaimLost) {
+		t.Errorf("Expected ErrClaimLost, got %v", err)
+	}
+	if err := keys.Release("payment-3", stale); !errors.Is(err, ErrClaimLost) {
+		t.Errorf("Expected ErrClaimLost, got %v", err)
+	}
+	if err := keys.Complete("payment-3", current, 1); err != nil {
+		t.Errorf("Expected the current owner to complete, got %v", err)
+	}
+
+	if err := keys.GetResult("missing", &result); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
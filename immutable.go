@@ -0,0 +1,101 @@
+package kvsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync/atomic"
+)
+
+// immutableMessage is the error raised by the immutability triggers.
+const immutableMessage = "key is immutable"
+
+// immutableTriggers returns the statements creating the immutability triggers of the given kv table,
+// in the database qualifier q. Writes which don't change the row, e.g. the same value set again, are allowed.
+func immutableTriggers(q string, table string) []string {
+	return []string{
+		`CREATE TRIGGER IF NOT EXISTS ` + q + quoteIdent(table+"_immutable_update") + ` BEFORE UPDATE OF key, value, expires_at, chunks ON ` + quoteIdent(table) + `
+			WHEN old.immutable = 1 AND new.immutable = 1 AND (new.key IS NOT old.key OR new.value IS NOT old.value OR new.expires_at IS NOT old.expires_at OR new.chunks IS NOT old.chunks) BEGIN
+			SELECT RAISE(ABORT, '` + immutableMessage + `');
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS ` + q + quoteIdent(table+"_immutable_delete") + ` BEFORE DELETE ON ` + quoteIdent(table) + ` WHEN old.immutable = 1 BEGIN
+			SELECT RAISE(ABORT, '` + immutableMessage + `');
+		END`,
+	}
+}
+
+// SetImmutable sets the value of a key which can't be changed nor deleted afterwards, e.g. a content-addressed blob.
+// Later writes and deletes of the key fail with ErrImmutable, including ExpireBatch, Clear and DeleteAll,
+// unless they are forced with WithForce or ForceDelete. Setting the same value again succeeds.
+// Immutable keys never expire.
+func (m *SQLite) SetImmutable(key string, value any) error {
+	_, err := m.set(context.Background(), key, value, WithImmutable())
+	return err
+}
+
+// IsImmutable reports whether the key exists and is immutable.
+func (m *SQLite) IsImmutable(key string) (bool, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	keyX, err := m.getKey(key)
+	if err != nil {
+		return false, err
+	}
+
+	err = m.db().in(m.databaseOf(keyX)).QueryRow("SELECT 1 FROM kv WHERE key = ? AND immutable = 1", keyX).Scan(new(int))
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// ForceDelete deletes the key like Delete, even if it is immutable.
+func (m *SQLite) ForceDelete(key string) error {
+	ctx := context.Background()
+	keyX, err := m.writeKey(key)
+	if err != nil {
+		return err
+	}
+
+	var n int64
+	err = m.writeTx(ctx, func(db *tracer) error {
+		db = db.in(m.databaseOf(keyX))
+		if err := unfreeze(ctx, db, keyX); err != nil {
+			return err
+		}
+
+		res, err := db.ExecContext(ctx, "DELETE FROM kv WHERE key = ?", keyX)
+		if err != nil {
+			return err
+		}
+
+		n, err = res.RowsAffected()
+		if n > 0 {
+			m.keyRemoved(keyX)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	atomic.AddInt64(&m.counters.deletes, 1)
+	if n > 0 {
+		m.publish(EventDelete, key)
+	}
+	return nil
+}
+
+// freeze makes the (prefixed) key immutable, in the transaction of the write.
+func freeze(ctx context.Context, db *tracer, keyX string) error {
+	_, err := db.ExecContext(ctx, "UPDATE kv SET immutable = 1 WHERE key = ?", keyX)
+	return err
+}
+
+// unfreeze makes the (prefixed) key mutable, in the transaction of a forced write.
+func unfreeze(ctx context.Context, db *tracer, keyX string) error {
+	_, err := db.ExecContext(ctx, "UPDATE kv SET immutable = 0 WHERE key = ? AND immutable = 1", keyX)
+	return err
+}
@@ -0,0 +1,71 @@
+package kvsqlite
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSetImmutable(t *testing.T) {
+	client, err := New(&SQLiteConfig{
+		Path:   "/tmp/test.db",
+		Prefix: "go-zoox-test-immutable:" + time.Now().String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if err := client.SetImmutable("blob", "content"); err != nil {
+		t.Fatal(err)
+	}
+	if immutable, err := client.IsImmutable("blob"); err != nil || !immutable {
+		t.Fatalf("Expected blob to be immutable, got %v (%v)", immutable, err)
+	}
+
+	if err := client.SetImmutable("blob", "content"); err != nil {
+		t.Errorf("Expected setting the same value again to succeed, got %v", err)
+	}
+
+	for name, fn := range map[string]func() error{
+		"Set":       func() error { return client.Set("blob", "other") },
+		"Immutable": func() error { return client.SetImmutable("blob", "other") },
+		"Delete":    func() error { return client.Delete("blob") },
+		"Expire":    func() error { _, err := client.ExpireBatch([]string{"blob"}, time.Minute); return err },
+		"Clear":     client.Clear,
+	} {
+		if err := fn(); !errors.Is(err, ErrImmutable) {
+			t.Errorf("Expected %s to fail with ErrImmutable, got %v", name, err)
+		}
+	}
+
+	var value string
+	if client.Get("blob", &value); value != "content" {
+		t.Errorf("Expected content, got %s", value)
+	}
+
+	if _, err := client.SetWith("blob", "forced", WithForce(), WithImmutable()); err != nil {
+		t.Fatal(err)
+	}
+	if client.Get("blob", &value); value != "forced" {
+		t.Errorf("Expected forced, got %s", value)
+	}
+	if err := client.Delete("blob"); !errors.Is(err, ErrImmutable) {
+		t.Errorf("Expected the forced value to stay immutable, got %v", err)
+	}
+
+	if err := client.ForceDelete("blob"); err != nil {
+		t.Fatal(err)
+	}
+	if client.Has("blob") {
+		t.Error("Expected blob to be deleted")
+	}
+
+	client.Set("mutable", "value")
+	if immutable, _ := client.IsImmutable("mutable"); immutable {
+		t.Error("Expected mutable not to be immutable")
+	}
+	if err := client.Clear(); err != nil {
+		t.Errorf("Expected Clear to succeed without immutable keys, got %v", err)
+	}
+}
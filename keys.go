@@ -0,0 +1,77 @@
+package kvsqlite
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// KeySanitizer checks a key given to the store and returns the key to store it under,
+// or an error matching ErrInvalidKey to reject it. It must return the same key for the same input.
+type KeySanitizer func(key string) (string, error)
+
+// KeyRules are the rules of SanitizeKeys.
+type KeyRules struct {
+	// EscapeControlChars escapes control characters as \xNN or \uNNNN instead of rejecting the key.
+	EscapeControlChars bool
+
+	// Delimiter is the delimiter of key segments, e.g. ":". If set, the Delimiters are replaced by it,
+	// runs of it are collapsed and it is trimmed from both ends, so a:b, a/b and :a::b: are the same key.
+	Delimiter string
+
+	// Delimiters are the other delimiters to replace by Delimiter, e.g. "/" and ".".
+	Delimiters []string
+
+	// MaxLength rejects keys longer than MaxLength bytes once sanitized, 0 is no limit.
+	MaxLength int
+}
+
+// SanitizeKeys returns a KeySanitizer applying the given rules.
+// Keys which are not valid UTF-8 or empty once sanitized are always rejected.
+func SanitizeKeys(rules KeyRules) KeySanitizer {
+	return func(key string) (string, error) {
+		if !utf8.ValidString(key) {
+			return "", fmt.Errorf("%w: %q is not valid UTF-8", ErrInvalidKey, key)
+		}
+
+		var b strings.Builder
+		for _, r := range key {
+			if !unicode.IsControl(r) {
+				b.WriteRune(r)
+				continue
+			}
+
+			if !rules.EscapeControlChars {
+				return "", fmt.Errorf("%w: %q contains control characters", ErrInvalidKey, key)
+			}
+			if r < utf8.RuneSelf {
+				fmt.Fprintf(&b, `\x%02x`, r)
+			} else {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			}
+		}
+		sanitized := b.String()
+
+		if d := rules.Delimiter; d != "" {
+			for _, other := range rules.Delimiters {
+				if other != "" {
+					sanitized = strings.ReplaceAll(sanitized, other, d)
+				}
+			}
+			for strings.Contains(sanitized, d+d) {
+				sanitized = strings.ReplaceAll(sanitized, d+d, d)
+			}
+			sanitized = strings.TrimSuffix(strings.TrimPrefix(sanitized, d), d)
+		}
+
+		if sanitized == "" {
+			return "", fmt.Errorf("%w: %q is empty", ErrInvalidKey, key)
+		}
+		if rules.MaxLength > 0 && len(sanitized) > rules.MaxLength {
+			return "", fmt.Errorf("%w: %q is longer than %d bytes", ErrInvalidKey, key, rules.MaxLength)
+		}
+
+		return sanitized, nil
+	}
+}
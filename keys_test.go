@@ -0,0 +1,72 @@
+package kvsqlite
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSanitizeKeys(t *testing.T) {
+	sanitize := SanitizeKeys(KeyRules{Delimiter: ":", Delimiters: []string{"/"}, MaxLength: 16})
+	for key, expected := range map[string]string{
+		"user:1":     "user:1",
+		"user/1":     "user:1",
+		":user::1:":  "user:1",
+		"naïve:café": "naïve:café",
+	} {
+		if sanitized, err := sanitize(key); err != nil || sanitized != expected {
+			t.Errorf("Expected %q to be sanitized to %q, got %q (%v)", key, expected, sanitized, err)
+		}
+	}
+
+	for _, key := range []string{"user\n1", "user:\xff", "::", strings.Repeat("a", 17)} {
+		if _, err := sanitize(key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Expected %q to be rejected, got %v", key, err)
+		}
+	}
+
+	escape := SanitizeKeys(KeyRules{EscapeControlChars: true})
+	if sanitized, err := escape("user\n1\u0085"); err != nil || sanitized != `user\x0a1\u0085` {
+		t.Errorf("Expected control characters to be escaped, got %q (%v)", sanitized, err)
+	}
+}
+
+func TestKeySanitizer(t *testing.T) {
+	client, err := New(&SQLiteConfig{
+		Path:         "/tmp/test.db",
+		Prefix:       "go-zoox-test-keys:" + time.Now().String(),
+		KeySanitizer: SanitizeKeys(KeyRules{Delimiter: ":", Delimiters: []string{"/"}}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if err := client.Set("user/1", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	var value string
+	if err := client.Get("user:1", &value); err != nil || value != "value" {
+		t.Errorf("Expected both delimiters to reach the same key, got %q (%v)", value, err)
+	}
+	if keys := client.Keys(); len(keys) != 1 || keys[0] != "user:1" {
+		t.Errorf("Expected the normalized key, got %v", keys)
+	}
+
+	values, err := client.GetMulti([]string{"user/1"}, map[string]string{})
+	if err != nil || len(values.Found) != 1 || values.Found[0] != "user/1" {
+		t.Errorf("Expected GetMulti to report the given key, got %+v (%v)", values, err)
+	}
+
+	if err := client.Set("user\x001", "value"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey, got %v", err)
+	}
+	if err := client.Get("user\x001", &value); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey, got %v", err)
+	}
+	if err := client.Delete("user\x001"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey, got %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package kvsqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-zoox/kv/typing"
+)
+
+// ContextKV is the go-zoox/kv interface extended with context-aware, error-returning variants.
+type ContextKV interface {
+	typing.KV
+
+	// SetContext sets the value for the given key.
+	SetContext(ctx context.Context, key string, value any, maxAge ...time.Duration) error
+	// GetContext returns the value for the given key.
+	GetContext(ctx context.Context, key string, value any) error
+	// DeleteContext deletes the value for the given key.
+	DeleteContext(ctx context.Context, key string) error
+	// HasContext returns true if the given key exists in the kv.
+	HasContext(ctx context.Context, key string) (bool, error)
+	// KeysContext returns the keys of the kv.
+	KeysContext(ctx context.Context) ([]string, error)
+	// SizeContext returns the number of entries in the kv.
+	SizeContext(ctx context.Context) (int, error)
+	// ClearContext clears the kv.
+	ClearContext(ctx context.Context) error
+	// ForEachContext iterates over the kv and calls the given function for each entry.
+	ForEachContext(ctx context.Context, f func(key string, value any)) error
+}
+
+var (
+	_ typing.KV = (*SQLite)(nil)
+	_ ContextKV = (*SQLite)(nil)
+)
@@ -0,0 +1,58 @@
+package kvsqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestContextKV(t *testing.T) {
+	var client ContextKV = createClient()
+	client.Clear()
+	defer client.Clear()
+
+	ctx := context.Background()
+	if err := client.SetContext(ctx, "key", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := client.HasContext(ctx, "key"); err != nil || !ok {
+		t.Errorf("Expected key to exist, got %v (%v)", ok, err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := client.KeysContext(canceled); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestForEachCancel(t *testing.T) {
+	client := createClient()
+	client.Clear()
+	defer client.Clear()
+
+	for _, key := range []string{"key1", "key2", "key3"} {
+		client.Set(key, "value")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := client.ForEachContext(ctx, func(key string, value any) {
+		calls++
+		cancel()
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Errorf("Expected iteration to stop after 1 call with context.Canceled, got %d calls (%v)", calls, err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	calls = 0
+	err = client.ForEachParallelContext(ctx, 1, func(key string, raw []byte) error {
+		calls++
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || calls > 2 {
+		t.Errorf("Expected parallel iteration to stop promptly with context.Canceled, got %d calls (%v)", calls, err)
+	}
+}
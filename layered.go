@@ -0,0 +1,416 @@
+package kvsqlite
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-zoox/kv/typing"
+)
+
+// Consistency is the write consistency mode of a Layered store.
+type Consistency int
+
+const (
+	// WriteThrough writes to the upstream first and to the local tier only if it succeeded.
+	WriteThrough Consistency = iota
+	// WriteBehind writes to the local tier and propagates to the upstream in the background, in write order.
+	// Upstream errors are reported to LayeredOptions.OnError. See Layered.Flush and Layered.Close.
+	// The upstream receives a copy of the value made at write time, through its JSON encoding.
+	WriteBehind
+)
+
+// LayeredOptions are the options of a Layered store.
+type LayeredOptions struct {
+	// Consistency is the write consistency mode, default is WriteThrough.
+	Consistency Consistency
+
+	// LocalTTL is the TTL of values populated into the local tier on read, default is no expiration.
+	LocalTTL time.Duration
+
+	// OnError is called with upstream errors of background writes.
+	OnError func(op, key string, err error)
+
+	// QueueSize is the number of background writes which may be pending, default is 1024.
+	// Writes block while the queue is full.
+	QueueSize int
+
+	// ReadYourWrites makes Get and Has of WriteBehind serve the keys with queued writes from the queue,
+	// before the local tier, so a caller observes its own writes even if the local tier was changed by others.
+	ReadYourWrites bool
+}
+
+// ErrLayeredClosed is returned by the writes of a closed WriteBehind Layered store.
+var ErrLayeredClosed = errors.New("sqlite: layered store is closed")
+
+// layeredWrite is a write propagated to the upstream in the background.
+type layeredWrite struct {
+	op  string
+	key string
+	fn  func() error
+}
+
+// layeredPending are the background writes of a key which didn't reach the upstream yet.
+type layeredPending struct {
+	writes int
+
+	// deleted is true if the last of them deletes the key, value is the encoded value set otherwise
+	deleted bool
+	value   json.RawMessage
+}
+
+// Layered combines a SQLite store, as durable local tier, with an upstream KV, e.g. Redis.
+// Reads are served locally and fall through to the upstream on miss, populating the local tier.
+// Writes propagate to both according to the consistency mode.
+type Layered struct {
+	Local    *SQLite
+	Upstream typing.KV
+	Options  *LayeredOptions
+
+	// queue holds the background writes of WriteBehind, applied in order by a single worker.
+	queue   chan layeredWrite
+	stopped chan struct{}
+
+	closeMu sync.RWMutex
+	closed  bool
+
+	// pending indexes the queued writes by key, so reads never fall through to an upstream about to change
+	pendingMu sync.Mutex
+	pending   map[string]*layeredPending
+	clears    int
+}
+
+// NewLayered returns a new Layered store.
+func NewLayered(local *SQLite, upstream typing.KV, opts ...*LayeredOptions) *Layered {
+	opt := &LayeredOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		opt = opts[0]
+	}
+
+	l := &Layered{
+		Local:    local,
+		Upstream: upstream,
+		Options:  opt,
+	}
+
+	if opt.Consistency == WriteBehind {
+		size := opt.QueueSize
+		if size <= 0 {
+			size = 1024
+		}
+
+		l.queue = make(chan layeredWrite, size)
+		l.stopped = make(chan struct{})
+		l.pending = make(map[string]*layeredPending)
+		go l.run()
+	}
+
+	return l
+}
+
+func (l *Layered) run() {
+	defer close(l.stopped)
+
+	for w := range l.queue {
+		if err := w.fn(); err != nil && w.op != "" && l.Options.OnError != nil {
+			l.Options.OnError(w.op, w.key, err)
+		}
+
+		l.settle(w)
+	}
+}
+
+// record indexes the write about to be queued, see queued.
+func (l *Layered) record(w layeredWrite, value json.RawMessage) {
+	l.pendingMu.Lock()
+	defer l.pendingMu.Unlock()
+
+	switch w.op {
+	case "clear":
+		l.clears++
+		for _, p := range l.pending {
+			p.deleted, p.value = true, nil
+		}
+	case "set", "delete":
+		p := l.pending[w.key]
+		if p == nil {
+			p = &layeredPending{}
+			l.pending[w.key] = p
+		}
+
+		p.writes++
+		p.deleted, p.value = w.op == "delete", value
+	}
+}
+
+// settle drops the write from the index once it reached the upstream, or wasn't queued.
+func (l *Layered) settle(w layeredWrite) {
+	l.pendingMu.Lock()
+	defer l.pendingMu.Unlock()
+
+	switch w.op {
+	case "clear":
+		l.clears--
+	case "set", "delete":
+		if p := l.pending[w.key]; p != nil {
+			if p.writes--; p.writes == 0 {
+				delete(l.pending, w.key)
+			}
+		}
+	}
+}
+
+// queued reports whether a write which may change the key in the upstream is queued.
+func (l *Layered) queued(key string) bool {
+	if l.queue == nil {
+		return false
+	}
+
+	l.pendingMu.Lock()
+	defer l.pendingMu.Unlock()
+
+	return l.clears > 0 || l.pending[key] != nil
+}
+
+// enqueue queues the background write, or returns ErrLayeredClosed.
+func (l *Layered) enqueue(w layeredWrite) error {
+	l.closeMu.RLock()
+	defer l.closeMu.RUnlock()
+
+	if l.closed {
+		return ErrLayeredClosed
+	}
+
+	l.queue <- w
+	return nil
+}
+
+// checkOpen returns ErrLayeredClosed if the store is closed, so writes don't reach the local tier only.
+func (l *Layered) checkOpen() error {
+	l.closeMu.RLock()
+	defer l.closeMu.RUnlock()
+
+	if l.closed {
+		return ErrLayeredClosed
+	}
+
+	return nil
+}
+
+// Flush waits until the background writes queued so far reached the upstream. It does nothing with WriteThrough.
+func (l *Layered) Flush() error {
+	if l.queue == nil {
+		return nil
+	}
+
+	done := make(chan struct{})
+	if err := l.enqueue(layeredWrite{fn: func() error {
+		close(done)
+		return nil
+	}}); err != nil {
+		return err
+	}
+
+	<-done
+	return nil
+}
+
+// Close stops accepting writes and waits until the queued background writes reached the upstream.
+// It doesn't close the tiers.
+func (l *Layered) Close() error {
+	if l.queue == nil {
+		return nil
+	}
+
+	l.closeMu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.queue)
+	}
+	l.closeMu.Unlock()
+
+	<-l.stopped
+	return nil
+}
+
+var _ typing.KV = (*Layered)(nil)
+
+// propagate runs the upstream write fn, or queues it with WriteBehind. value is the encoded value of a set.
+func (l *Layered) propagate(op, key string, value json.RawMessage, fn func() error) error {
+	if l.queue == nil {
+		return fn()
+	}
+
+	w := layeredWrite{op: op, key: key, fn: fn}
+	l.record(w, value)
+	if err := l.enqueue(w); err != nil {
+		l.settle(w)
+		return err
+	}
+
+	return nil
+}
+
+// copyValue returns a deep copy of value, of the same type, decoded from its JSON encoding raw.
+func copyValue(value any, raw json.RawMessage) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	t := reflect.TypeOf(value)
+	ptr := t.Kind() == reflect.Ptr
+	if ptr {
+		t = t.Elem()
+	}
+
+	c := reflect.New(t)
+	if err := json.Unmarshal(raw, c.Interface()); err != nil {
+		return nil, err
+	}
+
+	if ptr {
+		return c.Interface(), nil
+	}
+	return c.Elem().Interface(), nil
+}
+
+// Set sets the value for the given key in both tiers.
+func (l *Layered) Set(key string, value any, maxAge ...time.Duration) error {
+	if err := l.checkOpen(); err != nil {
+		return err
+	}
+
+	if l.Options.Consistency == WriteBehind {
+		// the caller may modify the value before the upstream write runs
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		copied, err := copyValue(value, raw)
+		if err != nil {
+			return err
+		}
+
+		if err := l.Local.Set(key, value, maxAge...); err != nil {
+			return err
+		}
+
+		return l.propagate("set", key, raw, func() error {
+			return l.Upstream.Set(key, copied, maxAge...)
+		})
+	}
+
+	if err := l.Upstream.Set(key, value, maxAge...); err != nil {
+		return err
+	}
+
+	return l.Local.Set(key, value, maxAge...)
+}
+
+// lookupPending returns the last queued write of the key, deleted if a clear is queued after it,
+// and whether there is one. It always reports none without ReadYourWrites.
+func (l *Layered) lookupPending(key string) (layeredPending, bool) {
+	if l.queue == nil || !l.Options.ReadYourWrites {
+		return layeredPending{}, false
+	}
+
+	l.pendingMu.Lock()
+	defer l.pendingMu.Unlock()
+
+	if p := l.pending[key]; p != nil {
+		return *p, true
+	}
+	if l.clears > 0 {
+		return layeredPending{deleted: true}, true
+	}
+
+	return layeredPending{}, false
+}
+
+// Get returns the value for the given key, from the local tier if present or else from the upstream.
+// With WriteBehind, keys with queued writes are only read from the local tier, or the queue with ReadYourWrites.
+func (l *Layered) Get(key string, value any) error {
+	if p, ok := l.lookupPending(key); ok {
+		if p.deleted {
+			return nil
+		}
+
+		return json.Unmarshal(p.value, value)
+	}
+
+	found, err := l.Local.Lookup(key, value)
+	if err != nil || found || l.queued(key) {
+		return err
+	}
+
+	if !l.Upstream.Has(key) {
+		return nil
+	}
+
+	if err := l.Upstream.Get(key, value); err != nil {
+		return err
+	}
+
+	if l.Options.LocalTTL > 0 {
+		return l.Local.Set(key, value, l.Options.LocalTTL)
+	}
+
+	return l.Local.Set(key, value)
+}
+
+// Delete deletes the value for the given key from both tiers.
+func (l *Layered) Delete(key string) error {
+	if err := l.checkOpen(); err != nil {
+		return err
+	}
+
+	if err := l.Local.Delete(key); err != nil {
+		return err
+	}
+
+	return l.propagate("delete", key, nil, func() error {
+		return l.Upstream.Delete(key)
+	})
+}
+
+// Has returns true if the given key exists in either tier.
+// With WriteBehind, keys with queued writes are only checked in the local tier, or the queue with ReadYourWrites.
+func (l *Layered) Has(key string) bool {
+	if p, ok := l.lookupPending(key); ok {
+		return !p.deleted
+	}
+
+	return l.Local.Has(key) || (!l.queued(key) && l.Upstream.Has(key))
+}
+
+// Keys returns the keys of the upstream, which is the source of truth.
+func (l *Layered) Keys() []string {
+	return l.Upstream.Keys()
+}
+
+// Size returns the number of entries of the upstream, which is the source of truth.
+func (l *Layered) Size() int {
+	return l.Upstream.Size()
+}
+
+// Clear clears both tiers.
+func (l *Layered) Clear() error {
+	if err := l.checkOpen(); err != nil {
+		return err
+	}
+
+	if err := l.Local.Clear(); err != nil {
+		return err
+	}
+
+	return l.propagate("clear", "", nil, func() error {
+		return l.Upstream.Clear()
+	})
+}
+
+// ForEach iterates over the entries of the upstream, which is the source of truth.
+func (l *Layered) ForEach(f func(key string, value any)) {
+	l.Upstream.ForEach(f)
+}
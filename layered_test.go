@@ -0,0 +1,168 @@
+package kvsqlite
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-zoox/kv/memory"
+	"github.com/go-zoox/kv/typing"
+)
+
+func TestLayered(t *testing.T) {
+	local := createClient()
+	local.Clear()
+	defer local.Clear()
+
+	upstream := memory.New()
+	value := "from-upstream"
+	upstream.Set("key", &value)
+
+	layered := NewLayered(local, upstream)
+
+	var got string
+	if err := layered.Get("key", &got); err != nil || got != "from-upstream" {
+		t.Fatalf("Expected upstream value, got %q (%v)", got, err)
+	}
+	if !local.Has("key") {
+		t.Error("Expected local tier to be populated on read")
+	}
+
+	written := "written"
+	if err := layered.Set("key2", &written); err != nil {
+		t.Fatal(err)
+	}
+	if !local.Has("key2") || !upstream.Has("key2") {
+		t.Error("Expected write to propagate to both tiers")
+	}
+
+	if err := layered.Delete("key2"); err != nil {
+		t.Fatal(err)
+	}
+	if local.Has("key2") || upstream.Has("key2") {
+		t.Error("Expected delete to propagate to both tiers")
+	}
+}
+
+// slowKV delays the writes of a KV.
+type slowKV struct {
+	typing.KV
+}
+
+func (kv *slowKV) Set(key string, value any, maxAge ...time.Duration) error {
+	time.Sleep(20 * time.Millisecond)
+	return kv.KV.Set(key, value, maxAge...)
+}
+
+func TestLayeredWriteBehind(t *testing.T) {
+	local := createClient()
+	local.Clear()
+	defer local.Clear()
+
+	upstream := memory.New()
+	layered := NewLayered(local, &slowKV{upstream}, &LayeredOptions{Consistency: WriteBehind})
+
+	// the delete must not overtake the slower set
+	value := "value"
+	layered.Set("key", &value)
+	layered.Delete("key")
+	if err := layered.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if upstream.Has("key") {
+		t.Error("Expected the writes to reach the upstream in order")
+	}
+
+	layered.Set("pending", &value)
+	if err := layered.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !upstream.Has("pending") {
+		t.Error("Expected Close to drain the pending writes")
+	}
+
+	if err := layered.Set("closed", &value); !errors.Is(err, ErrLayeredClosed) {
+		t.Errorf("Expected ErrLayeredClosed, got %v", err)
+	}
+}
+
+func TestLayeredWriteBehindPending(t *testing.T) {
+	local := createClient()
+	local.Clear()
+	defer local.Clear()
+
+	upstream := memory.New()
+	old := "old"
+	upstream.Set("key", &old)
+
+	layered := NewLayered(local, &slowKV{upstream}, &LayeredOptions{Consistency: WriteBehind})
+	defer layered.Close()
+
+	// the slow set keeps the delete queued
+	value := "value"
+	layered.Set("other", &value)
+	layered.Delete("key")
+
+	var got string
+	if layered.Get("key", &got); got != "" || layered.Has("key") {
+		t.Errorf("Expected a deleted key not to be read from the upstream, got %q", got)
+	}
+	if local.Has("key") {
+		t.Error("Expected the local tier not to be populated while a write is queued")
+	}
+
+	if err := layered.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if layered.Get("key", &got); got != "" || upstream.Has("key") {
+		t.Errorf("Expected the tiers to agree once flushed, got %q", got)
+	}
+
+	// the upstream gets the value as it was written
+	m := map[string]int{"a": 1}
+	layered.Set("map", &m)
+	m["a"] = 2
+	if err := layered.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	var stored map[string]int
+	if err := upstream.Get("map", &stored); err != nil || stored["a"] != 1 {
+		t.Errorf("Expected the upstream to get the value at write time, got %v (%v)", stored, err)
+	}
+}
+
+func TestLayeredReadYourWrites(t *testing.T) {
+	local := createClient()
+	local.Clear()
+	defer local.Clear()
+
+	upstream := memory.New()
+	layered := NewLayered(local, &slowKV{upstream}, &LayeredOptions{Consistency: WriteBehind, ReadYourWrites: true})
+	defer layered.Close()
+
+	value := "mine"
+	layered.Set("key", &value)
+	layered.Set("deleted", &value)
+	layered.Delete("deleted")
+
+	// another writer changes the local tier while the writes are queued
+	other := "other"
+	local.Set("key", &other)
+	local.Set("deleted", &other)
+
+	var got string
+	if err := layered.Get("key", &got); err != nil || got != "mine" {
+		t.Errorf("Expected the queued write to be read, got %q (%v)", got, err)
+	}
+	if layered.Has("deleted") {
+		t.Error("Expected the queued delete to be observed")
+	}
+
+	if err := layered.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if layered.Get("key", &got); got != "other" {
+		t.Errorf("Expected the local tier to be read once flushed, got %q", got)
+	}
+}
@@ -0,0 +1,117 @@
+package kvsqlite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Storage layouts.
+const (
+	// LayoutShared stores the keys of all prefixes in the shared kv table, the default.
+	LayoutShared = "shared"
+	// LayoutTablePerPrefix stores the keys of each prefix in a table of its own, created on first use,
+	// so Size counts a whole table and DeleteAll drops and recreates it.
+	LayoutTablePerPrefix = "table-per-prefix"
+)
+
+// prefixTable returns the name of the kv table of the prefix for the given layout, "" for the shared kv table.
+func prefixTable(layout string, prefix string) (string, error) {
+	switch layout {
+	case "", LayoutShared:
+		return "", nil
+	case LayoutTablePerPrefix:
+		return "kvp_" + escapeName(prefix), nil
+	default:
+		return "", fmt.Errorf("sqlite: unknown layout %s", layout)
+	}
+}
+
+// escapeName escapes the given string to lowercase word characters, as _ and the hex code of other bytes,
+// so table names never need quoting in statements, nor collide with each other, as SQLite compares them case-insensitively.
+func escapeName(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "_%02x", c)
+		}
+	}
+
+	return b.String()
+}
+
+// quoteIdent quotes the given SQL identifier.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// tableIdent returns the identifier of the kv table of the store.
+func (m *SQLite) tableIdent() string {
+	if m.table == "" {
+		return "kv"
+	}
+
+	return quoteIdent(m.table)
+}
+
+// createPrefixTable creates the kv table of a prefix with its triggers in the given database, "" for main.
+// The statements run on core directly, since trigger bodies must not be qualified.
+func createPrefixTable(ctx context.Context, core execer, schema string, table string, valueType string) error {
+	q := ""
+	if schema != "" {
+		q = schema + "."
+	}
+
+	statements := []string{
+		"CREATE TABLE IF NOT EXISTS " + q + quoteIdent(table) + " (key TEXT PRIMARY KEY, value " + valueType + ", expires_at INTEGER)",
+		"CREATE INDEX IF NOT EXISTS " + q + quoteIdent(table+"_expires_at") + " ON " + quoteIdent(table) + " (expires_at)",
+	}
+	for _, statement := range statements {
+		if _, err := core.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	if err := migrate(core, schema, table); err != nil {
+		return err
+	}
+
+	triggers := append(chunkTriggers(q, table), tagTriggers(q, table)...)
+	for _, statement := range append(triggers, immutableTriggers(q, table)...) {
+		if _, err := core.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dropPrefixTable empties the kv table of the prefix by dropping and recreating it, in the transaction of db,
+// and returns the number of dropped keys.
+func (m *SQLite) dropPrefixTable(ctx context.Context, db *tracer) (int64, error) {
+	var n, immutable int64
+	if err := db.QueryRowContext(ctx, "SELECT count(*), coalesce(max(immutable), 0) FROM kv").Scan(&n, &immutable); err != nil {
+		return 0, err
+	}
+
+	// dropping the table doesn't fire the immutability triggers either
+	if immutable != 0 {
+		return 0, ErrImmutable
+	}
+
+	// dropping the table doesn't fire the delete triggers
+	for _, table := range []string{"kv_chunks", "kv_tags"} {
+		if _, err := db.ExecContext(ctx, "DELETE FROM "+table+" WHERE key LIKE ?", m.Config.Prefix+"%"); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err := db.ExecContext(ctx, "DROP TABLE kv"); err != nil {
+		return 0, err
+	}
+
+	return n, createPrefixTable(ctx, db.core, db.schema, m.table, m.valueType)
+}
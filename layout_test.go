@@ -0,0 +1,78 @@
+package kvsqlite
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLayoutTablePerPrefix(t *testing.T) {
+	os.Remove("/tmp/test-layout.db")
+	defer os.Remove("/tmp/test-layout.db")
+
+	open := func(prefix string) *SQLite {
+		client, err := New(&SQLiteConfig{
+			Path:        "/tmp/test-layout.db",
+			Prefix:      prefix,
+			Layout:      LayoutTablePerPrefix,
+			LargeValues: &LargeValueConfig{Threshold: 64, ChunkSize: 16},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client
+	}
+
+	users := open("Users:")
+	defer users.Close()
+	others := open("users:")
+	defer others.Close()
+
+	users.Set("1", "alice")
+	users.SetWith("2", strings.Repeat("x", 100), WithTags("big"))
+	users.Set("3", "carol", time.Hour)
+	others.Set("1", "other")
+
+	var value string
+	if err := users.Get("1", &value); err != nil || value != "alice" {
+		t.Errorf("Expected alice, got %q (%v)", value, err)
+	}
+	if err := users.Get("2", &value); err != nil || value != strings.Repeat("x", 100) {
+		t.Errorf("Expected the chunked value, got %q (%v)", value, err)
+	}
+	if users.Size() != 3 || others.Size() != 1 {
+		t.Errorf("Expected 3 and 1 keys, got %d and %d", users.Size(), others.Size())
+	}
+
+	// the prefixes only differ by case, they still get tables of their own
+	var tables, shared int
+	users.Core.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name LIKE 'kvp_%'").Scan(&tables)
+	users.Core.QueryRow("SELECT count(*) FROM kv").Scan(&shared)
+	if tables != 2 || shared != 0 {
+		t.Errorf("Expected 2 prefix tables and an empty kv table, got %d and %d", tables, shared)
+	}
+
+	n, err := users.DeleteAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 || users.Size() != 0 {
+		t.Errorf("Expected 3 deleted keys, got %d (%d left)", n, users.Size())
+	}
+
+	var chunks int
+	users.Core.QueryRow("SELECT count(*) FROM kv_chunks").Scan(&chunks)
+	if chunks != 0 {
+		t.Errorf("Expected the chunks to be deleted with the table, got %d", chunks)
+	}
+	if keys, _ := users.KeysByTag("big"); len(keys) != 0 {
+		t.Errorf("Expected the tags to be deleted with the table, got %v", keys)
+	}
+
+	// the recreated table works as before
+	users.Set("4", "dave")
+	if !users.Has("4") || !others.Has("1") {
+		t.Error("Expected the recreated table to be usable and the other prefix untouched")
+	}
+}
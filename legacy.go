@@ -0,0 +1,51 @@
+package kvsqlite
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+)
+
+// decodeLegacy decodes a value which is not valid JSON, e.g. written by another tool, as raw bytes, see Config.LegacyValues.
+func decodeLegacy(key string, data []byte, value any) error {
+	switch v := value.(type) {
+	case *[]byte:
+		*v = append([]byte(nil), data...)
+	case *string:
+		*v = string(data)
+	case *any:
+		*v = append([]byte(nil), data...)
+	default:
+		return &ValueTypeError{Key: key, Stored: "raw", Target: reflect.TypeOf(value).Elem(), Err: ErrValueTypeMismatch}
+	}
+
+	return nil
+}
+
+// NormalizeLegacy rewrites the values of the keys matching the glob pattern, default is all keys,
+// which were not written by the store into the encoding of the store, and returns how many were rewritten.
+// Values which are not valid JSON, see Config.LegacyValues, are stored as JSON strings, and with Config.Transformers
+// untransformed values are transformed. Values starting with "~" are taken as transformed and left unchanged.
+// It runs like UpdateWhere: in batches, keeping the expiration of the keys and skipping immutable keys.
+func (m *SQLite) NormalizeLegacy(pattern ...string) (int64, error) {
+	return m.NormalizeLegacyContext(context.Background(), pattern...)
+}
+
+// NormalizeLegacyContext is like NormalizeLegacy but honors ctx.
+func (m *SQLite) NormalizeLegacyContext(ctx context.Context, pattern ...string) (int64, error) {
+	opt := &UpdateWhereOptions{Where: "substr(value, 1, 1) <> '" + transformMarker + "'"}
+	if len(pattern) > 0 {
+		opt.Pattern = pattern[0]
+	}
+	if len(m.Config.Transformers) == 0 {
+		opt.Where += " AND NOT json_valid(value)"
+	}
+
+	return m.updateWhere(ctx, opt, func(key string, raw []byte) (any, bool, error) {
+		if json.Valid(raw) {
+			return json.RawMessage(raw), true, nil
+		}
+
+		return string(raw), true, nil
+	}, nil)
+}
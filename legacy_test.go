@@ -0,0 +1,92 @@
+package kvsqlite
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLegacyValues(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	if _, err := client.wdb().Exec("INSERT INTO kv (key, value, expires_at) VALUES (?, ?, 0), (?, ?, 0)",
+		client.Config.Prefix+"raw", "legacy value", client.Config.Prefix+"json", `{"name":"zero"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	var s string
+	if err := client.Get("raw", &s); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("Expected ErrCorrupt without legacy values, got %v", err)
+	}
+
+	client.Config.LegacyValues = true
+	defer func() { client.Config.LegacyValues = false }()
+
+	if err := client.Get("raw", &s); err != nil || s != "legacy value" {
+		t.Fatalf("Expected the raw value, got %q (%v)", s, err)
+	}
+
+	var b []byte
+	if err := client.Get("raw", &b); err != nil || string(b) != "legacy value" {
+		t.Fatalf("Expected the raw bytes, got %q (%v)", b, err)
+	}
+
+	var n int
+	if err := client.Get("raw", &n); !errors.Is(err, ErrValueTypeMismatch) {
+		t.Fatalf("Expected ErrValueTypeMismatch, got %v", err)
+	}
+
+	var v map[string]string
+	if err := client.Get("json", &v); err != nil || v["name"] != "zero" {
+		t.Fatalf("Expected JSON values to decode, got %v (%v)", v, err)
+	}
+
+	count, err := client.NormalizeLegacy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 normalized value, got %d", count)
+	}
+
+	client.Config.LegacyValues = false
+	s = ""
+	if err := client.Get("raw", &s); err != nil || s != "legacy value" {
+		t.Fatalf("Expected a JSON string after normalizing, got %q (%v)", s, err)
+	}
+}
+
+func TestNormalizeLegacyTransformers(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	client.Set("plain", "value")
+
+	client.Config.Transformers = []ValueTransformer{Gzip()}
+	defer func() { client.Config.Transformers = nil }()
+
+	count, err := client.NormalizeLegacy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 normalized value, got %d", count)
+	}
+
+	var stored string
+	if err := client.db().QueryRow("SELECT value FROM kv WHERE key = ?", client.Config.Prefix+"plain").Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored[:len(transformMarker)] != transformMarker {
+		t.Errorf("Expected a transformed value, got %q", stored)
+	}
+
+	var s string
+	if err := client.Get("plain", &s); err != nil || s != "value" {
+		t.Errorf("Expected value, got %q (%v)", s, err)
+	}
+
+	if count, _ := client.NormalizeLegacy(); count != 0 {
+		t.Errorf("Expected nothing left to normalize, got %d", count)
+	}
+}
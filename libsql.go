@@ -0,0 +1,82 @@
+package kvsqlite
+
+import (
+	"database/sql"
+	"net/url"
+	"strings"
+)
+
+// LibSQLOptions are the options of the libSQL dialect.
+type LibSQLOptions struct {
+	// Driver is the name of the database/sql driver of libSQL, default is "libsql",
+	// registered by importing github.com/tursodatabase/libsql-client-go/libsql.
+	Driver string
+
+	// AuthToken is the token authenticating to the database, e.g. of Turso, added to the DSN as authToken.
+	AuthToken string
+}
+
+type libSQLDialect struct {
+	driver    string
+	authToken string
+}
+
+// LibSQL returns the dialect of libSQL, e.g. a hosted Turso database, so the store runs on a remote database
+// with Config.Path set to its URL, e.g. libsql://db-org.turso.io. The driver isn't a dependency of this package:
+// the application imports the libSQL driver it uses.
+// The database is reached through HTTP, so pragmas, attached databases and backups aren't supported,
+// see Dialect.Supports.
+func LibSQL(opts ...*LibSQLOptions) Dialect {
+	opt := &LibSQLOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		opt = opts[0]
+	}
+
+	driver := opt.Driver
+	if driver == "" {
+		driver = "libsql"
+	}
+
+	return &libSQLDialect{driver: driver, authToken: opt.AuthToken}
+}
+
+func (d *libSQLDialect) Name() string {
+	return "libsql"
+}
+
+// Open ignores the parameters of the SQLite driver.
+func (d *libSQLDialect) Open(cfg *SQLiteConfig, params ...string) (*sql.DB, error) {
+	dsn := cfg.Path
+	if d.authToken != "" {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		dsn += separator + "authToken=" + url.QueryEscape(d.authToken)
+	}
+
+	return sql.Open(d.driver, dsn)
+}
+
+// ErrorKind classifies the errors of the server, which only reach the client as messages.
+func (d *libSQLDialect) ErrorKind(err error) error {
+	message := err.Error()
+	switch {
+	case strings.Contains(message, "SQLITE_BUSY") || strings.Contains(message, "database is locked"):
+		return ErrBusy
+	case strings.Contains(message, "SQLITE_READONLY") || strings.Contains(message, "readonly database"):
+		return ErrReadOnly
+	case strings.Contains(message, "SQLITE_TOOBIG") || strings.Contains(message, "too big"):
+		return ErrTooLarge
+	case strings.Contains(message, immutableMessage):
+		return ErrImmutable
+	case strings.Contains(message, "UNIQUE constraint failed"):
+		return ErrConflict
+	}
+
+	return nil
+}
+
+func (d *libSQLDialect) Supports(feature Feature) bool {
+	return false
+}
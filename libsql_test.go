@@ -0,0 +1,73 @@
+package kvsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+func init() {
+	// stands in for the libSQL driver, which speaks the same SQL
+	sql.Register("kvsqlite_libsql_test", &sqlite3.SQLiteDriver{})
+}
+
+func TestLibSQL(t *testing.T) {
+	path := "/tmp/test-libsql.db"
+	os.Remove(path)
+	defer os.Remove(path)
+
+	client, err := New(&SQLiteConfig{
+		Path:    "file:" + path,
+		Prefix:  "go-zoox-test:",
+		Dialect: LibSQL(&LibSQLOptions{Driver: "kvsqlite_libsql_test", AuthToken: "token"}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	client.Set("a", 1)
+	var v int
+	if err := client.Get("a", &v); err != nil || v != 1 {
+		t.Fatalf("Expected 1, got %d (%v)", v, err)
+	}
+
+	if _, err := client.Checkpoint(CheckpointPassive); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+
+	// draining skips the checkpoint
+	if err := client.Drain(context.Background()); err != nil {
+		t.Errorf("Expected Drain to succeed without pragmas, got %v", err)
+	}
+
+	if _, err := New(&SQLiteConfig{Path: "file:" + path, Prefix: "go-zoox-test:", Dialect: LibSQL(), JournalMode: "WAL"}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported for the journal mode, got %v", err)
+	}
+}
+
+func TestLibSQLErrorKind(t *testing.T) {
+	d := LibSQL()
+	cases := []struct {
+		message string
+		kind    error
+	}{
+		{"SQLITE_BUSY: database is locked", ErrBusy},
+		{"SQLITE_CONSTRAINT: UNIQUE constraint failed: kv.key", ErrConflict},
+		{"SQLITE_CONSTRAINT: key is immutable", ErrImmutable},
+		{"attempt to write a readonly database", ErrReadOnly},
+	}
+	for _, c := range cases {
+		if kind := d.ErrorKind(errors.New(c.message)); kind != c.kind {
+			t.Errorf("Expected %q to be %v, got %v", c.message, c.kind, kind)
+		}
+	}
+
+	if kind := d.ErrorKind(errors.New("other")); kind != nil {
+		t.Errorf("Expected no kind, got %v", kind)
+	}
+}
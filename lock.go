@@ -0,0 +1,129 @@
+package kvsqlite
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// ErrLocked is returned by TryAcquireLock when the lock is held by another owner.
+var ErrLocked = errors.New("sqlite: locked by another owner")
+
+// ErrLockLost is returned when an advisory lock expired and was taken by another owner.
+var ErrLockLost = errors.New("sqlite: advisory lock lost")
+
+// lockRetryInterval is how often AcquireLock retries to take a held lock.
+const lockRetryInterval = 50 * time.Millisecond
+
+// AdvisoryLock is a named lock held in the database, so it excludes other handles and processes.
+// It is a lease: if not refreshed within its TTL, e.g. because its process crashed, another owner can take it.
+//
+// Several processes may open the same database file. Each statement is atomic and SQLite serializes
+// writers with its file locks, waiting up to the busy timeout before failing with ErrBusy, so single
+// operations (Set, Delete, Append, SetWith with NX, ...) are safe without coordination.
+// The lock of a store handle only guards the goroutines of one process though, so sequences of
+// operations which must not interleave with other processes (read-modify-write, migrations, jobs
+// which must run once) take an advisory lock, and writers which must not survive a takeover use
+// AcquireEpoch. BloomFilter and MemoryCache assume a single writer and must not be used then,
+// and JournalMode WAL lets readers proceed while another process writes.
+type AdvisoryLock struct {
+	store *SQLite
+	name  string
+	owner string
+	ttl   time.Duration
+}
+
+// TryAcquireLock takes the advisory lock with the given name for ttl, or returns ErrLocked if it is held.
+// Lock names are scoped to the prefix of the store.
+func (m *SQLite) TryAcquireLock(name string, ttl time.Duration) (*AdvisoryLock, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+
+	l := &AdvisoryLock{
+		store: m,
+		name:  m.Config.Prefix + name,
+		owner: hex.EncodeToString(token),
+		ttl:   ttl,
+	}
+
+	ctx := context.Background()
+	var n int64
+	err := m.write(ctx, func(db *tracer) error {
+		ts := m.now()
+		res, err := db.ExecContext(ctx,
+			"INSERT INTO kv_locks (name, owner, expires_at) VALUES (?, ?, ?) ON CONFLICT(name) DO UPDATE SET owner = excluded.owner, expires_at = excluded.expires_at WHERE kv_locks.expires_at < ?",
+			l.name, l.owner, ts+ttl.Milliseconds(), ts,
+		)
+		if err != nil {
+			return err
+		}
+
+		n, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if n == 0 {
+		return nil, ErrLocked
+	}
+
+	return l, nil
+}
+
+// AcquireLock is like TryAcquireLock but waits until the lock is free or ctx is done.
+func (m *SQLite) AcquireLock(ctx context.Context, name string, ttl time.Duration) (*AdvisoryLock, error) {
+	ticker := time.NewTicker(lockRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		l, err := m.TryAcquireLock(name, ttl)
+		if !errors.Is(err, ErrLocked) {
+			return l, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh extends the lease of the lock by its TTL, or returns ErrLockLost if another owner took it.
+func (l *AdvisoryLock) Refresh() error {
+	return l.exec("UPDATE kv_locks SET expires_at = ? WHERE name = ? AND owner = ?", l.store.now()+l.ttl.Milliseconds(), l.name, l.owner)
+}
+
+// Unlock releases the lock, or returns ErrLockLost if another owner took it in the meantime.
+func (l *AdvisoryLock) Unlock() error {
+	return l.exec("DELETE FROM kv_locks WHERE name = ? AND owner = ?", l.name, l.owner)
+}
+
+func (l *AdvisoryLock) exec(query string, args ...any) error {
+	ctx := context.Background()
+	var n int64
+	err := l.store.write(ctx, func(db *tracer) error {
+		res, err := db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		n, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrLockLost
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package kvsqlite
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestAdvisoryLock(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	a, err := New(&SQLiteConfig{Path: "/tmp/test.db", Prefix: "go-zoox-test-lock:", Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := New(&SQLiteConfig{Path: "/tmp/test.db", Prefix: "go-zoox-test-lock:", Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	lock, err := a.TryAcquireLock("job", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := b.TryAcquireLock("job", time.Minute); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := b.AcquireLock(ctx, "job", time.Minute); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected AcquireLock to wait until ctx is done, got %v", err)
+	}
+
+	if err := lock.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the lease of a lock which is not refreshed runs out
+	if _, err := b.TryAcquireLock("job", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	clock.Add(2 * time.Minute)
+	lock, err = a.TryAcquireLock("job", time.Minute)
+	if err != nil {
+		t.Fatalf("Expected the expired lock to be taken over, got %v", err)
+	}
+	defer lock.Unlock()
+}
+
+// TestAdvisoryLockProcess is run by TestAdvisoryLockProcesses in child processes.
+func TestAdvisoryLockProcess(t *testing.T) {
+	if os.Getenv("KVSQLITE_LOCK_PROCESS") == "" {
+		t.Skip("only run as a child process")
+	}
+
+	client, err := New(&SQLiteConfig{Path: "/tmp/test-lock.db", Prefix: "go-zoox-test-lock:", JournalMode: "WAL"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// increments which would lose updates if they interleaved
+	for i := 0; i < 20; i++ {
+		lock, err := client.AcquireLock(context.Background(), "counter", 10*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var n int
+		client.Get("counter", &n)
+		if err := client.Set("counter", n+1); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := lock.Unlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAdvisoryLockProcesses(t *testing.T) {
+	os.Remove("/tmp/test-lock.db")
+	defer os.Remove("/tmp/test-lock.db")
+
+	client, err := New(&SQLiteConfig{Path: "/tmp/test-lock.db", Prefix: "go-zoox-test-lock:", JournalMode: "WAL"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	processes := make([]*exec.Cmd, 4)
+	for i := range processes {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestAdvisoryLockProcess$")
+		cmd.Env = append(os.Environ(), "KVSQLITE_LOCK_PROCESS=1")
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		processes[i] = cmd
+	}
+
+	for i, cmd := range processes {
+		if err := cmd.Wait(); err != nil {
+			t.Fatalf("process %d: %v", i, err)
+		}
+	}
+
+	var n int
+	if err := client.Get("counter", &n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 80 {
+		t.Errorf("Expected 80 increments, got %d", n)
+	}
+}
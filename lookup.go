@@ -0,0 +1,35 @@
+package kvsqlite
+
+import (
+	"bytes"
+	"context"
+)
+
+var nullValue = []byte("null")
+
+// Lookup is like Get but also reports whether the key exists,
+// so a key storing nil can be told apart from a missing one.
+func (m *SQLite) Lookup(key string, value any) (bool, error) {
+	data, found, err := m.read(context.Background(), key)
+	if err != nil || !found {
+		return false, err
+	}
+
+	return m.decodeRead(context.Background(), key, data, value)
+}
+
+// IsNil reports whether the key exists and its stored value is nil.
+func (m *SQLite) IsNil(key string) (bool, error) {
+	data, found, err := m.read(context.Background(), key)
+	if err != nil || !found {
+		return false, err
+	}
+
+	// transformed values are compared once restored
+	data, err = m.restore(data)
+	if err != nil {
+		return false, &CorruptValueError{Key: key, Err: err}
+	}
+
+	return bytes.Equal(bytes.TrimSpace(data), nullValue), nil
+}
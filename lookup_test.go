@@ -0,0 +1,56 @@
+package kvsqlite
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLookupNil(t *testing.T) {
+	client := createClient()
+	client.Clear()
+	defer client.Clear()
+
+	if err := client.Set("nil", nil); err != nil {
+		t.Fatal(err)
+	}
+	client.Set("empty", "")
+
+	value := &struct{}{}
+	if found, err := client.Lookup("nil", &value); err != nil || !found || value != nil {
+		t.Errorf("Expected nil value to be found, got %v %v (%v)", found, value, err)
+	}
+	if found, err := client.Lookup("missing", &value); err != nil || found {
+		t.Errorf("Expected missing key not to be found, got %v (%v)", found, err)
+	}
+
+	if isNil, _ := client.IsNil("nil"); !isNil {
+		t.Error("Expected nil to be nil")
+	}
+	if isNil, _ := client.IsNil("empty"); isNil {
+		t.Error("Expected empty string not to be nil")
+	}
+	if isNil, _ := client.IsNil("missing"); isNil {
+		t.Error("Expected missing key not to be nil")
+	}
+}
+
+func TestIsNilTransformed(t *testing.T) {
+	path := "/tmp/test-lookup-transformed.db"
+	os.Remove(path)
+	defer os.Remove(path)
+
+	client, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:", Transformers: []ValueTransformer{Gzip()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	client.Set("nil", nil)
+	client.Set("value", "x")
+	if isNil, err := client.IsNil("nil"); err != nil || !isNil {
+		t.Errorf("Expected a transformed nil to be nil, got %v (%v)", isNil, err)
+	}
+	if isNil, _ := client.IsNil("value"); isNil {
+		t.Error("Expected a transformed value not to be nil")
+	}
+}
@@ -0,0 +1,215 @@
+package kvsqlite
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+)
+
+// MemoryCacheConfig is the configuration of the in-memory tier.
+type MemoryCacheConfig struct {
+	// MaxBytes is the byte budget of cached values, default is 64 MiB.
+	MaxBytes int64
+
+	// PreloadConcurrency is the number of patterns Preload reads concurrently, default is 4.
+	PreloadConcurrency int
+}
+
+type cacheEntry struct {
+	key       string
+	value     []byte
+	expiresAt int64
+}
+
+// memoryCache is a LRU cache of stored values bounded by bytes.
+type memoryCache struct {
+	sync.Mutex
+	maxBytes int64
+	size     int64
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+func newMemoryCache(cfg *MemoryCacheConfig) *memoryCache {
+	maxBytes := cfg.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = 64 << 20
+	}
+
+	return &memoryCache{
+		maxBytes: maxBytes,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func entrySize(key string, value []byte) int64 {
+	return int64(len(key) + len(value))
+}
+
+func (c *memoryCache) get(key string, now int64) ([]byte, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := el.Value.(*cacheEntry)
+	if entry.expiresAt > 0 && entry.expiresAt < now {
+		c.removeElement(el)
+		return nil, false
+	}
+
+	c.ll.MoveToFront(el)
+	return entry.value, true
+}
+
+// put caches the value, evicting the least recently used entries to stay within budget.
+func (c *memoryCache) put(key string, value []byte, expiresAt int64) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.set(key, value, expiresAt)
+	for c.size > c.maxBytes && c.ll.Len() > 0 {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+// fill caches the value only if it fits in the remaining budget, and reports whether it did.
+func (c *memoryCache) fill(key string, value []byte, expiresAt int64) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.size+entrySize(key, value) > c.maxBytes {
+		return false
+	}
+
+	c.set(key, value, expiresAt)
+	return true
+}
+
+func (c *memoryCache) set(key string, value []byte, expiresAt int64) {
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+
+	c.items[key] = c.ll.PushFront(&cacheEntry{key, value, expiresAt})
+	c.size += entrySize(key, value)
+}
+
+func (c *memoryCache) remove(key string) {
+	c.Lock()
+	defer c.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+func (c *memoryCache) removeElement(el *list.Element) {
+	entry := c.ll.Remove(el).(*cacheEntry)
+	delete(c.items, entry.key)
+	c.size -= entrySize(entry.key, entry.value)
+}
+
+func (c *memoryCache) reset() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+	c.size = 0
+}
+
+func (m *SQLite) cacheGet(keyX string) ([]byte, bool) {
+	if m.cache == nil {
+		return nil, false
+	}
+
+	return m.cache.get(keyX, m.expiryCutoff())
+}
+
+// globEscape escapes the GLOB special characters of s.
+func globEscape(s string) string {
+	r := strings.NewReplacer("*", "[*]", "?", "[?]", "[", "[[]")
+	return r.Replace(s)
+}
+
+// Preload reads the keys matching the glob patterns, e.g. "user:*", into the in-memory tier,
+// until its byte budget is full, and returns the number of preloaded keys.
+// Keys with a sliding TTL are not preloaded, their reads must reach the database. It requires Config.MemoryCache.
+func (m *SQLite) Preload(patterns ...string) (int, error) {
+	if m.cache == nil {
+		return 0, nil
+	}
+
+	concurrency := m.Config.MemoryCache.PreloadConcurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var total int
+	var firstErr error
+
+	sem := make(chan struct{}, concurrency)
+	wg := &sync.WaitGroup{}
+	for _, pattern := range patterns {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(pattern string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			n, err := m.preload(ctx, pattern)
+
+			mu.Lock()
+			defer mu.Unlock()
+			total += n
+			if err != nil && firstErr == nil {
+				firstErr = err
+				cancel()
+			}
+		}(pattern)
+	}
+	wg.Wait()
+
+	return total, firstErr
+}
+
+func (m *SQLite) preload(ctx context.Context, pattern string) (int, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	rows, err := m.db().QueryContext(ctx,
+		"SELECT key, "+valueExpr+", expires_at FROM kv WHERE key GLOB ? AND sliding_ttl = 0 AND "+unexpired,
+		globEscape(m.Config.Prefix)+pattern, m.expiryCutoff(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		var key, value string
+		var expiresAt int64
+		if err := rows.Scan(&key, &value, &expiresAt); err != nil {
+			return n, err
+		}
+
+		if !m.cache.fill(key, []byte(value), expiresAt) {
+			// budget is full
+			break
+		}
+		n++
+	}
+
+	return n, rows.Err()
+}
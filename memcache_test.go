@@ -0,0 +1,47 @@
+package kvsqlite
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMemoryCache(t *testing.T) {
+	seed := createClient()
+	seed.Clear()
+	defer seed.Clear()
+
+	seed.Set("user:1", "alice")
+	seed.Set("user:2", "bob")
+	seed.Set("page:1", strings.Repeat("x", 100))
+
+	client, err := New(&SQLiteConfig{
+		Path:        "/tmp/test.db",
+		Prefix:      "go-zoox-test:",
+		MemoryCache: &MemoryCacheConfig{MaxBytes: 1024},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	n, err := client.Preload("user:*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 preloaded keys, got %d", n)
+	}
+
+	// served from memory even though the row changed behind our back
+	seed.Set("user:1", "changed")
+	var value string
+	if err := client.Get("user:1", &value); err != nil || value != "alice" {
+		t.Errorf("Expected preloaded value, got %q (%v)", value, err)
+	}
+
+	// writes through this handle invalidate the entry
+	client.Set("user:1", "carol")
+	if err := client.Get("user:1", &value); err != nil || value != "carol" {
+		t.Errorf("Expected written value, got %q (%v)", value, err)
+	}
+}
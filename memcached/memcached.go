@@ -0,0 +1,370 @@
+// Package memcached serves a SQLite store over the memcached text protocol (get, gets, set, delete and touch),
+// so legacy clients and runtimes with memcached support can use the store directly.
+package memcached
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	kvsqlite "github.com/go-zoox/kv-sqlite"
+)
+
+// maxKeyLength is the maximum length of memcached keys.
+const maxKeyLength = 250
+
+// relativeExptimeLimit is the exptime above which it is a unix timestamp instead of seconds from now.
+const relativeExptimeLimit = 60 * 60 * 24 * 30
+
+// casUnique derives the cas unique of gets from the stored value, so it changes whenever the value does.
+func casUnique(raw json.RawMessage) uint64 {
+	h := fnv.New64a()
+	h.Write(raw)
+	return h.Sum64()
+}
+
+// item is how memcached values are stored, so they stay JSON for the other users of the store.
+type item struct {
+	Flags uint32 `json:"flags"`
+	Data  []byte `json:"data"`
+}
+
+// Server serves a store over the memcached text protocol.
+type Server struct {
+	Store *kvsqlite.SQLite
+
+	// MaxValueSize is the maximum size of a value, default is 1 MiB like memcached.
+	MaxValueSize int
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+}
+
+// NewServer returns a new Server serving the given store.
+func NewServer(store *kvsqlite.SQLite) *Server {
+	return &Server{
+		Store:     store,
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+	}
+}
+
+// ErrServerClosed is returned by Serve after Close.
+var ErrServerClosed = errors.New("memcached: server closed")
+
+// ListenAndServe listens on the TCP address and serves connections until Close.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(l)
+}
+
+// Serve serves the connections accepted on l until Close.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			delete(s.listeners, l)
+			s.mu.Unlock()
+
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		go s.serveConn(conn)
+	}
+}
+
+// Close closes the listeners and the open connections.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+
+	return nil
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() {
+		conn.Close()
+
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			fmt.Fprint(w, "ERROR\r\n")
+		} else if fields[0] == "quit" {
+			w.Flush()
+			return
+		} else if err := s.handle(fields, r, w); err != nil {
+			// the connection can't be read further, e.g. a broken data block
+			w.Flush()
+			return
+		}
+
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// handle runs a command, the error is only returned if the connection must be closed.
+func (s *Server) handle(fields []string, r *bufio.Reader, w *bufio.Writer) error {
+	switch fields[0] {
+	case "get":
+		s.get(fields[1:], false, w)
+	case "gets":
+		s.get(fields[1:], true, w)
+	case "set":
+		return s.set(fields[1:], r, w)
+	case "delete":
+		s.delete(fields[1:], w)
+	case "touch":
+		s.touch(fields[1:], w)
+	case "version":
+		fmt.Fprintf(w, "VERSION %s\r\n", kvsqlite.Version)
+	default:
+		fmt.Fprint(w, "ERROR\r\n")
+	}
+
+	return nil
+}
+
+// get answers get, and gets if withCAS is set, which adds the cas unique of each value.
+func (s *Server) get(keys []string, withCAS bool, w *bufio.Writer) {
+	if len(keys) == 0 {
+		fmt.Fprint(w, "ERROR\r\n")
+		return
+	}
+
+	for _, key := range keys {
+		var raw json.RawMessage
+		found, err := s.Store.Lookup(key, &raw)
+		if err != nil {
+			fmt.Fprintf(w, "SERVER_ERROR %s\r\n", err)
+			return
+		}
+		if !found {
+			continue
+		}
+
+		// values written by other users of the store are served as their JSON encoding
+		it := item{Data: raw}
+		var stored item
+		if err := json.Unmarshal(raw, &stored); err == nil && stored.Data != nil {
+			it = stored
+		}
+
+		if withCAS {
+			fmt.Fprintf(w, "VALUE %s %d %d %d\r\n", key, it.Flags, len(it.Data), casUnique(raw))
+		} else {
+			fmt.Fprintf(w, "VALUE %s %d %d\r\n", key, it.Flags, len(it.Data))
+		}
+		w.Write(it.Data)
+		fmt.Fprint(w, "\r\n")
+	}
+
+	fmt.Fprint(w, "END\r\n")
+}
+
+// set handles set <key> <flags> <exptime> <bytes> [noreply].
+func (s *Server) set(args []string, r *bufio.Reader, w *bufio.Writer) error {
+	if len(args) < 4 {
+		fmt.Fprint(w, "ERROR\r\n")
+		return nil
+	}
+
+	key := args[0]
+	flags, errFlags := strconv.ParseUint(args[1], 10, 32)
+	exptime, errExptime := strconv.ParseInt(args[2], 10, 64)
+	size, errSize := strconv.Atoi(args[3])
+	noreply := len(args) > 4 && args[4] == "noreply"
+	if errFlags != nil || errExptime != nil || errSize != nil || size < 0 {
+		fmt.Fprint(w, "CLIENT_ERROR bad command line format\r\n")
+		return nil
+	}
+
+	maxSize := s.MaxValueSize
+	if maxSize <= 0 {
+		maxSize = 1 << 20
+	}
+	if size > maxSize {
+		fmt.Fprint(w, "SERVER_ERROR object too large for cache\r\n")
+		// the data block is skipped, so the connection stays usable
+		_, err := io.CopyN(io.Discard, r, int64(size)+2)
+		return err
+	}
+
+	data := make([]byte, size+2)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return err
+	}
+	if string(data[size:]) != "\r\n" {
+		fmt.Fprint(w, "CLIENT_ERROR bad data chunk\r\n")
+		return errors.New("memcached: bad data chunk")
+	}
+
+	if !validKey(key) {
+		fmt.Fprint(w, "CLIENT_ERROR bad key\r\n")
+		return nil
+	}
+
+	ttl, expired := s.ttl(exptime)
+	var err error
+	if expired {
+		_, err = s.Store.Remove(key)
+	} else if ttl > 0 {
+		err = s.Store.Set(key, item{Flags: uint32(flags), Data: data[:size]}, ttl)
+	} else {
+		err = s.Store.Set(key, item{Flags: uint32(flags), Data: data[:size]})
+		if err == nil {
+			// memcached exptime 0 never expires, whatever the value had before
+			_, err = s.Store.PersistBatch([]string{key})
+		}
+	}
+
+	s.reply(w, noreply, err, "STORED")
+	return nil
+}
+
+// delete handles delete <key> [noreply].
+func (s *Server) delete(args []string, w *bufio.Writer) {
+	if len(args) < 1 {
+		fmt.Fprint(w, "ERROR\r\n")
+		return
+	}
+
+	deleted, err := s.Store.Remove(args[0])
+	reply := "DELETED"
+	if err == nil && !deleted {
+		reply = "NOT_FOUND"
+	}
+
+	s.reply(w, len(args) > 1 && args[1] == "noreply", err, reply)
+}
+
+// touch handles touch <key> <exptime> [noreply].
+func (s *Server) touch(args []string, w *bufio.Writer) {
+	if len(args) < 2 {
+		fmt.Fprint(w, "ERROR\r\n")
+		return
+	}
+
+	exptime, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		fmt.Fprint(w, "CLIENT_ERROR bad command line format\r\n")
+		return
+	}
+
+	key := args[0]
+	var n int64
+	ttl, expired := s.ttl(exptime)
+	if expired {
+		var removed bool
+		removed, err = s.Store.Remove(key)
+		if removed {
+			n = 1
+		}
+	} else if ttl > 0 {
+		n, err = s.Store.ExpireBatch([]string{key}, ttl)
+	} else {
+		n, err = s.Store.PersistBatch([]string{key})
+	}
+
+	reply := "TOUCHED"
+	if err == nil && n == 0 {
+		reply = "NOT_FOUND"
+	}
+
+	s.reply(w, len(args) > 2 && args[2] == "noreply", err, reply)
+}
+
+func (s *Server) reply(w *bufio.Writer, noreply bool, err error, reply string) {
+	if noreply {
+		return
+	}
+
+	if err != nil {
+		fmt.Fprintf(w, "SERVER_ERROR %s\r\n", err)
+		return
+	}
+
+	fmt.Fprintf(w, "%s\r\n", reply)
+}
+
+// ttl converts a memcached exptime to a TTL, 0 being no expiration, and reports whether it is already expired.
+func (s *Server) ttl(exptime int64) (time.Duration, bool) {
+	switch {
+	case exptime < 0:
+		return 0, true
+	case exptime == 0:
+		return 0, false
+	case exptime <= relativeExptimeLimit:
+		return time.Duration(exptime) * time.Second, false
+	}
+
+	ttl := time.Until(time.Unix(exptime, 0))
+	return ttl, ttl <= 0
+}
+
+func validKey(key string) bool {
+	if len(key) == 0 || len(key) > maxKeyLength {
+		return false
+	}
+
+	for _, c := range []byte(key) {
+		if c <= ' ' || c == 0x7f {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,75 @@
+package memcached
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	kvsqlite "github.com/go-zoox/kv-sqlite"
+)
+
+func TestServer(t *testing.T) {
+	store, err := kvsqlite.New(&kvsqlite.SQLiteConfig{Path: "/tmp/test.db", Prefix: "go-zoox-test-memcached:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	defer store.Clear()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(store)
+	go server.Serve(l)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	expect := func(command string, lines ...string) {
+		t.Helper()
+		fmt.Fprint(conn, command)
+		for _, line := range lines {
+			got, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.TrimRight(got, "\r\n") != line {
+				t.Errorf("%q: expected %q, got %q", command, line, got)
+			}
+		}
+	}
+
+	expect("set key 5 0 5\r\nhello\r\n", "STORED")
+	expect("get key missing\r\n", "VALUE key 5 5", "hello", "END")
+	expect("touch key 100\r\n", "TOUCHED")
+	expect("touch missing 100\r\n", "NOT_FOUND")
+	expect("delete key\r\n", "DELETED")
+	expect("delete key\r\n", "NOT_FOUND")
+	expect("set key 0 0 2 noreply\r\nhi\r\nget key\r\n", "VALUE key 0 2", "hi", "END")
+	expect("set key 0 -1 2\r\nhi\r\n", "STORED")
+	expect("get key\r\n", "END")
+	expect("unknown\r\n", "ERROR")
+
+	// gets adds a cas unique, which changes with the value
+	expect("set cas 0 0 1\r\na\r\n", "STORED")
+	var raw json.RawMessage
+	store.Get("cas", &raw)
+	expect("gets cas\r\n", fmt.Sprintf("VALUE cas 0 1 %d", casUnique(raw)), "a", "END")
+	expect("set cas 0 0 1\r\nb\r\n", "STORED")
+	store.Get("cas", &raw)
+	expect("gets cas\r\n", fmt.Sprintf("VALUE cas 0 1 %d", casUnique(raw)), "b", "END")
+
+	// values written by other users of the store are served as JSON
+	store.Set("json", map[string]int{"a": 1})
+	expect("get json\r\n", "VALUE json 0 7", `{"a":1}`, "END")
+}
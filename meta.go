@@ -0,0 +1,207 @@
+package kvsqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SchemaVersion is the version of the database schema written by this package.
+const SchemaVersion = 1
+
+// ErrIncompatible is returned by New when the database was created with an incompatible configuration.
+var ErrIncompatible = errors.New("sqlite: incompatible store configuration")
+
+// Meta setting names. The codec and encryption are recorded per prefix, followed by ":" and the prefix,
+// as the transformers are configured per handle, see Meta.
+const (
+	MetaSchemaVersion = "schema_version"
+	MetaCodec         = "codec"
+	MetaEncryption    = "encryption"
+	MetaValueColumn   = "value_column"
+	MetaDefaultTTL    = "default_ttl"
+)
+
+type storeMeta struct {
+	defaultTTL time.Duration
+}
+
+// openMeta creates the meta table, records the settings of a new database
+// and validates the configuration against the settings of an existing one.
+// The codec and encryption record the transformers which wrote the values of the prefix,
+// so a store of the prefix missing one of them fails to open.
+func openMeta(core *sql.DB, cfg *SQLiteConfig, valueType string) (*storeMeta, error) {
+	if !cfg.ReadOnly {
+		if _, err := core.Exec("CREATE TABLE IF NOT EXISTS kv_meta (name TEXT PRIMARY KEY, value TEXT NOT NULL)"); err != nil {
+			return nil, err
+		}
+	}
+
+	stored, err := readMeta(core)
+	if err != nil {
+		return nil, err
+	}
+
+	codec, encryption := transformerMeta(cfg.Transformers)
+	expected := map[string]string{
+		MetaSchemaVersion:                      strconv.Itoa(SchemaVersion),
+		prefixMeta(MetaCodec, cfg.Prefix):      codec,
+		prefixMeta(MetaEncryption, cfg.Prefix): encryption,
+		MetaValueColumn:                        valueType,
+	}
+	if cfg.DefaultTTL > 0 {
+		expected[MetaDefaultTTL] = strconv.FormatInt(cfg.DefaultTTL.Milliseconds(), 10)
+	}
+
+	if version, ok := stored[MetaSchemaVersion]; ok {
+		v, err := strconv.Atoi(version)
+		if err != nil || v > SchemaVersion {
+			return nil, fmt.Errorf("%w: schema version %s is newer than supported %d", ErrIncompatible, version, SchemaVersion)
+		}
+	}
+
+	// values of the database written by a transformer which is not configured can't be read,
+	// configuring more transformers is fine, the values written before stay readable
+	for _, setting := range []string{MetaCodec, MetaEncryption} {
+		name := prefixMeta(setting, cfg.Prefix)
+		value, ok := stored[name]
+		if !ok {
+			continue
+		}
+
+		merged, missing := mergeTransformerMeta(value, expected[name])
+		if missing {
+			return nil, fmt.Errorf("%w: %s of prefix %q is %s, configured %s", ErrIncompatible, setting, cfg.Prefix, value, expected[name])
+		}
+		expected[name] = merged
+	}
+
+	if !cfg.ReadOnly {
+		for name, value := range expected {
+			if _, err := core.Exec("INSERT INTO kv_meta (name, value) VALUES (?, ?) ON CONFLICT(name) DO UPDATE SET value = excluded.value", name, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	meta := &storeMeta{defaultTTL: cfg.DefaultTTL}
+	if meta.defaultTTL == 0 {
+		if ms, ok := stored[MetaDefaultTTL]; ok {
+			n, err := strconv.ParseInt(ms, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("sqlite: invalid default TTL %s in meta table", ms)
+			}
+			meta.defaultTTL = time.Duration(n) * time.Millisecond
+		}
+	}
+
+	return meta, nil
+}
+
+// transformerMeta returns the codec and encryption settings of the given transformer chain, e.g. "json+gzip" and "aes-gcm".
+// Encrypting transformers are recorded as encryption, the others as part of the codec, in chain order.
+func transformerMeta(transformers []ValueTransformer) (string, string) {
+	codec := []string{"json"}
+	encryption := make([]string, 0)
+	for _, t := range transformers {
+		if _, ok := t.(encrypter); ok {
+			encryption = append(encryption, t.Name())
+		} else {
+			codec = append(codec, t.Name())
+		}
+	}
+
+	if len(encryption) == 0 {
+		return strings.Join(codec, "+"), "none"
+	}
+
+	return strings.Join(codec, "+"), strings.Join(encryption, ",")
+}
+
+// mergeTransformerMeta merges the configured codec or encryption setting into the stored one, keeping the names recorded first,
+// and reports whether a stored transformer name is missing from the configured ones.
+func mergeTransformerMeta(stored, configured string) (string, bool) {
+	split := func(value string) []string {
+		if value == "none" {
+			return nil
+		}
+
+		return strings.FieldsFunc(value, func(r rune) bool { return r == '+' || r == ',' })
+	}
+
+	configuredNames := split(configured)
+	has := make(map[string]bool, len(configuredNames))
+	for _, name := range configuredNames {
+		has[name] = true
+	}
+
+	merged := make([]string, 0)
+	recorded := make(map[string]bool)
+	for _, name := range split(stored) {
+		if !has[name] {
+			return "", true
+		}
+		merged = append(merged, name)
+		recorded[name] = true
+	}
+	for _, name := range configuredNames {
+		if !recorded[name] {
+			merged = append(merged, name)
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(configured, "json"):
+		return strings.Join(merged, "+"), false
+	case len(merged) == 0:
+		return "none", false
+	}
+	return strings.Join(merged, ","), false
+}
+
+// prefixMeta returns the name of the meta setting recorded per prefix.
+func prefixMeta(name, prefix string) string {
+	return name + ":" + prefix
+}
+
+func readMeta(core *sql.DB) (map[string]string, error) {
+	rows, err := core.Query("SELECT name, value FROM kv_meta WHERE name NOT GLOB ?", statsWindowMeta+"*")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	meta := make(map[string]string)
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, err
+		}
+		meta[name] = value
+	}
+
+	return meta, rows.Err()
+}
+
+// Meta returns the store-level settings recorded in the database,
+// with the codec and encryption of the prefix of the store as MetaCodec and MetaEncryption.
+func (m *SQLite) Meta() (map[string]string, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	meta, err := readMeta(m.core)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range []string{MetaCodec, MetaEncryption} {
+		if value, ok := meta[prefixMeta(name, m.Config.Prefix)]; ok {
+			meta[name] = value
+		}
+	}
+
+	return meta, nil
+}
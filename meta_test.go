@@ -0,0 +1,93 @@
+package kvsqlite
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMeta(t *testing.T) {
+	path := "/tmp/test-meta.db"
+	os.Remove(path)
+	defer os.Remove(path)
+
+	client, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:", DefaultTTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := client.Meta()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta[MetaCodec] != "json" || meta[MetaDefaultTTL] != "3600000" {
+		t.Errorf("Expected recorded settings, got %v", meta)
+	}
+	client.Close()
+
+	// a later handle keeps the default TTL
+	client, err = New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Set("key", "value")
+	if next, _ := client.NextToExpire(1); len(next) != 1 {
+		t.Error("Expected the persisted default TTL to apply to new keys")
+	}
+	client.Core.Exec("UPDATE kv_meta SET value = '99' WHERE name = ?", MetaSchemaVersion)
+	client.Close()
+
+	if _, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:"}); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Expected ErrIncompatible for a newer schema, got %v", err)
+	}
+}
+
+func TestMetaTransformers(t *testing.T) {
+	path := "/tmp/test-meta-transformers.db"
+	os.Remove(path)
+	defer os.Remove(path)
+
+	encrypt, err := AESGCM([]byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:", Transformers: []ValueTransformer{Gzip(), encrypt}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := client.Meta()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta[MetaCodec] != "json+gzip" || meta[MetaEncryption] != "aes-gcm" {
+		t.Errorf("Expected the transformer chain to be recorded, got %v", meta)
+	}
+	client.Close()
+
+	if _, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:", Transformers: []ValueTransformer{Gzip()}}); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Expected ErrIncompatible without the encryption, got %v", err)
+	}
+	if _, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:", Transformers: []ValueTransformer{encrypt}}); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Expected ErrIncompatible without the compression, got %v", err)
+	}
+
+	// more transformers keep the values written before readable
+	client, err = New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:", Transformers: []ValueTransformer{Gzip(), encrypt, Checksum()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if meta, _ := client.Meta(); meta[MetaCodec] != "json+gzip+crc32" {
+		t.Errorf("Expected the added transformer to be recorded, got %v", meta)
+	}
+
+	// the transformers are recorded per prefix
+	other, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-other:"})
+	if err != nil {
+		t.Fatalf("Expected another prefix to open without the transformers, got %v", err)
+	}
+	defer other.Close()
+	if meta, _ := other.Meta(); meta[MetaCodec] != "json" || meta[MetaEncryption] != "none" {
+		t.Errorf("Expected the settings of the other prefix, got %v", meta)
+	}
+}
@@ -0,0 +1,107 @@
+// Package migrate copies the data of another go-zoox/kv backend (Redis, memory, filesystem, ...)
+// into a SQLite store, e.g. to move small deployments off Redis.
+package migrate
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-zoox/kv/typing"
+
+	kvsqlite "github.com/go-zoox/kv-sqlite"
+)
+
+// Options are the options of a migration.
+type Options struct {
+	// TTL returns the remaining TTL of a key in the source, e.g. with PTTL for Redis.
+	// 0 is no expiration, a negative TTL skips the key as expired.
+	// Default is no expiration for all keys, as typing.KV doesn't expose TTLs.
+	TTL func(key string) (time.Duration, error)
+
+	// Resume skips the keys which already exist in the destination, so an interrupted migration
+	// can be run again without copying everything again.
+	Resume bool
+
+	// Progress is called after each key with the progress so far.
+	Progress func(Progress)
+}
+
+// Progress is the progress of a migration.
+type Progress struct {
+	// Total is the number of keys of the source when the migration started.
+	Total int
+
+	// Copied is the number of keys copied.
+	Copied int
+
+	// Skipped is the number of keys skipped, existing with Resume or expired.
+	Skipped int
+
+	// Key is the last processed key.
+	Key string
+}
+
+// Run copies the keys of src into dst and returns the final progress.
+// It stops at the first error, after which it can be resumed with Resume.
+func Run(src typing.KV, dst *kvsqlite.SQLite, opts ...*Options) (Progress, error) {
+	opt := &Options{}
+	if len(opts) > 0 && opts[0] != nil {
+		opt = opts[0]
+	}
+
+	progress := Progress{Total: src.Size()}
+	var firstErr error
+	src.ForEach(func(key string, value any) {
+		if firstErr != nil {
+			return
+		}
+
+		copied, err := copyKey(dst, opt, key, value)
+		if err != nil {
+			firstErr = err
+			return
+		}
+
+		if copied {
+			progress.Copied++
+		} else {
+			progress.Skipped++
+		}
+		progress.Key = key
+
+		if opt.Progress != nil {
+			opt.Progress(progress)
+		}
+	})
+
+	return progress, firstErr
+}
+
+func copyKey(dst *kvsqlite.SQLite, opt *Options, key string, value any) (bool, error) {
+	if opt.Resume {
+		exists, err := dst.HasContext(context.Background(), key)
+		if err != nil || exists {
+			return false, err
+		}
+	}
+
+	var ttl time.Duration
+	if opt.TTL != nil {
+		var err error
+		if ttl, err = opt.TTL(key); err != nil {
+			return false, err
+		}
+
+		if ttl < 0 {
+			return false, nil
+		}
+	}
+
+	options := make([]kvsqlite.SetOption, 0, 1)
+	if ttl > 0 {
+		options = append(options, kvsqlite.WithTTL(ttl))
+	}
+
+	_, err := dst.SetWith(key, value, options...)
+	return err == nil, err
+}
@@ -0,0 +1,77 @@
+package migrate
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-zoox/kv/memory"
+
+	kvsqlite "github.com/go-zoox/kv-sqlite"
+)
+
+func TestRun(t *testing.T) {
+	src := memory.New()
+	src.Set("a", "alice")
+	src.Set("b", map[string]any{"name": "bob"})
+	src.Set("c", "carol", time.Hour)
+	src.Set("expired", "x")
+
+	dst, err := kvsqlite.New(&kvsqlite.SQLiteConfig{Path: "/tmp/test.db", Prefix: "go-zoox-test-migrate:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	dst.Clear()
+	defer dst.Clear()
+
+	ttls := map[string]time.Duration{"c": time.Hour, "expired": -1}
+	calls := 0
+	progress, err := Run(src, dst, &Options{
+		TTL: func(key string) (time.Duration, error) {
+			return ttls[key], nil
+		},
+		Progress: func(Progress) {
+			calls++
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if progress.Total != 4 || progress.Copied != 3 || progress.Skipped != 1 || calls != 4 {
+		t.Errorf("Unexpected progress %+v after %d calls", progress, calls)
+	}
+
+	var b map[string]string
+	if err := dst.Get("b", &b); err != nil || b["name"] != "bob" {
+		t.Errorf("Expected b to be copied, got %v (%v)", b, err)
+	}
+	if expiresAt, _ := dst.ExpiresAt("c"); expiresAt.IsZero() {
+		t.Error("Expected the TTL of c to be copied")
+	}
+	if dst.Has("expired") {
+		t.Error("Expected the expired key to be skipped")
+	}
+
+	// resuming skips what was copied, and stops at the first error
+	src.Set("d", "dave")
+	failed := errors.New("failed")
+	progress, err = Run(src, dst, &Options{
+		Resume: true,
+		TTL: func(key string) (time.Duration, error) {
+			if key == "d" {
+				return 0, failed
+			}
+			return ttls[key], nil
+		},
+	})
+	if !errors.Is(err, failed) || progress.Copied != 0 {
+		t.Errorf("Expected the TTL error without copies, got %+v (%v)", progress, err)
+	}
+
+	progress, err = Run(src, dst, &Options{Resume: true})
+	if err != nil || progress.Copied != 2 || progress.Skipped != 3 {
+		t.Errorf("Expected d and expired to be copied on resume, got %+v (%v)", progress, err)
+	}
+}
@@ -0,0 +1,53 @@
+package kvsqlite
+
+import (
+	"context"
+	"errors"
+)
+
+// MigratePrefix renames all keys starting with oldPrefix to start with newPrefix instead,
+// atomically in a single transaction, and returns the number of renamed keys. Dependency links, see Depend, follow them.
+// The prefixes are raw key prefixes in the table, e.g. whole tenant prefixes, not relative to Config.Prefix.
+// If a renamed key already exists, nothing is renamed and ErrConflict is returned.
+func (m *SQLite) MigratePrefix(oldPrefix, newPrefix string) (int64, error) {
+	if err := m.checkAccessAll(true); err != nil {
+		return 0, err
+	}
+
+	if oldPrefix == "" {
+		return 0, errors.New("sqlite: old prefix is required")
+	}
+
+	ctx := context.Background()
+	var n int64
+	err := m.writeTx(ctx, func(db *tracer) error {
+		res, err := db.ExecContext(ctx,
+			"UPDATE kv SET key = ? || substr(key, length(?) + 1) WHERE substr(key, 1, length(?)) = ?",
+			newPrefix, oldPrefix, oldPrefix, oldPrefix,
+		)
+		if err != nil {
+			return err
+		}
+
+		n, err = res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		// links follow the renamed keys, or SweepDependencies would take their parents for deleted
+		for _, column := range []string{"parent", "child"} {
+			renamed := "? || substr(" + column + ", length(?) + 1)"
+			if _, err := db.ExecContext(ctx,
+				"UPDATE OR REPLACE kv_deps SET "+column+" = "+renamed+" WHERE substr("+column+", 1, length(?)) = ? AND "+renamed+" IN (SELECT key FROM kv)",
+				newPrefix, oldPrefix, oldPrefix, oldPrefix, newPrefix, oldPrefix,
+			); err != nil {
+				return err
+			}
+		}
+
+		m.keysCleared()
+		return m.rebuildBloom(ctx, db)
+	})
+
+	return n, err
+}
@@ -0,0 +1,37 @@
+package kvsqlite
+
+import "testing"
+
+func TestMigratePrefix(t *testing.T) {
+	client := createClient()
+	client.Clear()
+	defer client.Clear()
+
+	client.Set("v1:a", "a")
+	client.Set("v1:b", "b")
+	client.Set("other", "other")
+
+	n, err := client.MigratePrefix(client.Config.Prefix+"v1:", client.Config.Prefix+"v2:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 keys to be migrated, got %d", n)
+	}
+
+	var value string
+	if err := client.Get("v2:a", &value); err != nil || value != "a" {
+		t.Errorf("Expected v2:a to be 'a', got %q (%v)", value, err)
+	}
+	if client.Has("v1:a") || !client.Has("other") {
+		t.Error("Expected only v1: keys to be renamed")
+	}
+
+	client.Set("v1:b", "b")
+	if _, err := client.MigratePrefix(client.Config.Prefix+"v1:", client.Config.Prefix+"v2:"); err == nil {
+		t.Error("Expected conflicting migration to fail")
+	}
+	if !client.Has("v1:b") {
+		t.Error("Expected conflicting migration to be rolled back")
+	}
+}
@@ -0,0 +1,138 @@
+package kvsqlite
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// MigratingOptions are the options of a MigratingStore.
+type MigratingOptions struct {
+	// OldKey returns the key of the old schema of a key, it is required.
+	OldKey func(key string) string
+
+	// NewKey returns the key of the new schema of a key, default is the key itself.
+	NewKey func(key string) string
+
+	// RewriteOnRead copies values found under the old key to the new key, with their expiration,
+	// so the new schema warms up from reads. The old key is kept for readers still on the old schema.
+	RewriteOnRead bool
+
+	// WriteOld writes to the old key too, so readers still on the old schema see new values.
+	// Default is to write only the new key, and delete both.
+	WriteOld bool
+}
+
+// MigratingStore changes the key schema of a store without a cold cache, e.g. during a blue/green deployment:
+// reads are served from the new keys and fall back to the old keys, writes go to the new keys, and optionally to the old ones.
+type MigratingStore struct {
+	Store   *SQLite
+	Options *MigratingOptions
+}
+
+// NewMigratingStore returns a new MigratingStore.
+func NewMigratingStore(store *SQLite, opts *MigratingOptions) (*MigratingStore, error) {
+	if opts == nil || opts.OldKey == nil {
+		return nil, errors.New("sqlite: old key of the migrating store is required")
+	}
+
+	return &MigratingStore{Store: store, Options: opts}, nil
+}
+
+func (s *MigratingStore) newKey(key string) string {
+	if s.Options.NewKey == nil {
+		return key
+	}
+
+	return s.Options.NewKey(key)
+}
+
+// Set sets the value for the new key, and the old one with WriteOld.
+func (s *MigratingStore) Set(key string, value any, maxAge ...time.Duration) error {
+	if err := s.Store.Set(s.newKey(key), value, maxAge...); err != nil {
+		return err
+	}
+
+	if s.Options.WriteOld {
+		return s.Store.Set(s.Options.OldKey(key), value, maxAge...)
+	}
+
+	return nil
+}
+
+// Get returns the value for the new key, or else for the old key.
+func (s *MigratingStore) Get(key string, value any) error {
+	_, err := s.Lookup(key, value)
+	return err
+}
+
+// Lookup is like Get but reports whether the key was found under either schema.
+func (s *MigratingStore) Lookup(key string, value any) (bool, error) {
+	newKey := s.newKey(key)
+	found, err := s.Store.Lookup(newKey, value)
+	if err != nil || found {
+		return found, err
+	}
+
+	oldKey := s.Options.OldKey(key)
+	if oldKey == newKey {
+		return false, nil
+	}
+
+	found, err = s.Store.Lookup(oldKey, value)
+	if err != nil || !found || !s.Options.RewriteOnRead {
+		return found, err
+	}
+
+	return true, s.Store.copyIfAbsent(context.Background(), oldKey, newKey)
+}
+
+// Has reports whether the key exists under either schema.
+func (s *MigratingStore) Has(key string) bool {
+	return s.Store.Has(s.newKey(key)) || s.Store.Has(s.Options.OldKey(key))
+}
+
+// Delete deletes the value for both the new and the old key.
+func (s *MigratingStore) Delete(key string) error {
+	if err := s.Store.Delete(s.newKey(key)); err != nil {
+		return err
+	}
+
+	return s.Store.Delete(s.Options.OldKey(key))
+}
+
+// copyIfAbsent copies the stored value of src to dst with its expiration, unless dst exists.
+// The stored value is copied as is, so it is neither decoded nor transformed again.
+func (m *SQLite) copyIfAbsent(ctx context.Context, src, dst string) error {
+	copied := false
+	err := m.writeTx(ctx, func(db *tracer) error {
+		srcX, err := m.getKey(src)
+		if err != nil {
+			return err
+		}
+		dstX, err := m.writeKey(dst)
+		if err != nil {
+			return err
+		}
+
+		if existing, err := m.readRow(ctx, db, dstX); err != nil || existing != nil {
+			return err
+		}
+
+		r, err := m.readRow(ctx, db, srcX)
+		if err != nil || r == nil {
+			return err
+		}
+
+		copied = true
+		return m.writeRow(ctx, db, dstX, r)
+	})
+	if err != nil {
+		return err
+	}
+
+	if copied {
+		m.publish(EventSet, dst)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package kvsqlite
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMigratingStore(t *testing.T) {
+	client, err := New(&SQLiteConfig{
+		Path:   "/tmp/test.db",
+		Prefix: "go-zoox-test-migrating:" + time.Now().String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if _, err := NewMigratingStore(client, &MigratingOptions{}); err == nil {
+		t.Error("Expected an error without OldKey")
+	}
+
+	store, err := NewMigratingStore(client, &MigratingOptions{
+		OldKey:        func(key string) string { return "v1:" + key },
+		NewKey:        func(key string) string { return "v2:" + key },
+		RewriteOnRead: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.Set("v1:user", "old", time.Hour)
+
+	var value string
+	if found, err := store.Lookup("user", &value); err != nil || !found || value != "old" {
+		t.Fatalf("Expected the old value, got %q %v (%v)", value, found, err)
+	}
+	if !client.Has("v2:user") || !client.Has("v1:user") {
+		t.Error("Expected the value to be copied to the new key and kept under the old one")
+	}
+	if expiresAt, _ := client.ExpiresAt("v2:user"); expiresAt.IsZero() {
+		t.Error("Expected the copy to keep the expiration")
+	}
+
+	if err := store.Set("user", "new"); err != nil {
+		t.Fatal(err)
+	}
+	if store.Get("user", &value); value != "new" {
+		t.Errorf("Expected new, got %s", value)
+	}
+	if client.Get("v1:user", &value); value != "old" {
+		t.Errorf("Expected the old key to be left alone, got %s", value)
+	}
+
+	store.Options.WriteOld = true
+	store.Set("user", "both")
+	if client.Get("v1:user", &value); value != "both" {
+		t.Errorf("Expected the old key to be written with WriteOld, got %s", value)
+	}
+
+	if err := store.Delete("user"); err != nil {
+		t.Fatal(err)
+	}
+	if store.Has("user") {
+		t.Error("Expected both keys to be deleted")
+	}
+}
@@ -0,0 +1,163 @@
+package kvsqlite
+
+import (
+	"context"
+	"time"
+)
+
+// SetOption is a per-call option of SetWith.
+type SetOption func(*setOptions)
+
+type setOptions struct {
+	ttl       *time.Duration
+	expiresAt *time.Time
+	keepTTL   bool
+	sliding   bool
+	nx        bool
+	xx        bool
+	tags      []string
+	tagged    bool
+
+	// immutable makes the key immutable, force overwrites it if it is, see SetImmutable.
+	immutable bool
+	force     bool
+
+	// priority is the eviction priority of the key, nil to keep it, see WithPriority.
+	priority *int
+
+	// concern is the durability of the write, see WithWriteConcern.
+	concern WriteConcern
+
+	// changedOnly skips writing an unexpired row with the same value and expiration, see SetIfChanged.
+	changedOnly bool
+}
+
+// WithTTL expires the value after ttl, like the maxAge of Set. It replaces WithExpiresAt.
+func WithTTL(ttl time.Duration) SetOption {
+	return func(o *setOptions) {
+		o.ttl, o.expiresAt, o.sliding = &ttl, nil, false
+	}
+}
+
+// WithExpiresAt expires the value at the absolute time t, e.g. at midnight or with a token,
+// rounded up to the TTL resolution. A time in the past writes an already expired value. It replaces WithTTL.
+func WithExpiresAt(t time.Time) SetOption {
+	return func(o *setOptions) {
+		o.expiresAt, o.ttl, o.sliding = &t, nil, false
+	}
+}
+
+// WithSlidingTTL expires the value after ttl without reads: each read which finds it, by Get, Lookup, Has,
+// GetMulti, GetAllByPrefix or a Pipeline, pushes its expiration to ttl from then, e.g. for the idle timeout of sessions. It replaces WithTTL and WithExpiresAt.
+// Writes keeping the TTL, e.g. Set without maxAge, keep the sliding TTL, other writes end it.
+// Values with a sliding TTL aren't kept in the memory cache, so every Get reaches the database.
+func WithSlidingTTL(ttl time.Duration) SetOption {
+	return func(o *setOptions) {
+		o.ttl, o.expiresAt, o.sliding = &ttl, nil, true
+	}
+}
+
+// WithKeepTTL keeps the expiration of an existing unexpired key.
+// New or expired keys get the default TTL of the store. WithTTL and WithExpiresAt take precedence over it.
+func WithKeepTTL() SetOption {
+	return func(o *setOptions) {
+		o.keepTTL = true
+	}
+}
+
+// WithNX only writes the value if the key doesn't exist.
+func WithNX() SetOption {
+	return func(o *setOptions) {
+		o.nx = true
+	}
+}
+
+// WithXX only writes the value if the key already exists.
+func WithXX() SetOption {
+	return func(o *setOptions) {
+		o.xx = true
+	}
+}
+
+// WithTags replaces the tags of the key, see KeysByTag and DeleteByTag.
+// Without it, the key keeps its tags.
+func WithTags(tags ...string) SetOption {
+	return func(o *setOptions) {
+		o.tags = tags
+		o.tagged = true
+	}
+}
+
+// WithImmutable makes the key immutable once written, see SetImmutable.
+func WithImmutable() SetOption {
+	return func(o *setOptions) {
+		o.immutable = true
+	}
+}
+
+// WithForce overwrites the key even if it is immutable. Unless WithImmutable is given too, it becomes mutable.
+func WithForce() SetOption {
+	return func(o *setOptions) {
+		o.force = true
+	}
+}
+
+// WithPriority sets the eviction priority of the key, see Quota.Evict: keys with a lower priority are evicted first.
+// Without it, the key keeps its priority, 0 for new keys.
+func WithPriority(priority int) SetOption {
+	return func(o *setOptions) {
+		o.priority = &priority
+	}
+}
+
+// WithWriteConcern sets the durability of the write, see WriteConcern, e.g. WriteDurable for sessions
+// and WriteRelaxed for throwaway cache entries.
+func WithWriteConcern(concern WriteConcern) SetOption {
+	return func(o *setOptions) {
+		o.concern = concern
+	}
+}
+
+func ifChanged() SetOption {
+	return func(o *setOptions) {
+		o.changedOnly = true
+	}
+}
+
+// maxAgeOptions adapts the maxAge of Set to options:
+// without maxAge, the TTL policy matching the key applies, or else the TTL is kept.
+func (m *SQLite) maxAgeOptions(key string, maxAge []time.Duration) []SetOption {
+	if len(maxAge) > 0 {
+		return []SetOption{WithTTL(maxAge[0])}
+	}
+
+	if ttl, ok := m.policyTTL(key); ok {
+		return []SetOption{WithTTL(ttl)}
+	}
+
+	return []SetOption{WithKeepTTL()}
+}
+
+// SetWithExpiresAt sets the value for the given key, expiring at the absolute time t, see WithExpiresAt.
+func (m *SQLite) SetWithExpiresAt(key string, value any, t time.Time) error {
+	return m.SetWithExpiresAtContext(context.Background(), key, value, t)
+}
+
+// SetWithExpiresAtContext is like SetWithExpiresAt but honors ctx.
+func (m *SQLite) SetWithExpiresAtContext(ctx context.Context, key string, value any, t time.Time) error {
+	_, err := m.set(ctx, key, value, WithExpiresAt(t))
+	return err
+}
+
+// SetWith sets the value for the given key with the given options and reports whether it was written,
+// which is false when WithNX or WithXX prevented the write.
+// Without WithTTL, WithExpiresAt nor WithKeepTTL, the expiration is reset to the TTL policy matching the key,
+// or else to the default TTL of the store, if any.
+func (m *SQLite) SetWith(key string, value any, opts ...SetOption) (bool, error) {
+	return m.SetWithContext(context.Background(), key, value, opts...)
+}
+
+// SetWithContext is like SetWith but honors ctx.
+func (m *SQLite) SetWithContext(ctx context.Context, key string, value any, opts ...SetOption) (bool, error) {
+	return m.set(ctx, key, value, opts...)
+}
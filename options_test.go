@@ -0,0 +1,201 @@
+package kvsqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSetWith(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	if written, err := client.SetWith("nx", "a", WithNX(), WithTTL(time.Hour)); err != nil || !written {
+		t.Fatalf("Expected a new key to be written with NX, got %v (%v)", written, err)
+	}
+
+	if written, _ := client.SetWith("nx", "b", WithNX()); written {
+		t.Error("Expected NX to skip an existing key")
+	}
+
+	if written, _ := client.SetWith("xx", "a", WithXX()); written {
+		t.Error("Expected XX to skip a missing key")
+	}
+	if client.Has("xx") {
+		t.Error("Expected XX not to create the key")
+	}
+
+	if written, _ := client.SetWith("nx", "c", WithXX()); !written {
+		t.Error("Expected XX to write an existing key")
+	}
+
+	var value string
+	if client.Get("nx", &value); value != "c" {
+		t.Errorf("Expected c, got %s", value)
+	}
+
+	// without WithTTL nor WithKeepTTL the expiration is reset
+	expiresAt, err := client.ExpiresAt("nx")
+	if err != nil || !expiresAt.IsZero() {
+		t.Errorf("Expected the expiration to be reset, got %v (%v)", expiresAt, err)
+	}
+
+	// an expired key doesn't exist for NX
+	client.Set("expired", "a", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if written, _ := client.SetWith("expired", "b", WithNX(), WithTTL(time.Hour)); !written {
+		t.Error("Expected NX to write an expired key")
+	}
+}
+
+func TestSetWithKeepTTL(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	client.Set("keep", "a", time.Hour)
+	before, _ := client.ExpiresAt("keep")
+
+	client.SetWith("keep", "b", WithKeepTTL())
+	if after, err := client.ExpiresAt("keep"); err != nil || !after.Equal(before) {
+		t.Errorf("Expected the expiration %v to be kept, got %v (%v)", before, after, err)
+	}
+
+	client.SetWith("keep", "c", WithKeepTTL(), WithTTL(time.Minute))
+	if after, _ := client.ExpiresAt("keep"); !after.Before(before) {
+		t.Errorf("Expected WithTTL to take precedence, got %v", after)
+	}
+
+	// an expired expiration is not kept, the key is new again
+	client.Set("expired", "a", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	client.Set("expired", "b")
+
+	var value string
+	if err := client.Get("expired", &value); err != nil || value != "b" {
+		t.Errorf("Expected the rewritten key not to be expired, got %q (%v)", value, err)
+	}
+}
+
+func TestSetWithExpiresAt(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	at := time.Now().Add(3 * time.Hour).Truncate(time.Millisecond)
+	if err := client.SetWithExpiresAt("midnight", "a", at); err != nil {
+		t.Fatal(err)
+	}
+	if expiresAt, err := client.ExpiresAt("midnight"); err != nil || !expiresAt.Equal(at) {
+		t.Errorf("Expected expiration %v, got %v (%v)", at, expiresAt, err)
+	}
+
+	// the last of WithTTL and WithExpiresAt wins
+	client.SetWith("midnight", "b", WithTTL(time.Minute), WithExpiresAt(at))
+	if expiresAt, _ := client.ExpiresAt("midnight"); !expiresAt.Equal(at) {
+		t.Errorf("Expected expiration %v, got %v", at, expiresAt)
+	}
+	client.SetWith("midnight", "c", WithKeepTTL(), WithExpiresAt(at), WithTTL(time.Minute))
+	if expiresAt, _ := client.ExpiresAt("midnight"); !expiresAt.Before(at) {
+		t.Errorf("Expected WithTTL to win, got %v", expiresAt)
+	}
+
+	client.SetWithExpiresAt("past", "a", time.Now().Add(-time.Minute))
+	var value string
+	if found, _ := client.Lookup("past", &value); found {
+		t.Errorf("Expected a value expiring in the past to be expired")
+	}
+
+	client.Set("batch", "a")
+	if n, _ := client.ExpireAtBatch([]string{"batch"}, at); n != 1 {
+		t.Errorf("Expected 1 updated key, got %d", n)
+	}
+	if expiresAt, _ := client.ExpiresAt("batch"); !expiresAt.Equal(at) {
+		t.Errorf("Expected expiration %v, got %v", at, expiresAt)
+	}
+}
+
+func TestSetWithSlidingTTL(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	client, err := New(&SQLiteConfig{
+		Path:        "/tmp/test.db",
+		Prefix:      "go-zoox-test-sliding:" + time.Now().String(),
+		Clock:       clock,
+		MemoryCache: &MemoryCacheConfig{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	defer client.Clear()
+
+	if _, err := client.SetWith("session", "alice", WithSlidingTTL(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// each read pushes the expiration, so the key outlives its TTL while it is read
+	var v string
+	for i := 0; i < 3; i++ {
+		clock.Add(50 * time.Minute)
+		if found, err := client.Lookup("session", &v); err != nil || !found || v != "alice" {
+			t.Fatalf("Expected the session to slide, got %q (%v)", v, err)
+		}
+	}
+
+	// writes keeping the TTL keep it sliding
+	client.Set("session", "bob")
+	clock.Add(50 * time.Minute)
+	if found, _ := client.Lookup("session", &v); !found || v != "bob" {
+		t.Fatalf("Expected the session to keep sliding, got %q", v)
+	}
+
+	clock.Add(61 * time.Minute)
+	if found, _ := client.Lookup("session", &v); found {
+		t.Error("Expected the idle session to expire")
+	}
+
+	// preloaded keys keep sliding, they are not held in the memory tier
+	client.SetWith("preloaded", "carol", WithSlidingTTL(time.Hour))
+	if _, err := client.Preload("preloaded"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		clock.Add(50 * time.Minute)
+		if found, _ := client.Lookup("preloaded", &v); !found {
+			t.Fatal("Expected a preloaded session to slide")
+		}
+	}
+
+	// bulk reads slide too
+	client.SetWith("bulk:1", "dave", WithSlidingTTL(time.Hour))
+	for i := 0; i < 2; i++ {
+		clock.Add(50 * time.Minute)
+		values := map[string]string{}
+		if res, err := client.GetMulti([]string{"bulk:1"}, values); err != nil || len(res.Found) != 1 {
+			t.Fatalf("Expected GetMulti to slide, got %v (%v)", values, err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		clock.Add(50 * time.Minute)
+		values := map[string]string{}
+		if err := client.GetAllByPrefix("bulk:", values); err != nil || len(values) != 1 {
+			t.Fatalf("Expected GetAllByPrefix to slide, got %v (%v)", values, err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		clock.Add(50 * time.Minute)
+		var value string
+		if res, err := client.Pipeline().Get("bulk:1", &value).Exec(context.Background()); err != nil || !res[0].Found {
+			t.Fatalf("Expected a pipelined get to slide, got %v (%v)", res, err)
+		}
+	}
+
+	// writes with another TTL end it
+	client.SetWith("fixed", 1, WithSlidingTTL(time.Hour))
+	client.Set("fixed", 2, time.Hour)
+	var n int
+	clock.Add(50 * time.Minute)
+	client.Get("fixed", &n)
+	clock.Add(20 * time.Minute)
+	if found, _ := client.Lookup("fixed", &n); found {
+		t.Error("Expected a fixed TTL after Set with maxAge")
+	}
+}
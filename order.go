@@ -0,0 +1,114 @@
+package kvsqlite
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Fields to order keys by.
+const (
+	// OrderByKey orders keys by key, the order of Keys and ForEach.
+	OrderByKey = "key"
+	// OrderByCreatedAt orders keys by the time they were first written.
+	// Keys written before it was recorded come first.
+	OrderByCreatedAt = "created_at"
+	// OrderByExpiresAt orders keys by expiration time, keys which never expire come last.
+	OrderByExpiresAt = "expires_at"
+)
+
+// OrderBy is the order of KeysBy and ForEachBy. Ties are ordered by key, so the order is deterministic.
+type OrderBy struct {
+	Field string
+	Desc  bool
+}
+
+type orderedKey struct {
+	key   string
+	value int64
+}
+
+// KeysBy returns the unexpired keys of the kv in the given order.
+func (m *SQLite) KeysBy(order OrderBy) ([]string, error) {
+	return m.keysBy(context.Background(), order)
+}
+
+// ForEachBy is like ForEach but calls f in the given order and returns the error.
+func (m *SQLite) ForEachBy(order OrderBy, f func(string, interface{})) error {
+	keys, err := m.KeysBy(order)
+	if err != nil {
+		return err
+	}
+
+	return m.forEachKey(context.Background(), keys, f)
+}
+
+func (m *SQLite) keysBy(ctx context.Context, order OrderBy) ([]string, error) {
+	column := "0"
+	switch order.Field {
+	case "", OrderByKey:
+	case OrderByCreatedAt, OrderByExpiresAt:
+		column = order.Field
+	default:
+		return nil, fmt.Errorf("sqlite: unknown order field %s", order.Field)
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	// sorted after reading, so keys of all databases are in one order
+	entries := make([]orderedKey, 0)
+	for _, database := range m.databases() {
+		rows, err := m.db().in(database).QueryContext(ctx, "SELECT key, coalesce("+column+", 0) FROM kv where key like ? AND "+notReserved+" AND "+unexpired, m.Config.Prefix+"%", m.reservedPattern(), m.expiryCutoff())
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			if err := ctx.Err(); err != nil {
+				rows.Close()
+				return nil, err
+			}
+
+			var entry orderedKey
+			if err := rows.Scan(&entry.key, &entry.value); err != nil {
+				rows.Close()
+				return nil, err
+			}
+
+			if order.Field == OrderByExpiresAt && entry.value == 0 {
+				entry.value = math.MaxInt64
+			}
+			entry.key = entry.key[len(m.Config.Prefix):]
+			if !m.canRead(entry.key) {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if order.Desc {
+			a, b = b, a
+		}
+
+		if a.value != b.value {
+			return a.value < b.value
+		}
+		return a.key < b.key
+	})
+
+	keys := make([]string, len(entries))
+	for i, entry := range entries {
+		keys[i] = entry.key
+	}
+
+	return keys, nil
+}
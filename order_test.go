@@ -0,0 +1,62 @@
+package kvsqlite
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestKeysBy(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	client, err := New(&SQLiteConfig{Path: "/tmp/test.db", Prefix: "go-zoox-test-order:", Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	defer client.Clear()
+
+	client.Set("b", 1, time.Hour)
+	clock.Add(time.Second)
+	client.Set("c", 2)
+	clock.Add(time.Second)
+	client.Set("a", 3, time.Minute)
+
+	// overwriting keeps the creation time
+	client.Set("b", 4, time.Hour)
+
+	tests := []struct {
+		order OrderBy
+		keys  []string
+	}{
+		{OrderBy{}, []string{"a", "b", "c"}},
+		{OrderBy{Field: OrderByKey, Desc: true}, []string{"c", "b", "a"}},
+		{OrderBy{Field: OrderByCreatedAt}, []string{"b", "c", "a"}},
+		{OrderBy{Field: OrderByExpiresAt}, []string{"a", "b", "c"}},
+		{OrderBy{Field: OrderByExpiresAt, Desc: true}, []string{"c", "b", "a"}},
+	}
+	for _, test := range tests {
+		keys, err := client.KeysBy(test.order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(keys, test.keys) {
+			t.Errorf("Expected %v for %+v, got %v", test.keys, test.order, keys)
+		}
+	}
+
+	if _, err := client.KeysBy(OrderBy{Field: "value"}); err == nil {
+		t.Error("Expected an error for an unknown field")
+	}
+
+	if keys := client.Keys(); !reflect.DeepEqual(keys, []string{"a", "b", "c"}) {
+		t.Errorf("Expected Keys to be ordered by key, got %v", keys)
+	}
+
+	visited := make([]string, 0)
+	client.ForEachBy(OrderBy{Field: OrderByCreatedAt, Desc: true}, func(key string, value interface{}) {
+		visited = append(visited, key)
+	})
+	if !reflect.DeepEqual(visited, []string{"a", "c", "b"}) {
+		t.Errorf("Expected ForEachBy to follow the order, got %v", visited)
+	}
+}
@@ -0,0 +1,378 @@
+package kvsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-zoox/kv/typing"
+)
+
+// partitionTablePrefix is the name prefix of partition tables, followed by the bucket width in milliseconds
+// and the bucket index, so stores with different widths on one database never share them.
+const partitionTablePrefix = "kv_bucket_"
+
+// PartitionOptions are the options of a Partitioned store.
+type PartitionOptions struct {
+	// Bucket is the width of the expiration buckets, default is 1 hour.
+	Bucket time.Duration
+
+	// DefaultTTL is the TTL of keys set without maxAge, default is Bucket.
+	DefaultTTL time.Duration
+
+	// ExpireInterval is how often expired partitions are dropped in the background.
+	// Default is 0, which means Expire has to be called explicitly.
+	ExpireInterval time.Duration
+}
+
+// Partitioned is a store for short-lived data, which partitions rows into one table per expiration bucket,
+// e.g. hourly, so expiring them is a DROP TABLE of whole buckets instead of a DELETE per row.
+// It shares the database, prefix and locking of its SQLite store, but not its kv table,
+// so the keys of a Partitioned store are not visible from the SQLite store and vice versa.
+// Every key expires, keys without expiration don't belong in a Partitioned store.
+type Partitioned struct {
+	Store   *SQLite
+	Options *PartitionOptions
+
+	expirer *worker
+}
+
+// NewPartitioned returns a new Partitioned store on top of the given SQLite store.
+func NewPartitioned(store *SQLite, opts ...*PartitionOptions) *Partitioned {
+	opt := &PartitionOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		opt = opts[0]
+	}
+
+	if opt.Bucket <= 0 {
+		opt.Bucket = time.Hour
+	}
+	if opt.DefaultTTL <= 0 {
+		opt.DefaultTTL = opt.Bucket
+	}
+
+	p := &Partitioned{
+		Store:   store,
+		Options: opt,
+	}
+
+	if opt.ExpireInterval > 0 {
+		p.expirer = newWorker(opt.ExpireInterval, func() {
+			p.Expire()
+		})
+	}
+
+	return p
+}
+
+var _ typing.KV = (*Partitioned)(nil)
+
+// Close stops the background expiration, the SQLite store is not closed.
+func (p *Partitioned) Close() error {
+	if p.expirer != nil {
+		p.expirer.stop()
+	}
+
+	return nil
+}
+
+// tablePrefix returns the name prefix of the partition tables of the bucket width.
+func (p *Partitioned) tablePrefix() string {
+	return partitionTablePrefix + strconv.FormatInt(p.Options.Bucket.Milliseconds(), 10) + "_"
+}
+
+func (p *Partitioned) partitionTable(bucket int64) string {
+	return p.tablePrefix() + strconv.FormatInt(bucket, 10)
+}
+
+// partitions returns the bucket indexes of the existing partition tables of the bucket width, oldest first.
+func (p *Partitioned) partitions(ctx context.Context, db *tracer) ([]int64, error) {
+	rows, err := db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' AND name GLOB ?", p.tablePrefix()+"*")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := make([]int64, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+
+		bucket, err := strconv.ParseInt(strings.TrimPrefix(name, p.tablePrefix()), 10, 64)
+		if err != nil {
+			continue
+		}
+		buckets = append(buckets, bucket)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+	return buckets, nil
+}
+
+// livePartitions returns the partitions which may still hold unexpired rows.
+func (p *Partitioned) livePartitions(ctx context.Context, db *tracer) ([]int64, error) {
+	buckets, err := p.partitions(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	current := p.Store.now() / p.Options.Bucket.Milliseconds()
+	for i, bucket := range buckets {
+		if bucket >= current {
+			return buckets[i:], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Set sets the value for the given key, in the partition of its expiration bucket.
+// If maxAge is not given, Options.DefaultTTL applies.
+func (p *Partitioned) Set(key string, value any, maxAge ...time.Duration) error {
+	ctx := context.Background()
+	keyX, err := p.Store.writeKey(key)
+	if err != nil {
+		return err
+	}
+
+	valueX, err := p.Store.encodeValue(value)
+	if err != nil {
+		return err
+	}
+
+	ttl := p.Options.DefaultTTL
+	if len(maxAge) > 0 && maxAge[0] > 0 {
+		ttl = maxAge[0]
+	}
+	expiresAt := p.Store.expiresAt(ttl)
+	bucket := expiresAt / p.Options.Bucket.Milliseconds()
+
+	return p.Store.writeTx(ctx, func(db *tracer) error {
+		table := p.partitionTable(bucket)
+		if _, err := db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+table+" (key TEXT PRIMARY KEY, value BLOB, expires_at INTEGER NOT NULL)"); err != nil {
+			return err
+		}
+
+		buckets, err := p.livePartitions(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		// the key moves to another bucket if its expiration changed
+		for _, other := range buckets {
+			if other == bucket {
+				continue
+			}
+
+			if _, err := db.ExecContext(ctx, "DELETE FROM "+p.partitionTable(other)+" WHERE key = ?", keyX); err != nil {
+				return err
+			}
+		}
+
+		_, err = db.ExecContext(ctx, "INSERT INTO "+table+" (key, value, expires_at) VALUES (?, ?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at", keyX, valueX, expiresAt)
+		return err
+	})
+}
+
+// Get returns the value for the given key.
+func (p *Partitioned) Get(key string, value any) error {
+	data, found, err := p.read(key)
+	if err != nil || !found {
+		return err
+	}
+
+	return p.Store.decodeValue(key, data, value)
+}
+
+func (p *Partitioned) read(key string) ([]byte, bool, error) {
+	ctx := context.Background()
+	keyX, err := p.Store.getKey(key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	p.Store.RLock()
+	defer p.Store.RUnlock()
+
+	db := p.Store.db()
+	buckets, err := p.livePartitions(ctx, db)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// newest first, as long-lived keys are looked up the most
+	for i := len(buckets) - 1; i >= 0; i-- {
+		var value []byte
+		err := db.QueryRowContext(ctx, "SELECT value FROM "+p.partitionTable(buckets[i])+" WHERE key = ? AND expires_at >= ?", keyX, p.Store.now()).Scan(&value)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		return value, true, nil
+	}
+
+	return nil, false, nil
+}
+
+// Delete deletes the value for the given key.
+func (p *Partitioned) Delete(key string) error {
+	ctx := context.Background()
+	keyX, err := p.Store.writeKey(key)
+	if err != nil {
+		return err
+	}
+
+	return p.Store.write(ctx, func(db *tracer) error {
+		buckets, err := p.partitions(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		for _, bucket := range buckets {
+			if _, err := db.ExecContext(ctx, "DELETE FROM "+p.partitionTable(bucket)+" WHERE key = ?", keyX); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Has returns true if the given key exists and is not expired.
+func (p *Partitioned) Has(key string) bool {
+	_, found, err := p.read(key)
+	if err != nil {
+		panic(err)
+	}
+
+	return found
+}
+
+// Keys returns the unexpired keys, readable by the access policy of the store.
+func (p *Partitioned) Keys() []string {
+	keys, err := p.keys()
+	if err != nil {
+		panic(err)
+	}
+
+	return keys
+}
+
+func (p *Partitioned) keys() ([]string, error) {
+	ctx := context.Background()
+
+	p.Store.RLock()
+	defer p.Store.RUnlock()
+
+	db := p.Store.db()
+	buckets, err := p.livePartitions(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0)
+	for _, bucket := range buckets {
+		rows, err := db.QueryContext(ctx, "SELECT key FROM "+p.partitionTable(bucket)+" WHERE key LIKE ? AND expires_at >= ?", p.Store.Config.Prefix+"%", p.Store.now())
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var key string
+			if err := rows.Scan(&key); err != nil {
+				rows.Close()
+				return nil, err
+			}
+
+			if key = key[len(p.Store.Config.Prefix):]; p.Store.canRead(key) {
+				keys = append(keys, key)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Size returns the number of unexpired keys.
+func (p *Partitioned) Size() int {
+	return len(p.Keys())
+}
+
+// Clear deletes the keys of the prefix from all partitions.
+func (p *Partitioned) Clear() error {
+	ctx := context.Background()
+
+	return p.Store.write(ctx, func(db *tracer) error {
+		buckets, err := p.partitions(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		for _, bucket := range buckets {
+			if _, err := db.ExecContext(ctx, "DELETE FROM "+p.partitionTable(bucket)+" WHERE key LIKE ?", p.Store.Config.Prefix+"%"); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// ForEach iterates over the unexpired entries.
+func (p *Partitioned) ForEach(fn func(key string, value any)) {
+	for _, key := range p.Keys() {
+		var value any
+		if err := p.Get(key, &value); err != nil {
+			continue
+		}
+
+		fn(key, value)
+	}
+}
+
+// Expire drops the partitions whose whole bucket is expired and returns how many were dropped.
+// Partitions are shared by all prefixes of the same bucket width, which is safe as every row of an expired bucket is expired.
+func (p *Partitioned) Expire() (int, error) {
+	ctx := context.Background()
+
+	var dropped int
+	err := p.Store.write(ctx, func(db *tracer) error {
+		buckets, err := p.partitions(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		current := p.Store.now() / p.Options.Bucket.Milliseconds()
+		for _, bucket := range buckets {
+			if bucket >= current {
+				break
+			}
+
+			if _, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS "+p.partitionTable(bucket)); err != nil {
+				return err
+			}
+			dropped++
+		}
+
+		return nil
+	})
+
+	return dropped, err
+}
@@ -0,0 +1,74 @@
+package kvsqlite
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPartitioned(t *testing.T) {
+	path := "/tmp/test-partitioned.db"
+	os.Remove(path)
+	defer os.Remove(path)
+
+	clock := NewManualClock(time.UnixMilli(0).Add(10 * time.Hour))
+	store, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:", Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	p := NewPartitioned(store, &PartitionOptions{Bucket: time.Hour})
+	defer p.Close()
+
+	p.Set("short", "a", 30*time.Minute)
+	p.Set("long", "b", 3*time.Hour)
+
+	var value string
+	if p.Get("short", &value); value != "a" {
+		t.Errorf("Expected a, got %s", value)
+	}
+	if p.Size() != 2 {
+		t.Errorf("Expected 2 keys, got %d", p.Size())
+	}
+
+	// moving a key to another bucket removes it from the old one
+	p.Set("short", "c", 2*time.Hour)
+	p.Set("short", "c", 30*time.Minute)
+	if p.Size() != 2 {
+		t.Errorf("Expected 2 keys after moving, got %d", p.Size())
+	}
+
+	clock.Add(2 * time.Hour)
+	if p.Has("short") {
+		t.Error("Expected short to be expired")
+	}
+
+	dropped, err := p.Expire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dropped != 1 {
+		t.Errorf("Expected 1 dropped partition, got %d", dropped)
+	}
+
+	if !p.Has("long") {
+		t.Error("Expected long to survive")
+	}
+
+	// a store with another bucket width keeps its own partitions
+	minutely := NewPartitioned(store, &PartitionOptions{Bucket: time.Minute})
+	defer minutely.Close()
+	clock.Add(2 * time.Minute)
+	if _, err := minutely.Expire(); err != nil {
+		t.Fatal(err)
+	}
+	if !p.Has("long") || minutely.Has("long") {
+		t.Error("Expected stores of different widths not to share partitions")
+	}
+
+	p.Delete("long")
+	if p.Has("long") {
+		t.Error("Expected long to be deleted")
+	}
+}
@@ -0,0 +1,84 @@
+package kvsqlite
+
+import (
+	"context"
+)
+
+// matchPattern reports whether key matches the glob pattern,
+// where * matches any sequence of characters and ? matches any single character.
+func matchPattern(pattern, key string) bool {
+	p := []rune(pattern)
+	k := []rune(key)
+
+	// star and mark remember the last * to backtrack to
+	pi, ki := 0, 0
+	star, mark := -1, 0
+	for ki < len(k) {
+		switch {
+		case pi < len(p) && (p[pi] == '?' || p[pi] == k[ki]):
+			pi++
+			ki++
+		case pi < len(p) && p[pi] == '*':
+			star = pi
+			mark = ki
+			pi++
+		case star >= 0:
+			pi = star + 1
+			mark++
+			ki = mark
+		default:
+			return false
+		}
+	}
+
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+
+	return pi == len(p)
+}
+
+// DeleteByPattern deletes the keys matching the glob pattern, e.g. "page:*", and returns the number of deleted keys.
+// See DryRun to check what it would delete first, and DeleteByPatternBatched for many keys.
+func (m *SQLite) DeleteByPattern(pattern string) (int64, error) {
+	if err := m.checkAccessAll(true); err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+	removed := make([]string, 0)
+	err := m.writeWith(ctx, len(m.Config.Attach) > 0, func(db *tracer) error {
+		for _, database := range m.databases() {
+			rows, err := db.in(database).QueryContext(ctx, "DELETE FROM kv WHERE key GLOB ? AND "+notReserved+" RETURNING key", globEscape(m.Config.Prefix)+pattern, m.reservedPattern())
+			if err != nil {
+				return err
+			}
+
+			for rows.Next() {
+				var keyX string
+				if err := rows.Scan(&keyX); err != nil {
+					rows.Close()
+					return err
+				}
+
+				m.keyRemoved(keyX)
+				removed = append(removed, keyX[len(m.Config.Prefix):])
+			}
+			rows.Close()
+
+			if err := rows.Err(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, key := range removed {
+		m.publish(EventDelete, key)
+	}
+	return int64(len(removed)), nil
+}
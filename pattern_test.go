@@ -0,0 +1,51 @@
+package kvsqlite
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMatchPattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		key     string
+		match   bool
+	}{
+		{"session:*", "session:abc", true},
+		{"session:*", "session:", true},
+		{"session:*", "user:1", false},
+		{"user:?", "user:1", true},
+		{"user:?", "user:12", false},
+		{"*:profile", "user:42:profile", true},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxbyy", false},
+		{"*", "", true},
+	}
+
+	for _, c := range cases {
+		if got := matchPattern(c.pattern, c.key); got != c.match {
+			t.Errorf("matchPattern(%q, %q) = %v, expected %v", c.pattern, c.key, got, c.match)
+		}
+	}
+}
+
+func TestDeleteByPattern(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	for i := 0; i < 5; i++ {
+		client.Set(fmt.Sprintf("page:%d", i), i)
+	}
+	client.Set("auth:1", "token")
+
+	n, err := client.DeleteByPattern("page:*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 {
+		t.Errorf("Expected 5 deleted keys, got %d", n)
+	}
+	if keys := client.Keys(); len(keys) != 1 || keys[0] != "auth:1" {
+		t.Errorf("Expected only auth:1 to be left, got %v", keys)
+	}
+}
@@ -0,0 +1,159 @@
+package kvsqlite
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Pipeline stages commands to run them in a single transaction, like Redis pipelining, see SQLite.Pipeline.
+// It is not safe for concurrent use.
+type Pipeline struct {
+	store    *SQLite
+	commands []pipelineCommand
+}
+
+// PipelineResult is the result of a command of a Pipeline.
+type PipelineResult struct {
+	// Op is the command, set, get or delete.
+	Op  string
+	Key string
+
+	// Found reports whether the key existed, for get and delete.
+	Found bool
+
+	// Err is the error of the command, e.g. the decoding error of a get.
+	Err error
+}
+
+type pipelineCommand struct {
+	op    string
+	key   string
+	value any
+	write *setWrite
+	err   error
+}
+
+// Pipeline returns a new pipeline of the store.
+// Commands are staged by Set, Get and Delete, and run by Exec in a single transaction,
+// so a handler pays for one lock and one commit instead of one per command.
+func (m *SQLite) Pipeline() *Pipeline {
+	return &Pipeline{store: m}
+}
+
+// Set stages setting the value for the given key, like SQLite.Set.
+// The value is encoded right away, so it may be changed once Set returns.
+func (p *Pipeline) Set(key string, value any, maxAge ...time.Duration) *Pipeline {
+	w, err := p.store.prepareSet(key, value, p.store.maxAgeOptions(key, maxAge)...)
+	p.commands = append(p.commands, pipelineCommand{op: "set", key: key, write: w, err: err})
+	return p
+}
+
+// Get stages reading the value for the given key into value, which is decoded when Exec returns.
+// It reads the database in the transaction, bypassing the memory cache and the archive.
+func (p *Pipeline) Get(key string, value any) *Pipeline {
+	p.commands = append(p.commands, pipelineCommand{op: "get", key: key, value: value})
+	return p
+}
+
+// Delete stages deleting the value for the given key.
+func (p *Pipeline) Delete(key string) *Pipeline {
+	p.commands = append(p.commands, pipelineCommand{op: "delete", key: key})
+	return p
+}
+
+// Len returns the number of staged commands.
+func (p *Pipeline) Len() int {
+	return len(p.commands)
+}
+
+// Exec runs the staged commands in order in a single transaction and returns their results.
+// If a command fails, the transaction is rolled back and its error is returned, with the results of the commands before it.
+// Decoding errors of gets don't roll back the transaction, they are only reported in the results.
+// The pipeline is empty afterwards, so it can be reused.
+func (p *Pipeline) Exec(ctx context.Context) ([]PipelineResult, error) {
+	m := p.store
+	commands := p.commands
+	p.commands = nil
+
+	results := make([]PipelineResult, 0, len(commands))
+	raw := make([]*row, len(commands))
+	err := m.writeTx(ctx, func(db *tracer) error {
+		results = results[:0]
+		for i, c := range commands {
+			res := PipelineResult{Op: c.op, Key: c.key}
+
+			var err error
+			switch c.op {
+			case "set":
+				if err = c.err; err == nil {
+					_, err = m.applySet(ctx, db, c.write)
+				}
+			case "get":
+				var keyX string
+				if keyX, err = m.getKey(c.key); err == nil {
+					raw[i], err = m.readRow(ctx, db, keyX)
+					res.Found = raw[i] != nil
+					if err == nil && res.Found && raw[i].sliding > 0 && !m.Config.ReadOnly {
+						err = m.slideRow(ctx, db, slidingKey{keyX: keyX, sliding: raw[i].sliding, expiresAt: raw[i].expiresAt})
+					}
+				}
+			case "delete":
+				res.Found, err = m.deletePipelined(ctx, db, c.key)
+			}
+
+			res.Err = err
+			results = append(results, res)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return results, err
+	}
+
+	for i, c := range commands {
+		switch c.op {
+		case "set":
+			atomic.AddInt64(&m.counters.sets, 1)
+			m.publish(EventSet, c.key)
+		case "get":
+			if raw[i] == nil {
+				atomic.AddInt64(&m.counters.misses, 1)
+				continue
+			}
+
+			atomic.AddInt64(&m.counters.hits, 1)
+			results[i].Err = m.decodeValue(c.key, []byte(raw[i].value), c.value)
+		case "delete":
+			atomic.AddInt64(&m.counters.deletes, 1)
+			if results[i].Found {
+				m.publish(EventDelete, c.key)
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// deletePipelined deletes the given key in the transaction of a pipeline and reports whether it existed.
+func (m *SQLite) deletePipelined(ctx context.Context, db *tracer, key string) (bool, error) {
+	keyX, err := m.writeKey(key)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := db.in(m.databaseOf(keyX)).ExecContext(ctx, "DELETE FROM kv WHERE key = ?", keyX)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if n > 0 {
+		m.keyRemoved(keyX)
+	}
+	return n > 0, err
+}
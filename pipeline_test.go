@@ -0,0 +1,56 @@
+package kvsqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPipeline(t *testing.T) {
+	client, err := New(&SQLiteConfig{
+		Path:   "/tmp/test.db",
+		Prefix: "go-zoox-test-pipeline:" + time.Now().String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	client.Set("old", "value")
+
+	var a, missing string
+	p := client.Pipeline()
+	p.Set("a", "1").Set("b", "2", time.Minute).Get("a", &a).Get("missing", &missing).Delete("old")
+	if p.Len() != 5 {
+		t.Fatalf("Expected 5 staged commands, got %d", p.Len())
+	}
+
+	results, err := p.Exec(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 5 || !results[2].Found || results[3].Found || !results[4].Found {
+		t.Errorf("Unexpected results %+v", results)
+	}
+	if a != "1" {
+		t.Errorf("Expected a get to see the set staged before it, got %q", a)
+	}
+	if client.Has("old") || !client.Has("b") {
+		t.Error("Expected the pipeline to be applied")
+	}
+	if p.Len() != 0 {
+		t.Error("Expected the pipeline to be empty after Exec")
+	}
+
+	// a failing command rolls back the whole pipeline
+	client.SetImmutable("frozen", "value")
+	results, err = client.Pipeline().Set("c", "3").Delete("frozen").Exec(context.Background())
+	if !errors.Is(err, ErrImmutable) || len(results) != 2 || !errors.Is(results[1].Err, ErrImmutable) {
+		t.Errorf("Expected ErrImmutable, got %v %+v", err, results)
+	}
+	if client.Has("c") {
+		t.Error("Expected the set to be rolled back")
+	}
+	client.ForceDelete("frozen")
+}
@@ -0,0 +1,49 @@
+package kvsqlite
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// SetAndGetPrevious is like Set but also decodes the value it replaced into previous,
+// and reports whether there was one. The previous value is read in the transaction of the write,
+// so no write can come in between, unlike with Get then Set.
+// SQLite's RETURNING only returns the new row of an upsert, hence the read.
+func (m *SQLite) SetAndGetPrevious(key string, value any, previous any, maxAge ...time.Duration) (bool, error) {
+	return m.SetAndGetPreviousContext(context.Background(), key, value, previous, maxAge...)
+}
+
+// SetAndGetPreviousContext is like SetAndGetPrevious but honors ctx.
+func (m *SQLite) SetAndGetPreviousContext(ctx context.Context, key string, value any, previous any, maxAge ...time.Duration) (found bool, err error) {
+	start := m.recordStart()
+	defer func() { m.record("set", key, start, err) }()
+
+	w, err := m.prepareSet(key, value, m.maxAgeOptions(key, maxAge)...)
+	if err != nil {
+		return false, err
+	}
+
+	var prev *row
+	err = m.writeTx(ctx, func(db *tracer) error {
+		var err error
+		if prev, err = m.readRow(ctx, db, w.keyX); err != nil {
+			return err
+		}
+
+		_, err = m.applySet(ctx, db, w)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	atomic.AddInt64(&m.counters.sets, 1)
+	m.publish(EventSet, key)
+
+	if prev == nil {
+		return false, nil
+	}
+
+	return true, m.decodeValue(key, []byte(prev.value), previous)
+}
@@ -0,0 +1,41 @@
+package kvsqlite
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetAndGetPrevious(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	var prev string
+	found, err := client.SetAndGetPrevious("previous", "one", &prev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found {
+		t.Errorf("Expected no previous value, got %q", prev)
+	}
+
+	found, err = client.SetAndGetPrevious("previous", "two", &prev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || prev != "one" {
+		t.Errorf("Expected previous value one, got %q (%v)", prev, found)
+	}
+
+	var v string
+	if err := client.Get("previous", &v); err != nil || v != "two" {
+		t.Errorf("Expected two, got %q (%v)", v, err)
+	}
+
+	client.Set("expired", "old", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	prev = ""
+	if found, err := client.SetAndGetPrevious("expired", "new", &prev); err != nil || found {
+		t.Errorf("Expected no previous value for an expired key, got %q (%v)", prev, err)
+	}
+}
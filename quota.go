@@ -0,0 +1,164 @@
+package kvsqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrQuotaExceeded is returned when a write would exceed the quota of the prefix.
+var ErrQuotaExceeded = errors.New("sqlite: quota exceeded")
+
+// Quota is the limit of keys and bytes stored under a prefix.
+// Zero means no limit.
+type Quota struct {
+	MaxKeys  int64
+	MaxBytes int64
+
+	// Evict makes writes exceeding the quota evict other keys of the prefix instead of failing with ErrQuotaExceeded:
+	// expired keys first, then the lowest priority (see WithPriority), the least recently accessed (with TrackAccess)
+	// and the oldest. Keys with a higher priority than the written key and immutable keys are never evicted,
+	// so the write still fails if evicting the others is not enough. Evicted keys are not published as events.
+	Evict bool
+}
+
+// Usage is the number of keys and bytes (keys plus encoded values) stored under a prefix.
+type Usage struct {
+	Keys  int64
+	Bytes int64
+}
+
+// Usage returns the current usage of the prefix.
+func (m *SQLite) Usage() (*Usage, error) {
+	if err := m.checkAccessAll(false); err != nil {
+		return nil, err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	usage := &Usage{}
+	err := m.db().QueryRow(
+		"SELECT count(*), coalesce(sum(length(CAST(key AS BLOB)) + "+valueSizeExpr+"), 0) FROM kv WHERE key LIKE ?",
+		m.Config.Prefix+"%",
+	).Scan(&usage.Keys, &usage.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return usage, nil
+}
+
+// checkQuota checks whether writing size bytes to keyX stays within the quota, evicting other keys if enabled.
+// If appending is true, size is added to the existing value instead of replacing it.
+// priority is the priority the key is written with, nil for its current one.
+// It must be called with the write lock held.
+func (m *SQLite) checkQuota(ctx context.Context, db *tracer, keyX string, size int64, appending bool, priority *int) error {
+	quota := m.Config.Quota
+	if quota == nil || (quota.MaxKeys <= 0 && quota.MaxBytes <= 0) {
+		return nil
+	}
+
+	var others, othersBytes, existingBytes int64
+	err := db.QueryRowContext(ctx, `SELECT
+			count(*) - count(CASE WHEN key = ? THEN 1 END),
+			coalesce(sum(CASE WHEN key = ? THEN 0 ELSE length(CAST(key AS BLOB)) + `+valueSizeExpr+` END), 0),
+			coalesce(sum(CASE WHEN key = ? THEN `+valueSizeExpr+` ELSE 0 END), 0)
+		FROM kv WHERE key LIKE ?`,
+		keyX, keyX, keyX, m.Config.Prefix+"%",
+	).Scan(&others, &othersBytes, &existingBytes)
+	if err != nil {
+		return err
+	}
+
+	keys := others + 1
+	bytes := othersBytes + int64(len(keyX)) + size
+	if appending {
+		bytes += existingBytes
+	}
+
+	var excessKeys, excessBytes int64
+	if quota.MaxKeys > 0 && keys > quota.MaxKeys {
+		excessKeys = keys - quota.MaxKeys
+	}
+	if quota.MaxBytes > 0 && bytes > quota.MaxBytes {
+		excessBytes = bytes - quota.MaxBytes
+	}
+
+	if (excessKeys > 0 || excessBytes > 0) && quota.Evict {
+		evictedKeys, evictedBytes, err := m.evict(ctx, db, keyX, priority, excessKeys, excessBytes)
+		if err != nil {
+			return err
+		}
+
+		keys -= evictedKeys
+		bytes -= evictedBytes
+	}
+
+	if quota.MaxKeys > 0 && keys > quota.MaxKeys {
+		return fmt.Errorf("%w: %d keys exceeds limit of %d", ErrQuotaExceeded, keys, quota.MaxKeys)
+	}
+
+	if quota.MaxBytes > 0 && bytes > quota.MaxBytes {
+		return fmt.Errorf("%w: %d bytes exceeds limit of %d", ErrQuotaExceeded, bytes, quota.MaxBytes)
+	}
+
+	return nil
+}
+
+// evict deletes keys of the prefix other than keyX, in eviction order, until excessKeys keys and excessBytes bytes are freed,
+// or no more key may be evicted, and returns how many keys and bytes were freed.
+// Nothing is evicted if that is not enough, so a failing write doesn't lose keys.
+func (m *SQLite) evict(ctx context.Context, db *tracer, keyX string, priority *int, excessKeys, excessBytes int64) (int64, int64, error) {
+	var max any
+	if priority != nil {
+		max = *priority
+	}
+
+	rows, err := db.QueryContext(ctx,
+		`SELECT key, length(CAST(key AS BLOB)) + `+valueSizeExpr+` FROM kv
+			WHERE key LIKE ? AND `+notReserved+` AND key != ? AND immutable = 0
+				AND priority <= coalesce(?, (SELECT priority FROM kv WHERE key = ?), 0)
+			ORDER BY NOT `+unexpired+` DESC, priority, last_accessed_at, created_at, key`,
+		m.Config.Prefix+"%", m.reservedPattern(), keyX, max, keyX, m.expiryCutoff(),
+	)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var victims []string
+	var keys, bytes int64
+	for rows.Next() && (keys < excessKeys || bytes < excessBytes) {
+		var victim string
+		var size int64
+		if err := rows.Scan(&victim, &size); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+
+		victims = append(victims, victim)
+		keys++
+		bytes += size
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, 0, err
+	}
+	if err := rows.Close(); err != nil {
+		return 0, 0, err
+	}
+
+	if keys < excessKeys || bytes < excessBytes {
+		return 0, 0, nil
+	}
+
+	for _, victim := range victims {
+		if err := m.deleteRow(ctx, db, victim); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	atomic.AddInt64(&m.counters.evictions, keys)
+	return keys, bytes, nil
+}
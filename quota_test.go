@@ -0,0 +1,88 @@
+package kvsqlite
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQuota(t *testing.T) {
+	client, err := New(&SQLiteConfig{
+		Path:   "/tmp/test.db",
+		Prefix: "go-zoox-test-quota:",
+		Quota:  &Quota{MaxKeys: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.Clear()
+	defer client.Clear()
+
+	if err := client.Set("key1", "value1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Set("key2", "value2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Set("key2", "value2-updated"); err != nil {
+		t.Errorf("Expected overwrite within quota, got %v", err)
+	}
+	if err := client.Set("key3", "value3"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+
+	usage, err := client.Usage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Keys != 2 || usage.Bytes == 0 {
+		t.Errorf("Expected usage of 2 keys, got %+v", usage)
+	}
+}
+
+func TestQuotaEvictByPriority(t *testing.T) {
+	client, err := New(&SQLiteConfig{
+		Path:   "/tmp/test.db",
+		Prefix: "go-zoox-test-quota-evict:" + time.Now().String(),
+		Quota:  &Quota{MaxKeys: 3, Evict: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	client.SetWith("auth:1", "token", WithPriority(10))
+	client.SetWith("page:1", "html")
+	client.SetWith("page:2", "html")
+
+	// the oldest key of the lowest priority goes first
+	if err := client.Set("page:3", "html"); err != nil {
+		t.Fatal(err)
+	}
+	if client.Has("page:1") || !client.Has("page:2") || !client.Has("auth:1") {
+		t.Errorf("Expected page:1 to be evicted, got keys %v", client.Keys())
+	}
+
+	// overwriting keeps the priority
+	client.Set("auth:1", "token2")
+	client.Set("page:4", "html")
+	client.Set("page:5", "html")
+	if !client.Has("auth:1") {
+		t.Errorf("Expected auth:1 to be kept, got keys %v", client.Keys())
+	}
+
+	// keys with a higher priority are never evicted for a lower one
+	client.SetWith("auth:2", "token", WithPriority(10))
+	client.SetWith("auth:3", "token", WithPriority(10))
+	if err := client.Set("page:6", "html"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+	if keys := client.Keys(); len(keys) != 3 {
+		t.Errorf("Expected the 3 auth keys to be kept, got %v", keys)
+	}
+
+	if stats, _ := client.Stats(); stats.Evictions != 5 {
+		t.Errorf("Expected 5 evictions, got %d", stats.Evictions)
+	}
+}
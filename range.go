@@ -0,0 +1,142 @@
+package kvsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+)
+
+// RangeItem is a key read by GetRange with its stored value.
+type RangeItem struct {
+	Key string
+
+	store *SQLite
+	data  []byte
+}
+
+// Decode decodes the value of the item into value, like Get.
+func (i *RangeItem) Decode(value any) error {
+	return i.store.decodeValue(i.Key, i.data, value)
+}
+
+// GetRange returns the unexpired keys from start (inclusive) to end (exclusive) in lexicographic order, with their values,
+// e.g. GetRange("events:2024-01", "events:2024-02") for time-prefixed keys. An empty end means no upper bound.
+// If limit is given, at most limit items are returned, so a range can be consumed in pages by starting
+// the next page right after the last key, i.e. at its key + "\x00".
+func (m *SQLite) GetRange(start, end string, limit ...int) ([]RangeItem, error) {
+	return m.GetRangeContext(context.Background(), start, end, limit...)
+}
+
+// GetRangeContext is like GetRange but honors ctx.
+func (m *SQLite) GetRangeContext(ctx context.Context, start, end string, limit ...int) ([]RangeItem, error) {
+	if err := m.checkAccessAll(false); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT key, " + valueExpr + " FROM kv WHERE key LIKE ? AND " + notReserved + " AND key >= ? AND " + unexpired
+	args := []any{m.Config.Prefix + "%", m.reservedPattern(), m.Config.Prefix + start, m.expiryCutoff()}
+	if end != "" {
+		query += " AND key < ?"
+		args = append(args, m.Config.Prefix+end)
+	}
+	query += " ORDER BY key"
+
+	n := -1
+	if len(limit) > 0 && limit[0] > 0 {
+		n = limit[0]
+	}
+	query += " LIMIT ?"
+	args = append(args, n)
+
+	m.RLock()
+	defer m.RUnlock()
+
+	items := make([]RangeItem, 0)
+	for _, database := range m.databases() {
+		rows, err := m.db().in(database).QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var keyX, value string
+			if err := rows.Scan(&keyX, &value); err != nil {
+				rows.Close()
+				return nil, err
+			}
+
+			items = append(items, RangeItem{Key: keyX[len(m.Config.Prefix):], store: m, data: []byte(value)})
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	// keys may be spread over the attached databases
+	if len(m.Config.Attach) > 0 {
+		sort.Slice(items, func(i, j int) bool { return items[i].Key < items[j].Key })
+		if n >= 0 && len(items) > n {
+			items = items[:n]
+		}
+	}
+
+	return items, nil
+}
+
+// FirstKey returns the lexicographically smallest unexpired key, within the given sub-prefix if any,
+// e.g. FirstKey("events:") for the oldest of time-ordered keys. It returns ErrNotFound if there is none.
+func (m *SQLite) FirstKey(prefix ...string) (string, error) {
+	return m.edgeKey("ASC", prefix)
+}
+
+// LastKey is like FirstKey but returns the largest key, e.g. the watermark of time-ordered keys.
+func (m *SQLite) LastKey(prefix ...string) (string, error) {
+	return m.edgeKey("DESC", prefix)
+}
+
+// prefixEnd bounds the keys starting with a prefix: UTF-8 text never contains the byte 0xff.
+const prefixEnd = "\xff"
+
+func (m *SQLite) edgeKey(direction string, prefix []string) (string, error) {
+	if err := m.checkAccessAll(false); err != nil {
+		return "", err
+	}
+
+	sub := ""
+	if len(prefix) > 0 {
+		sub = prefix[0]
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	found := false
+	var edge string
+	for _, database := range m.databases() {
+		var keyX string
+		err := m.db().in(database).QueryRowContext(context.Background(),
+			"SELECT key FROM kv WHERE key >= ? AND key < ? AND "+notReserved+" AND "+unexpired+" ORDER BY key "+direction+" LIMIT 1",
+			m.Config.Prefix+sub, m.Config.Prefix+sub+prefixEnd, m.reservedPattern(), m.expiryCutoff(),
+		).Scan(&keyX)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		key := keyX[len(m.Config.Prefix):]
+		if !found || (direction == "ASC" && key < edge) || (direction == "DESC" && key > edge) {
+			edge, found = key, true
+		}
+	}
+
+	if !found {
+		return "", ErrNotFound
+	}
+
+	return edge, nil
+}
@@ -0,0 +1,73 @@
+package kvsqlite
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestGetRange(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	for i := 0; i < 30; i++ {
+		client.Set(fmt.Sprintf("user:%04d", 990+i), i)
+	}
+	client.Set("user:1005", "expired", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	items, err := client.GetRange("user:1000", "user:1010")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 9 || items[0].Key != "user:1000" || items[8].Key != "user:1009" {
+		t.Fatalf("Expected user:1000 to user:1009 without the expired key, got %+v", items)
+	}
+
+	var value int
+	if err := items[1].Decode(&value); err != nil || value != 11 {
+		t.Errorf("Expected 11, got %d (%v)", value, err)
+	}
+
+	// pages
+	page, _ := client.GetRange("user:1000", "", 5)
+	if len(page) != 5 || page[4].Key != "user:1004" {
+		t.Fatalf("Expected a page of 5 keys, got %+v", page)
+	}
+	page, _ = client.GetRange(page[4].Key+"\x00", "", 5)
+	if len(page) != 5 || page[0].Key != "user:1006" {
+		t.Errorf("Expected the next page from user:1006, got %+v", page)
+	}
+
+	if items, _ := client.GetRange("user:2000", "user:3000"); len(items) != 0 {
+		t.Errorf("Expected an empty range, got %+v", items)
+	}
+}
+
+func TestFirstKeyLastKey(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	client.Set("a", 1)
+	client.Set("events:2024-01-03", 1)
+	client.Set("events:2024-01-01", 1)
+	client.Set("events:2024-01-02", 1)
+	client.Set("z", 1)
+
+	if key, err := client.FirstKey(); err != nil || key != "a" {
+		t.Errorf("Expected a, got %s (%v)", key, err)
+	}
+	if key, err := client.LastKey(); err != nil || key != "z" {
+		t.Errorf("Expected z, got %s (%v)", key, err)
+	}
+	if key, _ := client.FirstKey("events:"); key != "events:2024-01-01" {
+		t.Errorf("Expected events:2024-01-01, got %s", key)
+	}
+	if key, _ := client.LastKey("events:"); key != "events:2024-01-03" {
+		t.Errorf("Expected events:2024-01-03, got %s", key)
+	}
+	if _, err := client.LastKey("missing:"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package kvsqlite
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Allow reports whether one more event for key is allowed by a sliding window rate limit
+// of limit events per window, and how many events remain in the current window.
+// Allowed events are recorded durably, so the limit holds across restarts and processes.
+// Events which left their window are pruned by every call, for all keys, so idle keys don't keep their rows.
+func (m *SQLite) Allow(key string, limit int, window time.Duration) (bool, int, error) {
+	if limit <= 0 || window <= 0 {
+		return false, 0, errors.New("sqlite: limit and window must be positive")
+	}
+
+	err := m.ensureSchema("ratelimit",
+		"CREATE TABLE IF NOT EXISTS kv_ratelimit (key TEXT NOT NULL, at INTEGER NOT NULL, expires_at INTEGER NOT NULL)",
+		"CREATE INDEX IF NOT EXISTS kv_ratelimit_key_at ON kv_ratelimit (key, at)",
+		"CREATE INDEX IF NOT EXISTS kv_ratelimit_expires_at ON kv_ratelimit (expires_at)",
+	)
+	if err != nil {
+		return false, 0, err
+	}
+
+	ctx := context.Background()
+	keyX, err := m.writeKey(key)
+	if err != nil {
+		return false, 0, err
+	}
+
+	ts := m.now()
+
+	var allowed bool
+	var remaining int
+	err = m.writeTx(ctx, func(db *tracer) error {
+		if _, err := db.ExecContext(ctx, "DELETE FROM kv_ratelimit WHERE key = ? AND at <= ?", keyX, ts-window.Milliseconds()); err != nil {
+			return err
+		}
+
+		// the windows of other keys may differ, their events are pruned by the expiry recorded with them
+		if _, err := db.ExecContext(ctx, "DELETE FROM kv_ratelimit WHERE expires_at <= ?", ts); err != nil {
+			return err
+		}
+
+		var count int
+		if err := db.QueryRowContext(ctx, "SELECT count(*) FROM kv_ratelimit WHERE key = ?", keyX).Scan(&count); err != nil {
+			return err
+		}
+
+		if count >= limit {
+			return nil
+		}
+
+		if _, err := db.ExecContext(ctx, "INSERT INTO kv_ratelimit (key, at, expires_at) VALUES (?, ?, ?)", keyX, ts, ts+window.Milliseconds()); err != nil {
+			return err
+		}
+
+		allowed = true
+		remaining = limit - count - 1
+		return nil
+	})
+
+	return allowed, remaining, err
+}
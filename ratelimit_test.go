@@ -0,0 +1,52 @@
+package kvsqlite
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	path := "/tmp/test-ratelimit.db"
+	os.Remove(path)
+	defer os.Remove(path)
+
+	clock := NewManualClock(time.Now())
+	client, err := New(&SQLiteConfig{
+		Path:   path,
+		Prefix: "go-zoox-test-ratelimit:",
+		Clock:  clock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	for i := 2; i >= 0; i-- {
+		allowed, remaining, err := client.Allow("ip:1", 3, time.Minute)
+		if err != nil || !allowed || remaining != i {
+			t.Fatalf("Expected allowed with %d remaining, got %v %d (%v)", i, allowed, remaining, err)
+		}
+		clock.Add(10 * time.Second)
+	}
+
+	if allowed, _, _ := client.Allow("ip:1", 3, time.Minute); allowed {
+		t.Error("Expected the 4th event in the window to be rejected")
+	}
+
+	// the first event leaves the window
+	clock.Add(31 * time.Second)
+	if allowed, remaining, _ := client.Allow("ip:1", 3, time.Minute); !allowed || remaining != 0 {
+		t.Errorf("Expected an event to be allowed once the window slides, got %v %d", allowed, remaining)
+	}
+
+	// the events of idle keys are pruned once they leave their window
+	client.Allow("ip:2", 3, time.Minute)
+	clock.Add(2 * time.Minute)
+	client.Allow("ip:1", 3, time.Minute)
+
+	var count int
+	if err := client.Core.QueryRow("SELECT count(*) FROM kv_ratelimit WHERE key = ?", client.Config.Prefix+"ip:2").Scan(&count); err != nil || count != 0 {
+		t.Errorf("Expected the events of an idle key to be pruned, got %d (%v)", count, err)
+	}
+}
@@ -0,0 +1,81 @@
+package kvsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// ErrUnscoped is returned by Query and Exec for statements which don't refer to @prefix.
+// It catches statements which forgot the prefix, it doesn't prove that a statement is limited to it.
+var ErrUnscoped = errors.New("sqlite: statement is not scoped to the prefix")
+
+// prefixParam matches the @prefix placeholder of Query and Exec.
+var prefixParam = regexp.MustCompile(`@prefix\b`)
+
+// Query runs a query on the database of the store, with the statistics and debug logs of the store.
+// The statement must be scoped to the prefix with the @prefix placeholder, replaced by the prefix as a string literal,
+// e.g. SELECT key FROM kv WHERE key LIKE @prefix || '%' AND value = ?. The kv tables refer to the tables of the prefix,
+// see Config.Layout. Keys are stored with the prefix and values as encoded by Set, and the schema may change between versions.
+//
+// Requiring @prefix is a best-effort lint against unscoped statements, not a sandbox: the statement runs as given,
+// so a condition like @prefix = @prefix OR 1 still reaches the keys of other prefixes. Don't pass untrusted SQL.
+func (m *SQLite) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if err := m.checkAccessAll(false); err != nil {
+		return nil, err
+	}
+
+	query, err := m.lintScope(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.db().QueryContext(ctx, query, args...)
+}
+
+// Exec runs a statement like Query, as a write of the store: with the write lock and the fencing token
+// of the store. The in-memory tiers are reset afterwards, since the statement may have changed any key of the prefix.
+func (m *SQLite) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if err := m.checkAccessAll(true); err != nil {
+		return nil, err
+	}
+
+	query, err := m.lintScope(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var res sql.Result
+	err = m.write(ctx, func(db *tracer) error {
+		var err error
+		if res, err = db.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+
+		if m.cache != nil {
+			m.cache.reset()
+		}
+		return m.rebuildBloom(ctx, db)
+	})
+
+	return res, err
+}
+
+// DB returns the database of the store, even with Config.HideCore.
+// Statements run on it bypass the store entirely, prefer Query and Exec.
+func (m *SQLite) DB() *sql.DB {
+	return m.core
+}
+
+// lintScope checks that the statement refers to the @prefix placeholder and replaces it with the prefix.
+// It only looks for the placeholder, it doesn't check how the statement uses it.
+func (m *SQLite) lintScope(query string) (string, error) {
+	if !prefixParam.MatchString(query) {
+		return "", ErrUnscoped
+	}
+
+	literal := "'" + strings.ReplaceAll(m.Config.Prefix, "'", "''") + "'"
+	return prefixParam.ReplaceAllLiteralString(query, literal), nil
+}
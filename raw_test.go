@@ -0,0 +1,55 @@
+package kvsqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQueryExec(t *testing.T) {
+	client, err := New(&SQLiteConfig{
+		Path:     "/tmp/test.db",
+		Prefix:   "go-zoox-test-raw:'" + time.Now().String(),
+		HideCore: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if client.Core != nil || client.DB() == nil {
+		t.Fatal("Expected Core to be hidden behind DB")
+	}
+
+	client.Set("a", 1)
+	client.Set("b", 2)
+
+	ctx := context.Background()
+	rows, err := client.Query(ctx, "SELECT substr(key, length(@prefix) + 1) FROM kv WHERE key LIKE @prefix || '%' AND json_extract(value, '$') > ? ORDER BY key", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for rows.Next() {
+		var key string
+		rows.Scan(&key)
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if len(keys) != 1 || keys[0] != "b" {
+		t.Errorf("Expected [b], got %v", keys)
+	}
+
+	if _, err := client.Query(ctx, "SELECT key FROM kv"); !errors.Is(err, ErrUnscoped) {
+		t.Errorf("Expected ErrUnscoped, got %v", err)
+	}
+
+	res, err := client.Exec(ctx, "DELETE FROM kv WHERE key LIKE @prefix || '%' AND json_extract(value, '$') = ?", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 || client.Has("a") || !client.Has("b") {
+		t.Errorf("Expected a to be deleted, got %d rows", n)
+	}
+}
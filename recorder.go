@@ -0,0 +1,86 @@
+package kvsqlite
+
+import (
+	"math"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Recorder records the latency of operations, e.g. into histograms of a metrics system.
+// Record is called synchronously after the operation, so it should not block.
+type Recorder interface {
+	// Record records an operation: get, set or delete, the key prefix (see RecorderConfig.Delimiter),
+	// how long the operation took and the error it returned, nil on success, including misses of get.
+	Record(op string, keyPrefix string, d time.Duration, err error)
+}
+
+// RecorderFunc is a function which implements Recorder.
+type RecorderFunc func(op string, keyPrefix string, d time.Duration, err error)
+
+// Record calls f.
+func (f RecorderFunc) Record(op string, keyPrefix string, d time.Duration, err error) {
+	f(op, keyPrefix, d, err)
+}
+
+// RecorderConfig is the configuration of latency recording, see Config.Recorder.
+type RecorderConfig struct {
+	Recorder Recorder
+
+	// SampleRate is the fraction of operations recorded, e.g. 0.01 for every hundredth operation.
+	// Operations which are not sampled don't read the clock. Default is 1, every operation.
+	SampleRate float64
+
+	// Delimiter ends the key prefix passed to Record, which is the part of the key (without Config.Prefix)
+	// before its first delimiter, "" for keys without delimiter, so prefixes can be used as metric labels.
+	// Default is ":".
+	Delimiter string
+}
+
+type recorder struct {
+	Recorder
+	every     uint64
+	delimiter string
+
+	n uint64
+}
+
+func newRecorder(cfg *RecorderConfig) *recorder {
+	r := &recorder{Recorder: cfg.Recorder, every: 1, delimiter: cfg.Delimiter}
+	if cfg.SampleRate > 0 && cfg.SampleRate < 1 {
+		r.every = uint64(math.Round(1 / cfg.SampleRate))
+	}
+	if r.delimiter == "" {
+		r.delimiter = ":"
+	}
+
+	return r
+}
+
+// recordStart returns the start time of an operation to record, zero if it is not sampled.
+func (m *SQLite) recordStart() time.Time {
+	r := m.recorder
+	if r == nil {
+		return time.Time{}
+	}
+
+	if r.every > 1 && atomic.AddUint64(&r.n, 1)%r.every != 0 {
+		return time.Time{}
+	}
+
+	return time.Now()
+}
+
+// record records the operation on the given key started at start, unless it was not sampled.
+func (m *SQLite) record(op string, key string, start time.Time, err error) {
+	if start.IsZero() {
+		return
+	}
+
+	prefix := ""
+	if i := strings.Index(key, m.recorder.delimiter); i >= 0 {
+		prefix = key[:i]
+	}
+
+	m.recorder.Record(op, prefix, time.Since(start), err)
+}
@@ -0,0 +1,73 @@
+package kvsqlite
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordedOp struct {
+	op     string
+	prefix string
+	err    error
+}
+
+func TestRecorder(t *testing.T) {
+	var mu sync.Mutex
+	var ops []recordedOp
+	recorder := RecorderFunc(func(op string, keyPrefix string, d time.Duration, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		ops = append(ops, recordedOp{op, keyPrefix, err})
+	})
+
+	client, err := New(&SQLiteConfig{
+		Path:     "/tmp/test.db",
+		Prefix:   "go-zoox-test-recorder:" + time.Now().String(),
+		Recorder: &RecorderConfig{Recorder: recorder},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	client.Set("user:1", "alice")
+	var value string
+	client.Get("user:1", &value)
+	client.Get("session", &value)
+	client.Delete("user:1")
+
+	expected := []recordedOp{{"set", "user", nil}, {"get", "user", nil}, {"get", "", nil}, {"delete", "user", nil}}
+	if len(ops) != len(expected) {
+		t.Fatalf("Expected %d recorded operations, got %+v", len(expected), ops)
+	}
+	for i, op := range expected {
+		if ops[i] != op {
+			t.Errorf("Expected %+v, got %+v", op, ops[i])
+		}
+	}
+}
+
+func TestRecorderSampling(t *testing.T) {
+	n := 0
+	client, err := New(&SQLiteConfig{
+		Path:   "/tmp/test.db",
+		Prefix: "go-zoox-test-recorder:" + time.Now().String(),
+		Recorder: &RecorderConfig{
+			Recorder:   RecorderFunc(func(string, string, time.Duration, error) { n++ }),
+			SampleRate: 0.25,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	for i := 0; i < 20; i++ {
+		client.Set("key", i)
+	}
+
+	if n != 5 {
+		t.Errorf("Expected 5 sampled operations, got %d", n)
+	}
+}
@@ -0,0 +1,152 @@
+package kvsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// CorruptPolicy is what Get does with stored values which are corrupt, see ErrCorrupt.
+type CorruptPolicy int
+
+const (
+	// CorruptError returns the CorruptValueError and leaves the value in place.
+	CorruptError CorruptPolicy = iota
+	// CorruptDelete deletes the value, so Get reports a miss and the value can be written again.
+	CorruptDelete
+	// CorruptQuarantine moves the value to the quarantine, see QuarantineList, and reports a miss.
+	CorruptQuarantine
+)
+
+// QuarantinedValue is a corrupt value moved to the quarantine.
+type QuarantinedValue struct {
+	Key string
+
+	// Value is the stored value, as read from the database.
+	Value []byte
+
+	// Err is the error the value failed to decode with.
+	Err string
+
+	QuarantinedAt time.Time
+}
+
+// decodeRead decodes the value read for the given key and, if it is corrupt, applies Config.OnCorrupt.
+// It reports false if the value was repaired away, which the caller treats as a miss.
+func (m *SQLite) decodeRead(ctx context.Context, key string, data []byte, value any) (bool, error) {
+	err := m.decodeValue(key, data, value)
+	if err == nil || !errors.Is(err, ErrCorrupt) || m.Config.OnCorrupt == CorruptError {
+		return true, err
+	}
+
+	repaired, repairErr := m.repair(ctx, key, data, err)
+	if repairErr != nil {
+		return true, repairErr
+	}
+	if !repaired {
+		// the value changed since it was read
+		return true, err
+	}
+
+	return false, nil
+}
+
+// repair deletes or quarantines the corrupt value of the given key, if it is still the stored one.
+func (m *SQLite) repair(ctx context.Context, key string, data []byte, cause error) (bool, error) {
+	quarantine := m.Config.OnCorrupt == CorruptQuarantine
+	if quarantine {
+		if err := m.ensureQuarantine(); err != nil {
+			return false, err
+		}
+	}
+
+	keyX, err := m.storedKey(key)
+	if err != nil {
+		return false, err
+	}
+
+	repaired := false
+	err = m.writeTx(ctx, func(db *tracer) error {
+		db = db.in(m.databaseOf(keyX))
+
+		var stored []byte
+		err := db.QueryRowContext(ctx, "SELECT "+valueExpr+" FROM kv WHERE key = ?", keyX).Scan(&stored)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && string(stored) != string(data)) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if quarantine {
+			if _, err := db.ExecContext(ctx,
+				"INSERT INTO kv_quarantine (key, value, error, quarantined_at) VALUES (?, ?, ?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value, error = excluded.error, quarantined_at = excluded.quarantined_at",
+				keyX, stored, cause.Error(), m.now(),
+			); err != nil {
+				return err
+			}
+		}
+
+		// a corrupt value is removed even if it is immutable
+		if err := unfreeze(ctx, db, keyX); err != nil {
+			return err
+		}
+		if err := m.deleteRow(ctx, db, keyX); err != nil {
+			return err
+		}
+
+		repaired = true
+		return nil
+	})
+	if err != nil || !repaired {
+		return false, err
+	}
+
+	if m.Config.Debug {
+		m.logger().Printf("[sqlite] repaired corrupt value of key %s: %s", key, cause)
+	}
+	m.publish(EventDelete, key)
+	return true, nil
+}
+
+func (m *SQLite) ensureQuarantine() error {
+	return m.ensureSchema("quarantine",
+		"CREATE TABLE IF NOT EXISTS kv_quarantine (key TEXT PRIMARY KEY, value BLOB, error TEXT NOT NULL, quarantined_at INTEGER NOT NULL)",
+	)
+}
+
+// QuarantineList returns the values of the prefix moved to the quarantine, see CorruptQuarantine, by key.
+func (m *SQLite) QuarantineList() ([]QuarantinedValue, error) {
+	if err := m.checkAccessAll(false); err != nil {
+		return nil, err
+	}
+
+	if err := m.ensureQuarantine(); err != nil {
+		return nil, err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	rows, err := m.db().Query("SELECT key, value, error, quarantined_at FROM kv_quarantine WHERE key LIKE ? ORDER BY key", m.Config.Prefix+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make([]QuarantinedValue, 0)
+	for rows.Next() {
+		var v QuarantinedValue
+		var at int64
+		if err := rows.Scan(&v.Key, &v.Value, &v.Err, &at); err != nil {
+			return nil, err
+		}
+
+		v.Key = v.Key[len(m.Config.Prefix):]
+		v.QuarantinedAt = time.UnixMilli(at)
+		values = append(values, v)
+	}
+
+	return values, rows.Err()
+}
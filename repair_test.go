@@ -0,0 +1,72 @@
+package kvsqlite
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestOnCorrupt(t *testing.T) {
+	path := "/tmp/test-repair.db"
+	os.Remove(path)
+	defer os.Remove(path)
+
+	prefix := "go-zoox-test-repair:" + time.Now().String()
+	open := func(policy CorruptPolicy) *SQLite {
+		client, err := New(&SQLiteConfig{Path: path, Prefix: prefix, OnCorrupt: policy, Transformers: []ValueTransformer{Checksum()}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client
+	}
+
+	client := open(CorruptError)
+	defer client.Close()
+
+	corrupt := func(key string) {
+		client.Set(key, "value")
+		// flip the payload so the checksum doesn't match
+		if _, err := client.Core.Exec("UPDATE kv SET value = substr(value, 1, length(value) - 4) || 'AAA=' WHERE key = ?", prefix+key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var value string
+	corrupt("a")
+	if err := client.Get("a", &value); !errors.Is(err, ErrCorrupt) || !errors.Is(err, ErrChecksum) {
+		t.Errorf("Expected ErrCorrupt, got %v", err)
+	}
+	if !client.Has("a") {
+		t.Error("Expected the corrupt value to be left in place")
+	}
+
+	deleting := open(CorruptDelete)
+	defer deleting.Close()
+	if found, err := deleting.Lookup("a", &value); err != nil || found {
+		t.Errorf("Expected a miss, got %v (%v)", found, err)
+	}
+	if deleting.Has("a") {
+		t.Error("Expected the corrupt value to be deleted")
+	}
+
+	quarantining := open(CorruptQuarantine)
+	defer quarantining.Close()
+	corrupt("b")
+	res, err := quarantining.GetMulti([]string{"b"}, map[string]string{})
+	if err != nil || len(res.Missing) != 1 || len(res.Errors) != 0 {
+		t.Errorf("Expected b to be missing, got %+v (%v)", res, err)
+	}
+
+	quarantined, err := quarantining.QuarantineList()
+	if err != nil || len(quarantined) != 1 || quarantined[0].Key != "b" || quarantined[0].Err == "" || len(quarantined[0].Value) == 0 {
+		t.Errorf("Expected b in the quarantine, got %+v (%v)", quarantined, err)
+	}
+
+	// invalid JSON is corrupt too
+	client.Core.Exec("INSERT INTO kv (key, value, expires_at) VALUES (?, '{', 0)", prefix+"c")
+	if err := client.Get("c", &value); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Expected ErrCorrupt, got %v", err)
+	}
+	client.Delete("c")
+}
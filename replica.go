@@ -0,0 +1,93 @@
+package kvsqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// MetaHeartbeat is the meta setting holding the time of the last heartbeat of the primary, in milliseconds.
+const MetaHeartbeat = "heartbeat"
+
+// ErrStale is returned by CheckFreshness when the replica lags behind its primary for too long.
+var ErrStale = errors.New("sqlite: replica is stale")
+
+// OpenReplica opens a read-only store on a copied or replicated database file at cfg.Path,
+// e.g. to serve reporting tools without loading the primary.
+// Writes fail with ErrReadOnly. The primary should call Heartbeat periodically, so Staleness can tell how far behind the replica is.
+func OpenReplica(cfg *SQLiteConfig) (*SQLite, error) {
+	replica := *cfg
+	replica.ReadOnly = true
+
+	return New(&replica)
+}
+
+// checkReadOnly checks that the configuration doesn't enable features which write in the background.
+func checkReadOnly(cfg *SQLiteConfig) error {
+	switch {
+	case cfg.JournalMode != "":
+		return errors.New("sqlite: journal mode can't be set on a read-only store")
+	case cfg.Vacuum != nil:
+		return errors.New("sqlite: vacuum is not supported on a read-only store")
+	case cfg.TrackAccess:
+		return errors.New("sqlite: access tracking is not supported on a read-only store")
+	case cfg.Archive != nil:
+		return errors.New("sqlite: archive is not supported on a read-only store")
+	case cfg.StatsKeys != nil:
+		return errors.New("sqlite: stats keys are not supported on a read-only store")
+	case cfg.StatsHistory != nil:
+		return errors.New("sqlite: statistics history is not supported on a read-only store")
+	case cfg.Dependencies != nil:
+		return errors.New("sqlite: dependencies are not supported on a read-only store")
+	}
+
+	return nil
+}
+
+// Heartbeat records the current time in the meta table, for replicas to measure their staleness.
+func (m *SQLite) Heartbeat() error {
+	return m.write(context.Background(), func(db *tracer) error {
+		_, err := db.Exec(
+			"INSERT INTO kv_meta (name, value) VALUES (?, ?) ON CONFLICT(name) DO UPDATE SET value = excluded.value",
+			MetaHeartbeat, strconv.FormatInt(m.now(), 10),
+		)
+		return err
+	})
+}
+
+// Staleness returns how long ago the primary recorded its last heartbeat seen by this store.
+// It returns an error if no heartbeat was ever recorded.
+func (m *SQLite) Staleness() (time.Duration, error) {
+	meta, err := m.Meta()
+	if err != nil {
+		return 0, err
+	}
+
+	value, ok := meta[MetaHeartbeat]
+	if !ok {
+		return 0, errors.New("sqlite: no heartbeat recorded")
+	}
+
+	heartbeat, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("sqlite: invalid heartbeat %s", value)
+	}
+
+	return time.Duration(m.now()-heartbeat) * time.Millisecond, nil
+}
+
+// CheckFreshness returns ErrStale if the staleness exceeds max.
+func (m *SQLite) CheckFreshness(max time.Duration) error {
+	staleness, err := m.Staleness()
+	if err != nil {
+		return err
+	}
+
+	if staleness > max {
+		return fmt.Errorf("%w: %s behind, tolerated %s", ErrStale, staleness, max)
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package kvsqlite
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestOpenReplica(t *testing.T) {
+	path, replicaPath := "/tmp/test-primary.db", "/tmp/test-replica.db"
+	for _, p := range []string{path, replicaPath} {
+		os.Remove(p)
+		defer os.Remove(p)
+	}
+
+	clock := NewManualClock(time.Now())
+	primary, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:", Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer primary.Close()
+
+	primary.Set("key", "value")
+	primary.Set("expiring", "value", time.Second)
+	if err := primary.Heartbeat(); err != nil {
+		t.Fatal(err)
+	}
+	if err := primary.BackupTo(replicaPath); err != nil {
+		t.Fatal(err)
+	}
+
+	replica, err := OpenReplica(&SQLiteConfig{Path: replicaPath, Prefix: "go-zoox-test:", Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replica.Close()
+
+	var value string
+	if replica.Get("key", &value); value != "value" {
+		t.Errorf("Expected value, got %s", value)
+	}
+
+	if err := replica.Set("key", "other"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+	if _, err := replica.NextSequence("orders"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly for sequences, got %v", err)
+	}
+	if n, err := replica.ZCard("board"); err != nil || n != 0 {
+		t.Errorf("Expected sorted sets to be readable, got %d (%v)", n, err)
+	}
+
+	clock.Add(time.Minute)
+	if staleness, _ := replica.Staleness(); staleness != time.Minute {
+		t.Errorf("Expected 1m staleness, got %s", staleness)
+	}
+
+	// expired keys are misses, the replica can't remove them
+	var expired string
+	if err := replica.Get("expiring", &expired); err != nil || expired != "" {
+		t.Errorf("Expected a miss for an expired key, got %q (%v)", expired, err)
+	}
+	if err := replica.CheckFreshness(time.Second); !errors.Is(err, ErrStale) {
+		t.Errorf("Expected ErrStale, got %v", err)
+	}
+	if err := replica.CheckFreshness(time.Hour); err != nil {
+		t.Errorf("Expected fresh, got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package kvsqlite
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// ReservedPrefix starts the keys of the reserved namespace, where the store keeps its own state, e.g. the
+// statistics keys. It is specific to the package, so user keys starting with "__" stay usable.
+// Reserved keys are left out of the operations over many keys, e.g. Keys, Size, ForEach, GetRange or ExportRESP,
+// are kept by Clear, DeleteByPattern and quota evictions, and can't be written nor deleted through the store,
+// so user data and internal state can't collide. They can still be read with Get.
+const ReservedPrefix = "__kvsqlite:"
+
+// ErrReservedKey is returned when writing or deleting a key of the reserved namespace, see ReservedPrefix.
+var ErrReservedKey = errors.New("sqlite: key is reserved")
+
+// notReserved is the SQL condition leaving out the reserved keys, bound to reservedPattern.
+const notReserved = "key NOT GLOB ?"
+
+// reservedPattern returns the glob pattern of the reserved keys of the store.
+func (m *SQLite) reservedPattern() string {
+	return globEscape(m.Config.Prefix+ReservedPrefix) + "*"
+}
+
+// checkReserved returns ErrReservedKey if the given key is reserved.
+func checkReserved(key string) error {
+	if strings.HasPrefix(key, ReservedPrefix) {
+		return ErrReservedKey
+	}
+
+	return nil
+}
+
+// countReserved returns the number of reserved keys, expired or not, in the kv tables of db.
+func (m *SQLite) countReserved(ctx context.Context, db *tracer) (int64, error) {
+	var n int64
+	err := db.QueryRowContext(ctx, "SELECT count(*) FROM kv WHERE key GLOB ?", m.reservedPattern()).Scan(&n)
+	return n, err
+}
@@ -0,0 +1,111 @@
+package kvsqlite
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReservedNamespace(t *testing.T) {
+	path := "/tmp/test-reserved.db"
+
+	for _, layout := range []string{LayoutShared, LayoutTablePerPrefix} {
+		t.Run(layout, func(t *testing.T) {
+			os.Remove(path)
+			defer os.Remove(path)
+
+			client, err := New(&SQLiteConfig{
+				Path:        path,
+				Prefix:      "go-zoox-test:",
+				Layout:      layout,
+				BloomFilter: &BloomConfig{},
+				StatsKeys:   &StatsKeysConfig{Interval: time.Hour},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+
+			if err := client.Set("__kvsqlite:mine", 1); !errors.Is(err, ErrReservedKey) {
+				t.Errorf("Expected ErrReservedKey, got %v", err)
+			}
+			if err := client.Delete("__kvsqlite:stats:sets"); !errors.Is(err, ErrReservedKey) {
+				t.Errorf("Expected ErrReservedKey, got %v", err)
+			}
+
+			client.Set("a", 1)
+			if keys := client.Keys(); len(keys) != 1 || keys[0] != "a" {
+				t.Errorf("Expected only the user keys, got %v", keys)
+			}
+			if size := client.Size(); size != 1 {
+				t.Errorf("Expected size 1, got %d", size)
+			}
+
+			seen := 0
+			client.ForEach(func(key string, value any) { seen++ })
+			if seen != 1 {
+				t.Errorf("Expected ForEach to skip reserved keys, got %d entries", seen)
+			}
+
+			var parallel int64
+			client.ForEachParallel(2, func(key string, raw []byte) error {
+				atomic.AddInt64(&parallel, 1)
+				return nil
+			})
+			if parallel != 1 {
+				t.Errorf("Expected ForEachParallel to skip reserved keys, got %d entries", parallel)
+			}
+
+			if first, err := client.FirstKey(); err != nil || first != "a" {
+				t.Errorf("Expected FirstKey to skip reserved keys, got %q (%v)", first, err)
+			}
+			if items, _ := client.GetRange("", ""); len(items) != 1 {
+				t.Errorf("Expected GetRange to skip reserved keys, got %d items", len(items))
+			}
+			if n, _ := client.ExportRESP(io.Discard); n != 1 {
+				t.Errorf("Expected ExportRESP to skip reserved keys, got %d keys", n)
+			}
+			if next, _ := client.NextToExpire(10); len(next) != 0 {
+				t.Errorf("Expected NextToExpire to skip reserved keys, got %v", next)
+			}
+
+			// only the namespace of the package is reserved
+			if err := client.Set("__typename", "User"); err != nil {
+				t.Errorf("Expected a user key starting with __ to be writable, got %v", err)
+			}
+			if !client.Has("__typename") || client.Size() != 2 {
+				t.Error("Expected a user key starting with __ to be listed")
+			}
+			if err := client.Delete("__typename"); err != nil {
+				t.Errorf("Expected a user key starting with __ to be deletable, got %v", err)
+			}
+
+			if err := client.Clear(); err != nil {
+				t.Fatal(err)
+			}
+			if client.Has("a") {
+				t.Error("Expected Clear to remove the user keys")
+			}
+
+			var sets int64
+			if err := client.Get("__kvsqlite:stats:sets", &sets); err != nil {
+				t.Errorf("Expected Clear to keep the reserved keys, got %v", err)
+			}
+
+			client.Set("b", 1)
+			if n, _ := client.DeleteByPattern("*"); n != 1 {
+				t.Errorf("Expected DeleteByPattern to delete the user key only, got %d", n)
+			}
+			client.Set("b", 1)
+			if n, _ := client.DeleteByPatternBatched("*"); n != 1 {
+				t.Errorf("Expected DeleteByPatternBatched to delete the user key only, got %d", n)
+			}
+			if err := client.Get("__kvsqlite:stats:sets", &sets); err != nil {
+				t.Errorf("Expected deletes by pattern to keep the reserved keys, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package kvsqlite
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidDump is returned by ImportRESP when the input is not a dump of SET commands.
+var ErrInvalidDump = errors.New("sqlite: invalid RESP dump")
+
+// ExportRESP writes the unexpired keys as Redis SET commands in the Redis protocol (RESP),
+// with PX for the remaining TTL, and returns the number of written keys.
+// Values are the stored JSON, as written by the go-zoox/kv Redis backend, so the dump can be loaded
+// into Redis with redis-cli --pipe. Keys are relative to the prefix of the store.
+// The keys are read in pages, so the dump is not a snapshot of a single point in time.
+// It requires access to all keys, see AccessPolicy.
+func (m *SQLite) ExportRESP(w io.Writer) (int, error) {
+	if err := m.checkAccessAll(false); err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+	bw := bufio.NewWriter(w)
+
+	n := 0
+	after := m.Config.Prefix
+	for {
+		entries, err := m.exportPage(ctx, after)
+		if err != nil {
+			return n, err
+		}
+
+		for _, entry := range entries {
+			value, err := m.restore([]byte(entry.value))
+			if err != nil {
+				return n, err
+			}
+
+			args := []string{"SET", entry.key[len(m.Config.Prefix):], string(value)}
+			if entry.expiresAt > 0 {
+				ttl := entry.expiresAt - m.now()
+				if ttl < 1 {
+					ttl = 1
+				}
+				args = append(args, "PX", strconv.FormatInt(ttl, 10))
+			}
+
+			writeRESP(bw, args)
+			n++
+		}
+
+		if len(entries) < forEachPageSize {
+			break
+		}
+		after = entries[len(entries)-1].key
+	}
+
+	return n, bw.Flush()
+}
+
+type exportEntry struct {
+	key       string
+	value     string
+	expiresAt int64
+}
+
+func (m *SQLite) exportPage(ctx context.Context, after string) ([]exportEntry, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	rows, err := m.db().QueryContext(ctx,
+		"SELECT key, "+valueExpr+", expires_at FROM kv WHERE key LIKE ? AND "+notReserved+" AND key > ? AND "+unexpired+" ORDER BY key LIMIT ?",
+		m.Config.Prefix+"%", m.reservedPattern(), after, m.expiryCutoff(), forEachPageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]exportEntry, 0)
+	for rows.Next() {
+		var entry exportEntry
+		if err := rows.Scan(&entry.key, &entry.value, &entry.expiresAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// writeRESP writes a command as an array of bulk strings, write errors are returned by w.Flush.
+func writeRESP(w *bufio.Writer, args []string) {
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+}
+
+// ImportRESP sets the keys of a dump of Redis SET commands in the Redis protocol (RESP),
+// e.g. written by ExportRESP, and returns the number of imported keys.
+// PX and EX set the TTL of a key. Values which are not JSON are stored as strings.
+func (m *SQLite) ImportRESP(r io.Reader) (int, error) {
+	br := bufio.NewReader(r)
+
+	n := 0
+	for {
+		args, err := readRESP(br)
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+
+		if len(args) < 3 || !strings.EqualFold(args[0], "SET") {
+			return n, fmt.Errorf("%w: unsupported command %q", ErrInvalidDump, args[0])
+		}
+
+		var value any = args[2]
+		if json.Valid([]byte(args[2])) {
+			value = json.RawMessage(args[2])
+		}
+
+		opts := make([]SetOption, 0, 1)
+		for i := 3; i < len(args); i += 2 {
+			if i+1 >= len(args) {
+				return n, fmt.Errorf("%w: missing value of %s", ErrInvalidDump, args[i])
+			}
+
+			ttl, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return n, fmt.Errorf("%w: invalid %s %q", ErrInvalidDump, args[i], args[i+1])
+			}
+
+			switch strings.ToUpper(args[i]) {
+			case "PX":
+				opts = append(opts, WithTTL(time.Duration(ttl)*time.Millisecond))
+			case "EX":
+				opts = append(opts, WithTTL(time.Duration(ttl)*time.Second))
+			default:
+				return n, fmt.Errorf("%w: unsupported option %s", ErrInvalidDump, args[i])
+			}
+		}
+
+		if _, err := m.SetWith(args[1], value, opts...); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// readRESP reads a command, an array of bulk strings, and returns io.EOF at the end of the input.
+func readRESP(r *bufio.Reader) ([]string, error) {
+	line, err := readRESPLine(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("%w: expected an array, got %q", ErrInvalidDump, line)
+	}
+	count, err := strconv.Atoi(line[1:])
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("%w: invalid array length %q", ErrInvalidDump, line)
+	}
+
+	args := make([]string, count)
+	for i := range args {
+		line, err := readRESPLine(r)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+
+		if !strings.HasPrefix(line, "$") {
+			return nil, fmt.Errorf("%w: expected a bulk string, got %q", ErrInvalidDump, line)
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("%w: invalid bulk string length %q", ErrInvalidDump, line)
+		}
+
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if string(buf[size:]) != "\r\n" {
+			return nil, fmt.Errorf("%w: bulk string not terminated by CRLF", ErrInvalidDump)
+		}
+		args[i] = string(buf[:size])
+	}
+
+	return args, nil
+}
+
+func readRESPLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		return "", io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
@@ -0,0 +1,61 @@
+package kvsqlite
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRESP(t *testing.T) {
+	client := createClient()
+	client.Clear()
+	defer client.Clear()
+
+	client.Set("a", "alice")
+	client.Set("b", map[string]int{"n": 1}, time.Hour)
+
+	var dump bytes.Buffer
+	n, err := client.ExportRESP(&dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 exported keys, got %d", n)
+	}
+	if !strings.HasPrefix(dump.String(), "*3\r\n$3\r\nSET\r\n$1\r\na\r\n$7\r\n\"alice\"\r\n*5\r\n") {
+		t.Errorf("Unexpected dump %q", dump.String())
+	}
+
+	client.Clear()
+	n, err = client.ImportRESP(&dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 imported keys, got %d", n)
+	}
+
+	var b map[string]int
+	if err := client.Get("b", &b); err != nil || b["n"] != 1 {
+		t.Errorf("Expected b to round trip, got %v (%v)", b, err)
+	}
+	if expiresAt, _ := client.ExpiresAt("b"); expiresAt.IsZero() {
+		t.Error("Expected the TTL of b to round trip")
+	}
+
+	// values written by other Redis clients are not JSON
+	if _, err := client.ImportRESP(strings.NewReader("*5\r\n$3\r\nSET\r\n$1\r\nc\r\n$5\r\nplain\r\n$2\r\nEX\r\n$2\r\n60\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	var c string
+	if err := client.Get("c", &c); err != nil || c != "plain" {
+		t.Errorf("Expected the plain value as a string, got %q (%v)", c, err)
+	}
+
+	for _, invalid := range []string{"*2\r\n$3\r\nDEL\r\n$1\r\na\r\n", "SET a b\r\n", "*3\r\n$3\r\nSET\r\n$1\r\na\r\n"} {
+		if _, err := client.ImportRESP(strings.NewReader(invalid)); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
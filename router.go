@@ -0,0 +1,450 @@
+package kvsqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-zoox/kv/typing"
+)
+
+// ErrNoStores is returned by the operations of a Router without stores.
+var ErrNoStores = errors.New("sqlite: router has no stores")
+
+// RouterOptions are the options of a Router.
+type RouterOptions struct {
+	// Replicas is the number of points of each store on the hash ring, default is 128.
+	// More points spread the keys more evenly.
+	Replicas int
+}
+
+// Router distributes keys across several independently opened stores, e.g. on different disks,
+// by consistent hashing of the keys over the names of the stores, so adding or removing a store
+// only moves the keys of its share of the ring.
+//
+// After AddStore or RemoveStore, keys are moved to their new store by Rebalance. Until it completes,
+// reads which miss on the new store fall back to the other stores, and deletes apply to all of them.
+type Router struct {
+	mu       sync.RWMutex
+	stores   map[string]*SQLite
+	ring     hashRing
+	replicas int
+
+	// draining are the removed stores which still hold keys, until Rebalance.
+	draining map[string]*SQLite
+	pending  bool
+}
+
+var _ typing.KV = (*Router)(nil)
+
+// NewRouter returns a new Router over the given stores by name.
+// The names place the stores on the ring, so they must stay the same across restarts, whatever their paths.
+func NewRouter(stores map[string]*SQLite, opts ...*RouterOptions) *Router {
+	opt := &RouterOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		opt = opts[0]
+	}
+
+	replicas := opt.Replicas
+	if replicas <= 0 {
+		replicas = 128
+	}
+
+	r := &Router{
+		stores:   make(map[string]*SQLite, len(stores)),
+		replicas: replicas,
+		draining: make(map[string]*SQLite),
+	}
+	for name, store := range stores {
+		r.stores[name] = store
+	}
+	r.ring = newHashRing(r.stores, replicas)
+
+	return r
+}
+
+type ringPoint struct {
+	hash uint64
+	name string
+}
+
+// hashRing is the sorted points of the stores on the ring.
+type hashRing []ringPoint
+
+func newHashRing(stores map[string]*SQLite, replicas int) hashRing {
+	ring := make(hashRing, 0, len(stores)*replicas)
+	for name := range stores {
+		for i := 0; i < replicas; i++ {
+			ring = append(ring, ringPoint{hash: hashKey(fmt.Sprintf("%s#%d", name, i)), name: name})
+		}
+	}
+
+	sort.Slice(ring, func(i, j int) bool {
+		if ring[i].hash == ring[j].hash {
+			return ring[i].name < ring[j].name
+		}
+		return ring[i].hash < ring[j].hash
+	})
+	return ring
+}
+
+// owner returns the name of the store of the given key, the first point at or after its hash,
+// or "" if the ring is empty.
+func (ring hashRing) owner(key string) string {
+	if len(ring) == 0 {
+		return ""
+	}
+
+	h := hashKey(key)
+	i := sort.Search(len(ring), func(i int) bool { return ring[i].hash >= h })
+	if i == len(ring) {
+		i = 0
+	}
+
+	return ring[i].name
+}
+
+func hashKey(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// Store returns the store the given key is routed to, nil if the router has no stores.
+func (r *Router) Store(key string) *SQLite {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.stores[r.ring.owner(key)]
+}
+
+// route returns the name and the store the given key is routed to, or ErrNoStores.
+func (r *Router) route(key string) (string, *SQLite, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	owner := r.ring.owner(key)
+	store := r.stores[owner]
+	if store == nil {
+		return "", nil, ErrNoStores
+	}
+
+	return owner, store, nil
+}
+
+// others returns the stores other than owner which may still hold keys of owner, sorted by name,
+// nil unless a rebalance is pending.
+func (r *Router) others(owner string) []*SQLite {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if !r.pending {
+		return nil
+	}
+
+	stores := make([]*SQLite, 0)
+	for _, name := range r.names(true) {
+		if name != owner {
+			stores = append(stores, r.store(name))
+		}
+	}
+
+	return stores
+}
+
+// names returns the names of the stores sorted, with the draining ones if requested.
+func (r *Router) names(draining bool) []string {
+	names := make([]string, 0, len(r.stores)+len(r.draining))
+	for name := range r.stores {
+		names = append(names, name)
+	}
+	if draining {
+		for name := range r.draining {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func (r *Router) store(name string) *SQLite {
+	if store, ok := r.stores[name]; ok {
+		return store
+	}
+
+	return r.draining[name]
+}
+
+// AddStore adds a store to the ring. Keys routed to it are moved by Rebalance.
+func (r *Router) AddStore(name string, store *SQLite) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.draining, name)
+	r.stores[name] = store
+	r.ring = newHashRing(r.stores, r.replicas)
+	r.pending = true
+}
+
+// RemoveStore removes a store from the ring. Its keys stay readable until Rebalance moves them to the remaining stores,
+// after which the store can be closed.
+func (r *Router) RemoveStore(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	store, ok := r.stores[name]
+	if !ok {
+		return
+	}
+
+	delete(r.stores, name)
+	r.draining[name] = store
+	r.ring = newHashRing(r.stores, r.replicas)
+	r.pending = true
+}
+
+// Rebalance moves the keys which are not in the store they are routed to, with their expiration, and returns how many were moved.
+// A key is copied unless its new store already has it, then deleted from its old store unless it was changed meanwhile.
+// Tags and access statistics are not moved.
+func (r *Router) Rebalance() (int64, error) {
+	ctx := context.Background()
+
+	r.mu.RLock()
+	names := r.names(true)
+	r.mu.RUnlock()
+
+	var moved int64
+	for _, name := range names {
+		r.mu.RLock()
+		src := r.store(name)
+		r.mu.RUnlock()
+
+		keys, err := src.KeysContext(ctx)
+		if err != nil {
+			return moved, err
+		}
+
+		for _, key := range keys {
+			r.mu.RLock()
+			owner := r.ring.owner(key)
+			dst := r.stores[owner]
+			r.mu.RUnlock()
+
+			if owner == name || dst == nil {
+				continue
+			}
+
+			ok, err := moveKey(ctx, key, src, dst)
+			if err != nil {
+				return moved, err
+			}
+			if ok {
+				moved++
+			}
+		}
+	}
+
+	r.mu.Lock()
+	r.draining = make(map[string]*SQLite)
+	r.pending = false
+	r.mu.Unlock()
+
+	return moved, nil
+}
+
+// moveKey moves the row of key from src to dst and reports whether it was copied.
+func moveKey(ctx context.Context, key string, src, dst *SQLite) (bool, error) {
+	srcX, err := src.writeKey(key)
+	if err != nil {
+		return false, err
+	}
+	dstX, err := dst.writeKey(key)
+	if err != nil {
+		return false, err
+	}
+
+	src.RLock()
+	r, err := src.readRow(ctx, src.db(), srcX)
+	src.RUnlock()
+	if err != nil || r == nil {
+		return false, err
+	}
+
+	copied := false
+	err = dst.writeTx(ctx, func(db *tracer) error {
+		if existing, err := dst.readRow(ctx, db, dstX); err != nil || existing != nil {
+			return err
+		}
+
+		copied = true
+		return dst.writeRow(ctx, db, dstX, r)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	removed := false
+	err = src.writeTx(ctx, func(db *tracer) error {
+		current, err := src.readRow(ctx, db, srcX)
+		if err != nil || current == nil || current.value != r.value || current.expiresAt != r.expiresAt {
+			return err
+		}
+
+		removed = true
+		return src.deleteRow(ctx, db, srcX)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if copied {
+		dst.publish(EventSet, key)
+	}
+	if removed {
+		src.publish(EventDelete, key)
+	}
+	return copied, nil
+}
+
+// Set sets the value for the given key in its store.
+func (r *Router) Set(key string, value any, maxAge ...time.Duration) error {
+	_, store, err := r.route(key)
+	if err != nil {
+		return err
+	}
+
+	return store.Set(key, value, maxAge...)
+}
+
+// Get returns the value for the given key from its store, or while a rebalance is pending, from the other stores on miss.
+func (r *Router) Get(key string, value any) error {
+	_, err := r.Lookup(key, value)
+	return err
+}
+
+// Lookup is like Get but also reports whether the key exists.
+func (r *Router) Lookup(key string, value any) (bool, error) {
+	owner, store, err := r.route(key)
+	if err != nil {
+		return false, err
+	}
+
+	found, err := store.Lookup(key, value)
+	if err != nil || found {
+		return found, err
+	}
+
+	for _, other := range r.others(owner) {
+		if found, err := other.Lookup(key, value); err != nil || found {
+			return found, err
+		}
+	}
+
+	return false, nil
+}
+
+// Delete deletes the value for the given key from its store, or while a rebalance is pending, from all stores.
+func (r *Router) Delete(key string) error {
+	owner, store, err := r.route(key)
+	if err != nil {
+		return err
+	}
+
+	if err := store.Delete(key); err != nil {
+		return err
+	}
+
+	for _, other := range r.others(owner) {
+		if err := other.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Has returns true if the given key exists, see Get. It returns false if the router has no stores.
+func (r *Router) Has(key string) bool {
+	owner, store, err := r.route(key)
+	if err != nil {
+		return false
+	}
+
+	if store.Has(key) {
+		return true
+	}
+
+	for _, other := range r.others(owner) {
+		if other.Has(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Keys returns the keys of all stores, sorted.
+func (r *Router) Keys() []string {
+	r.mu.RLock()
+	names := r.names(true)
+	stores := make([]*SQLite, 0, len(names))
+	for _, name := range names {
+		stores = append(stores, r.store(name))
+	}
+	r.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	keys := make([]string, 0)
+	for _, store := range stores {
+		for _, key := range store.Keys() {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// Size returns the number of keys of all stores.
+func (r *Router) Size() int {
+	return len(r.Keys())
+}
+
+// Clear clears all stores.
+func (r *Router) Clear() error {
+	r.mu.RLock()
+	names := r.names(true)
+	stores := make([]*SQLite, 0, len(names))
+	for _, name := range names {
+		stores = append(stores, r.store(name))
+	}
+	r.mu.RUnlock()
+
+	for _, store := range stores {
+		if err := store.Clear(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ForEach calls f with each key of all stores and its value, ordered by key.
+func (r *Router) ForEach(f func(key string, value any)) {
+	for _, key := range r.Keys() {
+		var value any
+		if err := r.Get(key, &value); err != nil {
+			f(key, nil)
+		} else {
+			f(key, value)
+		}
+	}
+}
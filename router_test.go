@@ -0,0 +1,112 @@
+package kvsqlite
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func openRouterStore(t *testing.T, name string) *SQLite {
+	path := "/tmp/test-router-" + name + ".db"
+	os.Remove(path)
+	t.Cleanup(func() { os.Remove(path) })
+
+	store, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return store
+}
+
+func TestRouter(t *testing.T) {
+	a, b, c := openRouterStore(t, "a"), openRouterStore(t, "b"), openRouterStore(t, "c")
+	router := NewRouter(map[string]*SQLite{"a": a, "b": b})
+
+	for i := 0; i < 200; i++ {
+		if err := router.Set(fmt.Sprintf("key:%d", i), i, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if a.Size() == 0 || b.Size() == 0 || a.Size()+b.Size() != 200 {
+		t.Fatalf("Expected the keys to be spread, got %d and %d", a.Size(), b.Size())
+	}
+	if router.Size() != 200 {
+		t.Errorf("Expected 200 keys, got %d", router.Size())
+	}
+
+	var value int
+	if err := router.Get("key:42", &value); err != nil || value != 42 {
+		t.Errorf("Expected 42, got %d (%v)", value, err)
+	}
+
+	router.AddStore("c", c)
+
+	// readable before the keys are moved
+	for i := 0; i < 200; i++ {
+		if found, err := router.Lookup(fmt.Sprintf("key:%d", i), &value); err != nil || !found || value != i {
+			t.Fatalf("Expected key:%d to be readable while rebalancing, got %d (%v)", i, value, err)
+		}
+	}
+
+	moved, err := router.Rebalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved == 0 || int(moved) != c.Size() {
+		t.Errorf("Expected the moved keys to be in c, got %d moved and %d in c", moved, c.Size())
+	}
+	if a.Size()+b.Size()+c.Size() != 200 {
+		t.Errorf("Expected 200 keys in total, got %d", a.Size()+b.Size()+c.Size())
+	}
+
+	for i := 0; i < 200; i++ {
+		key := fmt.Sprintf("key:%d", i)
+		if !router.Store(key).Has(key) {
+			t.Fatalf("Expected %s to be in its store", key)
+		}
+	}
+
+	// the expiration moves with the key
+	for _, key := range c.Keys() {
+		if expiresAt, _ := c.ExpiresAt(key); expiresAt.IsZero() {
+			t.Errorf("Expected %s to keep its expiration", key)
+		}
+		break
+	}
+
+	router.RemoveStore("a")
+	router.Delete("key:1")
+	if router.Has("key:1") {
+		t.Errorf("Expected key:1 to be deleted while rebalancing")
+	}
+
+	if _, err := router.Rebalance(); err != nil {
+		t.Fatal(err)
+	}
+	if a.Size() != 0 || b.Size()+c.Size() != 199 {
+		t.Errorf("Expected a to be drained, got %d, %d and %d", a.Size(), b.Size(), c.Size())
+	}
+}
+
+func TestRouterNoStores(t *testing.T) {
+	router := NewRouter(map[string]*SQLite{})
+	if err := router.Set("key", "value"); !errors.Is(err, ErrNoStores) {
+		t.Errorf("Expected ErrNoStores, got %v", err)
+	}
+
+	var value string
+	if err := router.Get("key", &value); !errors.Is(err, ErrNoStores) {
+		t.Errorf("Expected ErrNoStores, got %v", err)
+	}
+	if err := router.Delete("key"); !errors.Is(err, ErrNoStores) {
+		t.Errorf("Expected ErrNoStores, got %v", err)
+	}
+	if router.Has("key") || router.Store("key") != nil {
+		t.Error("Expected no store for the key")
+	}
+}
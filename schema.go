@@ -0,0 +1,25 @@
+package kvsqlite
+
+// ensureSchema runs the statements creating the tables of a feature once per store handle.
+// The statements must be idempotent, e.g. CREATE TABLE IF NOT EXISTS.
+func (m *SQLite) ensureSchema(name string, statements ...string) error {
+	m.schemasMu.Lock()
+	defer m.schemasMu.Unlock()
+
+	if m.schemas[name] {
+		return nil
+	}
+
+	for _, statement := range statements {
+		if _, err := m.wdb().Exec(statement); err != nil {
+			return m.classify(err)
+		}
+	}
+
+	if m.schemas == nil {
+		m.schemas = make(map[string]bool)
+	}
+	m.schemas[name] = true
+
+	return nil
+}
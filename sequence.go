@@ -0,0 +1,35 @@
+package kvsqlite
+
+import (
+	"context"
+)
+
+// NextSequence increments the sequence of the given name and returns its new value, starting at 1.
+// The increment is a single statement, so values are never handed out twice, even across processes,
+// and they are strictly increasing, e.g. for IDs or event numbers. Sequences are scoped to the prefix
+// and are not removed by Clear. Names are checked like keys, see Config.Access.
+func (m *SQLite) NextSequence(name string) (uint64, error) {
+	nameX, err := m.writeKey(name)
+	if err != nil {
+		return 0, err
+	}
+
+	err = m.ensureSchema("sequence",
+		"CREATE TABLE IF NOT EXISTS kv_sequences (name TEXT PRIMARY KEY, value INTEGER NOT NULL)",
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+
+	var value uint64
+	err = m.write(ctx, func(db *tracer) error {
+		return db.QueryRowContext(ctx,
+			"INSERT INTO kv_sequences (name, value) VALUES (?, 1) ON CONFLICT(name) DO UPDATE SET value = value + 1 RETURNING value",
+			nameX,
+		).Scan(&value)
+	})
+
+	return value, err
+}
@@ -0,0 +1,60 @@
+package kvsqlite
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNextSequence(t *testing.T) {
+	client, err := New(&SQLiteConfig{
+		Path:   "/tmp/test.db",
+		Prefix: "go-zoox-test-sequence:" + time.Now().String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	for i := uint64(1); i <= 3; i++ {
+		if value, err := client.NextSequence("orders"); err != nil || value != i {
+			t.Fatalf("Expected %d, got %d (%v)", i, value, err)
+		}
+	}
+
+	if value, _ := client.NextSequence("events"); value != 1 {
+		t.Errorf("Expected sequences to be independent, got %d", value)
+	}
+
+	var mu sync.Mutex
+	seen := map[uint64]bool{}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := client.NextSequence("orders")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if seen[value] {
+				t.Errorf("Expected %d to be handed out once", value)
+			}
+			seen[value] = true
+		}()
+	}
+	wg.Wait()
+
+	if value, _ := client.NextSequence("orders"); value != 24 {
+		t.Errorf("Expected 24, got %d", value)
+	}
+
+	if _, err := client.NextSequence("__kvsqlite:orders"); !errors.Is(err, ErrReservedKey) {
+		t.Errorf("Expected ErrReservedKey, got %v", err)
+	}
+}
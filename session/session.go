@@ -0,0 +1,142 @@
+// Package session is a session store with rolling expiration built on the KV primitives.
+package session
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/go-zoox/kv/typing"
+)
+
+// ErrNotFound is returned when a session does not exist or is expired.
+var ErrNotFound = errors.New("session: not found")
+
+// Options are the options of a session Store.
+type Options struct {
+	// Prefix is the key prefix of sessions, default is "session:".
+	Prefix string
+
+	// TTL is how long a session lives without activity, default is 24 hours.
+	TTL time.Duration
+
+	// DisableRolling disables extending the TTL of a session each time it is loaded.
+	// Rolling needs a KV which can update the TTL of a key alone, e.g. kvsqlite.SQLite, other KVs don't roll.
+	DisableRolling bool
+}
+
+// expirer is implemented by the KVs which update the TTL of existing keys without rewriting their values.
+type expirer interface {
+	ExpireBatch(keys []string, ttl time.Duration) (int64, error)
+}
+
+// Session is a user session.
+type Session struct {
+	ID        string         `json:"id"`
+	Values    map[string]any `json:"values"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// Store stores sessions in a KV.
+type Store struct {
+	KV      typing.KV
+	Options *Options
+}
+
+// New returns a new session Store.
+func New(kv typing.KV, opts ...*Options) *Store {
+	opt := &Options{}
+	if len(opts) > 0 && opts[0] != nil {
+		*opt = *opts[0]
+	}
+
+	if opt.Prefix == "" {
+		opt.Prefix = "session:"
+	}
+	if opt.TTL <= 0 {
+		opt.TTL = 24 * time.Hour
+	}
+
+	return &Store{
+		KV:      kv,
+		Options: opt,
+	}
+}
+
+func (s *Store) key(id string) string {
+	return s.Options.Prefix + id
+}
+
+// newID returns a random URL safe session ID with 256 bits of entropy.
+func newID() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Create creates and saves a new empty session.
+func (s *Store) Create() (*Session, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	sess := &Session{
+		ID:        id,
+		Values:    map[string]any{},
+		CreatedAt: time.Now(),
+	}
+	if err := s.Save(sess); err != nil {
+		return nil, err
+	}
+
+	return sess, nil
+}
+
+// Load returns the session with the given ID, extending its TTL unless rolling is disabled.
+func (s *Store) Load(id string) (*Session, error) {
+	if id == "" || !s.KV.Has(s.key(id)) {
+		return nil, ErrNotFound
+	}
+
+	sess := &Session{}
+	if err := s.KV.Get(s.key(id), sess); err != nil {
+		return nil, err
+	}
+
+	// expired sessions decode to nothing
+	if sess.ID != id {
+		return nil, ErrNotFound
+	}
+
+	// only the TTL is extended, so a concurrent Save or Destroy is not undone by the copy read here
+	if e, ok := s.KV.(expirer); ok && !s.Options.DisableRolling {
+		n, err := e.ExpireBatch([]string{s.key(id)}, s.Options.TTL)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return nil, ErrNotFound
+		}
+	}
+
+	return sess, nil
+}
+
+// Save saves the session and resets its TTL.
+func (s *Store) Save(sess *Session) error {
+	if sess.ID == "" {
+		return errors.New("session: id is required")
+	}
+
+	return s.KV.Set(s.key(sess.ID), sess, s.Options.TTL)
+}
+
+// Destroy deletes the session with the given ID.
+func (s *Store) Destroy(id string) error {
+	return s.KV.Delete(s.key(id))
+}
@@ -0,0 +1,108 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	kvsqlite "github.com/go-zoox/kv-sqlite"
+)
+
+func TestSession(t *testing.T) {
+	clock := kvsqlite.NewManualClock(time.Now())
+	kv, err := kvsqlite.New(&kvsqlite.SQLiteConfig{
+		Path:   "/tmp/test.db",
+		Prefix: "go-zoox-test-session:",
+		Clock:  clock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer kv.Close()
+	kv.Clear()
+	defer kv.Clear()
+
+	store := New(kv, &Options{TTL: time.Hour})
+
+	sess, err := store.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sess.ID) < 40 {
+		t.Errorf("Expected a long random ID, got %q", sess.ID)
+	}
+
+	sess.Values["user"] = "alice"
+	if err := store.Save(sess); err != nil {
+		t.Fatal(err)
+	}
+
+	// loading within the TTL extends it
+	clock.Add(50 * time.Minute)
+	if _, err := store.Load(sess.ID); err != nil {
+		t.Fatal(err)
+	}
+	clock.Add(50 * time.Minute)
+	loaded, err := store.Load(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Values["user"] != "alice" {
+		t.Errorf("Expected user to be alice, got %v", loaded.Values["user"])
+	}
+
+	clock.Add(2 * time.Hour)
+	if _, err := store.Load(sess.ID); err != ErrNotFound {
+		t.Errorf("Expected idle session to expire, got %v", err)
+	}
+
+	sess, _ = store.Create()
+	store.Destroy(sess.ID)
+	if _, err := store.Load(sess.ID); err != ErrNotFound {
+		t.Errorf("Expected destroyed session to be gone, got %v", err)
+	}
+}
+
+// countingKV counts the writes of values.
+type countingKV struct {
+	*kvsqlite.SQLite
+	sets int
+}
+
+func (kv *countingKV) Set(key string, value any, maxAge ...time.Duration) error {
+	kv.sets++
+	return kv.SQLite.Set(key, value, maxAge...)
+}
+
+func TestSessionRollingKeepsValue(t *testing.T) {
+	clock := kvsqlite.NewManualClock(time.Now())
+	sqlite, err := kvsqlite.New(&kvsqlite.SQLiteConfig{
+		Path:   "/tmp/test.db",
+		Prefix: "go-zoox-test-session-rolling:",
+		Clock:  clock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlite.Close()
+	sqlite.Clear()
+	defer sqlite.Clear()
+
+	kv := &countingKV{SQLite: sqlite}
+	store := New(kv, &Options{TTL: time.Hour})
+
+	sess, _ := store.Create()
+	kv.sets = 0
+
+	clock.Add(50 * time.Minute)
+	if _, err := store.Load(sess.ID); err != nil {
+		t.Fatal(err)
+	}
+	if kv.sets != 0 {
+		t.Errorf("Expected rolling to extend the TTL only, got %d writes", kv.sets)
+	}
+
+	clock.Add(50 * time.Minute)
+	if _, err := store.Load(sess.ID); err != nil {
+		t.Errorf("Expected the TTL to be extended, got %v", err)
+	}
+}
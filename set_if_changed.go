@@ -0,0 +1,14 @@
+package kvsqlite
+
+import (
+	"context"
+	"time"
+)
+
+// SetIfChanged is like Set but skips the write if the key already holds the same encoded value
+// with the same expiration, and reports whether it was written.
+// It saves WAL growth and disk churn for refresh jobs which mostly rewrite identical values.
+// Chunked values are always rewritten.
+func (m *SQLite) SetIfChanged(key string, value any, maxAge ...time.Duration) (bool, error) {
+	return m.set(context.Background(), key, value, append(m.maxAgeOptions(key, maxAge), ifChanged())...)
+}
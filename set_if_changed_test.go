@@ -0,0 +1,32 @@
+package kvsqlite
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetIfChanged(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	if written, err := client.SetIfChanged("changed", "a"); err != nil || !written {
+		t.Fatalf("Expected a new key to be written, got %v (%v)", written, err)
+	}
+
+	if written, _ := client.SetIfChanged("changed", "a"); written {
+		t.Error("Expected an identical value to be skipped")
+	}
+
+	if written, _ := client.SetIfChanged("changed", "b"); !written {
+		t.Error("Expected a changed value to be written")
+	}
+
+	if written, _ := client.SetIfChanged("changed", "b", time.Hour); !written {
+		t.Error("Expected a changed expiration to be written")
+	}
+
+	var value string
+	if client.Get("changed", &value); value != "b" {
+		t.Errorf("Expected b, got %s", value)
+	}
+}
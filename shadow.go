@@ -0,0 +1,354 @@
+package kvsqlite
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-zoox/kv/typing"
+)
+
+// DivergenceKind is the kind of a divergence found by a Shadow store.
+type DivergenceKind int
+
+const (
+	// DivergenceMissing is a key found in the primary store but not in the shadow store.
+	DivergenceMissing DivergenceKind = iota
+	// DivergenceExtra is a key found in the shadow store but not in the primary store.
+	DivergenceExtra
+	// DivergenceValue is a key whose values differ between the stores.
+	DivergenceValue
+	// DivergenceError is a read which failed in the shadow store only.
+	DivergenceError
+)
+
+// Divergence is a read whose result differs between the primary and the shadow store.
+type Divergence struct {
+	Kind DivergenceKind
+	Key  string
+
+	// Primary and Shadow are the JSON encodings of the values decoded from each store, for DivergenceValue.
+	Primary []byte
+	Shadow  []byte
+
+	// Err is the error of the shadow store, for DivergenceError, including its panics.
+	Err error
+}
+
+// ShadowOptions are the options of a Shadow store.
+type ShadowOptions struct {
+	// Percent is the percentage of reads also sent to the shadow store, from 0 (none, the default) to 100.
+	Percent float64
+
+	// Sync compares in the read, so its latency includes the shadow read.
+	// Default is to compare in the background, see Shadow.Wait and Shadow.Close.
+	Sync bool
+
+	// Workers is the number of background comparisons running at once, default is 4.
+	Workers int
+
+	// QueueSize is the number of background comparisons which may be pending, default is 1024.
+	// Comparisons are dropped while the queue is full, so the shadow store never slows down reads, see Shadow.Dropped.
+	QueueSize int
+
+	// OnDivergence is called with each divergence found.
+	OnDivergence func(d *Divergence)
+}
+
+// Shadow validates a migration of a store, e.g. to a new codec, value transformer or schema, against production traffic:
+// reads are served by the primary store, and a percentage of them is replayed on the shadow store and compared.
+// Values are compared by their JSON encoding once decoded, so encoding differences which decode the same don't diverge.
+// Writes only go to the primary store; the shadow store is typically another handle on the same database
+// with the migrated configuration, or a copy kept in sync by other means.
+type Shadow struct {
+	Primary  *SQLite
+	Shadowed typing.KV
+	Options  *ShadowOptions
+
+	queue   chan func() *Divergence
+	stopped sync.WaitGroup
+	dropped int64
+
+	// pending counts the queued and running comparisons, see Wait
+	mu      sync.Mutex
+	idle    *sync.Cond
+	pending int
+	closed  bool
+}
+
+// NewShadow returns a new Shadow store.
+func NewShadow(primary *SQLite, shadowed typing.KV, opts ...*ShadowOptions) *Shadow {
+	opt := &ShadowOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		opt = opts[0]
+	}
+
+	s := &Shadow{
+		Primary:  primary,
+		Shadowed: shadowed,
+		Options:  opt,
+	}
+	s.idle = sync.NewCond(&s.mu)
+
+	if !opt.Sync {
+		workers, size := opt.Workers, opt.QueueSize
+		if workers <= 0 {
+			workers = 4
+		}
+		if size <= 0 {
+			size = 1024
+		}
+
+		s.queue = make(chan func() *Divergence, size)
+		s.stopped.Add(workers)
+		for i := 0; i < workers; i++ {
+			go s.run()
+		}
+	}
+
+	return s
+}
+
+func (s *Shadow) run() {
+	defer s.stopped.Done()
+
+	for fn := range s.queue {
+		s.report(fn)
+
+		s.mu.Lock()
+		s.pending--
+		if s.pending == 0 {
+			s.idle.Broadcast()
+		}
+		s.mu.Unlock()
+	}
+}
+
+// report runs the comparison and reports its divergence. A panic of the shadow store is reported as an error.
+func (s *Shadow) report(fn func() *Divergence) {
+	var d *Divergence
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				d = &Divergence{Kind: DivergenceError, Err: fmt.Errorf("sqlite: shadow store panicked: %v", r)}
+			}
+		}()
+
+		d = fn()
+	}()
+
+	if d != nil && s.Options.OnDivergence != nil {
+		s.Options.OnDivergence(d)
+	}
+}
+
+var _ typing.KV = (*Shadow)(nil)
+
+// sampled reports whether the current read is sent to the shadow store.
+func (s *Shadow) sampled() bool {
+	percent := s.Options.Percent
+	return percent >= 100 || (percent > 0 && rand.Float64()*100 < percent)
+}
+
+// compare runs fn, which compares one read, in the background unless Sync.
+func (s *Shadow) compare(key string, fn func() *Divergence) {
+	withKey := func() *Divergence {
+		d := fn()
+		if d != nil && d.Key == "" {
+			d.Key = key
+		}
+		return d
+	}
+
+	if s.queue == nil {
+		s.report(withKey)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.queue <- withKey:
+		s.pending++
+	default:
+		atomic.AddInt64(&s.dropped, 1)
+	}
+}
+
+// Wait waits for the background comparisons queued so far.
+func (s *Shadow) Wait() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for s.pending > 0 {
+		s.idle.Wait()
+	}
+}
+
+// Dropped returns the number of comparisons dropped because the queue was full.
+func (s *Shadow) Dropped() int64 {
+	return atomic.LoadInt64(&s.dropped)
+}
+
+// Close stops comparing reads and waits for the background comparisons. It doesn't close the stores.
+func (s *Shadow) Close() error {
+	if s.queue == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	s.stopped.Wait()
+	return nil
+}
+
+// has reports whether the key exists in the shadow store, with its error if the store reports them.
+func (s *Shadow) has(key string) (bool, error) {
+	if c, ok := s.Shadowed.(interface {
+		HasContext(ctx context.Context, key string) (bool, error)
+	}); ok {
+		return c.HasContext(context.Background(), key)
+	}
+
+	return s.Shadowed.Has(key), nil
+}
+
+// lookup reads the key from the shadow store into value and reports whether it was found.
+func (s *Shadow) lookup(key string, value any) (bool, error) {
+	if l, ok := s.Shadowed.(interface {
+		Lookup(key string, value any) (bool, error)
+	}); ok {
+		return l.Lookup(key, value)
+	}
+
+	found, err := s.has(key)
+	if err != nil || !found {
+		return false, err
+	}
+
+	return true, s.Shadowed.Get(key, value)
+}
+
+// Get returns the value for the given key from the primary store, and compares it with the shadow store if sampled.
+func (s *Shadow) Get(key string, value any) error {
+	found, err := s.Primary.Lookup(key, value)
+	if err != nil || !s.sampled() {
+		return err
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil
+	}
+
+	// the value is encoded now, the caller may modify it before the comparison runs
+	var primary []byte
+	if found {
+		if primary, err = json.Marshal(value); err != nil {
+			return nil
+		}
+	}
+
+	elemType := rv.Type().Elem()
+	s.compare(key, func() *Divergence {
+		shadow := reflect.New(elemType).Interface()
+		shadowFound, err := s.lookup(key, shadow)
+		if err != nil {
+			return &Divergence{Kind: DivergenceError, Key: key, Err: err}
+		}
+
+		switch {
+		case found && !shadowFound:
+			return &Divergence{Kind: DivergenceMissing, Key: key, Primary: primary}
+		case !found && shadowFound:
+			encoded, _ := json.Marshal(shadow)
+			return &Divergence{Kind: DivergenceExtra, Key: key, Shadow: encoded}
+		case !found:
+			return nil
+		}
+
+		encoded, err := json.Marshal(shadow)
+		if err != nil {
+			return &Divergence{Kind: DivergenceError, Key: key, Err: err}
+		}
+		if !bytes.Equal(primary, encoded) {
+			return &Divergence{Kind: DivergenceValue, Key: key, Primary: primary, Shadow: encoded}
+		}
+
+		return nil
+	})
+
+	return nil
+}
+
+// Has returns true if the given key exists in the primary store, and compares it with the shadow store if sampled.
+func (s *Shadow) Has(key string) bool {
+	found := s.Primary.Has(key)
+	if !s.sampled() {
+		return found
+	}
+
+	s.compare(key, func() *Divergence {
+		shadowFound, err := s.has(key)
+		if err != nil {
+			return &Divergence{Kind: DivergenceError, Key: key, Err: err}
+		}
+
+		switch {
+		case found && !shadowFound:
+			return &Divergence{Kind: DivergenceMissing, Key: key}
+		case !found && shadowFound:
+			return &Divergence{Kind: DivergenceExtra, Key: key}
+		}
+
+		return nil
+	})
+
+	return found
+}
+
+// Set sets the value for the given key in the primary store.
+func (s *Shadow) Set(key string, value any, maxAge ...time.Duration) error {
+	return s.Primary.Set(key, value, maxAge...)
+}
+
+// Delete deletes the value for the given key from the primary store.
+func (s *Shadow) Delete(key string) error {
+	return s.Primary.Delete(key)
+}
+
+// Keys returns the keys of the primary store.
+func (s *Shadow) Keys() []string {
+	return s.Primary.Keys()
+}
+
+// Size returns the number of entries of the primary store.
+func (s *Shadow) Size() int {
+	return s.Primary.Size()
+}
+
+// Clear clears the primary store.
+func (s *Shadow) Clear() error {
+	return s.Primary.Clear()
+}
+
+// ForEach iterates over the entries of the primary store.
+func (s *Shadow) ForEach(f func(key string, value any)) {
+	s.Primary.ForEach(f)
+}
@@ -0,0 +1,159 @@
+package kvsqlite
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/go-zoox/kv/memory"
+	"github.com/go-zoox/kv/typing"
+)
+
+func TestShadow(t *testing.T) {
+	primary := createClient()
+	primary.Clear()
+	defer primary.Clear()
+
+	shadowed, err := New(&SQLiteConfig{Path: "/tmp/test.db", Prefix: "go-zoox-test-shadow:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shadowed.Clear()
+	defer shadowed.Clear()
+
+	var mu sync.Mutex
+	divergences := map[string]DivergenceKind{}
+	shadow := NewShadow(primary, shadowed, &ShadowOptions{
+		Percent: 100,
+		OnDivergence: func(d *Divergence) {
+			mu.Lock()
+			defer mu.Unlock()
+			divergences[d.Key] = d.Kind
+		},
+	})
+
+	primary.Set("same", map[string]int{"a": 1, "b": 2})
+	shadowed.Set("same", map[string]int{"b": 2, "a": 1})
+	primary.Set("differs", "old")
+	shadowed.Set("differs", "new")
+	primary.Set("missing", "value")
+	shadowed.Set("extra", "value")
+
+	for _, key := range []string{"same", "differs", "missing", "extra", "absent"} {
+		var value any
+		if err := shadow.Get(key, &value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	shadow.Wait()
+
+	expected := map[string]DivergenceKind{"differs": DivergenceValue, "missing": DivergenceMissing, "extra": DivergenceExtra}
+	if len(divergences) != len(expected) {
+		t.Errorf("Expected %v, got %v", expected, divergences)
+	}
+	for key, kind := range expected {
+		if got, ok := divergences[key]; !ok || got != kind {
+			t.Errorf("Expected divergence %d for %s, got %d (%v)", kind, key, got, ok)
+		}
+	}
+
+	// reads are served by the primary store
+	var value string
+	if err := shadow.Get("differs", &value); err != nil || value != "old" {
+		t.Errorf("Expected the primary value, got %q (%v)", value, err)
+	}
+	if shadow.Has("extra") {
+		t.Error("Expected Has to be served by the primary store")
+	}
+	shadow.Close()
+}
+
+func TestShadowPercent(t *testing.T) {
+	primary := createClient()
+	primary.Clear()
+	defer primary.Clear()
+
+	shadowed, err := New(&SQLiteConfig{Path: "/tmp/test.db", Prefix: "go-zoox-test-shadow:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shadowed.Clear()
+
+	primary.Set("key", "value")
+
+	compared := 0
+	shadow := NewShadow(primary, shadowed, &ShadowOptions{
+		Percent: 50,
+		Sync:    true,
+		OnDivergence: func(d *Divergence) {
+			compared++
+		},
+	})
+
+	for i := 0; i < 1000; i++ {
+		var value string
+		shadow.Get("key", &value)
+	}
+	if compared < 350 || compared > 650 {
+		t.Errorf("Expected about half of the reads to be compared, got %d", compared)
+	}
+}
+
+// panickingKV panics on every read, like the Has of a failing store.
+type panickingKV struct {
+	typing.KV
+}
+
+func (panickingKV) Has(key string) bool {
+	panic("disk I/O error")
+}
+
+func TestShadowPanic(t *testing.T) {
+	primary := createClient()
+	primary.Clear()
+	defer primary.Clear()
+
+	primary.Set("key", "value")
+
+	var mu sync.Mutex
+	var errs []error
+	shadow := NewShadow(primary, panickingKV{memory.New()}, &ShadowOptions{
+		Percent: 100,
+		OnDivergence: func(d *Divergence) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, d.Err)
+		},
+	})
+	defer shadow.Close()
+
+	var value string
+	shadow.Get("key", &value)
+	shadow.Has("key")
+	shadow.Wait()
+
+	if len(errs) != 2 || errs[0] == nil || errs[1] == nil {
+		t.Errorf("Expected the panics to be reported as errors, got %v", errs)
+	}
+}
+
+func TestShadowOff(t *testing.T) {
+	primary := createClient()
+	primary.Clear()
+	defer primary.Clear()
+
+	primary.Set("key", "value")
+
+	compared := 0
+	shadow := NewShadow(primary, memory.New(), &ShadowOptions{
+		Sync: true,
+		OnDivergence: func(d *Divergence) {
+			compared++
+		},
+	})
+
+	var value string
+	shadow.Get("key", &value)
+	if compared != 0 {
+		t.Errorf("Expected no read to be shadowed by default, got %d", compared)
+	}
+}
@@ -0,0 +1,59 @@
+package kvsqlite
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sizeCache is the last exact count of the keys, see SizeApprox.
+type sizeCache struct {
+	sync.Mutex
+	size       int
+	at         time.Time
+	refreshing bool
+}
+
+func (c *sizeCache) store(size int) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.size, c.at = size, time.Now()
+}
+
+// SizeApprox returns the number of unexpired keys as of the last exact count, so frequent callers
+// such as health endpoints don't scan the table each time. A count older than Config.SizeApproxMaxAge
+// is still returned, and refreshed in the background. Only the first call, if Size wasn't called before, counts synchronously.
+func (m *SQLite) SizeApprox() (int, error) {
+	maxAge := m.Config.SizeApproxMaxAge
+	if maxAge <= 0 {
+		maxAge = time.Minute
+	}
+
+	c := &m.sizes
+	c.Lock()
+	if c.at.IsZero() {
+		c.Unlock()
+		return m.SizeContext(context.Background())
+	}
+
+	size := c.size
+	if time.Since(c.at) > maxAge && !c.refreshing {
+		c.refreshing = true
+		go m.refreshSize()
+	}
+	c.Unlock()
+
+	return size, nil
+}
+
+func (m *SQLite) refreshSize() {
+	defer func() {
+		m.sizes.Lock()
+		m.sizes.refreshing = false
+		m.sizes.Unlock()
+	}()
+
+	// a failed refresh keeps the previous count, the next call retries
+	m.SizeContext(context.Background())
+}
@@ -0,0 +1,52 @@
+package kvsqlite
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSizeApprox(t *testing.T) {
+	client, err := New(&SQLiteConfig{
+		Path:             "/tmp/test.db",
+		Prefix:           "go-zoox-test-size-approx:" + time.Now().String(),
+		SizeApproxMaxAge: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	client.Set("a", 1)
+	client.Set("b", 2)
+
+	if size, err := client.SizeApprox(); err != nil || size != 2 {
+		t.Fatalf("Expected 2, got %d (%v)", size, err)
+	}
+
+	// cached
+	client.Set("c", 3)
+	if size, _ := client.SizeApprox(); size != 2 {
+		t.Errorf("Expected the cached count 2, got %d", size)
+	}
+
+	// stale, refreshed in the background
+	time.Sleep(60 * time.Millisecond)
+	client.SizeApprox()
+	deadline := time.Now().Add(time.Second)
+	for {
+		if size, _ := client.SizeApprox(); size == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the count to be refreshed to 3")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// exact counts refresh it
+	client.Set("d", 4)
+	client.Size()
+	if size, _ := client.SizeApprox(); size != 4 {
+		t.Errorf("Expected 4 after Size, got %d", size)
+	}
+}
@@ -0,0 +1,180 @@
+package kvsqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// DefaultSizeBuckets are the upper bounds in bytes of the buckets of ValueSizeHistogram when none are given.
+var DefaultSizeBuckets = []int64{256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20}
+
+// SizeBucket is the number of values whose size is at most Max bytes, and above the Max of the previous bucket.
+type SizeBucket struct {
+	Max   int64
+	Count int64
+}
+
+// SizeHistogram is the distribution of the sizes of the stored values of unexpired keys, in bytes.
+type SizeHistogram struct {
+	// Buckets are the values by increasing upper bound.
+	Buckets []SizeBucket
+
+	// Beyond is the number of values larger than the last bucket.
+	Beyond int64
+
+	Count int64
+	Sum   int64
+
+	// P50, P95 and Max are the median, 95th percentile and largest sizes.
+	P50 int64
+	P95 int64
+	Max int64
+}
+
+// SizeHistograms are the value size histograms by key prefix, see ValueSizeHistogram.
+type SizeHistograms map[string]*SizeHistogram
+
+// ValueSizeHistogram returns the distribution of the sizes of the stored values by key prefix,
+// the part of the key before its first delimiter, "" for keys without delimiter or if delimiter is "",
+// in buckets with the given increasing upper bounds, DefaultSizeBuckets if none are given,
+// e.g. to spot which entries are ballooning the database. Sizes are those of the encoded, transformed values.
+func (m *SQLite) ValueSizeHistogram(delimiter string, bounds ...int64) (SizeHistograms, error) {
+	if len(bounds) == 0 {
+		bounds = DefaultSizeBuckets
+	}
+	for i := 1; i < len(bounds); i++ {
+		if bounds[i] <= bounds[i-1] {
+			return nil, errors.New("sqlite: size buckets must be increasing")
+		}
+	}
+
+	sizes, err := m.valueSizes(delimiter)
+	if err != nil {
+		return nil, err
+	}
+
+	histograms := make(SizeHistograms, len(sizes))
+	for prefix, values := range sizes {
+		sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+		h := &SizeHistogram{Count: int64(len(values))}
+		for _, bound := range bounds {
+			h.Buckets = append(h.Buckets, SizeBucket{Max: bound})
+		}
+
+		for _, size := range values {
+			h.Sum += size
+
+			i := sort.Search(len(bounds), func(i int) bool { return size <= bounds[i] })
+			if i < len(bounds) {
+				h.Buckets[i].Count++
+			} else {
+				h.Beyond++
+			}
+		}
+
+		h.P50 = percentile(values, 0.5)
+		h.P95 = percentile(values, 0.95)
+		h.Max = values[len(values)-1]
+		histograms[prefix] = h
+	}
+
+	return histograms, nil
+}
+
+// percentile returns the nearest-rank percentile p of the sorted values.
+func percentile(sorted []int64, p float64) int64 {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+
+	return sorted[rank]
+}
+
+// valueSizes returns the sizes of the stored values of the unexpired keys by key prefix.
+func (m *SQLite) valueSizes(delimiter string) (map[string][]int64, error) {
+	if err := m.checkAccessAll(false); err != nil {
+		return nil, err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	sizes := make(map[string][]int64)
+	for _, database := range m.databases() {
+		rows, err := m.db().in(database).QueryContext(context.Background(),
+			"SELECT key, "+valueSizeExpr+" FROM kv WHERE key LIKE ? AND "+unexpired,
+			m.Config.Prefix+"%", m.expiryCutoff(),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var keyX string
+			var size int64
+			if err := rows.Scan(&keyX, &size); err != nil {
+				rows.Close()
+				return nil, err
+			}
+
+			prefix := ""
+			if delimiter != "" {
+				if i := strings.Index(keyX[len(m.Config.Prefix):], delimiter); i >= 0 {
+					prefix = keyX[len(m.Config.Prefix) : len(m.Config.Prefix)+i]
+				}
+			}
+			sizes[prefix] = append(sizes[prefix], size)
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return sizes, nil
+}
+
+// labelEscaper escapes Prometheus label values.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WritePrometheus writes the histograms in the Prometheus text format as the histogram metric name,
+// with cumulative buckets labeled by prefix, e.g. for a /metrics handler.
+func (histograms SizeHistograms) WritePrometheus(w io.Writer, name string) error {
+	prefixes := make([]string, 0, len(histograms))
+	for prefix := range histograms {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	if _, err := fmt.Fprintf(w, "# TYPE %s histogram\n", name); err != nil {
+		return err
+	}
+
+	for _, prefix := range prefixes {
+		h := histograms[prefix]
+		label := `prefix="` + labelEscaper.Replace(prefix) + `"`
+
+		cumulative := int64(0)
+		for _, bucket := range h.Buckets {
+			cumulative += bucket.Count
+			if _, err := fmt.Fprintf(w, "%s_bucket{%s,le=\"%d\"} %d\n", name, label, bucket.Max, cumulative); err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n%s_sum{%s} %d\n%s_count{%s} %d\n",
+			name, label, h.Count, name, label, h.Sum, name, label, h.Count); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
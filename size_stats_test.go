@@ -0,0 +1,61 @@
+package kvsqlite
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValueSizeHistogram(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	for i := 0; i < 20; i++ {
+		client.Set(fmt.Sprintf("page:%d", i), strings.Repeat("x", 100*(i+1)))
+	}
+	client.Set("auth:1", "token")
+	client.Set("flag", true)
+
+	histograms, err := client.ValueSizeHistogram(":", 500, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pages := histograms["page"]
+	if pages == nil || pages.Count != 20 {
+		t.Fatalf("Expected 20 page values, got %+v", pages)
+	}
+	// sizes are the JSON strings, i.e. 100 * n + 2 bytes
+	if pages.Buckets[0].Count != 4 || pages.Buckets[1].Count != 5 || pages.Beyond != 11 {
+		t.Errorf("Expected 4, 5 and 11 beyond, got %+v beyond %d", pages.Buckets, pages.Beyond)
+	}
+	if pages.P50 != 1002 || pages.P95 != 1902 || pages.Max != 2002 {
+		t.Errorf("Expected p50 1002, p95 1902 and max 2002, got %d, %d and %d", pages.P50, pages.P95, pages.Max)
+	}
+
+	if auth := histograms["auth"]; auth == nil || auth.Count != 1 || auth.Max != 7 {
+		t.Errorf("Expected 1 auth value of 7 bytes, got %+v", auth)
+	}
+	if other := histograms[""]; other == nil || other.Count != 1 {
+		t.Errorf("Expected 1 value without prefix, got %+v", other)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := histograms.WritePrometheus(buf, "kv_value_size_bytes"); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`kv_value_size_bytes_bucket{prefix="page",le="1000"} 9`,
+		`kv_value_size_bytes_bucket{prefix="page",le="+Inf"} 20`,
+		`kv_value_size_bytes_count{prefix="auth"} 1`,
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("Expected %s in:\n%s", line, buf.String())
+		}
+	}
+
+	if _, err := client.ValueSizeHistogram("", 10, 5); err == nil {
+		t.Errorf("Expected an error for decreasing buckets")
+	}
+}
@@ -0,0 +1,70 @@
+package kvsqlite
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// checkSplitReadWrite checks that the configuration can be used with Config.SplitReadWrite.
+func checkSplitReadWrite(cfg *SQLiteConfig) error {
+	switch {
+	case cfg.ReadOnly:
+		return errors.New("sqlite: read/write splitting is not supported on a read-only store")
+	case cfg.JournalMode != "" && !strings.EqualFold(cfg.JournalMode, "WAL"):
+		return errors.New("sqlite: read/write splitting requires journal mode WAL")
+	case cfg.MemoryCache != nil:
+		return errors.New("sqlite: read/write splitting is not supported with the memory cache")
+	}
+
+	return nil
+}
+
+// openWriter opens the connection writes run on with Config.SplitReadWrite.
+// It is a single connection, so writes of this handle never contend for the SQLite write lock,
+// and its transactions take the write lock when they begin, instead of failing with SQLITE_BUSY
+// when they upgrade from a read.
+func openWriter(cfg *SQLiteConfig) (*sql.DB, error) {
+	writer, err := openCore(cfg, "_txlock=immediate")
+	if err != nil {
+		return nil, err
+	}
+
+	writer.SetMaxOpenConns(1)
+	writer.SetMaxIdleConns(1)
+	writer.SetConnMaxLifetime(0)
+	writer.SetConnMaxIdleTime(0)
+
+	return writer, nil
+}
+
+// writeCore returns the database writes run on.
+func (m *SQLite) writeCore() *sql.DB {
+	if m.writer != nil {
+		return m.writer
+	}
+
+	return m.core
+}
+
+// lockWrites locks the store for a write and returns the function unlocking it.
+// With Config.SplitReadWrite, writes only exclude each other, so reads proceed on their own connections
+// while a write is running; operations which must exclude both still take the write lock of the store.
+func (m *SQLite) lockWrites() func() {
+	if m.writer == nil {
+		m.Lock()
+		return m.Unlock
+	}
+
+	m.RLock()
+	start := time.Now()
+	m.writeMu.Lock()
+	atomic.AddInt64(&m.counters.lockWait, int64(time.Since(start)))
+
+	return func() {
+		m.writeMu.Unlock()
+		m.RUnlock()
+	}
+}
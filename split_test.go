@@ -0,0 +1,115 @@
+package kvsqlite
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSplitReadWrite(t *testing.T) {
+	path := "/tmp/test-split.db"
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		os.Remove(path + suffix)
+		defer os.Remove(path + suffix)
+	}
+
+	client, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:", SplitReadWrite: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var mode string
+	if err := client.Core.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.EqualFold(mode, "wal") {
+		t.Fatalf("Expected journal mode wal, got %s", mode)
+	}
+
+	if err := client.Set("key", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a read completes while a write transaction is open
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- client.writeTx(context.Background(), func(db *tracer) error {
+			if _, err := db.Exec("UPDATE kv SET value = json_quote('next') WHERE key = ?", client.Config.Prefix+"key"); err != nil {
+				return err
+			}
+
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	read := make(chan string, 1)
+	go func() {
+		var value string
+		client.Get("key", &value)
+		read <- value
+	}()
+
+	select {
+	case value := <-read:
+		if value != "value" {
+			t.Errorf("Expected the committed value, got %s", value)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the read not to wait for the write")
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	var value string
+	if client.Get("key", &value); value != "next" {
+		t.Errorf("Expected next, got %s", value)
+	}
+
+	client.Set("counter", 0)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				if err := client.Set("counter", j); err != nil {
+					errs <- err
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				var v int
+				if err := client.Get("counter", &v); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if _, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:", SplitReadWrite: true, JournalMode: "DELETE"}); err == nil {
+		t.Error("Expected an error with journal mode DELETE")
+	}
+	if _, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:", SplitReadWrite: true, MemoryCache: &MemoryCacheConfig{}}); err == nil {
+		t.Error("Expected an error with the memory cache")
+	}
+}
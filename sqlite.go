@@ -1,10 +1,12 @@
 package kvsqlite
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -13,17 +15,205 @@ import (
 // SQLite is a Key-Value Store in SQLite
 type SQLite struct {
 	sync.RWMutex
+
+	// Core is the database of the store, nil with Config.HideCore.
+	// Statements run on it bypass the statistics, the in-memory tiers and the layout, see Query and Exec.
 	Core   *sql.DB
 	Config *SQLiteConfig
+
+	core *sql.DB
+
+	// writer is the connection writes run on with Config.SplitReadWrite, nil otherwise.
+	writer  *sql.DB
+	writeMu sync.Mutex
+
+	access *accessTracker
+	bloom  *bloomFilter
+	cache  *memoryCache
+
+	// bloomTx are the bloom filter updates of the running transaction, guarded by the write lock.
+	bloomTx *bloomPending
+
+	vacuum *worker
+
+	counters *counters
+	sizes    sizeCache
+	recorder *recorder
+
+	archiver  *worker
+	statsKeys *worker
+	history   *statsHistory
+
+	dependencies *worker
+
+	policiesMu sync.RWMutex
+	policies   []TTLPolicy
+
+	subscriptionsMu sync.RWMutex
+	subscriptions   map[*subscription]struct{}
+
+	schemasMu sync.Mutex
+	schemas   map[string]bool
+
+	// defaultTTL is the store-level default TTL, from Config.DefaultTTL or the meta table.
+	defaultTTL time.Duration
+
+	// epoch is the fencing token held by this handle, guarded by the write lock.
+	epoch int64
+
+	// table is the kv table of the prefix with LayoutTablePerPrefix, "" for the shared kv table.
+	table     string
+	valueType string
 }
 
 // SQLiteConfig is the configuration for Redis
 type SQLiteConfig struct {
-	// Path to the SQLite database file.
+	// Path to the SQLite database file, or the URL of the database with the dialect, e.g. libsql://db-org.turso.io.
 	Path string
 
+	// Dialect is the database engine of the store, default is SQLiteDialect, see LibSQL.
+	Dialect Dialect
+
 	// Prefix is the prefix to use for all keys
 	Prefix string
+
+	// KeySanitizer checks and rewrites the keys given to the store before the prefix is added,
+	// e.g. SanitizeKeys. Keys it rejects fail with its error. Default is to store keys as given.
+	KeySanitizer KeySanitizer
+
+	// Access restricts the keys this handle may read and write by prefix, default is all keys.
+	Access *AccessPolicy
+
+	// ValueColumnType is the declared type of the value column of new databases, BLOB (default) or TEXT.
+	// Existing databases must match it, see MigrateValueColumn.
+	ValueColumnType string
+
+	// JournalMode sets the journal mode of the database, e.g. WAL.
+	// Default is the SQLite default (DELETE).
+	JournalMode string
+
+	// Layout is the storage layout, LayoutShared (default) or LayoutTablePerPrefix.
+	// Changing it doesn't move the keys already stored under Prefix, and MigratePrefix only renames keys within the table of the handle.
+	Layout string
+
+	// SplitReadWrite runs writes on a dedicated connection, in BEGIN IMMEDIATE transactions,
+	// and reads on the pool of Core, without waiting for writes of this handle. It requires WAL,
+	// which is the default JournalMode then, and can't be used with MemoryCache.
+	SplitReadWrite bool
+
+	// HideCore leaves Core nil, so statements can only run through Query and Exec, or DB explicitly.
+	HideCore bool
+
+	// ReadOnly opens the database read-only, e.g. a replica, see OpenReplica.
+	ReadOnly bool
+
+	// Quota limits the number of keys and bytes stored under Prefix.
+	// Default is no limit.
+	Quota *Quota
+
+	// Debug logs every executed statement with its bound parameters and duration.
+	Debug bool
+
+	// DebugRedactKeys redacts keys in debug logs, keeping only the prefix.
+	DebugRedactKeys bool
+
+	// Logger is the logger used for debug logs, default is the standard logger.
+	Logger Logger
+
+	// Clock is the time source used for expiration, default is the system clock.
+	// Inject a ManualClock to test expiration without sleeping.
+	Clock Clock
+
+	// ClockSkew is the tolerated difference between the clocks of processes sharing the database.
+	// Keys are only considered expired ClockSkew after their expiration time,
+	// so a process with a clock ahead of the writer's neither misses nor removes them early.
+	ClockSkew time.Duration
+
+	// Transformers transform encoded values in order before they are written, e.g. Gzip then AESGCM,
+	// and in reverse order when they are read. Values written with other transformers, or none, stay readable
+	// as long as their transformers are configured. Transformed values are opaque to SQLite,
+	// so Append and the other features which process values in SQL can't be used with them.
+	Transformers []ValueTransformer
+
+	// OnCorrupt is what Get, Lookup and GetMulti do with stored values which are corrupt,
+	// i.e. which fail to restore (see Checksum) or aren't valid JSON. Default is CorruptError.
+	OnCorrupt CorruptPolicy
+
+	// StrictDecoding makes Get fail on object fields unknown to the destination struct.
+	StrictDecoding bool
+
+	// UseNumber decodes numbers into interface{} destinations, e.g. in ForEach, as json.Number instead of float64,
+	// so integers above 2^53 such as IDs keep their precision.
+	UseNumber bool
+
+	// LegacyValues reads stored values which are not valid JSON, e.g. rows written by another tool, as raw bytes
+	// instead of corrupt values: into *[]byte, *string or *any (as []byte), see NormalizeLegacy to migrate them.
+	// Truncated JSON values are then read as raw bytes too.
+	LegacyValues bool
+
+	// TTLResolution is the precision of expiration times, e.g. time.Second.
+	// Expiration times are rounded up to it, default is time.Millisecond.
+	TTLResolution time.Duration
+
+	// DefaultTTL is the TTL of new keys set without maxAge nor matching TTL policy.
+	// It is persisted in the database, so later handles opened without it keep using it.
+	DefaultTTL time.Duration
+
+	// TTLPolicies are the default TTLs by key pattern, applied on Set when no explicit maxAge is given.
+	// The first matching policy wins.
+	TTLPolicies []TTLPolicy
+
+	// Vacuum enables incremental auto-vacuum, run in the background when fragmentation exceeds a threshold.
+	// Enabling it on an existing database runs a full VACUUM once.
+	Vacuum *VacuumConfig
+
+	// BloomFilter enables an in-memory bloom filter over keys, so misses of Has and Get skip the database.
+	// It is rebuilt on startup and only valid if this handle is the only writer of its prefix.
+	BloomFilter *BloomConfig
+
+	// MemoryCache enables an in-memory LRU tier of stored values in front of the database.
+	// It is only valid if this handle is the only writer of its prefix.
+	MemoryCache *MemoryCacheConfig
+
+	// LargeValues enables splitting values above a size threshold into chunk rows, reassembled on read.
+	// Chunked values are readable by every handle, whether it enables LargeValues or not.
+	LargeValues *LargeValueConfig
+
+	// Attach attaches more database files to the store, keys are routed to them by prefix.
+	Attach []AttachedDatabase
+
+	// Archive enables moving keys which are not accessed anymore to an archive database.
+	Archive *ArchiveConfig
+
+	// TrackAccess enables per-key access statistics (access count and last access time).
+	TrackAccess bool
+
+	// AccessFlushInterval is how often tracked access statistics are written to the database.
+	// Default is 1 second.
+	AccessFlushInterval time.Duration
+
+	// StatsKeys publishes the store statistics as keys under the prefix, refreshed in the background,
+	// see WriteStatsKeys. Default is not to write them.
+	StatsKeys *StatsKeysConfig
+
+	// StatsHistory persists the counters of the store by window in the meta table, so they survive restarts
+	// and can be compared across deploys, see StatsHistory. Default is not to keep them.
+	StatsHistory *StatsHistoryConfig
+
+	// Dependencies enables links between keys, deleting the dependents of a deleted or expired key,
+	// see Depend. Default is not to cascade.
+	Dependencies *DependencyConfig
+
+	// SizeApproxMaxAge is how old the count returned by SizeApprox may be before it is refreshed, default is 1 minute.
+	SizeApproxMaxAge time.Duration
+
+	// Expvar publishes the store statistics via expvar under this name, e.g. for /debug/vars.
+	// Default is not to publish them.
+	Expvar string
+
+	// Recorder records the latency of get, set and delete operations, sampled.
+	// Default is not to record them.
+	Recorder *RecorderConfig
 }
 
 // New returns a new MemoryKV.
@@ -36,236 +226,1037 @@ func New(cfg *SQLiteConfig) (*SQLite, error) {
 		return nil, errors.New("prefix is required")
 	}
 
-	core, err := sql.Open("sqlite3", cfg.Path)
+	if err := checkDialect(cfg); err != nil {
+		return nil, err
+	}
+
+	core, err := openCore(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create the table if it doesn't exist
-	_, err = core.Exec("CREATE TABLE IF NOT EXISTS kv (key TEXT PRIMARY KEY, value BLOB, expires_at INTEGER)")
+	if cfg.ReadOnly {
+		if err := checkReadOnly(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.SplitReadWrite {
+		if err := checkSplitReadWrite(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	journalMode := cfg.JournalMode
+	if cfg.SplitReadWrite && journalMode == "" {
+		journalMode = "WAL"
+	}
+
+	if journalMode != "" {
+		if err := setJournalMode(core, journalMode); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Vacuum != nil {
+		if err := enableIncrementalVacuum(core); err != nil {
+			return nil, err
+		}
+	}
+
+	valueType, err := valueColumnType(cfg.ValueColumnType)
+	if err != nil {
+		return nil, err
+	}
+
+	table, err := prefixTable(cfg.Layout, cfg.Prefix)
 	if err != nil {
 		return nil, err
 	}
 
-	return &SQLite{
-		Core:   core,
-		Config: cfg,
-	}, nil
+	if cfg.Archive != nil && cfg.Archive.Path == "" {
+		return nil, errors.New("sqlite: archive path is required")
+	}
+
+	if cfg.ReadOnly {
+		if err := checkValueColumn(core, valueType); err != nil {
+			return nil, err
+		}
+	} else if err := createSchema(core, cfg, valueType); err != nil {
+		return nil, err
+	}
+
+	if table != "" && !cfg.ReadOnly {
+		for _, db := range append([]AttachedDatabase{{}}, attachedDatabases(cfg)...) {
+			if err := createPrefixTable(context.Background(), core, db.Name, table, valueType); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	meta, err := openMeta(core, cfg, valueType)
+	if err != nil {
+		return nil, err
+	}
+
+	var writer *sql.DB
+	if cfg.SplitReadWrite {
+		if writer, err = openWriter(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	m := &SQLite{
+		Config:     cfg,
+		core:       core,
+		writer:     writer,
+		defaultTTL: meta.defaultTTL,
+		counters:   &counters{},
+		table:      table,
+		valueType:  valueType,
+	}
+	m.policies = append(m.policies, cfg.TTLPolicies...)
+	if !cfg.HideCore {
+		m.Core = core
+	}
+
+	if cfg.Recorder != nil && cfg.Recorder.Recorder != nil {
+		m.recorder = newRecorder(cfg.Recorder)
+	}
+
+	if cfg.BloomFilter != nil {
+		m.bloom = newBloomFilter(cfg.BloomFilter)
+		if err := m.rebuildBloom(context.Background(), m.db()); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.MemoryCache != nil {
+		m.cache = newMemoryCache(cfg.MemoryCache)
+	}
+
+	if cfg.TrackAccess {
+		m.access = newAccessTracker(m, cfg.AccessFlushInterval)
+	}
+
+	if cfg.Vacuum != nil {
+		m.startVacuum()
+	}
+
+	if cfg.Archive != nil && cfg.Archive.Interval > 0 {
+		m.startArchiver()
+	}
+
+	if cfg.Expvar != "" {
+		m.publishExpvar(cfg.Expvar)
+	}
+
+	if cfg.StatsKeys != nil {
+		m.startStatsKeys()
+	}
+
+	if cfg.StatsHistory != nil {
+		m.startStatsHistory()
+	}
+
+	if cfg.Dependencies != nil {
+		m.startDependencies()
+	}
+
+	return m, nil
 }
 
-func (m *SQLite) getKey(key string) string {
-	return m.Config.Prefix + key
+// createSchema creates the tables of the store if they don't exist and migrates them.
+func createSchema(core *sql.DB, cfg *SQLiteConfig, valueType string) error {
+	// Create the table if it doesn't exist
+	_, err := core.Exec("CREATE TABLE IF NOT EXISTS kv (key TEXT PRIMARY KEY, value " + valueType + ", expires_at INTEGER)")
+	if err != nil {
+		return err
+	}
+
+	if err := checkValueColumn(core, valueType); err != nil {
+		return err
+	}
+
+	_, err = core.Exec("CREATE INDEX IF NOT EXISTS kv_expires_at ON kv (expires_at)")
+	if err != nil {
+		return err
+	}
+
+	_, err = core.Exec("CREATE TABLE IF NOT EXISTS kv_epoch (prefix TEXT PRIMARY KEY, epoch INTEGER NOT NULL)")
+	if err != nil {
+		return err
+	}
+
+	_, err = core.Exec("CREATE TABLE IF NOT EXISTS kv_locks (name TEXT PRIMARY KEY, owner TEXT NOT NULL, expires_at INTEGER NOT NULL)")
+	if err != nil {
+		return err
+	}
+
+	if err := migrate(core, "", "kv"); err != nil {
+		return err
+	}
+
+	for _, statement := range immutableTriggers("", "kv") {
+		if _, err := core.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	if err := createChunkSchema(core, ""); err != nil {
+		return err
+	}
+
+	if err := createTagSchema(core, ""); err != nil {
+		return err
+	}
+
+	if err := createDependencySchema(core); err != nil {
+		return err
+	}
+
+	if err := createZSetSchema(core); err != nil {
+		return err
+	}
+
+	return createAttachedSchemas(core, attachedDatabases(cfg), valueType)
 }
 
-func (m *SQLite) encodeValue(value any) (string, error) {
-	raw, err := json.Marshal(value)
+// columns are the columns added to the kv table after its initial schema.
+var columns = []struct {
+	Name       string
+	Definition string
+}{
+	{"access_count", "INTEGER NOT NULL DEFAULT 0"},
+	{"last_accessed_at", "INTEGER NOT NULL DEFAULT 0"},
+	{"chunks", "INTEGER NOT NULL DEFAULT 0"},
+	{"created_at", "INTEGER NOT NULL DEFAULT 0"},
+	{"immutable", "INTEGER NOT NULL DEFAULT 0"},
+	{"priority", "INTEGER NOT NULL DEFAULT 0"},
+	{"sliding_ttl", "INTEGER NOT NULL DEFAULT 0"},
+}
+
+// migrate adds the missing columns to the given kv table of the given database, "" for main.
+func migrate(core execer, schema string, table string) error {
+	if schema == "" {
+		schema = "main"
+	}
+
+	ctx := context.Background()
+	rows, err := core.QueryContext(ctx, "SELECT name FROM pragma_table_info(?, ?)", table, schema)
 	if err != nil {
+		return err
+	}
+
+	existing := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+
+	for _, column := range columns {
+		if existing[column.Name] {
+			continue
+		}
+
+		if _, err := core.ExecContext(ctx, "ALTER TABLE "+schema+"."+quoteIdent(table)+" ADD COLUMN "+column.Name+" "+column.Definition); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close stops background work and closes the database.
+func (m *SQLite) Close() error {
+	m.stopBackground()
+
+	if m.writer != nil {
+		if err := m.writer.Close(); err != nil {
+			m.core.Close()
+			return err
+		}
+	}
+
+	return m.core.Close()
+}
+
+// write runs fn with the write lock held.
+// When a fencing token is held, fn runs in a transaction which first checks that the token is still current.
+func (m *SQLite) write(ctx context.Context, fn func(db *tracer) error) error {
+	return m.writeWith(ctx, false, fn)
+}
+
+// writeTx is like write but always runs fn in a transaction, for writes made of several statements.
+func (m *SQLite) writeTx(ctx context.Context, fn func(db *tracer) error) error {
+	return m.writeWith(ctx, true, fn)
+}
+
+func (m *SQLite) writeWith(ctx context.Context, transactional bool, fn func(db *tracer) error) error {
+	unlock := m.lockWrites()
+	defer unlock()
+
+	return m.runWrite(ctx, m.writeCore(), transactional, fn)
+}
+
+// runWrite runs the write on core with the write lock held, see writeWith.
+func (m *SQLite) runWrite(ctx context.Context, core writeConn, transactional bool, fn func(db *tracer) error) error {
+	if m.epoch == 0 && !transactional {
+		return m.classify(fn(&tracer{store: m, core: core, table: m.table}))
+	}
+
+	start := time.Now()
+	tx, err := core.BeginTx(ctx, nil)
+	m.sqliteDone(start, m.classify(err))
+	if err != nil {
+		return m.classify(err)
+	}
+
+	committed := false
+	finishBloom := m.deferBloom(core)
+	defer func() { finishBloom(committed) }()
+
+	db := m.tx(tx)
+	if m.epoch != 0 {
+		if err := m.checkEpoch(ctx, db); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := fn(db); err != nil {
+		tx.Rollback()
+		return m.classify(err)
+	}
+
+	start = time.Now()
+	err = m.classify(tx.Commit())
+	m.sqliteDone(start, err)
+	committed = err == nil
+	return err
+}
+
+// stopBackground stops the background goroutines, flushing what they buffered.
+func (m *SQLite) stopBackground() {
+	m.closeSubscriptions()
+
+	if m.Config.Expvar != "" {
+		m.unpublishExpvar(m.Config.Expvar)
+	}
+
+	if m.access != nil {
+		m.access.stop()
+	}
+
+	if m.vacuum != nil {
+		m.vacuum.stop()
+	}
+
+	if m.archiver != nil {
+		m.archiver.stop()
+	}
+
+	if m.statsKeys != nil {
+		m.statsKeys.stop()
+	}
+
+	if m.history != nil {
+		m.stopStatsHistory()
+	}
+
+	if m.dependencies != nil {
+		m.dependencies.stop()
+	}
+}
+
+// getKey returns the key stored in the database for the given key to read, checked by Config.KeySanitizer
+// and Config.Access.
+func (m *SQLite) getKey(key string) (string, error) {
+	if err := m.checkAccess(key, false); err != nil {
 		return "", err
 	}
 
-	return string(raw), nil
+	return m.storedKey(key)
 }
 
-func (m *SQLite) decodeValue(data []byte, value any) error {
-	return json.Unmarshal(data, value)
+// writeKey is like getKey for a key to write.
+func (m *SQLite) writeKey(key string) (string, error) {
+	if err := checkReserved(key); err != nil {
+		return "", err
+	}
+	if err := m.checkAccess(key, true); err != nil {
+		return "", err
+	}
+
+	return m.storedKey(key)
 }
 
-func now() int64 {
-	return time.Now().UnixMilli()
+// storedKey returns the key stored in the database for the given key, checked by Config.KeySanitizer only,
+// for the keys the store maintains itself.
+func (m *SQLite) storedKey(key string) (string, error) {
+	if m.Config.KeySanitizer != nil {
+		sanitized, err := m.Config.KeySanitizer(key)
+		if err != nil {
+			return "", err
+		}
+		key = sanitized
+	}
+
+	return m.Config.Prefix + key, nil
+}
+
+func (m *SQLite) encodeValue(value any) (string, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return m.transform(raw)
 }
 
 // Set sets the value for the given key.
 // If maxAge is greater than 0, then the value will be expired after maxAge miliseconds.
+// If maxAge is not given, the TTL policy matching the key applies, or else the current expiration is kept (see WithKeepTTL),
+// or else for new keys the default TTL of the store applies.
 func (m *SQLite) Set(key string, value any, maxAge ...time.Duration) error {
-	m.Lock()
-	// defer m.Unlock()
+	return m.SetContext(context.Background(), key, value, maxAge...)
+}
 
-	var expiresAt int64
-	if len(maxAge) > 0 {
-		expiresAt = now() + int64(maxAge[0]/time.Millisecond)
-	} else {
-		m.Unlock()
-
-		if m.Has(key) {
-			keyX := m.getKey(key)
-			stmt, err := m.Core.Prepare("SELECT expires_at FROM kv WHERE key = ?")
-			if err != nil {
-				panic(err)
-			}
+// SetContext is like Set but honors ctx.
+func (m *SQLite) SetContext(ctx context.Context, key string, value any, maxAge ...time.Duration) error {
+	_, err := m.set(ctx, key, value, m.maxAgeOptions(key, maxAge)...)
+	return err
+}
 
-			res := stmt.QueryRow(keyX)
-			if res.Err() != nil {
-				panic(res.Err())
-			}
+// set writes the value of the given key with the given options and reports whether it was written.
+func (m *SQLite) set(ctx context.Context, key string, value any, opts ...SetOption) (written bool, err error) {
+	start := m.recordStart()
+	defer func() { m.record("set", key, start, err) }()
 
-			// use origin expiresAt
-			if err := res.Scan(&expiresAt); err != nil {
-				// panic(err)
-				m.RUnlock()
-				return nil
-			}
-		}
+	w, err := m.prepareSet(key, value, opts...)
+	if err != nil {
+		return false, err
+	}
+
+	err = m.writeConcerned(ctx, w.opt.concern, w.transactional(m), func(db *tracer) error {
+		var err error
+		written, err = m.applySet(ctx, db, w)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	atomic.AddInt64(&m.counters.sets, 1)
+	if written {
+		m.publish(EventSet, key)
+	}
+	return written, nil
+}
+
+// setWrite is a write of set, encoded before the write lock is taken.
+type setWrite struct {
+	key    string
+	keyX   string
+	valueX string
+	opt    *setOptions
+}
+
+// transactional reports whether the write is made of several statements.
+func (w *setWrite) transactional(m *SQLite) bool {
+	return m.shouldChunk(len(w.valueX)) || w.opt.nx || w.opt.xx || w.opt.tagged || w.opt.immutable || w.opt.force || w.opt.priority != nil || w.opt.sliding ||
+		(m.Config.Quota != nil && m.Config.Quota.Evict)
+}
 
-		m.Lock()
+// prepareSet encodes the write of the value of the given key with the given options.
+func (m *SQLite) prepareSet(key string, value any, opts ...SetOption) (*setWrite, error) {
+	opt := &setOptions{}
+	for _, o := range opts {
+		o(opt)
 	}
 
-	keyX := m.getKey(key)
-	stmt, err := m.Core.Prepare("INSERT OR REPLACE INTO kv (key, value, expires_at) VALUES (?, ?, ?)")
+	keyX, err := m.writeKey(key)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	valueX, err := m.encodeValue(value)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if opt.ttl == nil && opt.expiresAt == nil && !opt.keepTTL {
+		if ttl, ok := m.policyTTL(key); ok {
+			opt.ttl = &ttl
+		}
+	}
+
+	return &setWrite{key: key, keyX: keyX, valueX: valueX, opt: opt}, nil
+}
+
+// applySet runs the write with db, in a transaction if it is transactional, and reports whether it was written.
+func (m *SQLite) applySet(ctx context.Context, db *tracer, w *setWrite) (bool, error) {
+	keyX, valueX, opt := w.keyX, w.valueX, w.opt
+
+	db = db.in(m.databaseOf(keyX))
+	if opt.nx || opt.xx {
+		var current int64
+		err := db.QueryRowContext(ctx, "SELECT expires_at FROM kv WHERE key = ?", keyX).Scan(&current)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return false, err
+		}
+
+		exists := err == nil && (current == 0 || current >= m.expiryCutoff())
+		if (opt.nx && exists) || (opt.xx && !exists) {
+			return false, nil
+		}
+	}
+
+	// the expiration of new rows, and of existing rows unless the TTL is kept
+	var expiresAt int64
+	if opt.ttl != nil {
+		expiresAt = m.expiresAt(*opt.ttl)
+	} else if opt.expiresAt != nil {
+		expiresAt = m.expiresAtTime(*opt.expiresAt)
+	} else if m.defaultTTL > 0 {
+		expiresAt = m.expiresAt(m.defaultTTL)
+	}
+
+	// immutable keys never expire, see SetImmutable
+	keepTTL := opt.keepTTL && opt.ttl == nil && opt.expiresAt == nil
+	if opt.immutable {
+		expiresAt, keepTTL = 0, false
+	}
+
+	if err := m.checkQuota(ctx, db, keyX, int64(len(valueX)), false, opt.priority); err != nil {
+		return false, err
+	}
+
+	if opt.force {
+		if err := unfreeze(ctx, db, keyX); err != nil {
+			return false, err
+		}
+	}
+
+	m.keyWritten(keyX)
+
+	written, err := m.upsert(ctx, db, keyX, valueX, expiresAt, keepTTL, opt.changedOnly)
+	if err != nil || !written {
+		return false, err
+	}
+
+	if opt.tagged {
+		if err := m.setTags(ctx, db, keyX, opt.tags); err != nil {
+			return false, err
+		}
+	}
+
+	if opt.priority != nil {
+		if _, err := db.ExecContext(ctx, "UPDATE kv SET priority = ? WHERE key = ?", *opt.priority, keyX); err != nil {
+			return false, err
+		}
+	}
+
+	if opt.sliding && !opt.immutable {
+		if _, err := db.ExecContext(ctx, "UPDATE kv SET sliding_ttl = ? WHERE key = ?", opt.ttl.Milliseconds(), keyX); err != nil {
+			return false, err
+		}
+	}
+
+	if opt.immutable {
+		return true, freeze(ctx, db, keyX)
 	}
-	_, err = stmt.Exec(keyX, valueX, expiresAt)
+
+	return true, nil
+}
+
+// upsert writes the row of keyX, in chunks if the value is large, and reports whether it was written.
+// If keepTTL is true, an unexpired row keeps its expiration and expiresAt only applies to new rows.
+// Chunked values must be written in a transaction. If changedOnly is true, see set.
+func (m *SQLite) upsert(ctx context.Context, db *tracer, keyX string, valueX string, expiresAt int64, keepTTL bool, changedOnly bool) (bool, error) {
+	// decided in the statement itself, so a concurrent expiration change can't be lost
+	expires, expiresArgs := "excluded.expires_at", []any{}
+	sliding, slidingArgs := "0", []any{}
+	if keepTTL {
+		expires = "CASE WHEN kv.expires_at > 0 AND kv.expires_at < ? THEN excluded.expires_at ELSE kv.expires_at END"
+		expiresArgs = append(expiresArgs, m.expiryCutoff())
+		sliding = "CASE WHEN kv.expires_at > 0 AND kv.expires_at < ? THEN 0 ELSE kv.sliding_ttl END"
+		slidingArgs = append(slidingArgs, m.expiryCutoff())
+	}
+	set := "value = excluded.value, expires_at = " + expires + ", sliding_ttl = " + sliding + ", chunks = 0"
+
+	if m.shouldChunk(len(valueX)) {
+		args := append(append([]any{keyX, expiresAt, m.now()}, expiresArgs...), slidingArgs...)
+		if _, err := db.ExecContext(ctx, "INSERT INTO kv (key, value, expires_at, chunks, created_at) VALUES (?, 'null', ?, 0, ?) ON CONFLICT(key) DO UPDATE SET "+set, args...); err != nil {
+			return false, err
+		}
+
+		return true, m.writeChunks(ctx, db, keyX, []byte(valueX))
+	}
+
+	query := "INSERT INTO kv (key, value, expires_at, created_at) VALUES (?, ?, ?, ?) ON CONFLICT(key) DO UPDATE SET " + set
+	args := append(append([]any{keyX, valueX, expiresAt, m.now()}, expiresArgs...), slidingArgs...)
+	if changedOnly {
+		query += " WHERE kv.chunks > 0 OR kv.value IS NOT excluded.value OR kv.expires_at IS NOT (" + expires + ") OR (kv.expires_at > 0 AND kv.expires_at < ?)"
+		args = append(args, expiresArgs...)
+		args = append(args, m.expiryCutoff())
+	}
+
+	res, err := db.ExecContext(ctx, query, args...)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	m.Unlock()
-	return nil
+	n, err := res.RowsAffected()
+	return n > 0, err
 }
 
 // Get returns the value for the given key.
 func (m *SQLite) Get(key string, value any) error {
-	m.RLock()
+	return m.GetContext(context.Background(), key, value)
+}
 
-	keyX := m.getKey(key)
-	stmt, err := m.Core.Prepare("SELECT value, expires_at FROM kv WHERE key = ?")
+// GetContext is like Get but honors ctx.
+func (m *SQLite) GetContext(ctx context.Context, key string, value any) error {
+	data, found, err := m.read(ctx, key)
+	if err != nil || !found {
+		return err
+	}
+
+	_, err = m.decodeRead(ctx, key, data, value)
+	return err
+}
+
+// read returns the stored value of the given key, removing it if expired.
+func (m *SQLite) read(ctx context.Context, key string) ([]byte, bool, error) {
+	start := m.recordStart()
+	data, found, err := m.fetch(ctx, key)
+	m.record("get", key, start, err)
+	if err == nil {
+		if found {
+			atomic.AddInt64(&m.counters.hits, 1)
+		} else {
+			atomic.AddInt64(&m.counters.misses, 1)
+		}
+	}
+
+	return data, found, err
+}
+
+func (m *SQLite) fetch(ctx context.Context, key string) ([]byte, bool, error) {
+	keyX, err := m.getKey(key)
 	if err != nil {
-		panic(err)
+		return nil, false, err
+	}
+
+	if !m.mayContain(keyX) {
+		return m.unarchive(ctx, key)
+	}
+
+	if value, ok := m.cacheGet(keyX); ok {
+		if m.access != nil {
+			m.access.touch(keyX)
+		}
+
+		return value, true, nil
 	}
 
-	res := stmt.QueryRow(keyX)
+	m.RLock()
+
+	// expired rows are still selected, without their value, to be removed below
+	res := m.db().in(m.databaseOf(keyX)).QueryRowContext(ctx, "SELECT CASE WHEN "+unexpired+" THEN "+valueExpr+" END, expires_at, "+unexpired+", sliding_ttl FROM kv WHERE key = ?", m.expiryCutoff(), m.expiryCutoff(), keyX)
 	if res.Err() != nil {
-		panic(res.Err())
+		m.RUnlock()
+		return nil, false, m.classify(res.Err())
 	}
 
-	var valueX string
-	var expiresAt int64
-	if err := res.Scan(&valueX, &expiresAt); err != nil {
-		// panic(err)
+	var valueX sql.NullString
+	var expiresAt, sliding int64
+	var alive bool
+	if err := res.Scan(&valueX, &expiresAt, &alive, &sliding); err != nil {
 		m.RUnlock()
-		return nil
+		if errors.Is(err, sql.ErrNoRows) {
+			return m.unarchive(ctx, key)
+		}
+
+		return nil, false, m.classify(err)
+	}
+
+	// populated under the read lock, so no write can invalidate the entry in between
+	if m.cache != nil && alive && sliding == 0 {
+		m.cache.put(keyX, []byte(valueX.String), expiresAt)
 	}
 
 	m.RUnlock()
-	if expiresAt > 0 && expiresAt < now() {
-		m.Delete(key)
+	if !alive {
+		// a read-only store leaves the expired row to the primary
+		if m.Config.ReadOnly {
+			return nil, false, nil
+		}
+
+		removed, err := m.removeExpired(ctx, keyX)
+		if removed {
+			m.publish(EventExpire, key)
+		}
+		return nil, false, err
+	}
+
+	if sliding > 0 && !m.Config.ReadOnly {
+		if err := m.slide(ctx, keyX, sliding, expiresAt); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if m.access != nil {
+		m.access.touch(keyX)
+	}
+
+	return []byte(valueX.String), true, nil
+}
+
+// slidingKey is a key read with a sliding TTL, see WithSlidingTTL.
+type slidingKey struct {
+	keyX      string
+	sliding   int64
+	expiresAt int64
+}
+
+// slide pushes the expiration of keyX to its sliding TTL from now, see slideAll.
+func (m *SQLite) slide(ctx context.Context, keyX string, sliding int64, expiresAt int64) error {
+	return m.slideAll(ctx, []slidingKey{{keyX: keyX, sliding: sliding, expiresAt: expiresAt}})
+}
+
+// slideAll pushes the expirations of the keys read with a sliding TTL in a single write. Read-only stores don't slide.
+func (m *SQLite) slideAll(ctx context.Context, keys []slidingKey) error {
+	moving := make([]slidingKey, 0, len(keys))
+	for _, k := range keys {
+		if m.expiresAt(time.Duration(k.sliding)*time.Millisecond) > k.expiresAt {
+			moving = append(moving, k)
+		}
+	}
+	if len(moving) == 0 || m.Config.ReadOnly {
 		return nil
 	}
 
-	return m.decodeValue([]byte(valueX), value)
+	return m.write(ctx, func(db *tracer) error {
+		for _, k := range moving {
+			if err := m.slideRow(ctx, db, k); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// slideRow pushes the expiration of the key to its sliding TTL from now, unless the key was rewritten
+// since it was read or its expiration wouldn't move at the TTL resolution. It must be called with the write lock held.
+func (m *SQLite) slideRow(ctx context.Context, db *tracer, k slidingKey) error {
+	next := m.expiresAt(time.Duration(k.sliding) * time.Millisecond)
+	if next <= k.expiresAt {
+		return nil
+	}
+
+	_, err := db.in(m.databaseOf(k.keyX)).ExecContext(ctx,
+		"UPDATE kv SET expires_at = ? WHERE key = ? AND sliding_ttl = ? AND expires_at > 0 AND expires_at < ?",
+		next, k.keyX, k.sliding, next,
+	)
+	return err
+}
+
+// removeExpired deletes the row of keyX if it is still expired, it may have been rewritten in between.
+func (m *SQLite) removeExpired(ctx context.Context, keyX string) (bool, error) {
+	var n int64
+	var dependents []string
+	err := m.writeWith(ctx, m.Config.Dependencies != nil, func(db *tracer) error {
+		res, err := db.in(m.databaseOf(keyX)).ExecContext(ctx, "DELETE FROM kv WHERE key = ? AND NOT "+unexpired, keyX, m.expiryCutoff())
+		if err != nil {
+			return err
+		}
+
+		if n, err = res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+
+		m.keyRemoved(keyX)
+		dependents, err = m.cascade(ctx, db, keyX)
+		return err
+	})
+	if err == nil {
+		m.publishDependents(dependents)
+	}
+
+	return n > 0, err
 }
 
 // Delete deletes the value for the given key.
 func (m *SQLite) Delete(key string) error {
-	m.Lock()
-	defer m.Unlock()
+	return m.DeleteContext(context.Background(), key)
+}
 
-	stmt, err := m.Core.Prepare("DELETE FROM kv WHERE key = ?")
-	if err != nil {
-		return err
+// DeleteContext is like Delete but honors ctx.
+func (m *SQLite) DeleteContext(ctx context.Context, key string) error {
+	_, err := m.RemoveContext(ctx, key)
+	return err
+}
+
+// Remove deletes the value for the given key and reports whether it existed.
+func (m *SQLite) Remove(key string) (bool, error) {
+	return m.RemoveContext(context.Background(), key)
+}
+
+// RemoveContext is like Remove but honors ctx.
+func (m *SQLite) RemoveContext(ctx context.Context, key string) (bool, error) {
+	start := m.recordStart()
+	removed, err := m.remove(ctx, key)
+	m.record("delete", key, start, err)
+	if err == nil {
+		atomic.AddInt64(&m.counters.deletes, 1)
 	}
+	if removed {
+		m.publish(EventDelete, key)
+	}
+
+	return removed, err
+}
 
-	_, err = stmt.Exec(m.getKey(key))
+func (m *SQLite) remove(ctx context.Context, key string) (bool, error) {
+	keyX, err := m.writeKey(key)
 	if err != nil {
+		return false, err
+	}
+
+	var n int64
+	var dependents []string
+	err = m.writeWith(ctx, m.Config.Dependencies != nil, func(db *tracer) error {
+		res, err := db.in(m.databaseOf(keyX)).ExecContext(ctx, "DELETE FROM kv WHERE key = ?", keyX)
+		if err != nil {
+			return err
+		}
+
+		if n, err = res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+
+		m.keyRemoved(keyX)
+		dependents, err = m.cascade(ctx, db, keyX)
 		return err
+	})
+	if err == nil {
+		m.publishDependents(dependents)
 	}
 
-	return nil
+	return n > 0, err
 }
 
-// Has returns true if the given key exists in the kv.
+// Has returns true if the given key exists in the kv and is not expired.
 func (m *SQLite) Has(key string) bool {
-	m.RLock()
-	defer m.RUnlock()
-
-	stmt, err := m.Core.Prepare("SELECT 1 FROM kv WHERE key = ?")
+	ok, err := m.HasContext(context.Background(), key)
 	if err != nil {
 		panic(err)
 	}
 
-	res := stmt.QueryRow(m.getKey(key))
-	if res.Err() != nil {
-		panic(res.Err())
+	return ok
+}
+
+// HasContext is like Has but honors ctx and returns the error instead of panicking.
+func (m *SQLite) HasContext(ctx context.Context, key string) (bool, error) {
+	keyX, err := m.getKey(key)
+	if err != nil {
+		return false, err
+	}
+
+	if !m.mayContain(keyX) {
+		return m.hasArchived(ctx, key)
 	}
 
+	m.RLock()
 	var value int
-	if err := res.Scan(&value); err != nil {
-		return false
+	err = m.db().in(m.databaseOf(keyX)).QueryRowContext(ctx, "SELECT 1 FROM kv WHERE key = ? AND "+unexpired, keyX, m.expiryCutoff()).Scan(&value)
+	m.RUnlock()
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return m.hasArchived(ctx, key)
+		}
+
+		return false, err
 	}
 
-	return value > 0
+	return value > 0, nil
 }
 
-// Keys returns the keys of the kv.
+// Keys returns the unexpired keys of the kv, ordered by key.
 func (m *SQLite) Keys() []string {
-	m.RLock()
-	defer m.RUnlock()
+	keys, err := m.KeysContext(context.Background())
+	if err != nil {
+		panic(err)
+	}
 
-	rows, err := m.Core.Query("SELECT key FROM kv where key like ?", m.Config.Prefix+"%")
+	return keys
+}
+
+// KeysContext is like Keys but honors ctx and returns the error instead of panicking.
+func (m *SQLite) KeysContext(ctx context.Context) ([]string, error) {
+	return m.keysBy(ctx, OrderBy{Field: OrderByKey})
+}
+
+// Size returns the number of unexpired elements in the kv.
+func (m *SQLite) Size() int {
+	size, err := m.SizeContext(context.Background())
 	if err != nil {
 		panic(err)
 	}
-	defer rows.Close()
 
-	keys := make([]string, 0)
-	for rows.Next() {
-		var key string
-		if err := rows.Scan(&key); err != nil {
-			panic(err)
-		}
+	return size
+}
 
-		keys = append(keys, key[len(m.Config.Prefix):])
+// SizeContext is like Size but honors ctx and returns the error instead of panicking.
+// The count is exact, and refreshes the one of SizeApprox.
+func (m *SQLite) SizeContext(ctx context.Context) (int, error) {
+	size, err := m.count(ctx)
+	if err != nil {
+		return 0, err
 	}
 
-	return keys
+	m.sizes.store(size)
+	return size, nil
 }
 
-// Size returns the number of elements in the kv.
-func (m *SQLite) Size() int {
+func (m *SQLite) count(ctx context.Context) (int, error) {
+	if err := m.checkAccessAll(false); err != nil {
+		return 0, err
+	}
+
 	m.RLock()
 	defer m.RUnlock()
 
-	res, err := m.Core.Query("SELECT count(*) FROM kv where key like ?", m.Config.Prefix+"%")
-	if err != nil {
-		panic(err)
+	query, args := "SELECT count(*) FROM kv where key like ? AND "+notReserved+" AND "+unexpired, []any{m.Config.Prefix + "%", m.reservedPattern(), m.expiryCutoff()}
+	if m.table != "" {
+		// the table only holds the prefix, so only the expiration index is scanned
+		query, args = "SELECT count(*) FROM kv WHERE "+notReserved+" AND "+unexpired, []any{m.reservedPattern(), m.expiryCutoff()}
 	}
-	defer res.Close()
 
-	var count int
-	res.Next()
-	if err := res.Scan(&count); err != nil {
-		panic(err)
+	var size int
+	for _, database := range m.databases() {
+		var count int
+		if err := m.db().in(database).QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+			return 0, err
+		}
+		size += count
 	}
 
-	return count
+	return size, nil
 }
 
-// Clear removes all elements from the kv.
+// Clear removes all elements from the kv, in a single write, see ClearBatched for large stores.
 func (m *SQLite) Clear() error {
-	m.Lock()
-	defer m.Unlock()
+	return m.ClearContext(context.Background())
+}
 
-	_, err := m.Core.Exec("DELETE FROM kv where key like ?", m.Config.Prefix+"%")
+// ClearContext is like Clear but honors ctx.
+func (m *SQLite) ClearContext(ctx context.Context) error {
+	_, err := m.DeleteAllContext(ctx)
 	return err
 }
 
-// ForEach calls the given function for each key-value pair in the kv.
+// DeleteAll removes all elements from the kv and returns the number of removed elements.
+// The sorted sets of the prefix are removed too, but not counted. See DryRun to check what it would remove first.
+func (m *SQLite) DeleteAll() (int64, error) {
+	return m.DeleteAllContext(context.Background())
+}
+
+// DeleteAllContext is like DeleteAll but honors ctx.
+func (m *SQLite) DeleteAllContext(ctx context.Context) (int64, error) {
+	if err := m.checkAccessAll(true); err != nil {
+		return 0, err
+	}
+
+	var n int64
+	err := m.writeWith(ctx, true, func(db *tracer) error {
+		var kept int64
+		for _, database := range m.databases() {
+			reserved, err := m.countReserved(ctx, db.in(database))
+			if err != nil {
+				return err
+			}
+			kept += reserved
+
+			// the table can only be dropped if it holds no reserved keys
+			if m.table != "" && reserved == 0 {
+				dropped, err := m.dropPrefixTable(ctx, db.in(database))
+				if err != nil {
+					return err
+				}
+
+				n += dropped
+				continue
+			}
+
+			res, err := db.in(database).ExecContext(ctx, "DELETE FROM kv where key like ? AND "+notReserved, m.Config.Prefix+"%", m.reservedPattern())
+			if err != nil {
+				return err
+			}
+
+			affected, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			n += affected
+		}
+
+		// kv_zset is never qualified, it resolves to the main database
+		if _, err := db.ExecContext(ctx, "DELETE FROM kv_zset WHERE key LIKE ?", m.Config.Prefix+"%"); err != nil {
+			return err
+		}
+
+		m.keysCleared()
+		if kept > 0 && m.bloom != nil {
+			return m.rebuildBloom(ctx, db)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	m.publish(EventClear, "")
+	return n, nil
+}
+
+// ForEach calls the given function for each key-value pair in the kv, ordered by key.
 func (m *SQLite) ForEach(f func(string, interface{})) {
-	m.RLock()
-	defer m.RUnlock()
+	m.ForEachContext(context.Background(), f)
+}
+
+// ForEachContext is like ForEach but honors ctx and returns the error.
+func (m *SQLite) ForEachContext(ctx context.Context, f func(string, interface{})) error {
+	keys, err := m.KeysContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return m.forEachKey(ctx, keys, f)
+}
 
-	keys := m.Keys()
+// forEachKey calls f with the value of each of the given keys, nil if it can't be read.
+func (m *SQLite) forEachKey(ctx context.Context, keys []string, f func(string, interface{})) error {
 	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		var value any
-		if err := m.Get(key, &value); err != nil {
+		if err := m.GetContext(ctx, key, &value); err != nil {
 			f(key, nil)
 		} else {
 			f(key, value)
 		}
 	}
+
+	return nil
 }
@@ -21,3 +21,24 @@ func createClient() *SQLite {
 func TestKV(t *testing.T) {
 	test.RunTestCases(t, createClient(), []string{"maxAge"})
 }
+
+func TestRemove(t *testing.T) {
+	client := createClient()
+	client.Clear()
+	defer client.Clear()
+
+	client.Set("key1", "value1")
+	client.Set("key2", "value2")
+	client.Set("key3", "value3")
+
+	if ok, err := client.Remove("key1"); err != nil || !ok {
+		t.Errorf("Expected key1 to be removed, got %v (%v)", ok, err)
+	}
+	if ok, err := client.Remove("key1"); err != nil || ok {
+		t.Errorf("Expected key1 to be already removed, got %v (%v)", ok, err)
+	}
+
+	if n, err := client.DeleteAll(); err != nil || n != 2 {
+		t.Errorf("Expected 2 keys to be removed, got %d (%v)", n, err)
+	}
+}
@@ -0,0 +1,99 @@
+package kvsqlite
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// Stats are the operation statistics of a store handle since it was opened.
+type Stats struct {
+	// Gets is the number of reads, Hits and Misses tell whether they found a value.
+	Gets   int64
+	Hits   int64
+	Misses int64
+
+	// HitRatio is Hits / Gets, 0 without reads.
+	HitRatio float64
+
+	Sets    int64
+	Deletes int64
+
+	// DBSize is the size of the main database file in bytes.
+	DBSize int64
+
+	// LockWait is the total time spent waiting for the store lock, read or write.
+	LockWait time.Duration
+
+	// SQLiteTime is the total time spent inside SQLite running statements and committing transactions,
+	// including waiting for the database lock of other connections or processes.
+	// Iterating the rows of queries is not included.
+	SQLiteTime time.Duration
+
+	// Busy is the number of statements which failed because the database was locked, see ErrBusy.
+	Busy int64
+
+	// Evictions is the number of keys evicted to stay within the quota, see Quota.Evict.
+	Evictions int64
+}
+
+// counters are the operation counters of a handle, updated atomically.
+type counters struct {
+	hits    int64
+	misses  int64
+	sets    int64
+	deletes int64
+
+	lockWait   int64
+	sqliteTime int64
+	busy       int64
+	evictions  int64
+}
+
+// Lock locks the store for writing, the time spent waiting is recorded in Stats.
+func (m *SQLite) Lock() {
+	start := time.Now()
+	m.RWMutex.Lock()
+	atomic.AddInt64(&m.counters.lockWait, int64(time.Since(start)))
+}
+
+// RLock locks the store for reading, the time spent waiting is recorded in Stats.
+func (m *SQLite) RLock() {
+	start := time.Now()
+	m.RWMutex.RLock()
+	atomic.AddInt64(&m.counters.lockWait, int64(time.Since(start)))
+}
+
+// sqliteDone records the time spent in SQLite since start and whether it ended busy.
+func (m *SQLite) sqliteDone(start time.Time, err error) {
+	atomic.AddInt64(&m.counters.sqliteTime, int64(time.Since(start)))
+	if errors.Is(err, ErrBusy) {
+		atomic.AddInt64(&m.counters.busy, 1)
+	}
+}
+
+// Stats returns the operation statistics of the store.
+func (m *SQLite) Stats() (Stats, error) {
+	stats := Stats{
+		Hits:    atomic.LoadInt64(&m.counters.hits),
+		Misses:  atomic.LoadInt64(&m.counters.misses),
+		Sets:    atomic.LoadInt64(&m.counters.sets),
+		Deletes: atomic.LoadInt64(&m.counters.deletes),
+
+		LockWait:   time.Duration(atomic.LoadInt64(&m.counters.lockWait)),
+		SQLiteTime: time.Duration(atomic.LoadInt64(&m.counters.sqliteTime)),
+		Busy:       atomic.LoadInt64(&m.counters.busy),
+		Evictions:  atomic.LoadInt64(&m.counters.evictions),
+	}
+	stats.Gets = stats.Hits + stats.Misses
+	if stats.Gets > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(stats.Gets)
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	err := m.db().QueryRowContext(context.Background(), "SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()").Scan(&stats.DBSize)
+	return stats, err
+}
@@ -0,0 +1,194 @@
+package kvsqlite
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StatsHistoryConfig is the configuration of the statistics history, see Config.StatsHistory.
+type StatsHistoryConfig struct {
+	// Window is the length of the windows the counters are summed in, and how often they are written, default is 1 minute.
+	Window time.Duration
+
+	// Retention is how long windows are kept, default is 7 days.
+	Retention time.Duration
+}
+
+// StatsWindow is the activity of the store in a window of the statistics history, summed over all the handles
+// of the prefix, e.g. the processes of a deployment.
+type StatsWindow struct {
+	Start time.Time
+
+	Gets   int64
+	Hits   int64
+	Misses int64
+
+	// HitRatio is Hits / Gets, 0 without reads.
+	HitRatio float64
+
+	Sets      int64
+	Deletes   int64
+	Busy      int64
+	Evictions int64
+}
+
+// statsWindowMeta starts the names of the windows in the meta table, followed by the prefix and the start.
+const statsWindowMeta = "stats_window:"
+
+// statsSample is a reading of the counters which are persisted.
+type statsSample struct {
+	hits, misses, sets, deletes, busy, evictions int64
+}
+
+// statsHistory writes the counters of the handle to the meta table by window.
+type statsHistory struct {
+	sync.Mutex
+	cfg StatsHistoryConfig
+
+	// last is the sample written last, the next write adds the difference.
+	last   statsSample
+	worker *worker
+}
+
+func (m *SQLite) sampleStats() statsSample {
+	return statsSample{
+		hits:      atomic.LoadInt64(&m.counters.hits),
+		misses:    atomic.LoadInt64(&m.counters.misses),
+		sets:      atomic.LoadInt64(&m.counters.sets),
+		deletes:   atomic.LoadInt64(&m.counters.deletes),
+		busy:      atomic.LoadInt64(&m.counters.busy),
+		evictions: atomic.LoadInt64(&m.counters.evictions),
+	}
+}
+
+func (m *SQLite) startStatsHistory() {
+	cfg := *m.Config.StatsHistory
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = 7 * 24 * time.Hour
+	}
+
+	m.history = &statsHistory{cfg: cfg}
+	m.history.worker = newWorker(cfg.Window, func() {
+		if err := m.FlushStatsHistory(); err != nil && m.Config.Debug {
+			m.logger().Printf("[sqlite] writing statistics history failed: %s", err)
+		}
+	})
+}
+
+func (m *SQLite) stopStatsHistory() {
+	m.history.worker.stop()
+	if err := m.FlushStatsHistory(); err != nil && m.Config.Debug {
+		m.logger().Printf("[sqlite] writing statistics history failed: %s", err)
+	}
+}
+
+// windowName returns the name in the meta table of the window starting at start, in ms.
+// Starts are zero-padded, so names order like them.
+func (m *SQLite) windowName(start int64) string {
+	return fmt.Sprintf("%s%s:%016d", statsWindowMeta, m.Config.Prefix, start)
+}
+
+// FlushStatsHistory adds the counters since the last write to the current window of the statistics history now,
+// and removes the windows past the retention, see Config.StatsHistory. It is done in the background every window.
+func (m *SQLite) FlushStatsHistory() error {
+	h := m.history
+	if h == nil {
+		return nil
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	sample := m.sampleStats()
+	delta := statsSample{
+		hits:      sample.hits - h.last.hits,
+		misses:    sample.misses - h.last.misses,
+		sets:      sample.sets - h.last.sets,
+		deletes:   sample.deletes - h.last.deletes,
+		busy:      sample.busy - h.last.busy,
+		evictions: sample.evictions - h.last.evictions,
+	}
+
+	window := h.cfg.Window.Milliseconds()
+	now := m.now()
+	start := now - now%window
+
+	ctx := context.Background()
+	err := m.writeTx(ctx, func(db *tracer) error {
+		if delta != (statsSample{}) {
+			if _, err := db.ExecContext(ctx,
+				`INSERT INTO kv_meta (name, value) VALUES (?, json_object('hits', ?, 'misses', ?, 'sets', ?, 'deletes', ?, 'busy', ?, 'evictions', ?))
+				ON CONFLICT(name) DO UPDATE SET value = json_object(
+					'hits', json_extract(value, '$.hits') + json_extract(excluded.value, '$.hits'),
+					'misses', json_extract(value, '$.misses') + json_extract(excluded.value, '$.misses'),
+					'sets', json_extract(value, '$.sets') + json_extract(excluded.value, '$.sets'),
+					'deletes', json_extract(value, '$.deletes') + json_extract(excluded.value, '$.deletes'),
+					'busy', json_extract(value, '$.busy') + json_extract(excluded.value, '$.busy'),
+					'evictions', json_extract(value, '$.evictions') + json_extract(excluded.value, '$.evictions'))`,
+				m.windowName(start), delta.hits, delta.misses, delta.sets, delta.deletes, delta.busy, delta.evictions,
+			); err != nil {
+				return err
+			}
+		}
+
+		_, err := db.ExecContext(ctx, "DELETE FROM kv_meta WHERE name GLOB ? AND name < ?",
+			globEscape(statsWindowMeta+m.Config.Prefix+":")+"*", m.windowName(now-h.cfg.Retention.Milliseconds()))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	h.last = sample
+	return nil
+}
+
+// StatsHistory returns the windows of the statistics history of the prefix starting within [from, to), oldest first,
+// see Config.StatsHistory. Windows without activity are left out. The current window includes the activity of this
+// handle up to its last write, see FlushStatsHistory.
+func (m *SQLite) StatsHistory(from, to time.Time) ([]StatsWindow, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	rows, err := m.db().Query(
+		`SELECT name, json_extract(value, '$.hits'), json_extract(value, '$.misses'), json_extract(value, '$.sets'),
+			json_extract(value, '$.deletes'), json_extract(value, '$.busy'), json_extract(value, '$.evictions')
+		FROM kv_meta WHERE name GLOB ? AND name >= ? AND name < ? ORDER BY name`,
+		globEscape(statsWindowMeta+m.Config.Prefix+":")+"*", m.windowName(from.UnixMilli()), m.windowName(to.UnixMilli()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	base := len(m.windowName(0)) - 16
+	windows := make([]StatsWindow, 0)
+	for rows.Next() {
+		var name string
+		var w StatsWindow
+		if err := rows.Scan(&name, &w.Hits, &w.Misses, &w.Sets, &w.Deletes, &w.Busy, &w.Evictions); err != nil {
+			return nil, err
+		}
+
+		start, err := strconv.ParseInt(name[base:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("sqlite: invalid statistics window %s", name)
+		}
+
+		w.Start = time.UnixMilli(start)
+		w.Gets = w.Hits + w.Misses
+		if w.Gets > 0 {
+			w.HitRatio = float64(w.Hits) / float64(w.Gets)
+		}
+		windows = append(windows, w)
+	}
+
+	return windows, rows.Err()
+}
@@ -0,0 +1,86 @@
+package kvsqlite
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatsHistory(t *testing.T) {
+	path := "/tmp/test-stats-history.db"
+	os.Remove(path)
+	defer os.Remove(path)
+
+	clock := NewManualClock(time.UnixMilli(time.Now().UnixMilli() / 60000 * 60000))
+	open := func() *SQLite {
+		client, err := New(&SQLiteConfig{
+			Path:         path,
+			Prefix:       "go-zoox-test:",
+			Clock:        clock,
+			StatsHistory: &StatsHistoryConfig{Window: time.Minute, Retention: time.Hour},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client
+	}
+
+	client := open()
+	var v string
+	client.Set("a", "1")
+	client.Get("a", &v)
+	client.Get("missing", &v)
+	if err := client.FlushStatsHistory(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the counters of a restarted handle add up in the same window
+	client.Close()
+	client = open()
+	defer client.Close()
+
+	client.Get("a", &v)
+	if err := client.FlushStatsHistory(); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Add(time.Minute)
+	client.Set("b", "2")
+	if err := client.FlushStatsHistory(); err != nil {
+		t.Fatal(err)
+	}
+
+	windows, err := client.StatsHistory(clock.Now().Add(-time.Hour), clock.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(windows) != 2 {
+		t.Fatalf("Expected 2 windows, got %+v", windows)
+	}
+	if w := windows[0]; w.Gets != 3 || w.Hits != 2 || w.Sets != 1 || w.HitRatio < 0.66 {
+		t.Errorf("Unexpected first window %+v", w)
+	}
+	if w := windows[1]; w.Sets != 1 || w.Gets != 0 || !w.Start.Equal(windows[0].Start.Add(time.Minute)) {
+		t.Errorf("Unexpected second window %+v", w)
+	}
+
+	meta, err := client.Meta()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name := range meta {
+		if strings.HasPrefix(name, statsWindowMeta) {
+			t.Errorf("Expected only settings in the meta, got %s", name)
+		}
+	}
+
+	// windows past the retention are removed
+	clock.Add(2 * time.Hour)
+	if err := client.FlushStatsHistory(); err != nil {
+		t.Fatal(err)
+	}
+	if windows, _ := client.StatsHistory(time.Time{}, clock.Now().Add(time.Minute)); len(windows) != 0 {
+		t.Errorf("Expected the windows to expire, got %+v", windows)
+	}
+}
@@ -0,0 +1,97 @@
+package kvsqlite
+
+import (
+	"context"
+	"time"
+)
+
+// StatsKeysConfig is the configuration of the statistics keys, see Config.StatsKeys.
+type StatsKeysConfig struct {
+	// Prefix is the prefix of the statistics keys, relative to Config.Prefix, default is __kvsqlite:stats:,
+	// in the reserved namespace, see ReservedPrefix.
+	Prefix string
+
+	// Interval is how often the statistics keys are refreshed, default is 10 seconds.
+	// They expire after three intervals, so the statistics of a stopped handle don't linger.
+	Interval time.Duration
+}
+
+// WriteStatsKeys writes the statistics of the store to the statistics keys now, see Config.StatsKeys:
+// gets, hits, misses, hit_ratio, sets, deletes, db_size, lock_wait_ms, sqlite_time_ms, busy and evictions,
+// e.g. __kvsqlite:stats:hits, so dashboards which can only read the KV interface get them too.
+// Writing them is not counted in the statistics.
+func (m *SQLite) WriteStatsKeys() error {
+	cfg := m.statsKeysConfig()
+
+	stats, err := m.Stats()
+	if err != nil {
+		return err
+	}
+
+	values := map[string]any{
+		"gets":           stats.Gets,
+		"hits":           stats.Hits,
+		"misses":         stats.Misses,
+		"hit_ratio":      stats.HitRatio,
+		"sets":           stats.Sets,
+		"deletes":        stats.Deletes,
+		"db_size":        stats.DBSize,
+		"lock_wait_ms":   stats.LockWait.Milliseconds(),
+		"sqlite_time_ms": stats.SQLiteTime.Milliseconds(),
+		"busy":           stats.Busy,
+		"evictions":      stats.Evictions,
+	}
+
+	rows := make(map[string]*row, len(values))
+	expiresAt := m.expiresAt(3 * cfg.Interval)
+	for name, value := range values {
+		keyX, err := m.storedKey(cfg.Prefix + name)
+		if err != nil {
+			return err
+		}
+
+		valueX, err := m.encodeValue(value)
+		if err != nil {
+			return err
+		}
+		rows[keyX] = &row{value: valueX, expiresAt: expiresAt}
+	}
+
+	return m.writeTx(context.Background(), func(db *tracer) error {
+		for keyX, r := range rows {
+			if err := m.writeRow(context.Background(), db, keyX, r); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// statsKeysConfig returns the configuration of the statistics keys with its defaults.
+func (m *SQLite) statsKeysConfig() StatsKeysConfig {
+	var cfg StatsKeysConfig
+	if m.Config.StatsKeys != nil {
+		cfg = *m.Config.StatsKeys
+	}
+
+	if cfg.Prefix == "" {
+		cfg.Prefix = ReservedPrefix + "stats:"
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Second
+	}
+
+	return cfg
+}
+
+func (m *SQLite) startStatsKeys() {
+	refresh := func() {
+		if err := m.WriteStatsKeys(); err != nil && m.Config.Debug {
+			m.logger().Printf("[sqlite] writing stats keys failed: %s", err)
+		}
+	}
+
+	refresh()
+	m.statsKeys = newWorker(m.statsKeysConfig().Interval, refresh)
+}
@@ -0,0 +1,46 @@
+package kvsqlite
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsKeys(t *testing.T) {
+	client, err := New(&SQLiteConfig{
+		Path:      "/tmp/test.db",
+		Prefix:    "go-zoox-test-stats-keys:" + time.Now().String(),
+		StatsKeys: &StatsKeysConfig{Interval: time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var sets int64
+	if err := client.Get("__kvsqlite:stats:sets", &sets); err != nil || sets != 0 {
+		t.Fatalf("Expected the stats keys to be written on open, got %d (%v)", sets, err)
+	}
+
+	client.Set("key", "value")
+	var value string
+	client.Get("key", &value)
+	client.Get("missing", &value)
+
+	if err := client.WriteStatsKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	var hits, misses int64
+	var ratio float64
+	client.Get("__kvsqlite:stats:sets", &sets)
+	client.Get("__kvsqlite:stats:hits", &hits)
+	client.Get("__kvsqlite:stats:misses", &misses)
+	client.Get("__kvsqlite:stats:hit_ratio", &ratio)
+	if sets != 1 || hits < 2 || misses < 1 || ratio <= 0 {
+		t.Errorf("Unexpected stats keys: sets %d, hits %d, misses %d, hit ratio %f", sets, hits, misses, ratio)
+	}
+
+	if expiresAt, _ := client.ExpiresAt("__kvsqlite:stats:hits"); expiresAt.IsZero() {
+		t.Error("Expected the stats keys to expire")
+	}
+}
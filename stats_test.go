@@ -0,0 +1,69 @@
+package kvsqlite
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	client, err := New(&SQLiteConfig{Path: "/tmp/test.db", Prefix: "go-zoox-test-stats:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	defer client.Clear()
+
+	client.Set("a", 1)
+	client.Set("b", 2)
+
+	var value int
+	client.Get("a", &value)
+	client.Get("missing", &value)
+	client.Delete("b")
+
+	stats, err := client.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Gets != 2 || stats.Hits != 1 || stats.Misses != 1 || stats.HitRatio != 0.5 {
+		t.Errorf("Unexpected read stats %+v", stats)
+	}
+	if stats.Sets != 2 || stats.Deletes != 1 {
+		t.Errorf("Unexpected write stats %+v", stats)
+	}
+	if stats.DBSize <= 0 {
+		t.Errorf("Expected the database size, got %d", stats.DBSize)
+	}
+}
+
+func TestStatsTiming(t *testing.T) {
+	client, err := New(&SQLiteConfig{Path: "/tmp/test.db", Prefix: "go-zoox-test-stats:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	defer client.Clear()
+
+	client.Lock()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		client.Unlock()
+	}()
+	client.Set("a", 1)
+
+	stats, err := client.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.LockWait < 40*time.Millisecond {
+		t.Errorf("Expected the lock wait to be recorded, got %s", stats.LockWait)
+	}
+	if stats.SQLiteTime <= 0 || stats.SQLiteTime >= stats.LockWait {
+		t.Errorf("Expected the SQLite time to be recorded apart from the lock wait, got %s", stats.SQLiteTime)
+	}
+	if stats.Busy != 0 {
+		t.Errorf("Expected no busy statements, got %d", stats.Busy)
+	}
+}
@@ -0,0 +1,218 @@
+package kvsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrStreamTransformed is returned by SetReader and PutFile on a store with Config.Transformers,
+// whose transformers need the whole value.
+var ErrStreamTransformed = errors.New("sqlite: streamed values can't be transformed")
+
+// streamChunkSize is the size of the pieces a streamed value is written and read in.
+const streamChunkSize = 256 << 10
+
+// SetReader sets the value of the given key to the raw bytes read from r.
+// The value is written in chunks within a single transaction, so r is never held fully in memory.
+// If LargeValues is enabled, the chunks are stored as chunk rows, whatever the size of the value.
+// The stored bytes are not JSON encoded, so the value can only be read back with GetReader.
+// maxAge is handled like in Set. Streamed values can't be transformed, so it returns ErrStreamTransformed
+// if Config.Transformers are set, rather than storing the value untransformed.
+func (m *SQLite) SetReader(key string, r io.Reader, maxAge ...time.Duration) error {
+	if len(m.Config.Transformers) > 0 {
+		return ErrStreamTransformed
+	}
+
+	ctx := context.Background()
+	keyX, err := m.writeKey(key)
+	if err != nil {
+		return err
+	}
+
+	if len(maxAge) == 0 {
+		if ttl, ok := m.policyTTL(key); ok {
+			maxAge = []time.Duration{ttl}
+		}
+	}
+
+	err = m.writeTx(ctx, func(db *tracer) error {
+		db = db.in(m.databaseOf(keyX))
+		var expiresAt int64
+		if len(maxAge) > 0 {
+			expiresAt = m.expiresAt(maxAge[0])
+		} else {
+			// use origin expiresAt, an expired key is written as a new one
+			err := db.QueryRowContext(ctx, "SELECT expires_at FROM kv WHERE key = ? AND "+unexpired, keyX, m.expiryCutoff()).Scan(&expiresAt)
+			if errors.Is(err, sql.ErrNoRows) && m.defaultTTL > 0 {
+				expiresAt = m.expiresAt(m.defaultTTL)
+			} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+		}
+
+		m.keyWritten(keyX)
+
+		_, err := db.ExecContext(ctx, "INSERT INTO kv (key, value, expires_at, created_at) VALUES (?, X'', ?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at, chunks = 0", keyX, expiresAt, m.now())
+		if err != nil {
+			return err
+		}
+
+		_, size := m.chunkSizes()
+		chunked := size > 0
+		if !chunked {
+			size = streamChunkSize
+		}
+
+		buf := make([]byte, size)
+		var seq int
+		for {
+			n, err := io.ReadFull(r, buf)
+			if n > 0 {
+				var execErr error
+				if chunked {
+					_, execErr = db.ExecContext(ctx, "INSERT INTO kv_chunks (key, seq, data) VALUES (?, ?, ?)", keyX, seq, buf[:n])
+					seq++
+				} else {
+					_, execErr = db.ExecContext(ctx, "UPDATE kv SET value = value || ? WHERE key = ?", buf[:n], keyX)
+				}
+				if execErr != nil {
+					return execErr
+				}
+			}
+
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if seq > 0 {
+			if _, err := db.ExecContext(ctx, "UPDATE kv SET chunks = ? WHERE key = ?", seq, keyX); err != nil {
+				return err
+			}
+		}
+
+		// the whole value is in place now, so only the other keys and this one count
+		return m.checkQuota(ctx, db, keyX, 0, true, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	m.publish(EventSet, key)
+	return nil
+}
+
+// GetReader returns a reader of the raw bytes stored for the given key, read in chunks on demand.
+// It returns ErrNotFound if the key does not exist, or ErrExpired if it is expired.
+// Overwriting the key while it is being read may yield a mix of both values.
+func (m *SQLite) GetReader(key string) (io.ReadCloser, error) {
+	return m.openReader(key)
+}
+
+func (m *SQLite) openReader(key string) (*valueReader, error) {
+	keyX, err := m.getKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !m.mayContain(keyX) {
+		return nil, ErrNotFound
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	var size, expiresAt, chunks, chunkSize int64
+	err = m.db().in(m.databaseOf(keyX)).QueryRow(
+		"SELECT "+valueSizeExpr+", expires_at, chunks, coalesce((SELECT length(data) FROM kv_chunks WHERE kv_chunks.key = kv.key AND seq = 0), 0) FROM kv WHERE key = ?",
+		keyX,
+	).Scan(&size, &expiresAt, &chunks, &chunkSize)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, m.classify(err)
+	}
+	if expiresAt > 0 && expiresAt < m.expiryCutoff() {
+		return nil, ErrExpired
+	}
+
+	if m.access != nil {
+		m.access.touch(keyX)
+	}
+
+	reader := &valueReader{store: m, keyX: keyX, size: size}
+	if chunks > 0 {
+		reader.chunkSize = chunkSize
+	}
+
+	return reader, nil
+}
+
+// valueReader reads a stored value with one query per chunk.
+type valueReader struct {
+	store  *SQLite
+	keyX   string
+	size   int64
+	offset int64
+	closed bool
+
+	// chunkSize is the size of the chunk rows of a chunked value, 0 if not chunked.
+	chunkSize int64
+}
+
+func (r *valueReader) Read(p []byte) (int, error) {
+	if r.closed {
+		return 0, errors.New("sqlite: read on closed reader")
+	}
+
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	n := int64(len(p))
+	if n > streamChunkSize {
+		n = streamChunkSize
+	}
+	if n > r.size-r.offset {
+		n = r.size - r.offset
+	}
+
+	r.store.RLock()
+	defer r.store.RUnlock()
+
+	var chunk []byte
+	var err error
+	// substr is 1-indexed
+	db := r.store.db().in(r.store.databaseOf(r.keyX))
+	if r.chunkSize > 0 {
+		seq, within := r.offset/r.chunkSize, r.offset%r.chunkSize
+		err = db.QueryRow("SELECT substr(data, ?, ?) FROM kv_chunks WHERE key = ? AND seq = ?", within+1, n, r.keyX, seq).Scan(&chunk)
+	} else {
+		err = db.QueryRow("SELECT substr(CAST(value AS BLOB), ?, ?) FROM kv WHERE key = ?", r.offset+1, n, r.keyX).Scan(&chunk)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(chunk) == 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	copied := copy(p, chunk)
+	r.offset += int64(copied)
+	return copied, nil
+}
+
+func (r *valueReader) Close() error {
+	r.closed = true
+	return nil
+}
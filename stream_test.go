@@ -0,0 +1,82 @@
+package kvsqlite
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestSetReaderGetReader(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), streamChunkSize/16*3+7)
+	if err := client.SetReader("stream", bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := client.GetReader("stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("Expected %d bytes back, got %d", len(data), len(got))
+	}
+
+	if _, err := client.GetReader("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestSetReaderQuota(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+	client.Clear()
+
+	client.Config.Quota = &Quota{MaxBytes: 1024}
+	defer func() { client.Config.Quota = nil }()
+
+	err := client.SetReader("stream", bytes.NewReader(make([]byte, 4096)))
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected ErrQuotaExceeded, got %v", err)
+	}
+	if _, err := client.GetReader("stream"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the failed write to be rolled back, got %v", err)
+	}
+}
+
+func TestSetReaderExpired(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	client, err := New(&SQLiteConfig{Path: "/tmp/test.db", Prefix: "go-zoox-test:", Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	defer client.Clear()
+
+	client.SetReader("stream", bytes.NewReader([]byte("old")), time.Second)
+	clock.Add(time.Minute)
+
+	// overwriting an expired key doesn't keep its expiration
+	if err := client.SetReader("stream", bytes.NewReader([]byte("new"))); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := client.GetReader("stream")
+	if err != nil {
+		t.Fatalf("Expected the overwritten key to be readable, got %v", err)
+	}
+	defer r.Close()
+
+	if got, _ := io.ReadAll(r); string(got) != "new" {
+		t.Errorf("Expected new, got %q", got)
+	}
+}
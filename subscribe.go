@@ -0,0 +1,177 @@
+package kvsqlite
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType is the type of a keyspace event.
+type EventType string
+
+const (
+	// EventSet is published when a key is written.
+	EventSet EventType = "set"
+	// EventDelete is published when a key is deleted.
+	EventDelete EventType = "delete"
+	// EventExpire is published when an expired key is removed.
+	EventExpire EventType = "expire"
+	// EventClear is published when all keys are removed, its Key is empty.
+	EventClear EventType = "clear"
+)
+
+// Event is a keyspace event of this store handle.
+type Event struct {
+	Type EventType
+	Key  string
+	Time time.Time
+}
+
+// Backpressure is what happens to events when a subscriber's buffer is full.
+type Backpressure int
+
+const (
+	// DropNewest drops the event being published.
+	DropNewest Backpressure = iota
+	// DropOldest drops the oldest buffered event to make room.
+	DropOldest
+	// Block blocks the writer until the subscriber catches up.
+	Block
+)
+
+// SubscribeOptions are the options of a subscription.
+type SubscribeOptions struct {
+	// Buffer is the size of the channel buffer, default is 64.
+	Buffer int
+
+	// Backpressure is what happens when the buffer is full, default is DropNewest.
+	Backpressure Backpressure
+}
+
+type subscription struct {
+	sync.Mutex
+	pattern      string
+	ch           chan Event
+	backpressure Backpressure
+	closed       bool
+
+	// done unblocks a blocked send when the subscription is canceled
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// SubscribePattern returns a channel of the events on keys matching the glob pattern, e.g. "user:*",
+// and a function to cancel the subscription, which closes the channel.
+// Clear events are delivered to every subscriber.
+func (m *SQLite) SubscribePattern(pattern string, opts ...*SubscribeOptions) (<-chan Event, func()) {
+	opt := &SubscribeOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		opt = opts[0]
+	}
+
+	buffer := opt.Buffer
+	if buffer <= 0 {
+		buffer = 64
+	}
+
+	sub := &subscription{
+		pattern:      pattern,
+		ch:           make(chan Event, buffer),
+		backpressure: opt.Backpressure,
+		done:         make(chan struct{}),
+	}
+
+	m.subscriptionsMu.Lock()
+	if m.subscriptions == nil {
+		m.subscriptions = make(map[*subscription]struct{})
+	}
+	m.subscriptions[sub] = struct{}{}
+	m.subscriptionsMu.Unlock()
+
+	return sub.ch, func() {
+		m.subscriptionsMu.Lock()
+		delete(m.subscriptions, sub)
+		m.subscriptionsMu.Unlock()
+
+		sub.close()
+	}
+}
+
+// publish delivers an event to the matching subscribers.
+func (m *SQLite) publish(typ EventType, key string) {
+	m.subscriptionsMu.RLock()
+	matched := make([]*subscription, 0, len(m.subscriptions))
+	for sub := range m.subscriptions {
+		if typ == EventClear || matchPattern(sub.pattern, key) {
+			matched = append(matched, sub)
+		}
+	}
+	m.subscriptionsMu.RUnlock()
+
+	if len(matched) == 0 {
+		return
+	}
+
+	event := Event{Type: typ, Key: key, Time: m.clock().Now()}
+	for _, sub := range matched {
+		sub.send(event)
+	}
+}
+
+func (m *SQLite) closeSubscriptions() {
+	m.subscriptionsMu.Lock()
+	defer m.subscriptionsMu.Unlock()
+
+	for sub := range m.subscriptions {
+		sub.close()
+	}
+	m.subscriptions = nil
+}
+
+func (s *subscription) send(event Event) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	switch s.backpressure {
+	case Block:
+		select {
+		case s.ch <- event:
+		case <-s.done:
+		}
+	case DropOldest:
+		for {
+			select {
+			case s.ch <- event:
+				return
+			default:
+			}
+
+			select {
+			case <-s.ch:
+			default:
+			}
+		}
+	default:
+		select {
+		case s.ch <- event:
+		default:
+		}
+	}
+}
+
+func (s *subscription) close() {
+	s.doneOnce.Do(func() {
+		close(s.done)
+	})
+
+	s.Lock()
+	defer s.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
@@ -0,0 +1,48 @@
+package kvsqlite
+
+import "testing"
+
+func TestSubscribePattern(t *testing.T) {
+	client := createClient()
+	client.Clear()
+	defer client.Clear()
+
+	events, cancel := client.SubscribePattern("user:*")
+	defer cancel()
+
+	client.Set("user:1", "a")
+	client.Set("page:1", "b")
+	client.Delete("user:1")
+
+	if e := <-events; e.Type != EventSet || e.Key != "user:1" {
+		t.Errorf("Expected set user:1, got %v", e)
+	}
+	if e := <-events; e.Type != EventDelete || e.Key != "user:1" {
+		t.Errorf("Expected delete user:1, got %v", e)
+	}
+	select {
+	case e := <-events:
+		t.Errorf("Expected no more events, got %v", e)
+	default:
+	}
+}
+
+func TestSubscribeDropOldest(t *testing.T) {
+	client := createClient()
+	client.Clear()
+	defer client.Clear()
+
+	events, cancel := client.SubscribePattern("*", &SubscribeOptions{Buffer: 1, Backpressure: DropOldest})
+
+	client.Set("key1", "a")
+	client.Set("key2", "b")
+
+	if e := <-events; e.Key != "key2" {
+		t.Errorf("Expected the newest event to be kept, got %v", e)
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Error("Expected channel to be closed")
+	}
+}
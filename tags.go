@@ -0,0 +1,135 @@
+package kvsqlite
+
+import (
+	"context"
+	"database/sql"
+)
+
+// createTagSchema creates the table of key tags in the given database, "" for main.
+// Triggers keep it in sync with the kv table.
+func createTagSchema(core *sql.DB, schema string) error {
+	q := ""
+	if schema != "" {
+		q = schema + "."
+	}
+
+	statements := []string{
+		"CREATE TABLE IF NOT EXISTS " + q + "kv_tags (tag TEXT NOT NULL, key TEXT NOT NULL, PRIMARY KEY (tag, key)) WITHOUT ROWID",
+		"CREATE INDEX IF NOT EXISTS " + q + "kv_tags_key ON kv_tags (key)",
+	}
+	for _, statement := range append(statements, tagTriggers(q, "kv")...) {
+		if _, err := core.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tagTriggers returns the statements creating the tag triggers of the given kv table, in the database qualifier q.
+func tagTriggers(q string, table string) []string {
+	return []string{
+		`CREATE TRIGGER IF NOT EXISTS ` + q + quoteIdent(table+"_tags_delete") + ` AFTER DELETE ON ` + quoteIdent(table) + ` BEGIN
+			DELETE FROM kv_tags WHERE key = old.key;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS ` + q + quoteIdent(table+"_tags_rename") + ` AFTER UPDATE OF key ON ` + quoteIdent(table) + ` WHEN old.key <> new.key BEGIN
+			UPDATE kv_tags SET key = new.key WHERE key = old.key;
+		END`,
+	}
+}
+
+// setTags replaces the tags of the (prefixed) key, in the transaction of the write.
+func (m *SQLite) setTags(ctx context.Context, db *tracer, keyX string, tags []string) error {
+	if _, err := db.ExecContext(ctx, "DELETE FROM kv_tags WHERE key = ?", keyX); err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		if _, err := db.ExecContext(ctx, "INSERT OR IGNORE INTO kv_tags (tag, key) VALUES (?, ?)", tag, keyX); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// KeysByTag returns the unexpired keys tagged with the given tag, sorted.
+func (m *SQLite) KeysByTag(tag string) ([]string, error) {
+	if err := m.checkAccessAll(false); err != nil {
+		return nil, err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	ctx := context.Background()
+	keys := make([]string, 0)
+	for _, database := range m.databases() {
+		rows, err := m.db().in(database).QueryContext(ctx, `SELECT kv.key FROM kv_tags JOIN kv ON kv.key = kv_tags.key
+			WHERE kv_tags.tag = ? AND kv.key LIKE ? AND (kv.expires_at = 0 OR kv.expires_at >= ?) ORDER BY kv.key`, tag, m.Config.Prefix+"%", m.expiryCutoff())
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var key string
+			if err := rows.Scan(&key); err != nil {
+				rows.Close()
+				return nil, err
+			}
+
+			keys = append(keys, key[len(m.Config.Prefix):])
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return keys, nil
+}
+
+// DeleteByTag deletes the keys tagged with the given tag and returns the number of deleted keys.
+func (m *SQLite) DeleteByTag(tag string) (int64, error) {
+	if err := m.checkAccessAll(true); err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+	removed := make([]string, 0)
+	err := m.writeWith(ctx, len(m.Config.Attach) > 0, func(db *tracer) error {
+		for _, database := range m.databases() {
+			rows, err := db.in(database).QueryContext(ctx, "DELETE FROM kv WHERE key LIKE ? AND key IN (SELECT key FROM kv_tags WHERE tag = ?) RETURNING key", m.Config.Prefix+"%", tag)
+			if err != nil {
+				return err
+			}
+
+			for rows.Next() {
+				var keyX string
+				if err := rows.Scan(&keyX); err != nil {
+					rows.Close()
+					return err
+				}
+
+				m.keyRemoved(keyX)
+				removed = append(removed, keyX[len(m.Config.Prefix):])
+			}
+			rows.Close()
+
+			if err := rows.Err(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, key := range removed {
+		m.publish(EventDelete, key)
+	}
+	return int64(len(removed)), nil
+}
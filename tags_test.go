@@ -0,0 +1,54 @@
+package kvsqlite
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTags(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	client.SetWith("user:1", "a", WithTags("users", "admins"))
+	client.SetWith("user:2", "b", WithTags("users"))
+	client.SetWith("user:3", "c", WithTags("users"), WithTTL(time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+
+	keys, err := client.KeysByTag("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keys, []string{"user:1", "user:2"}) {
+		t.Errorf("Expected the unexpired tagged keys, got %v", keys)
+	}
+
+	// Set keeps the tags, WithTags replaces them
+	client.Set("user:1", "a2")
+	if keys, _ := client.KeysByTag("admins"); len(keys) != 1 {
+		t.Errorf("Expected Set to keep the tags, got %v", keys)
+	}
+	client.SetWith("user:1", "a3", WithTags())
+	if keys, _ := client.KeysByTag("admins"); len(keys) != 0 {
+		t.Errorf("Expected WithTags to replace the tags, got %v", keys)
+	}
+
+	n, err := client.DeleteByTag("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 deleted keys, got %d", n)
+	}
+	if client.Has("user:2") || !client.Has("user:1") {
+		t.Error("Expected only the keys still tagged to be deleted")
+	}
+
+	// deleting a key drops its tags
+	client.SetWith("user:4", "d", WithTags("users"))
+	client.Delete("user:4")
+	client.Set("user:4", "d")
+	if keys, _ := client.KeysByTag("users"); len(keys) != 0 {
+		t.Errorf("Expected the tags to be dropped with the key, got %v", keys)
+	}
+}
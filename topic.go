@@ -0,0 +1,207 @@
+package kvsqlite
+
+import (
+	"context"
+	"time"
+)
+
+// topicPollInterval is how often Receive checks for new messages.
+const topicPollInterval = 50 * time.Millisecond
+
+// Message is a message published to a topic.
+type Message struct {
+	// ID is the position of the message, increasing in publish order across the topics of the database.
+	ID          int64
+	Topic       string
+	PublishedAt time.Time
+
+	store *SQLite
+	data  []byte
+}
+
+// Decode decodes the payload of the message into value, like Get.
+func (msg *Message) Decode(value any) error {
+	return msg.store.decodeValue(msg.Topic, msg.data, value)
+}
+
+// Consumer consumes a topic for a consumer group, see SQLite.Subscribe.
+type Consumer struct {
+	store *SQLite
+	topic string
+	group string
+}
+
+func (m *SQLite) ensureTopics() error {
+	return m.ensureSchema("topic",
+		"CREATE TABLE IF NOT EXISTS kv_topic_messages (id INTEGER PRIMARY KEY AUTOINCREMENT, topic TEXT NOT NULL, payload BLOB, published_at INTEGER NOT NULL)",
+		"CREATE INDEX IF NOT EXISTS kv_topic_messages_topic_id ON kv_topic_messages (topic, id)",
+		"CREATE TABLE IF NOT EXISTS kv_topic_offsets (topic TEXT NOT NULL, grp TEXT NOT NULL, offset INTEGER NOT NULL, PRIMARY KEY (topic, grp)) WITHOUT ROWID",
+	)
+}
+
+// Publish appends the message to the topic and returns its ID.
+// Messages are durable: they stay in the topic until TrimTopic, whether they were consumed or not.
+func (m *SQLite) Publish(topic string, msg any) (int64, error) {
+	if err := m.ensureTopics(); err != nil {
+		return 0, err
+	}
+
+	valueX, err := m.encodeValue(msg)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+
+	var id int64
+	err = m.write(ctx, func(db *tracer) error {
+		return db.QueryRowContext(ctx,
+			"INSERT INTO kv_topic_messages (topic, payload, published_at) VALUES (?, ?, ?) RETURNING id",
+			m.Config.Prefix+topic, valueX, m.now(),
+		).Scan(&id)
+	})
+
+	return id, err
+}
+
+// Subscribe returns the consumer of the topic for the given consumer group.
+// Each group receives every message of the topic once it acknowledges them, consumers of the same group
+// share its offset. Delivery is at least once: messages are received again until they are acknowledged,
+// including by other processes after a crash.
+//
+// A new group starts at the beginning of the topic, and is registered so TrimTopic keeps its messages.
+func (m *SQLite) Subscribe(topic, group string) (*Consumer, error) {
+	if err := m.ensureTopics(); err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	err := m.write(ctx, func(db *tracer) error {
+		_, err := db.ExecContext(ctx, "INSERT OR IGNORE INTO kv_topic_offsets (topic, grp, offset) VALUES (?, ?, 0)", m.Config.Prefix+topic, group)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Consumer{store: m, topic: topic, group: group}, nil
+}
+
+// Fetch returns up to limit messages after the offset of the group, oldest first, without acknowledging them.
+func (c *Consumer) Fetch(limit int) ([]Message, error) {
+	m := c.store
+	if err := m.ensureTopics(); err != nil {
+		return nil, err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	topicX := m.Config.Prefix + c.topic
+	rows, err := m.db().Query(
+		`SELECT id, payload, published_at FROM kv_topic_messages
+			WHERE topic = ? AND id > coalesce((SELECT offset FROM kv_topic_offsets WHERE topic = ? AND grp = ?), 0)
+			ORDER BY id LIMIT ?`,
+		topicX, topicX, c.group, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make([]Message, 0)
+	for rows.Next() {
+		msg := Message{Topic: c.topic, store: m}
+		var publishedAt int64
+		if err := rows.Scan(&msg.ID, &msg.data, &publishedAt); err != nil {
+			return nil, err
+		}
+
+		msg.PublishedAt = time.UnixMilli(publishedAt)
+		messages = append(messages, msg)
+	}
+
+	return messages, rows.Err()
+}
+
+// Receive is like Fetch but waits until at least one message is available or ctx is done.
+func (c *Consumer) Receive(ctx context.Context, limit int) ([]Message, error) {
+	ticker := time.NewTicker(topicPollInterval)
+	defer ticker.Stop()
+
+	for {
+		messages, err := c.Fetch(limit)
+		if err != nil || len(messages) > 0 {
+			return messages, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Ack acknowledges the messages of the group up to the given ID, which are not received by the group anymore.
+// The offset never moves backwards.
+func (c *Consumer) Ack(id int64) error {
+	m := c.store
+	if err := m.ensureTopics(); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	return m.write(ctx, func(db *tracer) error {
+		_, err := db.ExecContext(ctx,
+			"INSERT INTO kv_topic_offsets (topic, grp, offset) VALUES (?, ?, ?) ON CONFLICT(topic, grp) DO UPDATE SET offset = max(offset, excluded.offset)",
+			m.Config.Prefix+c.topic, c.group, id,
+		)
+		return err
+	})
+}
+
+// Offset returns the ID of the last message acknowledged by the group, 0 if none.
+func (c *Consumer) Offset() (int64, error) {
+	m := c.store
+	if err := m.ensureTopics(); err != nil {
+		return 0, err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	var offset int64
+	err := m.db().QueryRow(
+		"SELECT coalesce((SELECT offset FROM kv_topic_offsets WHERE topic = ? AND grp = ?), 0)",
+		m.Config.Prefix+c.topic, c.group,
+	).Scan(&offset)
+	return offset, err
+}
+
+// TrimTopic deletes the messages of the topic acknowledged by every consumer group, and returns how many were deleted.
+// Without consumer groups, nothing is deleted.
+func (m *SQLite) TrimTopic(topic string) (int64, error) {
+	if err := m.ensureTopics(); err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+	topicX := m.Config.Prefix + topic
+
+	var n int64
+	err := m.write(ctx, func(db *tracer) error {
+		res, err := db.ExecContext(ctx,
+			"DELETE FROM kv_topic_messages WHERE topic = ? AND id <= (SELECT min(offset) FROM kv_topic_offsets WHERE topic = ?)",
+			topicX, topicX,
+		)
+		if err != nil {
+			return err
+		}
+
+		n, err = res.RowsAffected()
+		return err
+	})
+
+	return n, err
+}
@@ -0,0 +1,88 @@
+package kvsqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTopics(t *testing.T) {
+	client, err := New(&SQLiteConfig{
+		Path:   "/tmp/test.db",
+		Prefix: "go-zoox-test-topic:" + time.Now().String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	for _, msg := range []string{"a", "b", "c"} {
+		if _, err := client.Publish("orders", msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	billing, err := client.Subscribe("orders", "billing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages, err := billing.Fetch(2)
+	if err != nil || len(messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d (%v)", len(messages), err)
+	}
+
+	var payload string
+	if messages[0].Decode(&payload); payload != "a" {
+		t.Errorf("Expected a, got %s", payload)
+	}
+
+	// not acknowledged, so delivered again
+	if again, _ := billing.Fetch(2); len(again) != 2 || again[0].ID != messages[0].ID {
+		t.Errorf("Expected the same messages again, got %+v", again)
+	}
+
+	if err := billing.Ack(messages[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	messages, _ = billing.Fetch(10)
+	if len(messages) != 1 {
+		t.Fatalf("Expected the last message, got %d", len(messages))
+	}
+	if messages[0].Decode(&payload); payload != "c" {
+		t.Errorf("Expected c, got %s", payload)
+	}
+
+	// groups have their own offset
+	shipping, err := client.Subscribe("orders", "shipping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all, _ := shipping.Fetch(10); len(all) != 3 {
+		t.Errorf("Expected 3 messages for another group, got %d", len(all))
+	}
+
+	// the offset never moves backwards
+	billing.Ack(messages[0].ID)
+	billing.Ack(1)
+	if offset, _ := billing.Offset(); offset != messages[0].ID {
+		t.Errorf("Expected offset %d, got %d", messages[0].ID, offset)
+	}
+
+	if n, err := client.TrimTopic("orders"); err != nil || n != 0 {
+		t.Errorf("Expected nothing trimmed before shipping acknowledges, got %d (%v)", n, err)
+	}
+	shipping.Ack(messages[0].ID)
+	if n, _ := client.TrimTopic("orders"); n != 3 {
+		t.Errorf("Expected 3 messages trimmed, got %d", n)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		client.Publish("orders", "d")
+	}()
+	if received, err := billing.Receive(ctx, 10); err != nil || len(received) != 1 {
+		t.Errorf("Expected to receive the new message, got %d (%v)", len(received), err)
+	}
+}
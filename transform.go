@@ -0,0 +1,210 @@
+package kvsqlite
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+)
+
+// ErrUnknownTransformer is returned when a stored value was written by a transformer which is not configured.
+var ErrUnknownTransformer = errors.New("sqlite: unknown value transformer")
+
+// ErrChecksum is returned when a stored value doesn't match its checksum, see Checksum and GetFile.
+var ErrChecksum = errors.New("sqlite: checksum mismatch")
+
+// transformMarker starts transformed values. JSON never starts with it, so plain values stay readable.
+const transformMarker = "~"
+
+// ValueTransformer transforms encoded values on their way to and from the database, e.g. to compress or encrypt them.
+type ValueTransformer interface {
+	// Name identifies the transformer in stored values, it must not change once values were written.
+	// It must not contain ':' nor ','.
+	Name() string
+
+	// Transform transforms an encoded value before it is written.
+	Transform(data []byte) ([]byte, error)
+
+	// Restore reverts Transform on a read value.
+	Restore(data []byte) ([]byte, error)
+}
+
+// transform applies the configured transformers in order to the encoded value.
+// Transformed values are stored as the marker, the names of the applied transformers and the base64 result,
+// e.g. "~gzip,aes-gcm:...", so values written with another chain (or none) can still be read.
+func (m *SQLite) transform(raw []byte) (string, error) {
+	transformers := m.Config.Transformers
+	if len(transformers) == 0 {
+		return string(raw), nil
+	}
+
+	names := make([]string, len(transformers))
+	for i, t := range transformers {
+		var err error
+		if raw, err = t.Transform(raw); err != nil {
+			return "", fmt.Errorf("sqlite: transform %s: %w", t.Name(), err)
+		}
+		names[i] = t.Name()
+	}
+
+	return transformMarker + strings.Join(names, ",") + ":" + base64.StdEncoding.EncodeToString(raw), nil
+}
+
+// restore reverts the transformers recorded in the stored value, in reverse order.
+// Values which are not transformed are returned as is.
+func (m *SQLite) restore(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(transformMarker)) {
+		return data, nil
+	}
+
+	header, payload, ok := strings.Cut(string(data[len(transformMarker):]), ":")
+	if !ok {
+		return nil, fmt.Errorf("%w: missing transformer names", ErrUnknownTransformer)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	names := strings.Split(header, ",")
+	for i := len(names) - 1; i >= 0; i-- {
+		t := m.transformer(names[i])
+		if t == nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownTransformer, names[i])
+		}
+
+		if raw, err = t.Restore(raw); err != nil {
+			return nil, fmt.Errorf("sqlite: restore %s: %w", names[i], err)
+		}
+	}
+
+	return raw, nil
+}
+
+func (m *SQLite) transformer(name string) ValueTransformer {
+	for _, t := range m.Config.Transformers {
+		if t.Name() == name {
+			return t
+		}
+	}
+
+	return nil
+}
+
+type checksumTransformer struct{}
+
+// Checksum returns a transformer prefixing values with their CRC-32, so values corrupted in the database
+// fail to restore with ErrChecksum instead of being decoded, see Config.OnCorrupt.
+// It should come last, after compression and encryption.
+func Checksum() ValueTransformer {
+	return checksumTransformer{}
+}
+
+func (checksumTransformer) Name() string {
+	return "crc32"
+}
+
+func (checksumTransformer) Transform(data []byte) ([]byte, error) {
+	out := make([]byte, 4, 4+len(data))
+	binary.BigEndian.PutUint32(out, crc32.ChecksumIEEE(data))
+	return append(out, data...), nil
+}
+
+func (checksumTransformer) Restore(data []byte) ([]byte, error) {
+	if len(data) < 4 || binary.BigEndian.Uint32(data) != crc32.ChecksumIEEE(data[4:]) {
+		return nil, ErrChecksum
+	}
+
+	return data[4:], nil
+}
+
+type gzipTransformer struct{}
+
+// Gzip returns a transformer compressing values with gzip.
+func Gzip() ValueTransformer {
+	return gzipTransformer{}
+}
+
+func (gzipTransformer) Name() string {
+	return "gzip"
+}
+
+func (gzipTransformer) Transform(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (gzipTransformer) Restore(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+// encrypter is implemented by the transformers which encrypt values, recorded as the encryption of the store, see MetaEncryption.
+type encrypter interface {
+	encrypts()
+}
+
+type aesGCMTransformer struct {
+	aead cipher.AEAD
+}
+
+func (t *aesGCMTransformer) encrypts() {}
+
+// AESGCM returns a transformer encrypting values with AES-GCM and the given 16, 24 or 32 bytes key.
+func AESGCM(key []byte) (ValueTransformer, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aesGCMTransformer{aead}, nil
+}
+
+func (t *aesGCMTransformer) Name() string {
+	return "aes-gcm"
+}
+
+func (t *aesGCMTransformer) Transform(data []byte) ([]byte, error) {
+	nonce := make([]byte, t.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return t.aead.Seal(nonce, nonce, data, nil), nil
+}
+
+func (t *aesGCMTransformer) Restore(data []byte) ([]byte, error) {
+	size := t.aead.NonceSize()
+	if len(data) < size {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	return t.aead.Open(nil, data[:size], data[size:], nil)
+}
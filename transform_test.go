@@ -0,0 +1,83 @@
+package kvsqlite
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTransformers(t *testing.T) {
+	// the database records the transformers which wrote values, see MetaCodec
+	path := "/tmp/test-transformers.db"
+	os.Remove(path)
+	defer os.Remove(path)
+
+	plain, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Close()
+
+	// a chain with only the compression can read the values written by it
+	compressing, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:", Transformers: []ValueTransformer{Gzip()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer compressing.Close()
+
+	key := []byte("0123456789abcdef")
+	encrypt, err := AESGCM(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := New(&SQLiteConfig{
+		Path:         path,
+		Prefix:       "go-zoox-test:",
+		Transformers: []ValueTransformer{Gzip(), encrypt},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	plain.Set("old", "written before")
+	client.Set("new", strings.Repeat("secret ", 100))
+
+	var stored string
+	client.Core.QueryRow("SELECT value FROM kv WHERE key = ?", "go-zoox-test:new").Scan(&stored)
+	if !strings.HasPrefix(stored, "~gzip,aes-gcm:") || strings.Contains(stored, "secret") {
+		t.Errorf("Expected the value to be compressed then encrypted, got %q", stored)
+	}
+
+	var value string
+	if err := client.Get("new", &value); err != nil || value != strings.Repeat("secret ", 100) {
+		t.Errorf("Expected the value to be restored, got %q (%v)", value, err)
+	}
+
+	// values written without transformers stay readable
+	if err := client.Get("old", &value); err != nil || value != "written before" {
+		t.Errorf("Expected the plain value, got %q (%v)", value, err)
+	}
+
+	// the old chain can't read what it doesn't know
+	if err := plain.Get("new", &value); !errors.Is(err, ErrUnknownTransformer) {
+		t.Errorf("Expected ErrUnknownTransformer, got %v", err)
+	}
+
+	compressing.Set("compressed", "value")
+	if err := client.Get("compressed", &value); err != nil || value != "value" {
+		t.Errorf("Expected a value of another chain to be read, got %q (%v)", value, err)
+	}
+
+	wrongKey, _ := AESGCM([]byte("fedcba9876543210"))
+	other, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:", Transformers: []ValueTransformer{Gzip(), wrongKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if err := other.Get("new", &value); err == nil {
+		t.Error("Expected decryption with another key to fail")
+	}
+}
@@ -0,0 +1,79 @@
+package kvsqlite
+
+import (
+	"context"
+	"time"
+)
+
+// ExpireBatch sets the TTL of the given keys which exist and are not expired,
+// in one statement per batch of keys within a single transaction, and returns how many were updated.
+func (m *SQLite) ExpireBatch(keys []string, ttl time.Duration) (int64, error) {
+	return m.updateExpiresAt(keys, m.expiresAt(ttl))
+}
+
+// ExpireAtBatch is like ExpireBatch but expires the keys at the absolute time t, see WithExpiresAt.
+func (m *SQLite) ExpireAtBatch(keys []string, t time.Time) (int64, error) {
+	return m.updateExpiresAt(keys, m.expiresAtTime(t))
+}
+
+// PersistBatch removes the expiration of the given keys which exist and are not expired,
+// and returns how many were updated.
+func (m *SQLite) PersistBatch(keys []string) (int64, error) {
+	return m.updateExpiresAt(keys, 0)
+}
+
+func (m *SQLite) updateExpiresAt(keys []string, expiresAt int64) (int64, error) {
+	ctx := context.Background()
+
+	groups, err := m.byDatabase(keys, true)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	err = m.writeTx(ctx, func(db *tracer) error {
+		ts := m.expiryCutoff()
+		for database, group := range groups {
+			for start := 0; start < len(group); start += maxBatchParams {
+				end := start + maxBatchParams
+				if end > len(group) {
+					end = len(group)
+				}
+				batch := group[start:end]
+
+				args := make([]any, 0, len(batch)+2)
+				args = append(args, expiresAt)
+				for _, keyX := range batch {
+					args = append(args, keyX)
+
+					// cached entries carry their expiration
+					if m.cache != nil {
+						m.cache.remove(keyX)
+					}
+				}
+				args = append(args, ts)
+
+				res, err := db.in(database).ExecContext(ctx,
+					"UPDATE kv SET expires_at = ? WHERE key IN ("+placeholders(len(batch))+") AND "+unexpired,
+					args...,
+				)
+				if err != nil {
+					return err
+				}
+
+				affected, err := res.RowsAffected()
+				if err != nil {
+					return err
+				}
+				n += affected
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
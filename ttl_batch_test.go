@@ -0,0 +1,41 @@
+package kvsqlite
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestExpireBatchPersistBatch(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	keys := make([]string, 0, 1200)
+	for i := 0; i < 1200; i++ {
+		key := fmt.Sprintf("batch:%d", i)
+		keys = append(keys, key)
+		client.Set(key, i)
+	}
+
+	n, err := client.ExpireBatch(append(keys, "missing"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1200 {
+		t.Errorf("Expected 1200 updated keys, got %d", n)
+	}
+
+	within, _ := client.ExpiringWithin(2 * time.Hour)
+	if len(within) != 1200 {
+		t.Errorf("Expected 1200 expiring keys, got %d", len(within))
+	}
+
+	if n, _ := client.PersistBatch(keys[:100]); n != 100 {
+		t.Errorf("Expected 100 persisted keys, got %d", n)
+	}
+
+	within, _ = client.ExpiringWithin(2 * time.Hour)
+	if len(within) != 1100 {
+		t.Errorf("Expected 1100 expiring keys, got %d", len(within))
+	}
+}
@@ -0,0 +1,34 @@
+package kvsqlite
+
+import "time"
+
+// TTLPolicy is the default TTL of keys matching Pattern,
+// applied on Set when no explicit maxAge is given.
+// Pattern is a glob where * matches any sequence of characters and ? any single character.
+type TTLPolicy struct {
+	Pattern string
+	TTL     time.Duration
+}
+
+// RegisterTTLPolicy registers a default TTL for keys matching pattern, e.g. "session:*".
+// Policies are checked in registration order (after Config.TTLPolicies) and the first match wins.
+func (m *SQLite) RegisterTTLPolicy(pattern string, ttl time.Duration) {
+	m.policiesMu.Lock()
+	defer m.policiesMu.Unlock()
+
+	m.policies = append(m.policies, TTLPolicy{pattern, ttl})
+}
+
+// policyTTL returns the default TTL for the given key, if any policy matches.
+func (m *SQLite) policyTTL(key string) (time.Duration, bool) {
+	m.policiesMu.RLock()
+	defer m.policiesMu.RUnlock()
+
+	for _, policy := range m.policies {
+		if matchPattern(policy.Pattern, key) {
+			return policy.TTL, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,42 @@
+package kvsqlite
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTTLPolicy(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	client, err := New(&SQLiteConfig{
+		Path:   "/tmp/test.db",
+		Prefix: "go-zoox-test-policy:",
+		Clock:  clock,
+		TTLPolicies: []TTLPolicy{
+			{Pattern: "session:*", TTL: 24 * time.Hour},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.Clear()
+	defer client.Clear()
+
+	client.RegisterTTLPolicy("page:*", 5*time.Minute)
+
+	client.Set("session:1", "s")
+	client.Set("page:home", "p")
+	client.Set("page:about", "p", time.Hour)
+	client.Set("user:1", "u")
+
+	clock.Add(10 * time.Minute)
+
+	var value string
+	for _, key := range []string{"session:1", "page:home", "page:about", "user:1"} {
+		client.Get(key, &value)
+	}
+
+	if !client.Has("session:1") || client.Has("page:home") || !client.Has("page:about") || !client.Has("user:1") {
+		t.Errorf("Expected only page:home to be expired, got keys %v", client.Keys())
+	}
+}
@@ -0,0 +1,110 @@
+package kvsqlite
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// DefaultTTLBuckets are the upper bounds of the buckets of TTLHistogram when none are given.
+var DefaultTTLBuckets = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour}
+
+// TTLBucket is the number of keys whose remaining TTL is at most Max, and above the Max of the previous bucket.
+type TTLBucket struct {
+	Max   time.Duration
+	Count int64
+}
+
+// TTLHistogram is the distribution of the remaining TTLs of the unexpired keys.
+type TTLHistogram struct {
+	// Buckets are the keys with expiration, by increasing upper bound.
+	Buckets []TTLBucket
+
+	// Beyond is the number of keys expiring after the last bucket.
+	Beyond int64
+
+	// Persistent is the number of keys without expiration.
+	Persistent int64
+}
+
+// TTLHistogram returns the distribution of the remaining TTLs of the keys in buckets with the given
+// increasing upper bounds, DefaultTTLBuckets if none are given, e.g. to anticipate miss storms.
+func (m *SQLite) TTLHistogram(bounds ...time.Duration) (*TTLHistogram, error) {
+	if len(bounds) == 0 {
+		bounds = DefaultTTLBuckets
+	}
+	for i := 1; i < len(bounds); i++ {
+		if bounds[i] <= bounds[i-1] {
+			return nil, errors.New("sqlite: TTL buckets must be increasing")
+		}
+	}
+
+	ts := m.now()
+	counts, err := m.countTTLs(ts, bounds)
+	if err != nil {
+		return nil, err
+	}
+
+	// counts are the persistent keys, the keys with expiration, then the keys expiring within each bound
+	h := &TTLHistogram{Persistent: counts[0], Beyond: counts[1]}
+	previous := int64(0)
+	for i, bound := range bounds {
+		h.Buckets = append(h.Buckets, TTLBucket{Max: bound, Count: counts[i+2] - previous})
+		previous = counts[i+2]
+	}
+	h.Beyond -= previous
+
+	return h, nil
+}
+
+// CountExpiringWithin returns the number of keys which will expire within d, e.g. to anticipate janitor load.
+// Unlike ExpiringWithin, the keys are only counted.
+func (m *SQLite) CountExpiringWithin(d time.Duration) (int64, error) {
+	counts, err := m.countTTLs(m.now(), []time.Duration{d})
+	if err != nil {
+		return 0, err
+	}
+
+	return counts[2], nil
+}
+
+// countTTLs counts in a single scan per database the unexpired keys without expiration, with expiration,
+// and expiring within each of the bounds from ts.
+func (m *SQLite) countTTLs(ts int64, bounds []time.Duration) ([]int64, error) {
+	if err := m.checkAccessAll(false); err != nil {
+		return nil, err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	columns := []string{"coalesce(sum(expires_at = 0), 0)", "coalesce(sum(expires_at > 0), 0)"}
+	var args []any
+	for _, bound := range bounds {
+		columns = append(columns, "coalesce(sum(expires_at > 0 AND expires_at <= ?), 0)")
+		args = append(args, ts+bound.Milliseconds())
+	}
+
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM kv WHERE key LIKE ? AND " + unexpired
+	args = append(args, m.Config.Prefix+"%", m.expiryCutoff())
+
+	counts := make([]int64, len(columns))
+	for _, database := range m.databases() {
+		row := make([]int64, len(columns))
+		dest := make([]any, len(columns))
+		for i := range row {
+			dest[i] = &row[i]
+		}
+
+		if err := m.db().in(database).QueryRowContext(context.Background(), query, args...).Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		for i, n := range row {
+			counts[i] += n
+		}
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,49 @@
+package kvsqlite
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTTLHistogram(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	client, err := New(&SQLiteConfig{
+		Path:   "/tmp/test.db",
+		Prefix: "go-zoox-test-ttl-stats:" + time.Now().String(),
+		Clock:  clock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	client.Set("persistent", 1)
+	client.Set("soon", 1, 30*time.Second)
+	client.Set("sooner", 1, 10*time.Second)
+	client.Set("later", 1, 10*time.Minute)
+	client.Set("much-later", 1, 48*time.Hour)
+	client.Set("expired", 1, time.Second)
+	clock.Add(2 * time.Second)
+
+	h, err := client.TTLHistogram(time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Persistent != 1 || h.Beyond != 1 || len(h.Buckets) != 2 || h.Buckets[0].Count != 2 || h.Buckets[1].Count != 1 {
+		t.Errorf("Unexpected histogram %+v", h)
+	}
+
+	if h, _ := client.TTLHistogram(); len(h.Buckets) != len(DefaultTTLBuckets) {
+		t.Errorf("Expected the default buckets, got %+v", h)
+	}
+	if _, err := client.TTLHistogram(time.Hour, time.Minute); err == nil {
+		t.Error("Expected an error for decreasing buckets")
+	}
+
+	if n, err := client.CountExpiringWithin(time.Minute); err != nil || n != 2 {
+		t.Errorf("Expected 2 keys expiring within a minute, got %d (%v)", n, err)
+	}
+	if n, _ := client.CountExpiringWithin(time.Hour); n != 3 {
+		t.Errorf("Expected 3 keys expiring within an hour, got %d", n)
+	}
+}
@@ -0,0 +1,74 @@
+package kvsqlite
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrInvalidJSON is returned by Update when fn returns a value which is not valid JSON.
+var ErrInvalidJSON = errors.New("sqlite: value is not valid JSON")
+
+// Update replaces the value of the given key with the result of fn, in a single write transaction,
+// so read-modify-write operations such as counters or merges of JSON objects don't lose concurrent updates.
+// fn gets the stored JSON value and whether the key exists (and is not expired), and returns the new JSON value,
+// or nil to leave the key unchanged. The key keeps its expiration, new keys get the default TTL of the store.
+// fn runs with the write lock held, so it must be quick and must not use the store.
+// A write of another process in between fails the update with ErrBusy instead of being overwritten.
+func (m *SQLite) Update(key string, fn func(current []byte, exists bool) ([]byte, error)) error {
+	return m.UpdateContext(context.Background(), key, fn)
+}
+
+// UpdateContext is like Update but honors ctx.
+func (m *SQLite) UpdateContext(ctx context.Context, key string, fn func(current []byte, exists bool) ([]byte, error)) (err error) {
+	start := m.recordStart()
+	defer func() { m.record("set", key, start, err) }()
+
+	keyX, err := m.writeKey(key)
+	if err != nil {
+		return err
+	}
+
+	written := false
+	err = m.writeTx(ctx, func(db *tracer) error {
+		written = false
+
+		r, err := m.readRow(ctx, db, keyX)
+		if err != nil {
+			return err
+		}
+
+		var current []byte
+		if r != nil {
+			if current, err = m.restore([]byte(r.value)); err != nil {
+				return &CorruptValueError{Key: key, Err: err}
+			}
+		}
+
+		next, err := fn(current, r != nil)
+		if err != nil || next == nil {
+			return err
+		}
+		if !json.Valid(next) {
+			return ErrInvalidJSON
+		}
+
+		valueX, err := m.transform(next)
+		if err != nil {
+			return err
+		}
+
+		written, err = m.applySet(ctx, db, &setWrite{key: key, keyX: keyX, valueX: valueX, opt: &setOptions{keepTTL: true}})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if written {
+		atomic.AddInt64(&m.counters.sets, 1)
+		m.publish(EventSet, key)
+	}
+	return nil
+}
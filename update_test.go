@@ -0,0 +1,79 @@
+package kvsqlite
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUpdate(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	incr := func(current []byte, exists bool) ([]byte, error) {
+		n := 0
+		if exists {
+			var err error
+			if n, err = strconv.Atoi(string(current)); err != nil {
+				return nil, err
+			}
+		}
+
+		return []byte(strconv.Itoa(n + 1)), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := client.Update("counter", incr); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var n int
+	if err := client.Get("counter", &n); err != nil || n != 20 {
+		t.Errorf("Expected 20, got %d (%v)", n, err)
+	}
+
+	unchanged := func(current []byte, exists bool) ([]byte, error) { return nil, nil }
+	if err := client.Update("missing", unchanged); err != nil {
+		t.Fatal(err)
+	}
+	if client.Has("missing") {
+		t.Error("Expected a nil result to leave the key unchanged")
+	}
+
+	invalid := func(current []byte, exists bool) ([]byte, error) { return []byte("{"), nil }
+	if err := client.Update("counter", invalid); !errors.Is(err, ErrInvalidJSON) {
+		t.Errorf("Expected ErrInvalidJSON, got %v", err)
+	}
+
+	failing := errors.New("failing")
+	if err := client.Update("counter", func(current []byte, exists bool) ([]byte, error) { return nil, failing }); !errors.Is(err, failing) {
+		t.Errorf("Expected the error of fn, got %v", err)
+	}
+}
+
+func TestUpdateKeepsTTL(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	client.Set("ttl", 1, time.Hour)
+	if err := client.Update("ttl", func(current []byte, exists bool) ([]byte, error) { return []byte("2"), nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	at, err := client.ExpiresAt("ttl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl := time.Until(at); ttl <= 0 || ttl > time.Hour {
+		t.Errorf("Expected the TTL to be kept, got %s", ttl)
+	}
+}
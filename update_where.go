@@ -0,0 +1,180 @@
+package kvsqlite
+
+import (
+	"context"
+)
+
+// UpdateWhereOptions select the keys rewritten by UpdateWhere.
+type UpdateWhereOptions struct {
+	// Pattern is a glob pattern of the keys to rewrite, e.g. "user:*", default is all keys.
+	Pattern string
+
+	// Where is an SQL condition on the stored JSON value, named value, e.g. "json_extract(value, '$.version') < ?",
+	// with Args bound to its placeholders. Default is all values. Transformed values are opaque to SQLite,
+	// so it can't be used with Config.Transformers.
+	Where string
+	Args  []any
+
+	// BatchSize is the number of keys read and rewritten per transaction, default is 500.
+	BatchSize int
+}
+
+// UpdateFunc returns the new value of key from its stored JSON value, or false to leave it unchanged.
+type UpdateFunc func(key string, raw []byte) (value any, ok bool, err error)
+
+// UpdateWhere rewrites the values of the keys selected by opt with fn, e.g. to migrate cached objects to a new schema,
+// and returns how many were rewritten. Keys are read in batches, fn runs without holding any lock,
+// and each batch is written in a single transaction, keeping the expiration of the keys.
+// Keys changed or removed since they were read are skipped, as are immutable keys.
+// It stops at the first error returned by fn, batches written before stay written.
+func (m *SQLite) UpdateWhere(opt *UpdateWhereOptions, fn UpdateFunc) (int64, error) {
+	return m.UpdateWhereContext(context.Background(), opt, fn)
+}
+
+// UpdateWhereContext is like UpdateWhere but honors ctx.
+func (m *SQLite) UpdateWhereContext(ctx context.Context, opt *UpdateWhereOptions, fn UpdateFunc) (int64, error) {
+	return m.updateWhere(ctx, opt, fn, nil)
+}
+
+// updateWhere runs UpdateWhere, or only reports the keys fn would rewrite to report if it is not nil.
+func (m *SQLite) updateWhere(ctx context.Context, opt *UpdateWhereOptions, fn UpdateFunc, report *DryRunReport) (int64, error) {
+	if err := m.checkAccessAll(true); err != nil {
+		return 0, err
+	}
+
+	if opt == nil {
+		opt = &UpdateWhereOptions{}
+	}
+
+	batchSize := opt.BatchSize
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	var total int64
+	after := ""
+	for {
+		page, err := m.updatePage(ctx, opt, after, batchSize)
+		if err != nil {
+			return total, err
+		}
+
+		type update struct {
+			stored string
+			w      *setWrite
+		}
+
+		updates := make([]update, 0, len(page))
+		for _, entry := range page {
+			raw, err := m.restore([]byte(entry.value))
+			if err != nil {
+				return total, err
+			}
+
+			value, ok, err := fn(entry.key, raw)
+			if err != nil {
+				return total, err
+			}
+			if !ok {
+				continue
+			}
+
+			if report != nil {
+				report.add(entry.key)
+				continue
+			}
+
+			valueX, err := m.encodeValue(value)
+			if err != nil {
+				return total, err
+			}
+
+			// stored keys are sanitized already
+			w := &setWrite{key: entry.key, keyX: m.Config.Prefix + entry.key, valueX: valueX, opt: &setOptions{keepTTL: true}}
+			updates = append(updates, update{entry.value, w})
+		}
+
+		if len(updates) > 0 {
+			written := make([]string, 0, len(updates))
+			err := m.writeTx(ctx, func(db *tracer) error {
+				written = written[:0]
+				for _, u := range updates {
+					r, err := m.readRow(ctx, db, u.w.keyX)
+					if err != nil {
+						return err
+					}
+					if r == nil || r.value != u.stored {
+						continue
+					}
+
+					if _, err := m.applySet(ctx, db, u.w); err != nil {
+						return err
+					}
+					written = append(written, u.w.key)
+				}
+
+				return nil
+			})
+			if err != nil {
+				return total, err
+			}
+
+			total += int64(len(written))
+			for _, key := range written {
+				m.publish(EventSet, key)
+			}
+		}
+
+		if len(page) < batchSize {
+			return total, nil
+		}
+		after = page[len(page)-1].key
+	}
+}
+
+type storedEntry struct {
+	key   string
+	value string
+}
+
+// updatePage returns up to limit mutable unexpired entries selected by opt with keys greater than after, ordered by key.
+func (m *SQLite) updatePage(ctx context.Context, opt *UpdateWhereOptions, after string, limit int) ([]storedEntry, error) {
+	pattern := opt.Pattern
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	where := opt.Where
+	if where == "" {
+		where = "1"
+	}
+
+	args := []any{globEscape(m.Config.Prefix) + pattern, m.reservedPattern(), m.Config.Prefix + after, m.expiryCutoff()}
+	args = append(args, opt.Args...)
+	args = append(args, limit)
+
+	m.RLock()
+	defer m.RUnlock()
+
+	rows, err := m.db().QueryContext(ctx,
+		"SELECT key, value FROM (SELECT key, "+valueExpr+" AS value FROM kv WHERE key GLOB ? AND "+notReserved+" AND key > ? AND immutable = 0 AND "+unexpired+") WHERE "+where+" ORDER BY key LIMIT ?",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := make([]storedEntry, 0, limit)
+	for rows.Next() {
+		var entry storedEntry
+		if err := rows.Scan(&entry.key, &entry.value); err != nil {
+			return nil, err
+		}
+
+		entry.key = entry.key[len(m.Config.Prefix):]
+		page = append(page, entry)
+	}
+
+	return page, rows.Err()
+}
@@ -0,0 +1,78 @@
+package kvsqlite
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type cachedUser struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	Email   string `json:"email,omitempty"`
+}
+
+func TestUpdateWhere(t *testing.T) {
+	client := createClient()
+	defer client.Clear()
+
+	for i := 0; i < 25; i++ {
+		client.Set(fmt.Sprintf("user:%d", i), cachedUser{Version: 1, Name: fmt.Sprint(i)}, time.Hour)
+	}
+	client.Set("user:new", cachedUser{Version: 2, Name: "new"})
+	client.Set("other", cachedUser{Version: 1, Name: "other"})
+	before, _ := client.ExpiresAt("user:3")
+
+	n, err := client.UpdateWhere(&UpdateWhereOptions{
+		Pattern:   "user:*",
+		Where:     "json_extract(value, '$.version') < ?",
+		Args:      []any{2},
+		BatchSize: 10,
+	}, func(key string, raw []byte) (any, bool, error) {
+		var user cachedUser
+		if err := json.Unmarshal(raw, &user); err != nil {
+			return nil, false, err
+		}
+
+		user.Version = 2
+		user.Email = user.Name + "@example.com"
+		return user, true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 25 {
+		t.Errorf("Expected 25 rewritten keys, got %d", n)
+	}
+
+	var user cachedUser
+	client.Get("user:3", &user)
+	if user.Version != 2 || user.Email != "3@example.com" {
+		t.Errorf("Expected the rewritten user, got %+v", user)
+	}
+	if after, _ := client.ExpiresAt("user:3"); !after.Equal(before) {
+		t.Errorf("Expected the expiration %v to be kept, got %v", before, after)
+	}
+
+	client.Get("other", &user)
+	if user.Version != 1 {
+		t.Errorf("Expected keys out of the pattern to be untouched, got %+v", user)
+	}
+
+	// returning false leaves the value unchanged
+	n, _ = client.UpdateWhere(nil, func(key string, raw []byte) (any, bool, error) {
+		return nil, false, nil
+	})
+	if n != 0 {
+		t.Errorf("Expected no rewritten key, got %d", n)
+	}
+
+	errStop := errors.New("stop")
+	if _, err := client.UpdateWhere(nil, func(key string, raw []byte) (any, bool, error) {
+		return nil, false, errStop
+	}); !errors.Is(err, errStop) {
+		t.Errorf("Expected the error of fn, got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package kvsqlite
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+)
+
+// VacuumConfig is the configuration of the background incremental vacuum.
+type VacuumConfig struct {
+	// Interval is how often fragmentation is checked, default is 1 minute.
+	Interval time.Duration
+
+	// Threshold is the fraction of free pages above which a vacuum runs, default is 0.1.
+	Threshold float64
+
+	// Pages is the maximum number of pages reclaimed per run, default (0) reclaims all free pages.
+	Pages int
+
+	// Window reports whether t is in a low-traffic window in which vacuum may run, default is always.
+	Window func(t time.Time) bool
+}
+
+// enableIncrementalVacuum switches the database to incremental auto-vacuum.
+// Changing the mode of an existing database needs a full VACUUM, which is done once here.
+func enableIncrementalVacuum(core *sql.DB) error {
+	var mode int
+	if err := core.QueryRow("PRAGMA auto_vacuum").Scan(&mode); err != nil {
+		return err
+	}
+
+	// 2 is INCREMENTAL
+	if mode == 2 {
+		return nil
+	}
+
+	if _, err := core.Exec("PRAGMA auto_vacuum = INCREMENTAL"); err != nil {
+		return err
+	}
+
+	_, err := core.Exec("VACUUM")
+	return err
+}
+
+// Fragmentation returns the fraction of pages in the database file which are free.
+func (m *SQLite) Fragmentation() (float64, error) {
+	if err := m.supports(FeaturePragmas); err != nil {
+		return 0, err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	var free, total int64
+	if err := m.db().QueryRow("PRAGMA freelist_count").Scan(&free); err != nil {
+		return 0, err
+	}
+	if err := m.db().QueryRow("PRAGMA page_count").Scan(&total); err != nil {
+		return 0, err
+	}
+
+	if total == 0 {
+		return 0, nil
+	}
+
+	return float64(free) / float64(total), nil
+}
+
+// IncrementalVacuum reclaims up to pages free pages, or all of them if pages is 0.
+// It requires incremental auto-vacuum, see Config.Vacuum. See Compact for a vacuum in steps with a report.
+func (m *SQLite) IncrementalVacuum(pages int) error {
+	if err := m.supports(FeaturePragmas); err != nil {
+		return err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	// the pragma reclaims one page per step, so the rows must be drained
+	rows, err := m.wdb().Query("PRAGMA incremental_vacuum(" + strconv.Itoa(pages) + ")")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+	}
+
+	return rows.Err()
+}
+
+// maintain runs an incremental vacuum when fragmentation exceeds the threshold inside the window.
+func (m *SQLite) maintain() {
+	cfg := m.Config.Vacuum
+	if cfg.Window != nil && !cfg.Window(m.clock().Now()) {
+		return
+	}
+
+	threshold := cfg.Threshold
+	if threshold <= 0 {
+		threshold = 0.1
+	}
+
+	fragmentation, err := m.Fragmentation()
+	if err != nil || fragmentation < threshold {
+		return
+	}
+
+	if err := m.IncrementalVacuum(cfg.Pages); err != nil && m.Config.Debug {
+		m.logger().Printf("[sqlite] incremental vacuum failed: %s", err)
+	}
+}
+
+func (m *SQLite) startVacuum() {
+	interval := m.Config.Vacuum.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	m.vacuum = newWorker(interval, m.maintain)
+}
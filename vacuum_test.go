@@ -0,0 +1,48 @@
+package kvsqlite
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIncrementalVacuum(t *testing.T) {
+	path := "/tmp/test-vacuum.db"
+	os.Remove(path)
+	defer os.Remove(path)
+
+	client, err := New(&SQLiteConfig{
+		Path:   path,
+		Prefix: "go-zoox-test:",
+		Vacuum: &VacuumConfig{Interval: time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	value := strings.Repeat("x", 4096)
+	for _, key := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		client.Set(key, value)
+	}
+	client.Clear()
+
+	before, err := client.Fragmentation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before == 0 {
+		t.Fatal("Expected free pages after clear")
+	}
+
+	client.maintain()
+
+	after, err := client.Fragmentation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after != 0 {
+		t.Errorf("Expected no free pages after vacuum, got %f", after)
+	}
+}
@@ -0,0 +1,140 @@
+package kvsqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Value column types.
+const (
+	// ValueColumnBlob declares the value column as BLOB, the default for compatibility.
+	ValueColumnBlob = "BLOB"
+	// ValueColumnText declares the value column as TEXT, so values are always text for SQLite JSON functions.
+	ValueColumnText = "TEXT"
+)
+
+// ErrValueColumnType is returned by New when the value column of an existing database
+// does not have the configured type. Convert it with MigrateValueColumn.
+var ErrValueColumnType = errors.New("sqlite: value column type mismatch")
+
+func valueColumnType(typ string) (string, error) {
+	switch strings.ToUpper(typ) {
+	case "", ValueColumnBlob:
+		return ValueColumnBlob, nil
+	case ValueColumnText:
+		return ValueColumnText, nil
+	default:
+		return "", fmt.Errorf("sqlite: unknown value column type %s", typ)
+	}
+}
+
+type columnInfo struct {
+	name       string
+	typ        string
+	notNull    bool
+	defaultVal sql.NullString
+	pk         bool
+}
+
+func tableColumns(core *sql.DB, table string) ([]columnInfo, error) {
+	rows, err := core.Query("SELECT name, type, \"notnull\", dflt_value, pk FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make([]columnInfo, 0)
+	for rows.Next() {
+		var c columnInfo
+		if err := rows.Scan(&c.name, &c.typ, &c.notNull, &c.defaultVal, &c.pk); err != nil {
+			return nil, err
+		}
+		columns = append(columns, c)
+	}
+
+	return columns, rows.Err()
+}
+
+// checkValueColumn checks that the value column of the kv table has the given type.
+func checkValueColumn(core *sql.DB, typ string) error {
+	columns, err := tableColumns(core, "kv")
+	if err != nil {
+		return err
+	}
+
+	for _, c := range columns {
+		if c.name == "value" && !strings.EqualFold(c.typ, typ) {
+			return fmt.Errorf("%w: value column is %s, configured %s", ErrValueColumnType, c.typ, typ)
+		}
+	}
+
+	return nil
+}
+
+// MigrateValueColumn converts the value column of the database at path to the given type (BLOB or TEXT),
+// casting the existing values. The table is rebuilt in a single transaction.
+func MigrateValueColumn(path string, typ string) error {
+	typ, err := valueColumnType(typ)
+	if err != nil {
+		return err
+	}
+
+	core, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer core.Close()
+
+	columns, err := tableColumns(core, "kv")
+	if err != nil {
+		return err
+	}
+
+	definitions := make([]string, len(columns))
+	names := make([]string, len(columns))
+	selects := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+		selects[i] = c.name
+
+		columnType := c.typ
+		if c.name == "value" {
+			columnType = typ
+			selects[i] = "CAST(value AS " + typ + ")"
+		}
+
+		definition := c.name + " " + columnType
+		if c.pk {
+			definition += " PRIMARY KEY"
+		}
+		if c.notNull {
+			definition += " NOT NULL"
+		}
+		if c.defaultVal.Valid {
+			definition += " DEFAULT " + c.defaultVal.String
+		}
+		definitions[i] = definition
+	}
+
+	tx, err := core.Begin()
+	if err != nil {
+		return err
+	}
+
+	statements := []string{
+		"CREATE TABLE kv_migrating (" + strings.Join(definitions, ", ") + ")",
+		"INSERT INTO kv_migrating (" + strings.Join(names, ", ") + ") SELECT " + strings.Join(selects, ", ") + " FROM kv",
+		"DROP TABLE kv",
+		"ALTER TABLE kv_migrating RENAME TO kv",
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,44 @@
+package kvsqlite
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestMigrateValueColumn(t *testing.T) {
+	path := "/tmp/test-value-column.db"
+	os.Remove(path)
+	defer os.Remove(path)
+
+	client, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Set("key", map[string]int{"a": 1})
+	client.Close()
+
+	if _, err := New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:", ValueColumnType: ValueColumnText}); !errors.Is(err, ErrValueColumnType) {
+		t.Fatalf("Expected ErrValueColumnType, got %v", err)
+	}
+
+	if err := MigrateValueColumn(path, ValueColumnText); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err = New(&SQLiteConfig{Path: path, Prefix: "go-zoox-test:", ValueColumnType: ValueColumnText})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var value map[string]int
+	if err := client.Get("key", &value); err != nil || value["a"] != 1 {
+		t.Errorf("Expected value to survive migration, got %v (%v)", value, err)
+	}
+
+	var a int
+	if err := client.Core.QueryRow("SELECT json_extract(value, '$.a') FROM kv").Scan(&a); err != nil || a != 1 {
+		t.Errorf("Expected JSON functions to work on TEXT values, got %d (%v)", a, err)
+	}
+}
@@ -0,0 +1,46 @@
+package kvsqlite
+
+import (
+	"sync"
+	"time"
+)
+
+// worker runs a function periodically in the background until stopped.
+type worker struct {
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func newWorker(interval time.Duration, fn func()) *worker {
+	w := &worker{
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(w.stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				fn()
+			case <-w.done:
+				return
+			}
+		}
+	}()
+
+	return w
+}
+
+// stop stops the worker and waits for a running call to return.
+func (w *worker) stop() {
+	w.once.Do(func() {
+		close(w.done)
+		<-w.stopped
+	})
+}
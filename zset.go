@@ -0,0 +1,210 @@
+package kvsqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// ZMember is a member of a sorted set with its score.
+type ZMember struct {
+	Member string
+	Score  float64
+}
+
+// createZSetSchema creates the table of the sorted sets, in the main database.
+// Keys are stored prefixed, so a single table serves every prefix and attached database.
+func createZSetSchema(core *sql.DB) error {
+	statements := []string{
+		"CREATE TABLE IF NOT EXISTS kv_zset (key TEXT NOT NULL, member TEXT NOT NULL, score REAL NOT NULL, PRIMARY KEY (key, member))",
+		"CREATE INDEX IF NOT EXISTS kv_zset_key_score ON kv_zset (key, score, member)",
+	}
+	for _, statement := range statements {
+		if _, err := core.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ZAdd adds member with score to the sorted set at key, or updates its score.
+// It reports whether the member was newly added.
+func (m *SQLite) ZAdd(key string, member string, score float64) (bool, error) {
+	keyX, err := m.writeKey(key)
+	if err != nil {
+		return false, err
+	}
+
+	ctx := context.Background()
+	var added bool
+	err = m.writeTx(ctx, func(db *tracer) error {
+		var exists int
+		err := db.QueryRowContext(ctx, "SELECT 1 FROM kv_zset WHERE key = ? AND member = ?", keyX, member).Scan(&exists)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		added = exists == 0
+
+		_, err = db.ExecContext(ctx,
+			"INSERT INTO kv_zset (key, member, score) VALUES (?, ?, ?) ON CONFLICT(key, member) DO UPDATE SET score = excluded.score",
+			keyX, member, score,
+		)
+		return err
+	})
+
+	return added, err
+}
+
+// ZRem removes member from the sorted set at key and reports whether it existed.
+func (m *SQLite) ZRem(key string, member string) (bool, error) {
+	keyX, err := m.writeKey(key)
+	if err != nil {
+		return false, err
+	}
+
+	ctx := context.Background()
+	var n int64
+	err = m.write(ctx, func(db *tracer) error {
+		res, err := db.ExecContext(ctx, "DELETE FROM kv_zset WHERE key = ? AND member = ?", keyX, member)
+		if err != nil {
+			return err
+		}
+
+		n, err = res.RowsAffected()
+		return err
+	})
+
+	return n > 0, err
+}
+
+// ZScore returns the score of member in the sorted set at key and whether it exists.
+func (m *SQLite) ZScore(key string, member string) (float64, bool, error) {
+	keyX, err := m.getKey(key)
+	if err != nil {
+		return 0, false, err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	var score float64
+	err = m.db().QueryRow("SELECT score FROM kv_zset WHERE key = ? AND member = ?", keyX, member).Scan(&score)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return score, true, nil
+}
+
+// ZCard returns the number of members of the sorted set at key.
+func (m *SQLite) ZCard(key string) (int, error) {
+	keyX, err := m.getKey(key)
+	if err != nil {
+		return 0, err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	var count int
+	err = m.db().QueryRow("SELECT count(*) FROM kv_zset WHERE key = ?", keyX).Scan(&count)
+	return count, err
+}
+
+// ZRank returns the rank (0-based, lowest score first) of member in the sorted set at key and whether it exists.
+func (m *SQLite) ZRank(key string, member string) (int, bool, error) {
+	score, found, err := m.ZScore(key, member)
+	if err != nil || !found {
+		return 0, false, err
+	}
+
+	keyX, err := m.getKey(key)
+	if err != nil {
+		return 0, false, err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	var rank int
+	err = m.db().QueryRow(
+		"SELECT count(*) FROM kv_zset WHERE key = ? AND (score < ? OR (score = ? AND member < ?))",
+		keyX, score, score, member,
+	).Scan(&rank)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return rank, true, nil
+}
+
+// ZRange returns the members of the sorted set at key ranked from start to stop (inclusive), lowest score first.
+// Negative indexes count from the end, e.g. -1 is the highest ranked member.
+func (m *SQLite) ZRange(key string, start, stop int) ([]ZMember, error) {
+	return m.zrange(key, start, stop, false)
+}
+
+// ZRevRange is like ZRange but ranks the highest score first.
+func (m *SQLite) ZRevRange(key string, start, stop int) ([]ZMember, error) {
+	return m.zrange(key, start, stop, true)
+}
+
+func (m *SQLite) zrange(key string, start, stop int, reverse bool) ([]ZMember, error) {
+	if start < 0 || stop < 0 {
+		count, err := m.ZCard(key)
+		if err != nil {
+			return nil, err
+		}
+
+		if start < 0 {
+			start += count
+		}
+		if stop < 0 {
+			stop += count
+		}
+	}
+	if start < 0 {
+		start = 0
+	}
+
+	members := make([]ZMember, 0)
+	if stop < start {
+		return members, nil
+	}
+
+	order := "score, member"
+	if reverse {
+		order = "score DESC, member DESC"
+	}
+
+	keyX, err := m.getKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	rows, err := m.db().Query(
+		"SELECT member, score FROM kv_zset WHERE key = ? ORDER BY "+order+" LIMIT ? OFFSET ?",
+		keyX, stop-start+1, start,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var z ZMember
+		if err := rows.Scan(&z.Member, &z.Score); err != nil {
+			return nil, err
+		}
+		members = append(members, z)
+	}
+
+	return members, rows.Err()
+}
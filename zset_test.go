@@ -0,0 +1,47 @@
+package kvsqlite
+
+import "testing"
+
+func TestZSet(t *testing.T) {
+	client := createClient()
+	client.Core.Exec("DELETE FROM kv_zset")
+
+	for member, score := range map[string]float64{"alice": 30, "bob": 10, "carol": 20} {
+		if added, err := client.ZAdd("board", member, score); err != nil || !added {
+			t.Fatalf("Expected %s to be added, got %v (%v)", member, added, err)
+		}
+	}
+	if added, _ := client.ZAdd("board", "bob", 40); added {
+		t.Error("Expected updating a score not to add a member")
+	}
+
+	top, err := client.ZRevRange("board", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) != 2 || top[0].Member != "bob" || top[1].Member != "alice" {
+		t.Errorf("Expected top 2 to be bob, alice, got %v", top)
+	}
+
+	if rank, found, _ := client.ZRank("board", "carol"); !found || rank != 0 {
+		t.Errorf("Expected carol to rank 0, got %d", rank)
+	}
+
+	last, _ := client.ZRange("board", -1, -1)
+	if len(last) != 1 || last[0].Member != "bob" {
+		t.Errorf("Expected highest member to be bob, got %v", last)
+	}
+
+	client.ZRem("board", "bob")
+	if n, _ := client.ZCard("board"); n != 2 {
+		t.Errorf("Expected 2 members, got %d", n)
+	}
+
+	// Clear removes the sorted sets of the prefix
+	if err := client.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := client.ZCard("board"); n != 0 {
+		t.Errorf("Expected Clear to remove the sorted set, got %d members", n)
+	}
+}